@@ -1,12 +1,16 @@
 package main
 
 import (
-	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/atsevan/airpollutionbot/translations"
+	"github.com/atsevan/airpollutionbot/translations"
 
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
@@ -16,21 +20,67 @@ import (
 )
 
 const (
-	authorContact     = "andrei+aqibot@tsevan.com"
-	aboutTextTmpl     = "Get the Air Quality Index (AQI) for the current location.\nContact: %s"
-	helpAQICmdMsg     = "/airQualityIndex - get the Air Quality Index for the location"
-	helpSubsCmdMsg    = "/subsriptions - list of the active subsriptions"
-	helpAboutCmdMsg   = "/about - into about the bot"
-	notifyMeCnfrmText = "OK. I will notify you if AQI changes in your location. /subsriptions"
-	cleanupNotifBtn   = "Cleanup AQI Subscriptions"
-	notifyMeDelText   = "OK. I won't notify you anymore"
-	safeToRetryErrMsg = "Error! Please, retry!"
-	numberSubsTmpl    = "You have %d subscription(s)"
-	aqiGetsWorseMsg   = "😷 AQI gets worse"
-	aqiGetsBetterMsg  = "😌 AQI gets better"
-	aqiText           = "Air Quality Index"
-	detailsText       = "Details"
-	unknownCmdMsg     = "Just share your location or try /start"
+	authorContact      = "andrei+aqibot@tsevan.com"
+	aboutTextTmpl      = "Get the Air Quality Index (AQI) for the current location.\nContact: %s"
+	helpAQICmdMsg      = "/airQualityIndex - get the Air Quality Index for the location"
+	helpSubsCmdMsg     = "/subsriptions - list of the active subsriptions"
+	helpAboutCmdMsg    = "/about - into about the bot"
+	notifyMePlaceTmpl  = "OK. I will notify you if AQI changes in %s (%.4f;%.4f). Current AQI: %s. /subsriptions"
+	notifyMeCoordsTmpl = "OK. I will notify you if AQI changes at %.4f;%.4f. Current AQI: %s. /subsriptions"
+	notifyMeExistsText = "This location is already subscribed. /subsriptions"
+	cleanupNotifBtn    = "Cleanup AQI Subscriptions"
+	notifyMeDelText    = "OK. I won't notify you anymore"
+	safeToRetryErrMsg  = "Error! Please, retry!"
+	numberSubsTmpl     = "You have %d subscription(s)"
+	aqiGetsWorseMsg    = "😷 AQI gets worse"
+	aqiGetsBetterMsg   = "😌 AQI gets better"
+	aqiPoorMsg         = "⚠️ Health warning: the air quality is poor"
+	aqiVeryPoorMsg     = "🚨 Health warning: the air quality is very poor"
+	limitOutdoorText   = "Limit outdoor activity and keep the windows closed"
+	callbackDoneText   = "✅ Done"
+	callbackFailedText = "❌ Failed. Please, retry!"
+	aqiText            = "Air Quality Index"
+	detailsText        = "Details"
+	unknownCmdMsg      = "Just share your location or try /start"
+	exportCaptionText  = "Your data stored by the bot"
+	forgetMeAskText    = "This deletes your location, settings, favorites, AQI history and subscriptions. Are you sure?"
+	forgetMeBtn        = "Yes, delete my data"
+	forgetMeDoneText   = "Done. All your data is deleted."
+	weatherTmpl        = "🌡 %.1f%s, 💨 %.1f %s"
+	weatherOnText      = "OK. AQI messages include the current weather"
+	weatherOffText     = "OK. AQI messages don't include the weather"
+	weatherUsageText   = "Usage: /weather on|off"
+	lastCheckedTmpl    = "Last checked: %s"
+	lastCheckedLayout  = "2006-01-02 15:04 MST"
+	notCheckedYetText  = "Not checked yet"
+	frequencySetTmpl   = "OK. I will check your subscriptions at most every %s"
+	frequencyDefText   = "OK. I will check your subscriptions every 30 minutes"
+	frequencyUsageMsg  = "Usage: /frequency hourly|daily|default or a duration like 3h"
+	highCOWarningTmpl  = "⚠️ High carbon monoxide (CO) level: %.0f μg/m³. Avoid busy roads and ventilate indoor spaces."
+
+	// DefaultDBPath is the SQLite DB file used if no other path is configured
+	DefaultDBPath = "./airpollutionbot.db"
+	// DefaultCacheTime is how long a DataPoint is served from the DB before fetching a new one
+	DefaultCacheTime = 10 * time.Minute
+	// DefaultCOThreshold is CO concentration in μg/m3 above which the CO warning is shown
+	DefaultCOThreshold = 9400
+	// DefaultCronConcurrency is the number of concurrent OWM API requests of Cron
+	DefaultCronConcurrency = 4
+	// DefaultDataPointRetention is how long CronCleanup keeps the DataPoints
+	DefaultDataPointRetention = 7 * 24 * time.Hour
+	// DefaultMaxSubscriptions is the number of the subscriptions a chat may have
+	DefaultMaxSubscriptions = 10
+
+	// editAQIMessageWindow is the time the last AQI message is edited instead of sending a new one
+	editAQIMessageWindow = 5 * time.Minute
+
+	minPollBackoff = time.Second
+	maxPollBackoff = 2 * time.Minute
+	// pollTimeout is the timeout in seconds of the getUpdates long polls
+	pollTimeout = 60
+	// telegramHTTPTimeout limits the requests of the default TelegramHTTPClient. It is above pollTimeout
+	// not to cut the long polls, a hung poll fails once it is exceeded.
+	telegramHTTPTimeout = 90 * time.Second
 )
 
 var (
@@ -44,17 +94,116 @@ var (
 	)
 	cleanupSubscriptionInline = tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(cleanupNotifBtn, "cleanup"),
+			callbackButton(cleanupNotifBtn, callbackCleanup),
 		),
 	)
 )
 
-func newLangPrinter(languageCode string) *message.Printer {
+// ErrInvalidTelegramToken is returned by NewBotWithOptions if Telegram rejects the token
+var ErrInvalidTelegramToken = errors.New("TELEGRAM_API_TOKEN is invalid or missing")
+
+// supportedLanguages are the languages of the message catalog. English, the fallback of the catalog, is the first one.
+var supportedLanguages = catalogLanguages()
+
+// langMatcher picks the closest supported language for a language code of Telegram
+var langMatcher = language.NewMatcher(supportedLanguages)
+
+func catalogLanguages() []language.Tag {
+	tags := []language.Tag{language.English}
+	for _, tag := range message.DefaultCatalog.Languages() {
+		if tag != language.English {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// loadTranslations merges the translation files of the dir into the message catalog and updates the supported languages
+func loadTranslations(dir string) error {
+	n, err := translations.LoadDir(dir)
+	if err != nil {
+		return err
+	}
+	supportedLanguages = catalogLanguages()
+	langMatcher = language.NewMatcher(supportedLanguages)
+	log.Printf("loaded %d translations from %s, languages: %v", n, dir, supportedLanguages)
+	return nil
+}
+
+// useLanguages limits the supported languages to the ones of the codes available in the message catalog.
+// The configured languages missing in the catalog are logged as warnings, the matcher would fall back to English for them.
+// All the catalog languages are supported if there are no codes. English is always supported as the fallback.
+func useLanguages(codes []string) {
+	available := catalogLanguages()
+	log.Printf("message catalog languages: %v", available)
+	if len(codes) == 0 {
+		return
+	}
+
+	tags := []language.Tag{language.English}
+	for _, code := range codes {
+		tag, err := language.Parse(code)
+		if err != nil {
+			log.Printf("WARNING: supported language %q is invalid: %v", code, err)
+			continue
+		}
+		switch {
+		case !containsLanguage(available, tag):
+			log.Printf("WARNING: supported language %s has no translations, English is shown instead", tag)
+		case !containsLanguage(tags, tag):
+			tags = append(tags, tag)
+		}
+	}
+	for _, tag := range available {
+		if !containsLanguage(tags, tag) {
+			log.Printf("WARNING: the %s translations are not used, the language is not supported", tag)
+		}
+	}
+	supportedLanguages = tags
+	langMatcher = language.NewMatcher(supportedLanguages)
+	log.Printf("supported languages: %v", supportedLanguages)
+}
+
+func containsLanguage(tags []language.Tag, tag language.Tag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultLanguage is the language of the users without a valid language code, e.g. an empty one
+var defaultLanguage = language.English
+
+// setDefaultLanguage sets defaultLanguage to the supported language closest to the languageCode
+func setDefaultLanguage(languageCode string) error {
+	lang, err := language.Parse(languageCode)
+	if err != nil {
+		return fmt.Errorf("default language: %w", err)
+	}
+	_, i, confidence := langMatcher.Match(lang)
+	if confidence == language.No {
+		return fmt.Errorf("default language %q is not supported, supported: %v", languageCode, supportedLanguages)
+	}
+	defaultLanguage = supportedLanguages[i]
+	return nil
+}
+
+// matchLanguage returns the supported language closest to the languageCode, e.g. ru for ru-RU.
+// English if there is none, defaultLanguage if the code is empty or invalid.
+func matchLanguage(languageCode string) language.Tag {
 	lang, err := language.Parse(languageCode)
 	if err != nil {
 		log.Print("newLangPrinter: ", err)
-		lang = language.English
+		return defaultLanguage
 	}
+	_, i, _ := langMatcher.Match(lang)
+	return supportedLanguages[i]
+}
+
+func newLangPrinter(languageCode string) *message.Printer {
+	lang := matchLanguage(languageCode)
 	log.Print("message.Printer: lang ", lang)
 	return message.NewPrinter(lang)
 }
@@ -63,73 +212,431 @@ type AQIProvider interface {
 	GetAirPollution(l *Location) (*ApiPollutionResponse, error)
 }
 
+// AQIPreviewer is an AQIProvider getting the air pollution without storing anything, not even the raw response
+type AQIPreviewer interface {
+	PreviewAirPollution(l *Location) (*ApiPollutionResponse, error)
+}
+
+// WeatherProvider returns the current weather for a location
+type WeatherProvider interface {
+	GetCurrentWeather(l *Location) (*Weather, error)
+}
+
+// Bot serves the updates of a Telegram token. The Bots of several tokens of a process share botServices
+// and the DB, each one notifying its own subscriptions.
 type Bot struct {
 	tApi  *tgbotapi.BotAPI
 	store *Store
-	wAPI  AQIProvider
+	*botServices
+
+	inlineLimiter    inlineLimiter
+	callbacks        callbackDeduper
+	pendingLocations pendingLocations
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	// cronMu prevents overlapping Cron runs from notifying twice
+	cronMu sync.Mutex
+}
+
+// botServices are the clients and the settings shared by the Bots of all the tokens
+type botServices struct {
+	wAPI    AQIProvider
+	weather WeatherProvider
+	// geocoder finds the places of inline queries and names the subscribed locations
+	geocoder Geocoder
+	tracer   Tracer
+	debug    bool
+	adminID  int64
+	// telegramClient performs the requests of the Telegram clients of all the tokens
+	telegramClient tgbotapi.HTTPClient
+	// coThreshold is CO concentration in μg/m3 above which the CO warning is shown
+	coThreshold float64
+	// dataPointsPerChat is the number of DataPoints CronCleanup keeps per subscribed chat. 0 keeps all of them.
+	dataPointsPerChat int
+	// dataPointRetention is how long CronCleanup keeps the DataPoints. 0 keeps all of them.
+	dataPointRetention time.Duration
+	// hysteresisMinDelta is the AQI change notified at once. Smaller changes are notified if they hold for two Cron runs.
+	// Every change is notified at once if 0.
+	hysteresisMinDelta int
+	// cronConcurrency is the number of concurrent OWM API requests of Cron, /nearby and /route
+	cronConcurrency int
+	// notifyCooldown is the minimal interval between the notifications of a subscription unless overridden by UserPrefs.
+	// Not limited if 0.
+	notifyCooldown time.Duration
+	// disabledCommands are the names of the commands rejected by the bot
+	disabledCommands map[string]bool
+	// rawCaptureRetention is how long CronCleanup keeps the raw OWM API responses. They aren't stored if 0.
+	rawCaptureRetention time.Duration
+	// cleanupInterval is how often CronCleanup runs. Unknown if 0.
+	cleanupInterval time.Duration
+	// spikeRatio is the rise of a pollutant between two checks of a subscription notified within the same AQI.
+	// Disabled if 0.
+	spikeRatio float64
+	// maintenance is the maintenance mode stored in the DB. Only the admin is served and Cron doesn't notify.
+	maintenance atomic.Bool
+	referrals   referralCounter
+}
+
+// BotOptions keeps the settings of a Bot. Zero values fall back to the defaults.
+type BotOptions struct {
+	TelegramAPIToken string
+	OWMApiToken      string
+	// DBPath is the SQLite DB file. DefaultDBPath if empty.
+	DBPath string
+	// CacheTime is how long a DataPoint is served from the DB. DefaultCacheTime if 0.
+	CacheTime time.Duration
+	// CoordinatePrecision is the number of decimals the stored coordinates are rounded to. Not rounded if 0.
+	CoordinatePrecision int
+	// MaxSubscriptions is the number of the subscriptions a chat may have. Not limited if 0.
+	MaxSubscriptions int
+	// DuplicateDistance is the distance in meters below which two subscriptions of a chat are the same location.
+	// DefaultDuplicateDistance if 0.
+	DuplicateDistance float64
+	// AdminID is a Telegram UserID allowed to run admin commands. 0 disables them.
+	AdminID int64
+	// COThreshold is CO concentration in μg/m3 above which the CO warning is shown. DefaultCOThreshold if 0.
+	COThreshold float64
+	// DataPointsPerChat is the number of DataPoints CronCleanup keeps per subscribed chat. 0 keeps all of them.
+	DataPointsPerChat int
+	// DataPointRetention is how long CronCleanup keeps the DataPoints. 0 keeps all of them.
+	DataPointRetention time.Duration
+	// DataPointMinChange is the relative change of a component below which Cron doesn't store
+	// a DataPoint of the same AQI as the last one. All of them are stored if 0.
+	DataPointMinChange float64
+	// SpikeRatio is the rise of a pollutant between two checks of a subscription, like 2 for doubling,
+	// notified even if the AQI is the same. Disabled if 0.
+	SpikeRatio float64
+	// HysteresisMinDelta is the AQI change notified at once. Smaller changes are notified if they hold for two Cron runs.
+	// Every change is notified at once if 0.
+	HysteresisMinDelta int
+	// CronConcurrency is the number of concurrent OWM API requests of Cron. DefaultCronConcurrency if 0.
+	CronConcurrency int
+	// NotifyCooldown is the minimal interval between the notifications of a subscription. Not limited if 0.
+	NotifyCooldown time.Duration
+	// DisabledCommands are the names of the commands rejected by the bot and not listed in /help and the command menu
+	DisabledCommands []string
+	// ReferralCodes are the referral codes of the /start deep links counted by name. The first maxReferralCodes
+	// ones are counted if empty.
+	ReferralCodes []string
+	// RawCaptureRetention is how long the raw OWM API air pollution responses are kept. They aren't stored if 0.
+	RawCaptureRetention time.Duration
+	// CleanupInterval is how often CronCleanup is scheduled. It is only reported by /privacy.
+	CleanupInterval time.Duration
+	// OWMApiEndpoint is the base URL of OWM API air pollution and weather requests. OWMApiEndpoint if empty.
+	OWMApiEndpoint string
+	// OWMFailureThreshold is the number of OWM API air pollution requests failed in a row alerting the admin.
+	// Disabled if 0.
+	OWMFailureThreshold int
+	// HTTPClient performs requests to OWM API. An http.Client with HTTPTimeout if nil.
+	HTTPClient HTTPClient
+	// TelegramHTTPClient performs requests to Telegram. An http.Client with telegramHTTPTimeout if nil.
+	TelegramHTTPClient tgbotapi.HTTPClient
+	// HTTPTimeout limits requests to OWM API of the default HTTPClient. DefaultHTTPTimeout if 0.
+	HTTPTimeout time.Duration
+	// Tracer traces update handling and OWM API calls. Tracing is disabled if nil.
+	Tracer Tracer
+	Debug  bool
+}
+
+func (o *BotOptions) setDefaults() {
+	if o.DBPath == "" {
+		o.DBPath = DefaultDBPath
+	}
+	if o.CronConcurrency <= 0 {
+		o.CronConcurrency = DefaultCronConcurrency
+	}
+	if o.Tracer == nil {
+		o.Tracer = noopTracer{}
+	}
+	if o.CacheTime == 0 {
+		o.CacheTime = DefaultCacheTime
+	}
+	if o.COThreshold == 0 {
+		o.COThreshold = DefaultCOThreshold
+	}
+	if o.HTTPTimeout == 0 {
+		o.HTTPTimeout = DefaultHTTPTimeout
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = &http.Client{Timeout: o.HTTPTimeout}
+	}
+	if o.TelegramHTTPClient == nil {
+		o.TelegramHTTPClient = &http.Client{Timeout: telegramHTTPTimeout}
+	}
+}
+
+// NewBot creates a PollutionBot storing its data in the SQLite DB at dbPath. Returns Bot and cleanUp() function.
+func NewBot(telegramAPIToken, owmApiToken, dbPath string, debug bool) (*Bot, func(), error) {
+	return NewBotWithOptions(BotOptions{
+		TelegramAPIToken: telegramAPIToken,
+		OWMApiToken:      owmApiToken,
+		DBPath:           dbPath,
+		Debug:            debug,
+	})
 }
 
-// NewBot creates a PollutionBot. Returns Bot and cleanUp() function.
-func NewBot(telegramAPIToken, owmApiToken string, debug bool) (*Bot, func()) {
+// NewBotWithOptions creates a PollutionBot configured by opts. Returns Bot and cleanUp() function.
+// ErrInvalidTelegramToken or ErrInvalidOWMToken is returned if an API rejects the token.
+func NewBotWithOptions(opts BotOptions) (*Bot, func(), error) {
+	opts.setDefaults()
 
-	botapi, err := tgbotapi.NewBotAPI(telegramAPIToken)
+	botapi, err := newBotAPI(opts.TelegramAPIToken, opts.TelegramHTTPClient)
 	if err != nil {
-		log.Panic("failed to create a tgbotapi client:", err)
+		return nil, nil, err
 	}
 
-	owmapi, err := NewOpenWheatherMapApi(owmApiToken)
+	owmapi, err := NewOpenWheatherMapApiWithClient(opts.OWMApiToken, opts.HTTPClient)
 	if err != nil {
-		log.Panic("failed to create an openwhethermapapi client:", err)
+		return nil, nil, fmt.Errorf("creating an OWM API client: %w", err)
 	}
 
-	if debug {
+	owmapi.Tracer = opts.Tracer
+	if opts.OWMApiEndpoint != "" {
+		if err := owmapi.SetAPIEndpoint(opts.OWMApiEndpoint); err != nil {
+			return nil, nil, err
+		}
+	}
+	if opts.Debug {
 		botapi.Debug = true
 		owmapi.Debug = true
 	}
+	if err := owmapi.CheckToken(); errors.Is(err, ErrInvalidOWMToken) {
+		return nil, nil, err
+	} else if err != nil {
+		// OWM API being down doesn't stop the bot, the requests are retried by Cron
+		log.Print("CheckToken: ", err)
+	}
 
-	db, err := sql.Open("sqlite3", "./airpollutionbot.db")
+	store, err := OpenStore(opts.DBPath)
 	if err != nil {
-		log.Panic("creating DB client: ", err)
+		return nil, nil, err
 	}
+	store.CacheTime = opts.CacheTime
+	store.CoordinatePrecision = opts.CoordinatePrecision
+	store.MaxSubscriptions = opts.MaxSubscriptions
+	store.DataPointMinChange = opts.DataPointMinChange
+	store.DuplicateDistance = opts.DuplicateDistance
+	if opts.RawCaptureRetention > 0 {
+		owmapi.RawCapture = store
+	}
+
+	services := &botServices{
+		wAPI:     owmapi,
+		weather:  owmapi,
+		geocoder: &cachedGeocoder{Geocoder: owmapi, store: store},
+		tracer:   opts.Tracer,
+		debug:    opts.Debug,
+		adminID:  opts.AdminID,
+
+		coThreshold:        opts.COThreshold,
+		dataPointsPerChat:  opts.DataPointsPerChat,
+		dataPointRetention: opts.DataPointRetention,
+		hysteresisMinDelta: opts.HysteresisMinDelta,
+		cronConcurrency:    opts.CronConcurrency,
 
-	store := &Store{
-		DB:        db,
-		CacheTime: 10 * time.Minute,
+		rawCaptureRetention: opts.RawCaptureRetention,
+		notifyCooldown:      opts.NotifyCooldown,
+		cleanupInterval:     opts.CleanupInterval,
+		spikeRatio:          opts.SpikeRatio,
+		telegramClient:      opts.TelegramHTTPClient,
+		disabledCommands:    map[string]bool{},
 	}
-	if err := store.Init(); err != nil {
-		log.Panic("cannot init DB: ", err)
+	bot := newRunner(botapi, store, services)
+	for _, name := range opts.DisabledCommands {
+		bot.disabledCommands[strings.TrimPrefix(name, "/")] = true
 	}
-
-	bot := &Bot{
-		tApi:  botapi,
-		store: store,
-		wAPI:  owmapi,
+	if len(opts.ReferralCodes) > 0 {
+		bot.referrals.codes = map[string]bool{}
+		for _, code := range opts.ReferralCodes {
+			bot.referrals.codes[code] = true
+		}
+	}
+	maintenance, err := store.Maintenance()
+	if err != nil {
+		log.Print("Maintenance: ", err)
+	}
+	bot.maintenance.Store(maintenance)
+	if opts.OWMFailureThreshold > 0 {
+		owmapi.Failures = &FailureMonitor{Threshold: opts.OWMFailureThreshold, OnAlert: bot.alertOWMFailures}
 	}
 
 	log.Printf("Authorized on account %s", botapi.Self.UserName)
+	bot.setMyCommands()
 
 	return bot, func() {
-		db.Close()
+		store.DB.Close()
+	}, nil
+}
+
+// NewRunner creates a Bot of another Telegram token sharing the services and the DB of the bot.
+// Its subscriptions are stored under the Telegram ID of the bot of the token.
+// ErrInvalidTelegramToken is returned if Telegram rejects the token.
+func (bot *Bot) NewRunner(token string) (*Bot, error) {
+	botapi, err := newBotAPI(token, bot.telegramClient)
+	if err != nil {
+		return nil, err
+	}
+	if botapi.Self.ID == bot.tApi.Self.ID {
+		return nil, fmt.Errorf("the token of %s is already used", botapi.Self.UserName)
+	}
+	botapi.Debug = bot.debug
+	runner := newRunner(botapi, bot.store.ForBot(botapi.Self.ID), bot.botServices)
+
+	log.Printf("Authorized on account %s", botapi.Self.UserName)
+	runner.setMyCommands()
+	return runner, nil
+}
+
+// newRunner returns a Bot serving the updates of the Telegram client with the store and the services
+func newRunner(botapi *tgbotapi.BotAPI, store *Store, services *botServices) *Bot {
+	return &Bot{
+		tApi:        botapi,
+		store:       store,
+		botServices: services,
+		stop:        make(chan struct{}),
 	}
 }
 
-// Run listens to Updates and process them by gorourines
+// newBotAPI creates a Telegram client performing requests by the HTTP client.
+// It returns ErrInvalidTelegramToken if the token is empty or rejected by Telegram.
+func newBotAPI(token string, client tgbotapi.HTTPClient) (*tgbotapi.BotAPI, error) {
+	if token == "" {
+		return nil, ErrInvalidTelegramToken
+	}
+	botapi, err := tgbotapi.NewBotAPIWithClient(token, tgbotapi.APIEndpoint, client)
+	var tgErr *tgbotapi.Error
+	if errors.As(err, &tgErr) && (tgErr.Code == http.StatusUnauthorized || tgErr.Code == http.StatusNotFound) {
+		return nil, ErrInvalidTelegramToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to Telegram: %w", err)
+	}
+	return botapi, nil
+}
+
+// Run long-polls Updates and process them by gorourines until Stop is called.
+// Failed polls are retried with an exponential backoff, resuming from the last processed Update.
 func (bot *Bot) Run() {
+	// getUpdates is rejected while a webhook is set, e.g. after running in the webhook mode
+	if _, err := bot.tApi.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Print("deleteWebhook: ", err)
+	}
+
 	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := bot.tApi.GetUpdatesChan(u)
+	u.Timeout = pollTimeout
+	backoff := minPollBackoff
+
+	for {
+		select {
+		case <-bot.stop:
+			return
+		default:
+		}
+
+		updates, err := bot.tApi.GetUpdates(u)
+		if err != nil {
+			log.Printf("GetUpdates: %v. Retrying in %v", err, backoff)
+			select {
+			case <-bot.stop:
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > maxPollBackoff {
+				backoff = maxPollBackoff
+			}
+			continue
+		}
+		backoff = minPollBackoff
+
+		for _, update := range updates {
+			if update.UpdateID < u.Offset {
+				continue
+			}
+			u.Offset = update.UpdateID + 1
+			go bot.handleUpdate(update)
+		}
+	}
+}
+
+// Stop stops receiving Updates. Run returns after the current poll is finished.
+func (bot *Bot) Stop() {
+	bot.stopOnce.Do(func() {
+		close(bot.stop)
+	})
+}
 
-	for update := range updates {
-		go bot.handleUpdate(update)
+// RunAll polls the updates of the bots concurrently until all of them are stopped
+func RunAll(bots []*Bot) {
+	var wg sync.WaitGroup
+	for _, b := range bots {
+		wg.Add(1)
+		go func(b *Bot) {
+			defer wg.Done()
+			b.Run()
+		}(b)
 	}
+	wg.Wait()
 }
 
 func (bot *Bot) handleUpdate(update tgbotapi.Update) {
+	span := bot.tracer.Start("handleUpdate")
+	defer span.End()
+
 	switch {
 	case update.Message != nil:
 		bot.handleMessage(update.Message)
 	case update.CallbackQuery != nil:
 		bot.handleCallbackQuery(update.CallbackQuery)
+	case update.InlineQuery != nil:
+		bot.handleInlineQuery(update.InlineQuery)
+	case update.MyChatMember != nil:
+		bot.handleMyChatMember(update.MyChatMember)
+	default:
+		if bot.debug {
+			log.Printf("unhandled update %d: %s", update.UpdateID, updateType(&update))
+		}
+	}
+}
+
+// updateType returns a name of the Update payload for logging purposes
+func updateType(update *tgbotapi.Update) string {
+	switch {
+	case update.EditedMessage != nil:
+		return "edited_message"
+	case update.ChannelPost != nil:
+		return "channel_post"
+	case update.EditedChannelPost != nil:
+		return "edited_channel_post"
+	case update.InlineQuery != nil:
+		return "inline_query"
+	case update.ChosenInlineResult != nil:
+		return "chosen_inline_result"
+	case update.ShippingQuery != nil:
+		return "shipping_query"
+	case update.PreCheckoutQuery != nil:
+		return "pre_checkout_query"
+	case update.Poll != nil:
+		return "poll"
+	case update.PollAnswer != nil:
+		return "poll_answer"
+	case update.ChatMember != nil:
+		return "chat_member"
+	case update.ChatJoinRequest != nil:
+		return "chat_join_request"
+	}
+	return "unknown"
+}
+
+// handleMyChatMember disables subscriptions of a chat where the bot was blocked or kicked
+func (bot *Bot) handleMyChatMember(upd *tgbotapi.ChatMemberUpdated) {
+	if !upd.NewChatMember.WasKicked() && !upd.NewChatMember.HasLeft() {
+		return
+	}
+	log.Printf("bot is %s in chat %d, disabling subscriptions", upd.NewChatMember.Status, upd.Chat.ID)
+	if err := bot.store.DeleteAQISubscriptions(upd.Chat.ID); err != nil {
+		log.Print("DeleteAQISubscriptions: ", err)
 	}
 }
 
@@ -158,15 +665,19 @@ func (bot *Bot) handleLocationMessage(msg *tgbotapi.Message) {
 	us.SetLocation(location)
 
 	if err := bot.store.UpdateUserSession(us); err != nil {
-		log.Panic("UpdateUserSession: ", err)
+		log.Print("UpdateUserSession: ", err)
+		bot.Send(tgbotapi.NewMessage(chatID, p.Sprintf(safeToRetryErrMsg)))
+		return
 	}
 
-	dp, err := bot.store.GetLastPD(chatID)
+	// the DataPoints are cached by the location, the ones of the other subscriptions of the chat aren't shown.
+	// A DataPoint failed to read is fetched again like an expired one
+	dp, err := bot.store.GetLastPDAt(chatID, location)
 	if err != nil {
-		log.Panic("GetLastPD: ", err)
+		log.Print("GetLastPDAt: ", err)
 	}
 
-	// Caching pollution results for bot.store.CacheTime (10 min)
+	// Caching pollution results for bot.store.CacheTime (DefaultCacheTime)
 	if time.Since(time.Unix(dp.Dt, 0)) > bot.store.CacheTime {
 		resp, err := bot.wAPI.GetAirPollution(location)
 		if err != nil {
@@ -174,48 +685,123 @@ func (bot *Bot) handleLocationMessage(msg *tgbotapi.Message) {
 			bot.Send(tgbotapi.NewMessage(chatID, p.Sprintf(safeToRetryErrMsg)))
 			return
 		}
-		if err := bot.store.AddDataPoint(chatID, &resp.DP); err != nil {
-			log.Panic("AddDataPoint: ", err)
+		if err := bot.store.AddDataPointAt(chatID, location, &resp.DP); err != nil {
+			log.Print("AddDataPointAt: ", err)
+			bot.Send(tgbotapi.NewMessage(chatID, p.Sprintf(safeToRetryErrMsg)))
+			return
 		}
-		dp, err = bot.store.GetLastPD(chatID)
+		dp, err = bot.store.GetLastPDAt(chatID, location)
 		if err != nil {
-			log.Panic("GetLastPD: ", err)
+			log.Print("GetLastPDAt: ", err)
+			bot.Send(tgbotapi.NewMessage(chatID, p.Sprintf(safeToRetryErrMsg)))
+			return
 		}
 	}
 
-	msgText := []string{
-		p.Sprintf(aqiText) + ": " + p.Sprintf(dp.Main.Aqi.String()),
-		"",
-		p.Sprintf(dp.Main.Aqi.Description()),
+	prefs, err := bot.store.GetUserPrefs(chatID)
+	if err != nil {
+		log.Print("GetUserPrefs: ", err)
 	}
 
-	tgMsg := tgbotapi.NewMessage(chatID, strings.Join(msgText, "\n"))
+	msgText := []string{FormatAQIMessage(dp, p, "", prefs.Profile, prefs.Scale, prefs.Theme)}
+	if w := bot.coWarning(dp, p); w != "" {
+		msgText = append(msgText, "", w)
+	}
+	msgText = append(msgText, "", HumanizeSince(time.Unix(dp.Dt, 0), p))
 
-	// show inline buttons - details and notifyMe
-	tgMsg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(
+	if prefs.Weather {
+		// AQI is still shown if the weather is not available
+		w, err := bot.weather.GetCurrentWeather(location)
+		if err != nil {
+			log.Print("GetCurrentWeather: ", err)
+		} else {
+			temp, speed := prefs.Units.Temperature(), prefs.Units.Speed()
+			msgText = append(msgText, p.Sprintf(weatherTmpl,
+				temp.FromMetric(w.Main.Temp), temp.Localized(p), speed.FromMetric(w.Wind.Speed), speed.Localized(p)))
+		}
+	}
+
+	// show inline buttons - details and notifyMe. notifyMe is hidden at the subscription limit.
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if !bot.atSubscriptionLimit(chatID) {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			callbackButton(
 				p.Sprintf("Notify Me on AQI changes"),
-				"notifyMe",
-			),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(
-				p.Sprintf(detailsText),
-				"details",
+				callbackNotifyMe,
+				locationCallbackArgs(location)...,
 			),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		callbackButton(
+			p.Sprintf(detailsText),
+			callbackDetails,
 		),
-	)
-	bot.Send(tgMsg)
+	))
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	bot.sendOrEditAQIMessage(chatID, strings.Join(msgText, "\n"), markup)
+	if prefs.Map {
+		bot.sendAQIVenue(chatID, location, dp.GetAQI(), p)
+	}
 }
 
-func (bot *Bot) Send(tgMsg tgbotapi.MessageConfig) {
-	if _, err := bot.tApi.Send(tgMsg); err != nil {
+// sendOrEditAQIMessage edits the last AQI message of the chat if it was sent within editAQIMessageWindow.
+// Otherwise, or if editing fails, it sends a new message.
+func (bot *Bot) sendOrEditAQIMessage(chatID int64, text string, markup tgbotapi.InlineKeyboardMarkup) {
+	messageID, sentAt, err := bot.store.GetLastMessage(chatID)
+	if err != nil {
+		log.Print("GetLastMessage: ", err)
+	}
+	if messageID != 0 && time.Since(sentAt) < editAQIMessageWindow {
+		edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, markup)
+		_, err := bot.tApi.Send(edit)
+		if err == nil {
+			if err := bot.store.SetLastMessage(chatID, messageID, time.Now()); err != nil {
+				log.Print("SetLastMessage: ", err)
+			}
+			return
+		}
+		log.Print("failed to edit the AQI message, sending a new one: ", err)
+	}
+
+	tgMsg := tgbotapi.NewMessage(chatID, text)
+	tgMsg.ReplyMarkup = markup
+	sent, err := bot.tApi.Send(tgMsg)
+	if err != nil {
+		log.Print("failed to send a telegram message: ", err)
+		return
+	}
+	if err := bot.store.SetLastMessage(chatID, sent.MessageID, time.Now()); err != nil {
+		log.Print("SetLastMessage: ", err)
+	}
+}
+
+// coWarning returns a warning if CO concentration of the DataPoint exceeds bot.coThreshold. Or an empty string
+func (bot *Bot) coWarning(dp *DataPoint, p *message.Printer) string {
+	co, ok := dp.Components["co"]
+	if !ok || co <= bot.coThreshold {
+		return ""
+	}
+	return p.Sprintf(highCOWarningTmpl, co)
+}
+
+// Send sends the message and logs the error, if any. The error is returned for the callers counting failures.
+func (bot *Bot) Send(tgMsg tgbotapi.Chattable) error {
+	_, err := bot.tApi.Send(tgMsg)
+	if err != nil {
 		log.Print("failed to send a telegram message: ", err)
 	}
+	return err
 }
 
 func (bot *Bot) handleMessage(msg *tgbotapi.Message) {
+	if bot.inMaintenance(msg.From.ID) {
+		bot.replyMaintenance(msg)
+		return
+	}
+	// any message answers the location request of /air, e.g. the location or another command
+	bot.pendingLocations.Resolve(msg.Chat.ID)
+
 	if msg.IsCommand() {
 		bot.handleCommand(msg)
 		return
@@ -247,8 +833,20 @@ func (bot *Bot) handleCommand(msg *tgbotapi.Message) {
 	tgMsg := tgbotapi.NewMessage(chatID, "")
 	tgMsg.ReplyToMessageID = msg.MessageID
 
+	if !bot.commandEnabled(msg.Command()) {
+		tgMsg.Text = p.Sprintf(cmdDisabledMsg)
+		bot.Send(tgMsg)
+		return
+	}
+
 	switch msg.Command() { // Extract the command from the Message.
 	case "airQualityIndex", "air":
+		// the coordinates of the argument are handled as a shared location
+		if location, err := ParseLocation(msg.CommandArguments()); err == nil {
+			bot.handleLocationMessage(locationMessage(msg, location))
+			return
+		}
+		bot.pendingLocations.Request(chatID, languageCode, time.Now())
 		tgMsg.Text = p.Sprintf("Share location!")
 		btn := tgbotapi.KeyboardButton{
 			RequestLocation: true,
@@ -256,6 +854,10 @@ func (bot *Bot) handleCommand(msg *tgbotapi.Message) {
 		}
 		tgMsg.ReplyMarkup = tgbotapi.NewOneTimeReplyKeyboard([]tgbotapi.KeyboardButton{btn})
 	case "start":
+		payload, err := parseStartPayload(msg.CommandArguments())
+		if err != nil {
+			log.Print("parseStartPayload: ", err)
+		}
 		msgText := []string{
 			p.Sprintf(helpAQICmdMsg),
 			p.Sprintf(helpSubsCmdMsg),
@@ -263,6 +865,18 @@ func (bot *Bot) handleCommand(msg *tgbotapi.Message) {
 		}
 		tgMsg.Text = strings.Join(msgText, "\n")
 		tgMsg.ReplyMarkup = keyboardCmds
+		if payload == nil {
+			break
+		}
+		if payload.referral != "" {
+			bot.referrals.Count(payload.referral)
+		}
+		if payload.location != nil {
+			// the shared location is handled after the help, offering to subscribe to it
+			bot.Send(tgMsg)
+			bot.handleLocationMessage(locationMessage(msg, payload.location))
+			return
+		}
 	case "subsriptions":
 		subs, err := bot.store.ListAQISubscriptions(chatID)
 		if err != nil {
@@ -272,19 +886,155 @@ func (bot *Bot) handleCommand(msg *tgbotapi.Message) {
 		msgText := []string{p.Sprintf(numberSubsTmpl, len(*subs)), ""}
 
 		if len(*subs) > 0 {
+			theme := bot.themeOf(chatID)
 			for _, s := range *subs {
+				lastChecked := p.Sprintf(notCheckedYetText)
+				if !s.LastCheckedAt.IsZero() {
+					lastChecked = p.Sprintf(lastCheckedTmpl, s.LastCheckedAt.Format(lastCheckedLayout))
+				}
+				if s.Label != "" {
+					msgText = append(msgText, p.Sprintf(subscriptionLabelTmpl, s.Label))
+				}
 				msgText = append(msgText,
 					p.Sprintf("Location: %f;%f. Last AQI: %s", s.Longitude, s.Latitude,
-						p.Sprintf(s.AirQualityIndex.String()),
+						theme.LocalizedAQI(s.AirQualityIndex, p),
 					),
+					lastChecked,
 				)
 			}
 			tgMsg.ReplyMarkup = cleanupSubscriptionInline
 		}
+		if quota := bot.quotaText(len(*subs), p); quota != "" {
+			msgText = append(msgText, "", quota)
+		}
 
 		tgMsg.Text = strings.Join(msgText, "\n")
 	case "about":
 		tgMsg.Text = p.Sprintf(aboutTextTmpl, authorContact)
+	case "privacy":
+		tgMsg.Text = bot.privacyText(p)
+	case "help":
+		tgMsg.Text = helpText(bot.enabledCommands(), p)
+	case "export":
+		data, err := bot.store.ExportUserData(chatID)
+		if err != nil {
+			log.Print("ExportUserData: ", err)
+			tgMsg.Text = p.Sprintf(safeToRetryErrMsg)
+			break
+		}
+		doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: "airpollutionbot-export.json", Bytes: data})
+		doc.Caption = p.Sprintf(exportCaptionText)
+		doc.ReplyToMessageID = msg.MessageID
+		bot.Send(doc)
+		return
+	case "forgetme":
+		tgMsg.Text = p.Sprintf(forgetMeAskText)
+		tgMsg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				callbackButton(p.Sprintf(forgetMeBtn), callbackForgetMe),
+			),
+		)
+	case "weather":
+		switch msg.CommandArguments() {
+		case "on", "off":
+			prefs, err := bot.store.GetUserPrefs(chatID)
+			if err != nil {
+				log.Print("GetUserPrefs: ", err)
+				tgMsg.Text = p.Sprintf(safeToRetryErrMsg)
+				break
+			}
+			prefs.Weather = msg.CommandArguments() == "on"
+			if err := bot.store.UpdateUserPrefs(prefs); err != nil {
+				log.Print("UpdateUserPrefs: ", err)
+				tgMsg.Text = p.Sprintf(safeToRetryErrMsg)
+				break
+			}
+			tgMsg.Text = p.Sprintf(weatherOffText)
+			if prefs.Weather {
+				tgMsg.Text = p.Sprintf(weatherOnText)
+			}
+		default:
+			tgMsg.Text = p.Sprintf(weatherUsageText)
+		}
+	case "units":
+		tgMsg.Text = bot.unitsText(chatID, msg.CommandArguments(), p)
+	case "scale":
+		tgMsg.Text = bot.scaleText(chatID, msg.CommandArguments(), p)
+	case "theme":
+		tgMsg.Text = bot.themeText(chatID, msg.CommandArguments(), p)
+	case "frequency":
+		frequency, err := parseFrequency(msg.CommandArguments())
+		if err != nil {
+			tgMsg.Text = p.Sprintf(frequencyUsageMsg)
+			break
+		}
+		if err := bot.store.SetSubscriptionsFrequency(chatID, frequency); err != nil {
+			log.Print("SetSubscriptionsFrequency: ", err)
+			tgMsg.Text = p.Sprintf(safeToRetryErrMsg)
+			break
+		}
+		tgMsg.Text = p.Sprintf(frequencyDefText)
+		if frequency > 0 {
+			tgMsg.Text = p.Sprintf(frequencySetTmpl, frequency)
+		}
+	case "label":
+		tgMsg.Text = bot.labelText(chatID, msg.CommandArguments(), p)
+	case "snooze":
+		tgMsg.Text = bot.snoozeText(chatID, msg.CommandArguments(), p)
+	case "cooldown":
+		tgMsg.Text = bot.cooldownText(chatID, msg.CommandArguments(), p)
+	case "components":
+		tgMsg.Text = bot.componentsText(chatID, p)
+	case "raw":
+		tgMsg.Text = bot.rawText(chatID, p)
+	case "digest":
+		tgMsg.Text = bot.setDigestText(chatID, msg.CommandArguments(), p)
+	case "peaks":
+		tgMsg.Text = bot.setPeaksText(chatID, msg.CommandArguments(), p)
+	case "map":
+		tgMsg.Text = bot.setMapText(chatID, msg.CommandArguments(), p)
+	case "check":
+		tgMsg.Text = bot.checkText(chatID, msg.CommandArguments(), p)
+	case "stats_me":
+		tgMsg.Text = bot.statsMeText(chatID, p)
+	case "test":
+		tgMsg.Text = bot.testNotificationText(chatID, msg.CommandArguments(), p)
+	case "top":
+		tgMsg.Text = bot.topText(chatID, p)
+	case "profile":
+		tgMsg.Text = bot.setProfileText(chatID, msg.CommandArguments(), p)
+	case "nearby":
+		tgMsg.Text = bot.nearbyText(chatID, p)
+	case "whereami":
+		tgMsg.Text = bot.whereAmIText(chatID, p)
+	case "favorite":
+		tgMsg.Text = bot.favoriteText(chatID, msg.CommandArguments(), p)
+	case "subscribe_all":
+		tgMsg.Text = bot.subscribeAllText(chatID, p)
+	case "restore":
+		tgMsg.Text = bot.restoreText(chatID, p)
+	case "route":
+		tgMsg.Text = bot.routeText(msg.CommandArguments(), p)
+	case "refresh":
+		tgMsg.Text = bot.refreshText(chatID, msg.CommandArguments(), p)
+	case "stats":
+		if !bot.isAdmin(msg.From.ID) {
+			tgMsg.Text = p.Sprintf(unknownCmdMsg)
+			break
+		}
+		tgMsg.Text = bot.statsText()
+	case "owmraw":
+		if !bot.isAdmin(msg.From.ID) {
+			tgMsg.Text = p.Sprintf(unknownCmdMsg)
+			break
+		}
+		tgMsg.Text = bot.rawResponsesText(msg.CommandArguments())
+	case "maintenance":
+		if !bot.isAdmin(msg.From.ID) {
+			tgMsg.Text = p.Sprintf(unknownCmdMsg)
+			break
+		}
+		tgMsg.Text = bot.maintenanceCommandText(msg.CommandArguments())
 	default:
 		tgMsg.Text = p.Sprintf(unknownCmdMsg)
 		tgMsg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
@@ -292,137 +1042,472 @@ func (bot *Bot) handleCommand(msg *tgbotapi.Message) {
 	bot.Send(tgMsg)
 }
 
+// parseFrequency parses the argument of the /frequency command. 0 means checking on every Cron run.
+func parseFrequency(arg string) (time.Duration, error) {
+	switch arg = strings.TrimSpace(arg); arg {
+	case "default":
+		return 0, nil
+	case "hourly":
+		return time.Hour, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(arg)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative frequency %v", d)
+	}
+	return d, nil
+}
+
+func (bot *Bot) isAdmin(userID int64) bool {
+	return bot.adminID != 0 && bot.adminID == userID
+}
+
+// statsText renders Store.Stats for the admin
+func (bot *Bot) statsText() string {
+	st, err := bot.store.Stats()
+	if err != nil {
+		log.Print("Stats: ", err)
+		return safeToRetryErrMsg
+	}
+	msgText := []string{
+		fmt.Sprintf("Users: %d", st.Users),
+		fmt.Sprintf("Subscribed chats: %d", st.SubscribedChats),
+		fmt.Sprintf("Enabled subscriptions: %d", st.EnabledSubscriptions),
+		fmt.Sprintf("Data points: %d", st.DataPoints),
+		"",
+	}
+	for aqi := AirQualityIndex(1); aqi <= 5; aqi++ {
+		msgText = append(msgText, fmt.Sprintf("%s: %d", aqi, st.SubscriptionsByAQI[aqi]))
+	}
+	return strings.Join(msgText, "\n")
+}
+
+// subscribeFromCallback subscribes the chat to the location of the notifyMe callback arguments.
+// It returns the subscribed location and its current AQI.
+// The session location is another one if the user shared a new location after the message with the button,
+// so the current AQI is fetched for the subscription then.
+// Buttons sent without the location subscribe to the session location.
+func (bot *Bot) subscribeFromCallback(chatID int64, args []string) (*Location, AirQualityIndex, error) {
+	us, err := bot.store.GetSessionByChatID(chatID)
+	if err != nil {
+		return nil, 0, err
+	}
+	location := us.Location()
+	if len(args) > 0 {
+		if location, err = parseLocationCallbackArgs(args); err != nil {
+			return nil, 0, err
+		}
+	}
+	if bot.store.CoordinatePrecision > 0 {
+		location = location.Round(bot.store.CoordinatePrecision)
+	}
+
+	var aqi AirQualityIndex
+	if us.Location().DistanceTo(location) < bot.store.duplicateDistance() {
+		dp, err := bot.store.GetLastPD(chatID)
+		if err != nil {
+			return nil, 0, err
+		}
+		aqi = dp.GetAQI()
+	} else {
+		resp, err := bot.wAPI.GetAirPollution(location)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(resp.DP) == 0 {
+			return nil, 0, errNoDataPoints
+		}
+		aqi = resp.DP[0].GetAQI()
+	}
+	if err := bot.store.AddAQISubscriptionAt(chatID, location, aqi); err != nil {
+		return nil, 0, err
+	}
+	return location, aqi, nil
+}
+
+// subscribedText confirms the subscription of the location with the name of the place, if it is found
+func (bot *Bot) subscribedText(location *Location, aqi AirQualityIndex, p *message.Printer) string {
+	if bot.geocoder != nil {
+		place, err := bot.geocoder.ReverseGeocode(location)
+		if err == nil && place.Name != "" {
+			return p.Sprintf(notifyMePlaceTmpl, place.String(), location.Latitude, location.Longitude, aqi.LocalizedString(p))
+		}
+		if err != nil && !errors.Is(err, errPlaceNotFound) {
+			log.Print("ReverseGeocode: ", err)
+		}
+	}
+	return p.Sprintf(notifyMeCoordsTmpl, location.Latitude, location.Longitude, aqi.LocalizedString(p))
+}
+
 func (bot *Bot) handleCallbackQuery(query *tgbotapi.CallbackQuery) {
 	var (
 		chatID       = query.Message.Chat.ID
 		messageID    = query.Message.MessageID
-		languageCode = query.Message.From.LanguageCode
+		languageCode = query.From.LanguageCode
 	)
 
-	// Respond to the callback query, telling Telegram to show the user
-	// a message with the data received.
-	callback := tgbotapi.NewCallback(
-		query.ID,
-		query.Data,
-	)
-	if _, err := bot.tApi.Request(callback); err != nil {
-		log.Panic(err)
-	}
 	p := newLangPrinter(languageCode)
 
+	// the callback query is answered with a toast of the outcome once it is handled
+	toast := ""
+	defer func() { bot.answerCallback(query.ID, toast) }()
+
+	if !bot.callbacks.First(query, time.Now()) {
+		log.Print("repeated callback query ignored: ", query.Data)
+		return
+	}
+	if bot.inMaintenance(query.From.ID) {
+		toast = p.Sprintf(maintenanceText)
+		return
+	}
+
 	tgMsg := tgbotapi.NewMessage(chatID, "")
 	tgMsg.ReplyToMessageID = messageID
 
-	switch query.Data {
-	case "notifyMe":
-		tgMsg.Text = notifyMeCnfrmText
-		err := bot.store.AddAQISubscription(chatID)
-		if err != nil {
-			log.Println("AddAQISubscription: ", err)
-			tgMsg.Text = p.Sprintf("Error: %v", err)
+	kind, args, err := DecodeCallback(query.Data)
+	if err != nil {
+		log.Print("DecodeCallback: ", err)
+		toast = p.Sprintf(callbackFailedText)
+		return
+	}
+
+	switch kind {
+	case callbackNotifyMe:
+		location, aqi, err := bot.subscribeFromCallback(chatID, args)
+		switch {
+		case errors.Is(err, ErrNotificationExists):
+			tgMsg.Text = p.Sprintf(notifyMeExistsText)
+		case errors.Is(err, ErrSubscriptionLimitReached):
+			tgMsg.Text = p.Sprintf(subsLimitTmpl, bot.store.MaxSubscriptions)
+		case err != nil:
+			log.Println("subscribeFromCallback: ", err)
+			tgMsg.Text = p.Sprintf(safeToRetryErrMsg)
+			toast = p.Sprintf(callbackFailedText)
+		default:
+			tgMsg.Text = bot.subscribedText(location, aqi, p)
 		}
-	case "details":
+	case callbackDetails:
 		dp, err := bot.store.GetLastPD(chatID)
 		if err != nil {
-			log.Panic(err)
+			log.Print("GetLastPD: ", err)
+			tgMsg.Text = p.Sprintf(safeToRetryErrMsg)
+			toast = p.Sprintf(callbackFailedText)
+			break
 		}
 
+		theme := bot.themeOf(chatID)
 		var msgText []string
 		msgText = append(msgText,
 			p.Sprintf(detailsText),
-			p.Sprintf(time.Unix(dp.Dt, 0).String()),
+			bot.scaleOf(chatID).Format(dp, theme, p),
+			HumanizeSince(time.Unix(dp.Dt, 0), p),
 			"",
 		)
-		for k, v := range dp.Components {
-			msgText = append(msgText, p.Sprintf("%s=%.2f", k, v))
+		for _, k := range sortedComponents(dp.Components) {
+			msgText = append(msgText, componentLine(k, dp.Components[k], theme, p))
 		}
 		tgMsg.Text = strings.Join(msgText, "\n")
-	case "cleanup":
+	case callbackCleanup:
+		tgMsg.Text = p.Sprintf(notifyMeDelText) + "\n" + p.Sprintf(restoreHintText)
 		err := bot.store.DeleteAQISubscriptions(chatID)
 		if err != nil {
 			log.Println("DeleteAQISubscriptions: ", err)
 			tgMsg.Text = p.Sprintf(safeToRetryErrMsg)
+			toast = p.Sprintf(callbackFailedText)
 		}
-		tgMsg.Text = p.Sprintf(notifyMeDelText)
+	case callbackForgetMe:
+		tgMsg.Text = p.Sprintf(forgetMeDoneText)
+		if err := bot.store.PurgeUser(chatID); err != nil {
+			log.Println("PurgeUser: ", err)
+			tgMsg.Text = p.Sprintf(safeToRetryErrMsg)
+			toast = p.Sprintf(callbackFailedText)
+		}
+	}
+	if toast == "" {
+		toast = p.Sprintf(callbackDoneText)
 	}
 
 	bot.Send(tgMsg)
 }
 
+// answerCallback answers the callback query with the toast, stopping the progress of the button.
+// An error, e.g. the query is too old, is only logged as the query is already handled.
+func (bot *Bot) answerCallback(queryID, toast string) {
+	if _, err := bot.tApi.Request(tgbotapi.NewCallback(queryID, toast)); err != nil {
+		log.Print("answering callback query: ", err)
+	}
+}
+
 // Cron runs every 30 minutes and checks the AQI for all enabled subscriptions.
 func (bot *Bot) Cron() {
+	if !bot.cronMu.TryLock() {
+		log.Print("Cron: the previous run is still in progress, skipping")
+		return
+	}
+	defer bot.cronMu.Unlock()
+	if bot.maintenance.Load() {
+		log.Print("Cron: maintenance mode, skipping")
+		return
+	}
+
 	subs, err := bot.store.ListEnabledSubscriptions()
 	if err != nil {
 		log.Printf("ListEnabledSubscriptions: %v", err)
 		return
 	}
 	log.Printf("%d subsription(s) to process", len(*subs))
-	i := 0
+	now := time.Now()
+	summary := cronSummary{}
+	defer summary.publish()
+	var due []AQISubscription
 	for _, s := range *subs {
-
-		location := &Location{
-			s.Latitude,
-			s.Longitude,
+		if s.Frequency > 0 && now.Sub(s.LastCheckedAt) < s.Frequency {
+			summary.add(outcomeSkippedFrequency)
+			continue
 		}
+		if now.Before(s.SnoozedUntil) {
+			summary.add(outcomeSuppressedSnooze)
+			continue
+		}
+		due = append(due, s)
+	}
 
-		resp, err := bot.wAPI.GetAirPollution(location)
+	// OWM API is queried concurrently, the DB is updated sequentially
+	fetched := bot.fetchSubscriptions(due)
+	changed := map[int64]AirQualityIndex{}
+	var (
+		notifications []tgbotapi.MessageConfig
+		// notifiedSubs are the IDs of the subscriptions of the notifications
+		notifiedSubs []int64
+	)
+	for i, s := range due {
+		resp, err := fetched[i].resp, fetched[i].err
 		if err != nil {
 			log.Print("GetAirPollution: ", err)
+			summary.add(outcomeFetchFailed)
 			continue
 		}
-		if err := bot.store.AddDataPoint(s.ChatID, &resp.DP); err != nil {
-			log.Print("AddDataPoint: ", err)
+		if len(resp.DP) == 0 {
+			log.Print("GetAirPollution: ", errNoDataPoints)
+			summary.add(outcomeFetchFailed)
 			continue
 		}
-		dp, err := bot.store.GetLastPD(s.ChatID)
-		if err != nil {
-			log.Print("GetLastPD: ", err)
+		if _, err := bot.store.AddChangedDataPoints(s.ChatID, s.Location(), &resp.DP); err != nil {
+			log.Print("AddChangedDataPoints: ", err)
+			summary.add(outcomeStoreFailed)
 			continue
 		}
+		// the fetched DataPoint is used as an unchanged one is not stored
+		dp := latestDataPoint(resp.DP)
+		// the start of the run is stored to not drift the next check of the subscription
+		if err := bot.store.MarkSubscriptionChecked(s.ID, now); err != nil {
+			log.Print("MarkSubscriptionChecked: ", err)
+		}
+		// the pollutants are compared with the ones of this check on the next run
+		if err := bot.store.SetSubscriptionComponents(s.ID, dp.Components); err != nil {
+			log.Print("SetSubscriptionComponents: ", err)
+		}
+		spike, spiked := bot.spikeOf(&s, dp)
+		prefs := bot.prefsOf(s.ChatID)
 
-		if dp.GetAQI() != s.AirQualityIndex {
-			err := bot.store.UpdateSubscriptionAQI(s.ID, dp.GetAQI())
-			if err != nil {
-				log.Print("UpdateSubscriptionAQI: ", err)
-				continue
+		alerted := false
+		switch {
+		// digest subscriptions are summarized by CronDigest instead of the alerts
+		case s.Mode == SubscriptionModeDigest:
+			if dp.GetAQI() != s.AirQualityIndex {
+				changed[s.ID] = dp.GetAQI()
 			}
+			summary.add(outcomeDigest)
+		case !bot.aqiChangeConfirmed(&s, dp.GetAQI()):
+			summary.add(outcomeSuppressedHysteresis)
+		case dp.GetAQI() == s.AirQualityIndex && !spiked:
+			summary.add(outcomeUnchanged)
+		// the AQI is still updated, so the change isn't notified after the cooldown
+		case bot.inCooldown(&s, prefs, now):
+			changed[s.ID] = dp.GetAQI()
+			summary.add(outcomeSuppressedCooldown)
+		// a spike of a pollutant within the same AQI is notified as well
+		case dp.GetAQI() == s.AirQualityIndex:
+			alerted = true
 
-			p := newLangPrinter(s.LanguageCode)
+			tgMsg := tgbotapi.NewMessage(s.ChatID, bot.formatSpikeNotification(&s, dp, spike, prefs, newLangPrinter(s.LanguageCode)))
+			tgMsg.ReplyMarkup = cleanupSubscriptionInline
+			notifications = append(notifications, tgMsg)
+			notifiedSubs = append(notifiedSubs, s.ID)
+		default:
+			changed[s.ID] = dp.GetAQI()
+			alerted = true
 
-			var msgText []string
-			msgText = []string{
-				p.Sprintf(aqiGetsBetterMsg),
-				"",
-				p.Sprintf(aqiText) + ": " + p.Sprintf(dp.Main.Aqi.String()),
-				"",
-				p.Sprintf(dp.Main.Aqi.Description()),
-			}
-			if dp.GetAQI() > s.AirQualityIndex {
-				msgText = []string{
-					p.Sprintf(aqiGetsWorseMsg),
-					"",
-					p.Sprintf(aqiText) + ": " + p.Sprintf(dp.Main.Aqi.String()),
-					"",
-					p.Sprintf(dp.Main.Aqi.Description()),
-				}
-			}
+			tgMsg := tgbotapi.NewMessage(s.ChatID, bot.formatCronNotification(&s, dp, prefs, newLangPrinter(s.LanguageCode)))
+			tgMsg.ReplyMarkup = cleanupSubscriptionInline
+			notifications = append(notifications, tgMsg)
+			notifiedSubs = append(notifiedSubs, s.ID)
+		}
 
-			tgMsg := tgbotapi.NewMessage(s.ChatID, strings.Join(msgText, "\n"))
+		// the history of all the subscriptions is kept for CronDigest and /stats_me
+		if err := bot.store.AddAQIHistory(s.ID, dp.GetAQI(), now, alerted); err != nil {
+			log.Print("AddAQIHistory: ", err)
+		}
+	}
 
-			tgMsg.ReplyMarkup = cleanupSubscriptionInline
-			bot.Send(tgMsg)
-			i++
+	// users are notified only if the new AQI is stored, otherwise they would be notified again on the next run
+	if err := bot.store.UpdateSubscriptionAQIBatch(changed); err != nil {
+		log.Print("UpdateSubscriptionAQIBatch: ", err)
+		summary[outcomeStoreFailed] += int64(len(notifications))
+		return
+	}
+	for i, tgMsg := range notifications {
+		if err := bot.Send(tgMsg); err != nil {
+			summary.add(outcomeSendFailed)
+			continue
+		}
+		if err := bot.store.MarkSubscriptionNotified(notifiedSubs[i], now); err != nil {
+			log.Print("MarkSubscriptionNotified: ", err)
 		}
+		summary.add(outcomeSent)
 	}
-	log.Printf("Sent %d messages", i)
+}
+
+// prefsOf returns the UserPrefs of the chat. The default ones if they can't be read.
+func (bot *Bot) prefsOf(chatID int64) *UserPrefs {
+	prefs, err := bot.store.GetUserPrefs(chatID)
+	if err != nil {
+		log.Print("GetUserPrefs: ", err)
+	}
+	return prefs
+}
+
+// formatCronNotification returns the text Cron notifies about the change of the AQI of the subscription
+// to the one of the DataPoint in the style of the UserPrefs of the chat
+func (bot *Bot) formatCronNotification(s *AQISubscription, dp *DataPoint, prefs *UserPrefs, p *message.Printer) string {
+	aqi := dp.GetAQI()
+	// crossing into the poor categories is a health warning rather than a change
+	warning := aqi > s.AirQualityIndex && aqi >= 4
+	header := p.Sprintf(aqiGetsBetterMsg)
+	switch {
+	case warning && aqi == 5:
+		header = p.Sprintf(aqiVeryPoorMsg)
+	case warning:
+		header = p.Sprintf(aqiPoorMsg)
+	case aqi > s.AirQualityIndex:
+		header = p.Sprintf(aqiGetsWorseMsg)
+	}
+	if s.Label != "" {
+		header += "\n" + p.Sprintf(subscriptionLabelTmpl, s.Label)
+	}
+	msgText := []string{FormatAQIMessage(dp, p, header, prefs.Profile, prefs.Scale, prefs.Theme)}
+	if warning {
+		msgText = append(msgText, "", p.Sprintf(limitOutdoorText))
+	}
+	if w := bot.coWarning(dp, p); w != "" {
+		msgText = append(msgText, "", w)
+	}
+	return strings.Join(msgText, "\n")
+}
+
+// fetchResult is the response of AQIProvider for a subscription
+type fetchResult struct {
+	resp *ApiPollutionResponse
+	err  error
+}
+
+// fetchSubscriptions gets the air pollution of the subscriptions by at most cronConcurrency concurrent requests.
+// The results are in the order of the subscriptions.
+func (bot *Bot) fetchSubscriptions(subs []AQISubscription) []fetchResult {
+	concurrency := bot.cronConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultCronConcurrency
+	}
+	results := make([]fetchResult, len(subs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range subs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			resp, err := bot.wAPI.GetAirPollution(subs[i].Location())
+			results[i] = fetchResult{resp, err}
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// aqiChangeConfirmed reports whether the AQI change of the subscription is notified.
+// A change below hysteresisMinDelta is kept pending and confirmed if the next run gets the same AQI,
+// so AQI flipping on a level boundary doesn't alert on every run.
+func (bot *Bot) aqiChangeConfirmed(s *AQISubscription, aqi AirQualityIndex) bool {
+	delta := int(aqi - s.AirQualityIndex)
+	if delta < 0 {
+		delta = -delta
+	}
+	pending := AirQualityIndex(0)
+	confirmed := true
+	if delta != 0 && delta < bot.hysteresisMinDelta && s.PendingAQI != aqi {
+		pending, confirmed = aqi, false
+	}
+	if pending != s.PendingAQI {
+		if err := bot.store.SetSubscriptionPendingAQI(s.ID, pending); err != nil {
+			log.Print("SetSubscriptionPendingAQI: ", err)
+		}
+	}
+	return confirmed
 }
 
 func (bot *Bot) CronCleanup() {
-	err := bot.store.ClenupAQISubscriptions()
+	err := bot.store.ClenupAQISubscriptions(time.Now().Add(-restoreWindow))
 	if err != nil {
 		log.Println("CronCleanup:", err)
 		return
 	}
 
+	if bot.dataPointsPerChat > 0 {
+		chatIDs, err := bot.store.ListSubscribedChatIDs()
+		if err != nil {
+			log.Println("CronCleanup:", err)
+			return
+		}
+		for _, chatID := range chatIDs {
+			if err := bot.store.TrimDataPoints(chatID, bot.dataPointsPerChat); err != nil {
+				log.Println("CronCleanup:", err)
+			}
+		}
+	}
+
+	// the AQI history is summarized over digestPeriod at most
+	if err := bot.store.DeleteAQIHistoryBefore(time.Now().Add(-digestPeriod)); err != nil {
+		log.Println("CronCleanup:", err)
+	}
+
+	if bot.dataPointRetention > 0 {
+		if err := bot.store.DeleteDataPointsBefore(time.Now().Add(-bot.dataPointRetention)); err != nil {
+			log.Println("CronCleanup:", err)
+		}
+	}
+
+	if bot.rawCaptureRetention > 0 {
+		if err := bot.store.DeleteRawResponsesBefore(time.Now().Add(-bot.rawCaptureRetention)); err != nil {
+			log.Println("CronCleanup:", err)
+		}
+	}
+
 	log.Println("CronCleanup complete")
 }
+
+// CronMaintenance compacts the DB file bloated by the deletes of CronCleanup
+func (bot *Bot) CronMaintenance() {
+	before, after, err := bot.store.Maintain()
+	if err != nil {
+		log.Println("CronMaintenance:", err)
+		return
+	}
+	log.Printf("CronMaintenance complete, DB size %d -> %d bytes", before, after)
+}
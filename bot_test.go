@@ -0,0 +1,1603 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// newTestBot returns a Bot of the services over the store without a Telegram client
+func newTestBot(store *Store, services *botServices) *Bot {
+	if services.tracer == nil {
+		services.tracer = noopTracer{}
+	}
+	if services.disabledCommands == nil {
+		services.disabledCommands = map[string]bool{}
+	}
+	return newRunner(nil, store, services)
+}
+
+// newFakeTelegramBot returns a Bot like newTestBot with a Telegram client served by the fake
+func newFakeTelegramBot(t *testing.T, store *Store, services *botServices, fake *fakeTelegram) *Bot {
+	t.Helper()
+	api, err := tgbotapi.NewBotAPIWithClient("1:test", tgbotapi.APIEndpoint, fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot := newTestBot(store, services)
+	bot.tApi = api
+	return bot
+}
+
+// fakeRequest is a request of a Telegram method
+type fakeRequest struct {
+	token  string
+	method string
+	params url.Values
+}
+
+// fakeTelegram is an HTTP client of tgbotapi answering like Telegram. It records the requests.
+type fakeTelegram struct {
+	mu       sync.Mutex
+	requests []fakeRequest
+	// fail are the methods answered with an error
+	fail map[string]bool
+	// invalidTokens are the tokens rejected as unauthorized
+	invalidTokens map[string]bool
+	// polls are the answers of the getUpdates requests in order
+	polls []fakePoll
+	// afterPolls is called on the getUpdates requests once the polls are answered
+	afterPolls func()
+}
+
+// fakePoll is an answer of getUpdates
+type fakePoll struct {
+	updates []tgbotapi.Update
+	fail    bool
+}
+
+func (f *fakeTelegram) Do(r *http.Request) (*http.Response, error) {
+	// the URLs are like https://api.telegram.org/bot<token>/<method>
+	token := strings.TrimPrefix(path.Base(path.Dir(r.URL.Path)), "bot")
+	method := path.Base(r.URL.Path)
+	if err := r.ParseMultipartForm(1 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, fakeRequest{token, method, r.Form})
+	if f.invalidTokens[token] {
+		return telegramResponse(`{"ok":false,"error_code":401,"description":"Unauthorized"}`), nil
+	}
+	if f.fail[method] {
+		return telegramResponse(`{"ok":false,"error_code":400,"description":"Bad Request: fake failure"}`), nil
+	}
+	var result interface{} = true
+	switch method {
+	case "getMe":
+		h := fnv.New32a()
+		h.Write([]byte(token))
+		result = tgbotapi.User{ID: int64(h.Sum32()), IsBot: true, UserName: "bot" + strconv.Itoa(int(h.Sum32()))}
+	case "getUpdates":
+		if len(f.polls) == 0 {
+			if f.afterPolls != nil {
+				f.afterPolls()
+			}
+			result = []tgbotapi.Update{}
+			break
+		}
+		var poll fakePoll
+		poll, f.polls = f.polls[0], f.polls[1:]
+		if poll.fail {
+			return telegramResponse(`{"ok":false,"error_code":502,"description":"Bad Gateway"}`), nil
+		}
+		result = poll.updates
+	case "sendMessage", "editMessageText", "sendDocument", "sendVenue", "sendLocation":
+		chatID, _ := strconv.ParseInt(r.Form.Get("chat_id"), 10, 64)
+		result = tgbotapi.Message{MessageID: len(f.requests), Chat: &tgbotapi.Chat{ID: chatID}, Text: r.Form.Get("text")}
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return telegramResponse(fmt.Sprintf(`{"ok":true,"result":%s}`, b)), nil
+}
+
+func telegramResponse(body string) *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}
+}
+
+// sent returns the params of the requests of the method
+func (f *fakeTelegram) sent(method string) []url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var params []url.Values
+	for _, r := range f.requests {
+		if r.method == method {
+			params = append(params, r.params)
+		}
+	}
+	return params
+}
+
+// texts returns the texts of the sent messages
+func (f *fakeTelegram) texts() []string {
+	var texts []string
+	for _, params := range f.sent("sendMessage") {
+		texts = append(texts, params.Get("text"))
+	}
+	return texts
+}
+
+// lastText returns the text of the last sent message. Empty if there is none.
+func (f *fakeTelegram) lastText() string {
+	texts := f.texts()
+	if len(texts) == 0 {
+		return ""
+	}
+	return texts[len(texts)-1]
+}
+
+// httpClientFunc is an HTTPClient of a function
+type httpClientFunc func(*http.Request) (*http.Response, error)
+
+func (f httpClientFunc) Do(r *http.Request) (*http.Response, error) { return f(r) }
+
+// fakeOWM returns an HTTPClient of OWM API answering the air pollution requests with the AQI
+func fakeOWM(aqi AirQualityIndex) httpClientFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		body := fmt.Sprintf(`{"coord":{"lat":%s,"lon":%s},"list":[{"dt":1700000000,"main":{"aqi":%d},"components":{"co":200,"pm2_5":5}}]}`,
+			r.URL.Query().Get("lat"), r.URL.Query().Get("lon"), aqi)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	}
+}
+
+// fakeAQI is an AQIProvider answering with a DataPoint of the AQI measured now. It counts the requests.
+type fakeAQI struct {
+	mu         sync.Mutex
+	aqi        AirQualityIndex
+	components map[string]float64
+	err        error
+	calls      int
+}
+
+func (f *fakeAQI) GetAirPollution(l *Location) (*ApiPollutionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return &ApiPollutionResponse{}, f.err
+	}
+	return &ApiPollutionResponse{Location: *l, DP: []DataPoint{newTestDataPoint(f.aqi, time.Now(), f.components)}}, nil
+}
+
+// requests returns the number of the requests
+func (f *fakeAQI) requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// aqiProviderFunc is an AQIProvider of a function
+type aqiProviderFunc func(l *Location) (*ApiPollutionResponse, error)
+
+func (f aqiProviderFunc) GetAirPollution(l *Location) (*ApiPollutionResponse, error) { return f(l) }
+
+// aqiResponse returns the response of a DataPoint of the AQI measured now at the location
+func aqiResponse(l *Location, aqi AirQualityIndex) *ApiPollutionResponse {
+	return &ApiPollutionResponse{Location: *l, DP: []DataPoint{newTestDataPoint(aqi, time.Now(), nil)}}
+}
+
+// fakeWeather is a WeatherProvider answering with the weather or the error
+type fakeWeather struct {
+	weather Weather
+	err     error
+}
+
+func (f *fakeWeather) GetCurrentWeather(l *Location) (*Weather, error) {
+	if f.err != nil {
+		return &Weather{}, f.err
+	}
+	w := f.weather
+	return &w, nil
+}
+
+// newTestLocationMessage returns the message of the chat sharing the location
+func newTestLocationMessage(chatID int64, l *Location) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: chatID, LanguageCode: "en"},
+		Chat:      &tgbotapi.Chat{ID: chatID},
+		Location:  &tgbotapi.Location{Latitude: l.Latitude, Longitude: l.Longitude},
+	}
+}
+
+func TestUpdateType(t *testing.T) {
+	tests := []struct {
+		update tgbotapi.Update
+		want   string
+	}{
+		{tgbotapi.Update{EditedMessage: &tgbotapi.Message{}}, "edited_message"},
+		{tgbotapi.Update{ChannelPost: &tgbotapi.Message{}}, "channel_post"},
+		{tgbotapi.Update{EditedChannelPost: &tgbotapi.Message{}}, "edited_channel_post"},
+		{tgbotapi.Update{ChosenInlineResult: &tgbotapi.ChosenInlineResult{}}, "chosen_inline_result"},
+		{tgbotapi.Update{Poll: &tgbotapi.Poll{}}, "poll"},
+		{tgbotapi.Update{ChatMember: &tgbotapi.ChatMemberUpdated{}}, "chat_member"},
+		{tgbotapi.Update{}, "unknown"},
+	}
+	for _, tt := range tests {
+		if got := updateType(&tt.update); got != tt.want {
+			t.Errorf("updateType() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestHandleUpdateUnhandledTypes(t *testing.T) {
+	bot := newTestBot(newTestStore(t), &botServices{debug: true})
+	// the updates the bot doesn't handle are only logged
+	for _, update := range []tgbotapi.Update{
+		{UpdateID: 1, EditedMessage: &tgbotapi.Message{Text: "edited"}},
+		{UpdateID: 2, ChannelPost: &tgbotapi.Message{Text: "post"}},
+		{UpdateID: 3},
+	} {
+		bot.handleUpdate(update)
+	}
+}
+
+func TestHandleMyChatMember(t *testing.T) {
+	tests := []struct {
+		status      string
+		wantEnabled int
+	}{
+		{status: "kicked", wantEnabled: 0},
+		{status: "left", wantEnabled: 0},
+		{status: "member", wantEnabled: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+			bot := newTestBot(store, &botServices{})
+
+			bot.handleUpdate(tgbotapi.Update{MyChatMember: &tgbotapi.ChatMemberUpdated{
+				Chat:          tgbotapi.Chat{ID: 1},
+				OldChatMember: tgbotapi.ChatMember{Status: "member"},
+				NewChatMember: tgbotapi.ChatMember{Status: tt.status},
+			}})
+
+			subs, err := store.ListAQISubscriptions(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*subs) != tt.wantEnabled {
+				t.Errorf("enabled subscriptions = %d, want %d", len(*subs), tt.wantEnabled)
+			}
+		})
+	}
+}
+
+func TestBotOptionsSetDefaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      BotOptions
+		dbPath    string
+		cacheTime time.Duration
+	}{
+		{name: "defaults", dbPath: DefaultDBPath, cacheTime: DefaultCacheTime},
+		{name: "set", opts: BotOptions{DBPath: "/data/bot.db", CacheTime: time.Minute}, dbPath: "/data/bot.db", cacheTime: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.setDefaults()
+			if opts.DBPath != tt.dbPath || opts.CacheTime != tt.cacheTime {
+				t.Errorf("setDefaults() = %q, %v, want %q, %v", opts.DBPath, opts.CacheTime, tt.dbPath, tt.cacheTime)
+			}
+			// the long polls are cut by the timeout of the default Telegram client only once they hang
+			if c, ok := opts.TelegramHTTPClient.(*http.Client); !ok || c.Timeout <= pollTimeout*time.Second {
+				t.Errorf("setDefaults() TelegramHTTPClient = %#v, want an http.Client timing out after the long poll", opts.TelegramHTTPClient)
+			}
+		})
+	}
+}
+
+func TestNewBotWithOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  BotOptions
+		check func(t *testing.T, bot *Bot)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, bot *Bot) {
+				if bot.store.CacheTime != DefaultCacheTime {
+					t.Errorf("CacheTime = %v, want %v", bot.store.CacheTime, DefaultCacheTime)
+				}
+				if bot.coThreshold != DefaultCOThreshold {
+					t.Errorf("coThreshold = %v, want %v", bot.coThreshold, DefaultCOThreshold)
+				}
+				if bot.cronConcurrency != DefaultCronConcurrency {
+					t.Errorf("cronConcurrency = %d, want %d", bot.cronConcurrency, DefaultCronConcurrency)
+				}
+				if owm := bot.wAPI.(*OpenWheatherMapApi); owm.RawCapture != nil || owm.Failures != nil {
+					t.Error("raw capture and failure monitoring are enabled by default")
+				}
+			},
+		},
+		{
+			name: "store settings",
+			opts: BotOptions{CacheTime: time.Minute, CoordinatePrecision: 2, MaxSubscriptions: 3, DuplicateDistance: 500},
+			check: func(t *testing.T, bot *Bot) {
+				s := bot.store
+				if s.CacheTime != time.Minute || s.CoordinatePrecision != 2 || s.MaxSubscriptions != 3 || s.DuplicateDistance != 500 {
+					t.Errorf("store settings = %v, %d, %d, %v", s.CacheTime, s.CoordinatePrecision, s.MaxSubscriptions, s.DuplicateDistance)
+				}
+			},
+		},
+		{
+			name: "bot settings",
+			opts: BotOptions{AdminID: 42, COThreshold: 5000, CronConcurrency: 2, DisabledCommands: []string{"/check", "route"}},
+			check: func(t *testing.T, bot *Bot) {
+				if !bot.isAdmin(42) || bot.isAdmin(43) {
+					t.Error("AdminID is not the admin")
+				}
+				if bot.coThreshold != 5000 || bot.cronConcurrency != 2 {
+					t.Errorf("coThreshold = %v, cronConcurrency = %d", bot.coThreshold, bot.cronConcurrency)
+				}
+				if bot.commandEnabled("check") || bot.commandEnabled("route") || !bot.commandEnabled("nearby") {
+					t.Errorf("disabledCommands = %v", bot.disabledCommands)
+				}
+			},
+		},
+		{
+			name: "OWM API settings",
+			opts: BotOptions{RawCaptureRetention: time.Hour, OWMFailureThreshold: 3},
+			check: func(t *testing.T, bot *Bot) {
+				owm := bot.wAPI.(*OpenWheatherMapApi)
+				if owm.RawCapture == nil {
+					t.Error("raw capture is disabled")
+				}
+				if owm.Failures == nil || owm.Failures.Threshold != 3 {
+					t.Errorf("Failures = %+v", owm.Failures)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeTelegram{}
+			opts := tt.opts
+			opts.TelegramAPIToken = "1:test"
+			opts.OWMApiToken = "owm"
+			opts.DBPath = filepath.Join(t.TempDir(), "bot.db")
+			opts.HTTPClient = fakeOWM(1)
+			opts.TelegramHTTPClient = fake
+
+			bot, cleanUp, err := NewBotWithOptions(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanUp()
+			if len(fake.sent("setMyCommands")) == 0 {
+				t.Error("the command menu is not set")
+			}
+			tt.check(t, bot)
+		})
+	}
+}
+
+func TestNewBotWithOptionsTokens(t *testing.T) {
+	unauthorized := httpClientFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(strings.NewReader(`{"cod":401}`)), Header: http.Header{}}, nil
+	})
+	down := httpClientFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	tests := []struct {
+		name          string
+		telegramToken string
+		owmToken      string
+		owm           HTTPClient
+		wantErr       error
+	}{
+		{name: "valid", telegramToken: "1:test", owmToken: "owm", owm: fakeOWM(1)},
+		{name: "missing Telegram token", owmToken: "owm", owm: fakeOWM(1), wantErr: ErrInvalidTelegramToken},
+		{name: "invalid Telegram token", telegramToken: "1:invalid", owmToken: "owm", owm: fakeOWM(1), wantErr: ErrInvalidTelegramToken},
+		{name: "missing OWM token", telegramToken: "1:test", owm: fakeOWM(1), wantErr: ErrInvalidOWMToken},
+		{name: "invalid OWM token", telegramToken: "1:test", owmToken: "owm", owm: unauthorized, wantErr: ErrInvalidOWMToken},
+		// OWM API being down doesn't stop the bot
+		{name: "OWM API down", telegramToken: "1:test", owmToken: "owm", owm: down},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeTelegram{invalidTokens: map[string]bool{"1:invalid": true}}
+			bot, cleanUp, err := NewBotWithOptions(BotOptions{
+				TelegramAPIToken:   tt.telegramToken,
+				OWMApiToken:        tt.owmToken,
+				DBPath:             filepath.Join(t.TempDir(), "bot.db"),
+				HTTPClient:         tt.owm,
+				TelegramHTTPClient: fake,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewBotWithOptions() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				// main exits with the error as the single line
+				if err.Error() != tt.wantErr.Error() {
+					t.Errorf("NewBotWithOptions() error = %q, want %q", err, tt.wantErr)
+				}
+				if bot != nil || cleanUp != nil {
+					t.Error("NewBotWithOptions() returned a bot with an error")
+				}
+				return
+			}
+			cleanUp()
+		})
+	}
+}
+
+// sentBy returns the texts of the messages sent with the token
+func (f *fakeTelegram) sentBy(token string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var texts []string
+	for _, r := range f.requests {
+		if r.token == token && r.method == "sendMessage" {
+			texts = append(texts, r.params.Get("text"))
+		}
+	}
+	return texts
+}
+
+func TestNewRunner(t *testing.T) {
+	fake := &fakeTelegram{invalidTokens: map[string]bool{"3:invalid": true}}
+	bot, cleanUp, err := NewBotWithOptions(BotOptions{
+		TelegramAPIToken:   "1:test",
+		OWMApiToken:        "owm",
+		DBPath:             filepath.Join(t.TempDir(), "bot.db"),
+		HTTPClient:         fakeOWM(3),
+		TelegramHTTPClient: fake,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp()
+	commands := len(fake.sent("setMyCommands"))
+
+	for _, tt := range []struct {
+		token   string
+		wantErr error
+	}{
+		{token: "", wantErr: ErrInvalidTelegramToken},
+		{token: "3:invalid", wantErr: ErrInvalidTelegramToken},
+	} {
+		if _, err := bot.NewRunner(tt.token); !errors.Is(err, tt.wantErr) {
+			t.Errorf("NewRunner(%q) error = %v, want %v", tt.token, err, tt.wantErr)
+		}
+	}
+	if _, err := bot.NewRunner("1:test"); err == nil {
+		t.Error("NewRunner() of the token of the bot = nil error, want an error")
+	}
+
+	runner, err := bot.NewRunner("2:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runner.tApi.Self.ID == bot.tApi.Self.ID || runner.store.BotID != runner.tApi.Self.ID || bot.store.BotID != 0 {
+		t.Errorf("bot IDs of the stores = %d and %d, want 0 and %d", bot.store.BotID, runner.store.BotID, runner.tApi.Self.ID)
+	}
+	if runner.store.DB != bot.store.DB || runner.botServices != bot.botServices {
+		t.Error("the runner doesn't share the DB and the services of the bot")
+	}
+	if n := len(fake.sent("setMyCommands")); n != 2*commands {
+		t.Errorf("setMyCommands sent %d time(s), want %d for each token", n, commands)
+	}
+
+	// each bot lists and notifies only its subscriptions, the session of the chat is shared
+	minsk, brest := &Location{53.9, 27.56}, &Location{52.1, 23.7}
+	addTestSubscription(t, bot.store, 1, minsk, 1)
+	addTestSubscription(t, runner.store, 1, brest, 1)
+	for _, tt := range []struct {
+		name  string
+		bot   *Bot
+		token string
+		want  Location
+	}{
+		{name: "bot", bot: bot, token: "1:test", want: *minsk},
+		{name: "runner", bot: runner, token: "2:test", want: *brest},
+	} {
+		if sub := onlySubscription(t, tt.bot.store, 1); *sub.Location() != tt.want {
+			t.Errorf("%s subscription = %v, want %v", tt.name, *sub.Location(), tt.want)
+		}
+		tt.bot.Cron()
+		if texts := fake.sentBy(tt.token); len(texts) != 1 {
+			t.Errorf("%s notifications = %q, want one", tt.name, texts)
+		}
+	}
+	if n := countRows(t, bot.store, "SELECT COUNT(*) FROM user_session WHERE chatid=1"); n != 1 {
+		t.Errorf("%d sessions of the chat, want 1 shared", n)
+	}
+}
+
+func TestRunRetriesPolls(t *testing.T) {
+	fake := &fakeTelegram{polls: []fakePoll{
+		{fail: true},
+		{updates: []tgbotapi.Update{{UpdateID: 1}, {UpdateID: 2}}},
+		{fail: true},
+		// the processed updates are skipped
+		{updates: []tgbotapi.Update{{UpdateID: 2}, {UpdateID: 3}}},
+	}}
+	bot := newFakeTelegramBot(t, newTestStore(t), &botServices{}, fake)
+	fake.afterPolls = bot.Stop
+
+	done := make(chan struct{})
+	go func() {
+		bot.Run()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("Run is not stopped")
+	}
+
+	var offsets []string
+	for _, params := range fake.sent("getUpdates") {
+		offsets = append(offsets, params.Get("offset"))
+	}
+	// the failed polls are retried from the same offset. The zero offset is omitted.
+	want := []string{"", "", "3", "3", "4"}
+	if !reflect.DeepEqual(offsets, want) {
+		t.Errorf("getUpdates offsets = %v, want %v", offsets, want)
+	}
+}
+
+func TestCOWarning(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	tests := []struct {
+		name       string
+		components map[string]float64
+		want       bool
+	}{
+		{name: "no CO", components: map[string]float64{"pm2_5": 5}},
+		{name: "below threshold", components: map[string]float64{"co": 9999}},
+		{name: "at threshold", components: map[string]float64{"co": 10000}},
+		{name: "above threshold", components: map[string]float64{"co": 10001}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			bot := newTestBot(store, &botServices{coThreshold: 10000})
+			dp := newTestDataPoint(2, time.Now(), tt.components)
+			warning := p.Sprintf(highCOWarningTmpl, tt.components["co"])
+
+			if got := bot.coWarning(&dp, p); (got != "") != tt.want {
+				t.Errorf("coWarning() = %q, want warning %v", got, tt.want)
+			}
+			prefs, err := store.GetUserPrefs(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := &AQISubscription{UserSession: UserSession{ChatID: 1}, AirQualityIndex: 1}
+			if got := bot.formatCronNotification(s, &dp, prefs, p); strings.Contains(got, warning) != tt.want {
+				t.Errorf("formatCronNotification() = %q, want warning %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthWarning(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to AirQualityIndex
+		header   string
+		warning  bool
+	}{
+		{name: "fair to moderate", from: 2, to: 3, header: aqiGetsWorseMsg},
+		{name: "moderate to poor", from: 3, to: 4, header: aqiPoorMsg, warning: true},
+		{name: "poor to very poor", from: 4, to: 5, header: aqiVeryPoorMsg, warning: true},
+		{name: "good to very poor", from: 1, to: 5, header: aqiVeryPoorMsg, warning: true},
+		{name: "very poor to poor", from: 5, to: 4, header: aqiGetsBetterMsg},
+		{name: "poor to moderate", from: 4, to: 3, header: aqiGetsBetterMsg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			bot := newTestBot(store, &botServices{})
+			prefs, err := store.GetUserPrefs(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := &AQISubscription{UserSession: UserSession{ChatID: 1}, AirQualityIndex: tt.from}
+			dp := newTestDataPoint(tt.to, time.Now(), nil)
+
+			for _, lang := range []language.Tag{language.English, language.Russian} {
+				p := message.NewPrinter(lang)
+				got := bot.formatCronNotification(s, &dp, prefs, p)
+				if header := p.Sprintf(tt.header); !strings.HasPrefix(got, header+"\n") {
+					t.Errorf("%s: formatCronNotification() = %q, want the header %q", lang, got, header)
+				}
+				if w := p.Sprintf(limitOutdoorText); strings.Contains(got, w) != tt.warning {
+					t.Errorf("%s: formatCronNotification() = %q, want the recommendation %v", lang, got, tt.warning)
+				}
+			}
+		})
+	}
+
+	// the warnings are translated
+	ru := message.NewPrinter(language.Russian)
+	for _, msg := range []string{aqiPoorMsg, aqiVeryPoorMsg, limitOutdoorText} {
+		if got := ru.Sprintf(msg); got == msg {
+			t.Errorf("%q is not translated into Russian", msg)
+		}
+	}
+}
+
+// newTestCommand returns the message of the chat with the command text like "/check 53.9,27.56"
+func newTestCommand(chatID int64, text string) *tgbotapi.Message {
+	cmd := strings.SplitN(text, " ", 2)[0]
+	return &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: chatID, LanguageCode: "en"},
+		Chat:      &tgbotapi.Chat{ID: chatID},
+		Text:      text,
+		Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(cmd)}},
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+
+	bot.handleMessage(newTestCommand(1, "/export"))
+
+	docs := fake.sent("sendDocument")
+	if len(docs) != 1 {
+		t.Fatalf("sent %d documents, want 1. Messages: %q", len(docs), fake.texts())
+	}
+	if got := docs[0].Get("chat_id"); got != "1" {
+		t.Errorf("document is sent to %s, want 1", got)
+	}
+}
+
+// newTestCallbackQuery returns the query of the button of the kind with the arguments tapped in the chat
+func newTestCallbackQuery(t *testing.T, chatID int64, languageCode, kind string, args ...string) *tgbotapi.CallbackQuery {
+	t.Helper()
+	data, err := EncodeCallback(kind, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tgbotapi.CallbackQuery{
+		ID:      "1",
+		From:    &tgbotapi.User{ID: chatID, LanguageCode: languageCode},
+		Message: &tgbotapi.Message{MessageID: 2, Chat: &tgbotapi.Chat{ID: chatID}},
+		Data:    data,
+	}
+}
+
+func TestForgetMeCallback(t *testing.T) {
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+
+	bot.handleMessage(newTestCommand(1, "/forgetme"))
+	if _, err := store.GetSessionByChatID(1); err != nil {
+		t.Fatalf("the data is deleted before the confirmation: %v", err)
+	}
+	bot.handleCallbackQuery(newTestCallbackQuery(t, 1, "en", callbackForgetMe))
+
+	if _, err := store.GetSessionByChatID(1); err == nil {
+		t.Error("the session is not deleted")
+	}
+	if got, want := fake.lastText(), forgetMeDoneText; got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+}
+
+func TestLocationMessageWeather(t *testing.T) {
+	var w Weather
+	w.Main.Temp, w.Wind.Speed = 21.5, 3
+	tests := []struct {
+		name    string
+		weather *fakeWeather
+		units   UnitSystem
+		want    string
+	}{
+		{name: "weather", weather: &fakeWeather{weather: w}, want: "🌡 21.5°C, 💨 3.0 m/s"},
+		{name: "metric", weather: &fakeWeather{weather: w}, units: UnitsMetric, want: "🌡 21.5°C, 💨 3.0 m/s"},
+		{name: "imperial", weather: &fakeWeather{weather: w}, units: UnitsImperial, want: "🌡 70.7°F, 💨 6.7 mph"},
+		{name: "failed weather", weather: &fakeWeather{err: errors.New("timeout")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			if err := store.UpdateUserPrefs(&UserPrefs{ChatID: 1, Weather: true, Units: tt.units}); err != nil {
+				t.Fatal(err)
+			}
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 2}, weather: tt.weather}, fake)
+
+			bot.handleMessage(newTestLocationMessage(1, &Location{53.9, 27.56}))
+
+			got := fake.lastText()
+			// the AQI is shown even if the weather fails
+			if !strings.Contains(got, ThemeEmoji.AQI(2)) {
+				t.Fatalf("reply = %q, want the AQI", got)
+			}
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("reply = %q, want the weather %q", got, tt.want)
+			}
+			if tt.want == "" && strings.Contains(got, "🌡") {
+				t.Errorf("reply = %q, want no weather", got)
+			}
+		})
+	}
+}
+
+// newTestCronBot returns a Bot of the store with the AQI provider and the fake Telegram running Cron
+func newTestCronBot(t *testing.T, store *Store, aqi *fakeAQI, fake *fakeTelegram) *Bot {
+	t.Helper()
+	return newFakeTelegramBot(t, store, &botServices{wAPI: aqi, cronConcurrency: 2}, fake)
+}
+
+// onlySubscription returns the single subscription of the chat
+func onlySubscription(t *testing.T, store *Store, chatID int64) AQISubscription {
+	t.Helper()
+	subs, err := store.ListAQISubscriptions(chatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*subs) != 1 {
+		t.Fatalf("chat %d has %d subscriptions, want 1", chatID, len(*subs))
+	}
+	return (*subs)[0]
+}
+
+func TestCronFrequency(t *testing.T) {
+	tests := []struct {
+		name        string
+		frequency   time.Duration
+		lastChecked time.Duration
+		wantChecked bool
+	}{
+		{name: "every run", frequency: 0, lastChecked: time.Minute, wantChecked: true},
+		{name: "daily within 24h", frequency: 24 * time.Hour, lastChecked: 23 * time.Hour},
+		{name: "daily after 24h", frequency: 24 * time.Hour, lastChecked: 25 * time.Hour, wantChecked: true},
+		{name: "daily never checked", frequency: 24 * time.Hour, wantChecked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+			if err := store.SetSubscriptionsFrequency(1, tt.frequency); err != nil {
+				t.Fatal(err)
+			}
+			if tt.lastChecked > 0 {
+				if err := store.MarkSubscriptionChecked(onlySubscription(t, store, 1).ID, time.Now().Add(-tt.lastChecked)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			aqi := &fakeAQI{aqi: 2}
+			bot := newTestCronBot(t, store, aqi, &fakeTelegram{})
+
+			bot.Cron()
+
+			if checked := aqi.requests() > 0; checked != tt.wantChecked {
+				t.Errorf("subscription checked = %v, want %v", checked, tt.wantChecked)
+			}
+		})
+	}
+}
+
+func TestCronMarksSubscriptionChecked(t *testing.T) {
+	tests := []struct {
+		name string
+		aqi  *fakeAQI
+		// wantMarked is whether the check time advances
+		wantMarked bool
+	}{
+		{name: "notified", aqi: &fakeAQI{aqi: 4}, wantMarked: true},
+		{name: "unchanged", aqi: &fakeAQI{aqi: 2}, wantMarked: true},
+		{name: "failed request", aqi: &fakeAQI{err: errors.New("timeout")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+			before := time.Now().Add(-time.Hour).Truncate(time.Second)
+			if err := store.MarkSubscriptionChecked(onlySubscription(t, store, 1).ID, before); err != nil {
+				t.Fatal(err)
+			}
+			bot := newTestCronBot(t, store, tt.aqi, &fakeTelegram{})
+
+			bot.Cron()
+
+			got := onlySubscription(t, store, 1).LastCheckedAt
+			if marked := got.After(before); marked != tt.wantMarked {
+				t.Errorf("LastCheckedAt = %v, before %v, want advanced %v", got, before, tt.wantMarked)
+			}
+		})
+	}
+}
+
+func TestSubscriptionsLastChecked(t *testing.T) {
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+
+	bot.handleMessage(newTestCommand(1, "/subsriptions"))
+	if got := fake.lastText(); !strings.Contains(got, notCheckedYetText) {
+		t.Errorf("reply = %q, want %q", got, notCheckedYetText)
+	}
+
+	checked := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	if err := store.MarkSubscriptionChecked(onlySubscription(t, store, 1).ID, checked); err != nil {
+		t.Fatal(err)
+	}
+	bot.handleMessage(newTestCommand(1, "/subsriptions"))
+	if got, want := fake.lastText(), fmt.Sprintf(lastCheckedTmpl, checked.Format(lastCheckedLayout)); !strings.Contains(got, want) {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+}
+
+func TestCronSkipsOverlappingRun(t *testing.T) {
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls int32
+	provider := aqiProviderFunc(func(l *Location) (*ApiPollutionResponse, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return aqiResponse(l, 4), nil
+	})
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{wAPI: provider}, fake)
+
+	done := make(chan struct{})
+	go func() {
+		bot.Cron()
+		close(done)
+	}()
+	<-started
+
+	// the second run returns without processing while the first one waits for OWM API
+	overlapping := make(chan struct{})
+	go func() {
+		bot.Cron()
+		close(overlapping)
+	}()
+	select {
+	case <-overlapping:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the overlapping Cron is not skipped")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("%d OWM API requests, want 1", n)
+	}
+
+	close(release)
+	<-done
+	if n := len(fake.sent("sendMessage")); n != 1 {
+		t.Errorf("%d notifications, want 1", n)
+	}
+}
+
+func TestSendOrEditAQIMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		// lastSent is how long ago the last AQI message was sent. None if zero.
+		lastSent time.Duration
+		failEdit bool
+		wantEdit bool
+		wantSent bool
+	}{
+		{name: "first message", wantSent: true},
+		{name: "recent message", lastSent: time.Minute, wantEdit: true},
+		{name: "old message", lastSent: time.Hour, wantSent: true},
+		{name: "failed edit", lastSent: time.Minute, failEdit: true, wantEdit: true, wantSent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSession(t, store, 1, &Location{53.9, 27.56})
+			if tt.lastSent > 0 {
+				if err := store.SetLastMessage(1, 7, time.Now().Add(-tt.lastSent)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fake := &fakeTelegram{fail: map[string]bool{"editMessageText": tt.failEdit}}
+			bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+
+			bot.sendOrEditAQIMessage(1, "AQI", tgbotapi.NewInlineKeyboardMarkup())
+
+			edits, sent := fake.sent("editMessageText"), fake.sent("sendMessage")
+			if (len(edits) > 0) != tt.wantEdit || (len(sent) > 0) != tt.wantSent {
+				t.Fatalf("edited %d, sent %d messages, want edit %v, send %v", len(edits), len(sent), tt.wantEdit, tt.wantSent)
+			}
+			if tt.wantEdit && edits[0].Get("message_id") != "7" {
+				t.Errorf("edited message %s, want 7", edits[0].Get("message_id"))
+			}
+			// the next AQI message edits the sent one
+			id, at, err := store.GetLastMessage(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id == 0 || time.Since(at) > time.Minute {
+				t.Errorf("last message = %d at %v, want a recent one", id, at)
+			}
+		})
+	}
+}
+
+func TestLocationMessageStoreErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		breakStore func(t *testing.T, store *Store)
+	}{
+		{name: "closed DB", breakStore: func(t *testing.T, store *Store) { store.DB.Close() }},
+		{name: "failed data point insert", breakStore: func(t *testing.T, store *Store) {
+			_, err := store.DB.Exec(`CREATE TRIGGER fail_insert BEFORE INSERT ON data_point BEGIN SELECT RAISE(ABORT, 'injected failure'); END`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 2}}, fake)
+			// the store errors are replied to instead of panicking
+			tt.breakStore(t, store)
+
+			bot.handleMessage(newTestLocationMessage(1, &Location{53.9, 27.56}))
+
+			if got := fake.lastText(); got != safeToRetryErrMsg {
+				t.Errorf("reply = %q, want %q", got, safeToRetryErrMsg)
+			}
+		})
+	}
+}
+
+func TestLocationMessageCoordinatePrecision(t *testing.T) {
+	store := newTestStore(t)
+	store.CoordinatePrecision = 2
+	var requested *Location
+	provider := aqiProviderFunc(func(l *Location) (*ApiPollutionResponse, error) {
+		requested = l
+		return aqiResponse(l, 3), nil
+	})
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{wAPI: provider}, fake)
+
+	bot.handleMessage(newTestLocationMessage(1, &Location{53.901234, 27.567891}))
+
+	// the AQI is still looked up and shown
+	if requested == nil {
+		t.Fatal("the AQI is not requested")
+	}
+	if got := fake.lastText(); !strings.Contains(got, ThemeEmoji.AQI(3)) {
+		t.Errorf("reply = %q, want the AQI", got)
+	}
+	us, err := store.GetSessionByChatID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := *us.Location(), (Location{53.9, 27.57}); got != want {
+		t.Errorf("stored location = %v, want %v", got, want)
+	}
+}
+
+func TestLocationMessageCachedByLocation(t *testing.T) {
+	home, work := &Location{53.9, 27.56}, &Location{53.85, 27.45}
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, home, 2)
+	addTestSubscription(t, store, 1, work, 4)
+	var requests int
+	provider := aqiProviderFunc(func(l *Location) (*ApiPollutionResponse, error) {
+		requests++
+		if *l == *home {
+			return aqiResponse(l, 2), nil
+		}
+		return aqiResponse(l, 4), nil
+	})
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{wAPI: provider, cronConcurrency: 1}, fake)
+	// the DataPoints of both subscriptions are stored, the one of work the last
+	bot.Cron()
+	if requests != 2 {
+		t.Fatalf("%d AQI requests by Cron, want 2", requests)
+	}
+
+	bot.handleMessage(newTestLocationMessage(1, home))
+
+	// the cached AQI of home is shown, not the last stored one of work
+	if got := fake.lastText(); !strings.Contains(got, ThemeEmoji.AQI(2)) {
+		t.Errorf("reply = %q, want the AQI of home", got)
+	}
+	if requests != 2 {
+		t.Errorf("%d AQI requests, want the cached DataPoint of home", requests)
+	}
+}
+
+func TestCallbackQueryLocalized(t *testing.T) {
+	l := &Location{53.9, 27.56}
+	tests := []struct {
+		kind string
+		args []string
+		// want returns the expected reply in the language of the printer
+		want func(p *message.Printer) string
+	}{
+		{
+			kind: callbackNotifyMe,
+			args: locationCallbackArgs(l),
+			want: func(p *message.Printer) string {
+				return p.Sprintf(notifyMeCoordsTmpl, l.Latitude, l.Longitude, AirQualityIndex(2).LocalizedString(p))
+			},
+		},
+		{
+			kind: callbackCleanup,
+			want: func(p *message.Printer) string { return p.Sprintf(notifyMeDelText) + "\n" + p.Sprintf(restoreHintText) },
+		},
+		{kind: callbackForgetMe, want: func(p *message.Printer) string { return p.Sprintf(forgetMeDoneText) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			replies := map[string]string{}
+			for _, lang := range []string{"en", "ru"} {
+				store := newTestStore(t)
+				addTestSession(t, store, 1, l)
+				dps := []DataPoint{newTestDataPoint(2, time.Now(), nil)}
+				if err := store.AddDataPoint(1, &dps); err != nil {
+					t.Fatal(err)
+				}
+				fake := &fakeTelegram{}
+				bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 2}}, fake)
+
+				bot.handleCallbackQuery(newTestCallbackQuery(t, 1, lang, tt.kind, tt.args...))
+
+				replies[lang] = fake.lastText()
+				if want := tt.want(message.NewPrinter(language.Make(lang))); replies[lang] != want {
+					t.Errorf("reply in %s = %q, want %q", lang, replies[lang], want)
+				}
+			}
+			if replies["en"] == replies["ru"] {
+				t.Errorf("reply in Russian = %q, the same as in English", replies["ru"])
+			}
+		})
+	}
+}
+
+func TestDetailsCallbackStableOrder(t *testing.T) {
+	store := newTestStore(t)
+	addTestSession(t, store, 1, &Location{53.9, 27.56})
+	dps := []DataPoint{newTestDataPoint(2, time.Now(), testComponents)}
+	if err := store.AddDataPoint(1, &dps); err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+
+	var first string
+	for i := 0; i < 10; i++ {
+		bot.callbacks = callbackDeduper{}
+		bot.handleCallbackQuery(newTestCallbackQuery(t, 1, "en", callbackDetails))
+		got := fake.lastText()
+		if i == 0 {
+			first = got
+			continue
+		}
+		if got != first {
+			t.Fatalf("details %d = %q, want %q", i, got, first)
+		}
+	}
+	var order []string
+	for _, line := range strings.Split(first, "\n") {
+		if name, _, ok := strings.Cut(line, ": "); ok && strings.Contains(line, "μg/m³") {
+			order = append(order, name)
+		}
+	}
+	want := []string{"PM2.5", "PM10", "O₃", "NO₂", "SO₂", "CO", "NH₃", "NO"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("details order = %v, want %v", order, want)
+	}
+}
+
+func TestCronHysteresis(t *testing.T) {
+	tests := []struct {
+		name     string
+		minDelta int
+		// runs are the AQIs of the consecutive Cron runs of the subscription to AQI 2
+		runs []AirQualityIndex
+		// want are the numbers of the notifications after each run
+		want []int
+	}{
+		{name: "boundary oscillation", minDelta: 2, runs: []AirQualityIndex{3, 2, 3, 2, 3, 2}, want: []int{0, 0, 0, 0, 0, 0}},
+		{name: "persisting change", minDelta: 2, runs: []AirQualityIndex{3, 3, 3}, want: []int{0, 1, 1}},
+		{name: "change of the minimal delta", minDelta: 2, runs: []AirQualityIndex{4}, want: []int{1}},
+		{name: "disabled", minDelta: 0, runs: []AirQualityIndex{3, 2, 3}, want: []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+			aqi := &fakeAQI{}
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: aqi, hysteresisMinDelta: tt.minDelta}, fake)
+
+			for i, run := range tt.runs {
+				aqi.aqi = run
+				bot.Cron()
+				if got := len(fake.sent("sendMessage")); got != tt.want[i] {
+					t.Fatalf("run %d of AQI %d: %d notifications, want %d", i+1, run, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCronConcurrency(t *testing.T) {
+	tests := []struct {
+		concurrency int
+		subs        int
+	}{
+		{concurrency: 1, subs: 4},
+		{concurrency: 3, subs: 8},
+		{concurrency: 8, subs: 3},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d of %d", tt.concurrency, tt.subs), func(t *testing.T) {
+			store := newTestStore(t)
+			for i := 0; i < tt.subs; i++ {
+				addTestSubscription(t, store, int64(i+1), &Location{53.9, 27.56 + float64(i)}, 1)
+			}
+			var running, maxRunning int32
+			// the AQI of the i-th subscription is 2 + i%4, so the results can't be mixed up
+			get := aqiProviderFunc(func(l *Location) (*ApiPollutionResponse, error) {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				return aqiResponse(l, AirQualityIndex(2+int(l.Longitude-27.56+0.5)%4)), nil
+			})
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: get, cronConcurrency: tt.concurrency}, fake)
+
+			bot.Cron()
+
+			if maxRunning > int32(tt.concurrency) {
+				t.Errorf("%d concurrent requests, want at most %d", maxRunning, tt.concurrency)
+			}
+			for i := 0; i < tt.subs; i++ {
+				if got, want := onlySubscription(t, store, int64(i+1)).AirQualityIndex, AirQualityIndex(2+i%4); got != want {
+					t.Errorf("subscription of chat %d AQI = %v, want %v", i+1, got, want)
+				}
+			}
+			if got := len(fake.sent("sendMessage")); got != tt.subs {
+				t.Errorf("%d notifications, want %d", got, tt.subs)
+			}
+		})
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	tests := []struct {
+		code string
+		want language.Tag
+	}{
+		{"en", language.English},
+		{"ru", language.Russian},
+		{"ru-RU", language.Russian},
+		{"be", language.Make("be")},
+		{"be-BY", language.Make("be")},
+		{"en-GB", language.English},
+		{"pt-BR", language.English},
+		{"zh-Hans", language.English},
+		{"", language.English},
+		{"not a language", language.English},
+	}
+	for _, tt := range tests {
+		if got := matchLanguage(tt.code); got != tt.want {
+			t.Errorf("matchLanguage(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultLanguage(t *testing.T) {
+	defer func(lang language.Tag) { defaultLanguage = lang }(defaultLanguage)
+
+	tests := []struct {
+		defaultCode string
+		code        string
+		want        language.Tag
+	}{
+		{defaultCode: "ru", code: "", want: language.Russian},
+		{defaultCode: "ru", code: "not a language", want: language.Russian},
+		{defaultCode: "ru", code: "en", want: language.English},
+		{defaultCode: "ru", code: "pt-BR", want: language.English},
+		{defaultCode: "be-BY", code: "", want: language.Make("be")},
+		{defaultCode: "en", code: "", want: language.English},
+	}
+	for _, tt := range tests {
+		if err := setDefaultLanguage(tt.defaultCode); err != nil {
+			t.Fatal(err)
+		}
+		if got := matchLanguage(tt.code); got != tt.want {
+			t.Errorf("matchLanguage(%q) of the default %q = %v, want %v", tt.code, tt.defaultCode, got, tt.want)
+		}
+	}
+
+	for _, code := range []string{"pt", "not a language"} {
+		if err := setDefaultLanguage(code); err == nil {
+			t.Errorf("setDefaultLanguage(%q) = nil, want an error", code)
+		}
+	}
+	if defaultLanguage != language.English {
+		t.Errorf("defaultLanguage = %v after the errors, want the last one set %v", defaultLanguage, language.English)
+	}
+
+	// a user without the language code is replied in the default language
+	if err := setDefaultLanguage("ru"); err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, newTestStore(t), &botServices{}, fake)
+	ru := newTestCommand(1, "/start")
+	ru.From.LanguageCode = "ru"
+	bot.handleMessage(ru)
+	want := fake.lastText()
+	empty := newTestCommand(1, "/start")
+	empty.From.LanguageCode = ""
+	bot.handleMessage(empty)
+	if got := fake.lastText(); got != want {
+		t.Errorf("/start without the language code = %q, want %q", got, want)
+	}
+}
+
+func TestUseLanguages(t *testing.T) {
+	defer func(tags []language.Tag, matcher language.Matcher, lang language.Tag) {
+		supportedLanguages, langMatcher, defaultLanguage = tags, matcher, lang
+	}(supportedLanguages, langMatcher, defaultLanguage)
+
+	// the missing and the invalid languages are skipped
+	useLanguages([]string{"ru", "pt", "not a language"})
+	if want := []language.Tag{language.English, language.Russian}; !reflect.DeepEqual(supportedLanguages, want) {
+		t.Errorf("supportedLanguages = %v, want %v", supportedLanguages, want)
+	}
+	// Belarusian is closer to Russian than to English
+	for code, want := range map[string]language.Tag{"ru-RU": language.Russian, "be": language.Russian, "pt": language.English} {
+		if got := matchLanguage(code); got != want {
+			t.Errorf("matchLanguage(%q) = %v, want %v", code, got, want)
+		}
+	}
+
+	if err := setDefaultLanguage("pt"); err == nil {
+		t.Error("setDefaultLanguage() of an unsupported language = nil, want an error")
+	}
+	if err := setDefaultLanguage("ru-RU"); err != nil {
+		t.Fatal(err)
+	}
+	if got := matchLanguage(""); got != language.Russian {
+		t.Errorf("matchLanguage(\"\") = %v, want the default %v", got, language.Russian)
+	}
+}
+
+func TestCatalogLanguages(t *testing.T) {
+	compiled := message.DefaultCatalog
+	t.Cleanup(func() { message.DefaultCatalog = compiled })
+
+	if got, want := catalogLanguages(), []language.Tag{language.English, language.Make("be"), language.Russian}; !sameLanguages(got, want) {
+		t.Errorf("catalogLanguages() = %v, want %v", got, want)
+	}
+
+	// English is the first one even if it has no messages
+	builder := catalog.NewBuilder()
+	if err := builder.SetString(language.Ukrainian, "Details", "Подробиці"); err != nil {
+		t.Fatal(err)
+	}
+	message.DefaultCatalog = builder
+	if got, want := catalogLanguages(), []language.Tag{language.English, language.Ukrainian}; !reflect.DeepEqual(got, want) {
+		t.Errorf("catalogLanguages() = %v, want %v", got, want)
+	}
+}
+
+// sameLanguages reports whether the tags are the same ignoring the order of the ones after the first
+func sameLanguages(a, b []language.Tag) bool {
+	if len(a) != len(b) || len(a) == 0 || a[0] != b[0] {
+		return false
+	}
+	for _, tag := range b {
+		if !containsLanguage(a, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUseLanguagesWarnings(t *testing.T) {
+	defer func(tags []language.Tag, matcher language.Matcher) {
+		supportedLanguages, langMatcher = tags, matcher
+	}(supportedLanguages, langMatcher)
+	tests := []struct {
+		name     string
+		codes    []string
+		want     []language.Tag
+		warnings []string
+	}{
+		{name: "all the catalog", want: catalogLanguages()},
+		{name: "all configured", codes: []string{"ru", "be", "en"}, want: []language.Tag{language.English, language.Russian, language.Make("be")}},
+		{name: "duplicates", codes: []string{"ru", "ru"}, want: []language.Tag{language.English, language.Russian},
+			warnings: []string{"the be translations are not used"}},
+		{name: "missing translations", codes: []string{"ru", "be", "pt"}, want: []language.Tag{language.English, language.Russian, language.Make("be")},
+			warnings: []string{"supported language pt has no translations"}},
+		{name: "invalid code", codes: []string{"ru", "be", "not a language"}, want: []language.Tag{language.English, language.Russian, language.Make("be")},
+			warnings: []string{`supported language "not a language" is invalid`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supportedLanguages = catalogLanguages()
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			useLanguages(tt.codes)
+
+			if !sameLanguages(supportedLanguages, tt.want) {
+				t.Errorf("supportedLanguages = %v, want %v", supportedLanguages, tt.want)
+			}
+			if !strings.Contains(logs.String(), "message catalog languages: ") {
+				t.Errorf("logs = %q, want the catalog languages", logs.String())
+			}
+			if n := strings.Count(logs.String(), "WARNING"); n != len(tt.warnings) {
+				t.Errorf("logs = %q, want %d warning(s)", logs.String(), len(tt.warnings))
+			}
+			for _, w := range tt.warnings {
+				if !strings.Contains(logs.String(), w) {
+					t.Errorf("logs = %q, want the warning %q", logs.String(), w)
+				}
+			}
+		})
+	}
+}
+
+// notifyMeCallbacks returns the data of the notifyMe buttons of the messages sent and edited in order
+func notifyMeCallbacks(t *testing.T, fake *fakeTelegram) []string {
+	t.Helper()
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	var data []string
+	for _, r := range fake.requests {
+		markup := r.params.Get("reply_markup")
+		if markup == "" {
+			continue
+		}
+		var keyboard tgbotapi.InlineKeyboardMarkup
+		if err := json.Unmarshal([]byte(markup), &keyboard); err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range keyboard.InlineKeyboard {
+			for _, b := range row {
+				if b.CallbackData != nil && strings.HasPrefix(*b.CallbackData, callbackNotifyMe) {
+					data = append(data, *b.CallbackData)
+				}
+			}
+		}
+	}
+	return data
+}
+
+func TestNotifyMeTwoQuickLocations(t *testing.T) {
+	minsk, london := &Location{53.9, 27.56}, &Location{51.51, -0.13}
+	provider := aqiProviderFunc(func(l *Location) (*ApiPollutionResponse, error) {
+		if l.DistanceTo(london) < 1000 {
+			return aqiResponse(l, 4), nil
+		}
+		return aqiResponse(l, 2), nil
+	})
+	tests := []struct {
+		name string
+		// pressed is the index of the button pressed after sharing both locations
+		pressed  int
+		want     Location
+		wantAQI  AirQualityIndex
+		wantText string
+	}{
+		{name: "the button of the first location", pressed: 0, want: *minsk, wantAQI: 2, wantText: "at 53.9000;27.5600"},
+		{name: "the button of the second location", pressed: 1, want: *london, wantAQI: 4, wantText: "at 51.5100;-0.1300"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: provider}, fake)
+
+			bot.handleMessage(newTestLocationMessage(1, minsk))
+			bot.handleMessage(newTestLocationMessage(1, london))
+			buttons := notifyMeCallbacks(t, fake)
+			if len(buttons) != 2 {
+				t.Fatalf("notifyMe buttons = %q, want one per location", buttons)
+			}
+			query := newTestCallbackQuery(t, 1, "en", callbackNotifyMe)
+			query.Data = buttons[tt.pressed]
+			bot.handleCallbackQuery(query)
+
+			s := onlySubscription(t, store, 1)
+			if *s.Location() != tt.want || s.AirQualityIndex != tt.wantAQI {
+				t.Errorf("subscribed to %+v of AQI %v, want %+v of AQI %v", *s.Location(), s.AirQualityIndex, tt.want, tt.wantAQI)
+			}
+			if got := fake.lastText(); !strings.Contains(got, tt.wantText) {
+				t.Errorf("reply = %q, want %q", got, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestRepeatedCallbackIgnored(t *testing.T) {
+	store := newTestStore(t)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 2}}, fake)
+	bot.handleMessage(newTestLocationMessage(1, &Location{53.9, 27.56}))
+	buttons := notifyMeCallbacks(t, fake)
+	if len(buttons) != 1 {
+		t.Fatalf("notifyMe buttons = %q, want one", buttons)
+	}
+	query := newTestCallbackQuery(t, 1, "en", callbackNotifyMe)
+	query.Data = buttons[0]
+
+	bot.handleCallbackQuery(query)
+	sent := len(fake.sent("sendMessage"))
+	changes := countRows(t, store, "SELECT total_changes()")
+	// the same query and another tap of the same button
+	bot.handleCallbackQuery(query)
+	tap := *query
+	tap.ID = "2"
+	bot.handleCallbackQuery(&tap)
+
+	if n := len(fake.sent("sendMessage")) - sent; n != 0 {
+		t.Errorf("repeated callbacks sent %d messages, want 0", n)
+	}
+	if n := countRows(t, store, "SELECT total_changes()") - changes; n != 0 {
+		t.Errorf("repeated callbacks changed %d DB rows, want 0", n)
+	}
+	if n := countRows(t, store, "SELECT COUNT(*) FROM subscription WHERE chat_id=1"); n != 1 {
+		t.Errorf("%d subscriptions, want 1", n)
+	}
+	// the repeated callbacks are still answered, stopping the progress of the button
+	if n := len(fake.sent("answerCallbackQuery")); n != 3 {
+		t.Errorf("%d callback answers, want 3", n)
+	}
+}
+
+func TestCallbackAnswer(t *testing.T) {
+	tests := []struct {
+		name string
+		// data is the data of the callback query, the notifyMe button of the shared location if empty
+		data string
+		// failAnswer makes Telegram reject the answers of the callback queries
+		failAnswer bool
+		toast      string
+		subscribed bool
+	}{
+		{name: "done", toast: callbackDoneText, subscribed: true},
+		{name: "answer rejected", failAnswer: true, toast: callbackDoneText, subscribed: true},
+		{name: "malformed data", data: "notifyMe:north:east", toast: callbackFailedText},
+		{name: "undecodable data", data: strings.Repeat("a", maxCallbackDataLen+1), toast: callbackFailedText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 2}}, fake)
+			bot.handleMessage(newTestLocationMessage(1, &Location{53.9, 27.56}))
+			if tt.failAnswer {
+				fake.fail = map[string]bool{"answerCallbackQuery": true}
+			}
+			query := newTestCallbackQuery(t, 1, "en", callbackNotifyMe)
+			query.Data = tt.data
+			if tt.data == "" {
+				query.Data = notifyMeCallbacks(t, fake)[0]
+			}
+
+			bot.handleCallbackQuery(query)
+
+			answers := fake.sent("answerCallbackQuery")
+			if len(answers) != 1 {
+				t.Fatalf("%d callback answers, want 1", len(answers))
+			}
+			if got := answers[0].Get("text"); got != tt.toast {
+				t.Errorf("toast = %q, want %q", got, tt.toast)
+			}
+			subscribed := countRows(t, store, "SELECT COUNT(*) FROM subscription WHERE chat_id=1") == 1
+			if subscribed != tt.subscribed {
+				t.Errorf("subscribed = %v, want %v", subscribed, tt.subscribed)
+			}
+		})
+	}
+
+	// the toast is localized
+	store := newTestStore(t)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+	bot.handleCallbackQuery(newTestCallbackQuery(t, 1, "ru", callbackCleanup))
+	ru := message.NewPrinter(language.Russian)
+	if got, want := fake.sent("answerCallbackQuery")[0].Get("text"), ru.Sprintf(callbackDoneText); got != want || got == callbackDoneText {
+		t.Errorf("toast in Russian = %q, want %q", got, want)
+	}
+}
+
+func TestNotifyMeConfirmation(t *testing.T) {
+	en := message.NewPrinter(language.English)
+	minsk := Place{Name: "Minsk", Country: "BY"}
+	tests := []struct {
+		name     string
+		geocoder Geocoder
+		lang     string
+		want     string
+	}{
+		{name: "place", geocoder: &fakeGeocoder{place: minsk}, lang: "en",
+			want: "OK. I will notify you if AQI changes in Minsk, BY (53.9000;27.5600). Current AQI: " + AirQualityIndex(3).LocalizedString(en) + ". /subsriptions"},
+		{name: "no geocoder", lang: "en",
+			want: "OK. I will notify you if AQI changes at 53.9000;27.5600. Current AQI: " + AirQualityIndex(3).LocalizedString(en) + ". /subsriptions"},
+		{name: "place not found", geocoder: &fakeGeocoder{err: errPlaceNotFound}, lang: "en",
+			want: "OK. I will notify you if AQI changes at 53.9000;27.5600. Current AQI: " + AirQualityIndex(3).LocalizedString(en) + ". /subsriptions"},
+		{name: "geocoding failed", geocoder: &fakeGeocoder{err: errors.New("timeout")}, lang: "en",
+			want: "OK. I will notify you if AQI changes at 53.9000;27.5600. Current AQI: " + AirQualityIndex(3).LocalizedString(en) + ". /subsriptions"},
+		{name: "localized", geocoder: &fakeGeocoder{place: minsk}, lang: "ru",
+			want: message.NewPrinter(language.Russian).Sprintf(notifyMePlaceTmpl, "Minsk, BY", 53.9, 27.56, AirQualityIndex(3).LocalizedString(message.NewPrinter(language.Russian)))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 3}, geocoder: tt.geocoder}, fake)
+			bot.handleMessage(newTestLocationMessage(1, &Location{53.9, 27.56}))
+			query := newTestCallbackQuery(t, 1, tt.lang, callbackNotifyMe)
+			query.Data = notifyMeCallbacks(t, fake)[0]
+
+			bot.handleCallbackQuery(query)
+
+			if got := fake.lastText(); got != tt.want {
+				t.Errorf("confirmation = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	ru := message.NewPrinter(language.Russian)
+	for _, tmpl := range []string{notifyMePlaceTmpl, notifyMeCoordsTmpl} {
+		if ru.Sprintf(tmpl) == en.Sprintf(tmpl) {
+			t.Errorf("%q is not translated into Russian", tmpl)
+		}
+	}
+}
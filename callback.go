@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxCallbackDataLen is the limit of Telegram on the callback data of inline buttons in bytes
+const maxCallbackDataLen = 64
+
+// callbackDedupWindow is how long a handled callback query is remembered. Repeated ones are ignored meanwhile.
+const callbackDedupWindow = 5 * time.Second
+
+// callbackSeparator separates the kind and the arguments of the callback data
+const callbackSeparator = ":"
+
+// kinds of the callback data handled by handleCallbackQuery
+const (
+	callbackNotifyMe = "notifyMe"
+	callbackDetails  = "details"
+	callbackCleanup  = "cleanup"
+	callbackForgetMe = "forgetMe"
+)
+
+// ErrCallbackTooLong is returned if the encoded callback data exceeds maxCallbackDataLen
+var ErrCallbackTooLong = fmt.Errorf("callback data is longer than %d bytes", maxCallbackDataLen)
+
+// EncodeCallback returns the callback data of the kind with the arguments as "kind:arg1:arg2".
+// The kind and the arguments must not contain the separator.
+func EncodeCallback(kind string, args ...string) (string, error) {
+	if kind == "" {
+		return "", errors.New("empty callback kind")
+	}
+	for _, s := range append([]string{kind}, args...) {
+		if strings.Contains(s, callbackSeparator) {
+			return "", fmt.Errorf("callback part %q contains %q", s, callbackSeparator)
+		}
+	}
+	data := strings.Join(append([]string{kind}, args...), callbackSeparator)
+	if len(data) > maxCallbackDataLen {
+		return "", ErrCallbackTooLong
+	}
+	return data, nil
+}
+
+// DecodeCallback returns the kind and the arguments of the callback data encoded by EncodeCallback.
+// The data comes from the users' clients, so any malformed one is an error.
+func DecodeCallback(data string) (string, []string, error) {
+	if len(data) > maxCallbackDataLen {
+		return "", nil, ErrCallbackTooLong
+	}
+	parts := strings.Split(data, callbackSeparator)
+	if parts[0] == "" {
+		return "", nil, fmt.Errorf("no callback kind in %q", data)
+	}
+	return parts[0], parts[1:], nil
+}
+
+// callbackButton returns an inline button with the encoded callback data. It panics on invalid data.
+func callbackButton(text, kind string, args ...string) tgbotapi.InlineKeyboardButton {
+	data, err := EncodeCallback(kind, args...)
+	if err != nil {
+		log.Panic("EncodeCallback: ", err)
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(text, data)
+}
+
+// locationCallbackArgs returns the callback arguments of the location as "latitude", "longitude"
+func locationCallbackArgs(l *Location) []string {
+	return []string{
+		strconv.FormatFloat(l.Latitude, 'f', 6, 64),
+		strconv.FormatFloat(l.Longitude, 'f', 6, 64),
+	}
+}
+
+// parseLocationCallbackArgs returns the location of the callback arguments encoded by locationCallbackArgs
+func parseLocationCallbackArgs(args []string) (*Location, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected latitude and longitude, got %d arguments", len(args))
+	}
+	lat, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("latitude: %w", err)
+	}
+	lon, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("longitude: %w", err)
+	}
+	l := &Location{lat, lon}
+	if !l.InRange() {
+		return nil, fmt.Errorf("coordinates %f;%f are out of range", lat, lon)
+	}
+	return l, nil
+}
+
+// callbackDeduper remembers the handled callback queries for callbackDedupWindow.
+// A query is the same if it has the same ID, e.g. redelivered to the webhook,
+// or the same user tapped the same button of the message again.
+type callbackDeduper struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// First reports whether the query is not a repeated one and records it
+func (d *callbackDeduper) First(query *tgbotapi.CallbackQuery, now time.Time) bool {
+	keys := []string{"id:" + query.ID}
+	if query.From != nil && query.Message != nil {
+		keys = append(keys, fmt.Sprintf("tap:%d:%d:%d:%s", query.From.ID, query.Message.Chat.ID, query.Message.MessageID, query.Data))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seen == nil {
+		d.seen = map[string]time.Time{}
+	}
+	for k, t := range d.seen {
+		if now.Sub(t) >= callbackDedupWindow {
+			delete(d.seen, k)
+		}
+	}
+	first := true
+	for _, k := range keys {
+		if _, ok := d.seen[k]; ok {
+			first = false
+		}
+		d.seen[k] = now
+	}
+	return first
+}
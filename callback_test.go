@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestCallbackRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		kind string
+		args []string
+		want string
+	}{
+		{name: "no arguments", kind: callbackDetails, want: "details"},
+		{name: "location", kind: callbackNotifyMe, args: locationCallbackArgs(&Location{-33.868820, 151.209296}),
+			want: "notifyMe:-33.868820:151.209296"},
+		{name: "empty argument", kind: "page", args: []string{"", "2"}, want: "page::2"},
+		{name: "at the limit", kind: "k", args: []string{strings.Repeat("a", maxCallbackDataLen-2)},
+			want: "k:" + strings.Repeat("a", maxCallbackDataLen-2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeCallback(tt.kind, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data != tt.want {
+				t.Errorf("EncodeCallback() = %q, want %q", data, tt.want)
+			}
+			kind, args, err := DecodeCallback(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind != tt.kind || len(args) != len(tt.args) || (len(args) > 0 && !reflect.DeepEqual(args, tt.args)) {
+				t.Errorf("DecodeCallback() = %q, %q, want %q, %q", kind, args, tt.kind, tt.args)
+			}
+		})
+	}
+}
+
+func TestCallbackErrors(t *testing.T) {
+	over := strings.Repeat("a", maxCallbackDataLen-1)
+	if _, err := EncodeCallback("k", over); !errors.Is(err, ErrCallbackTooLong) {
+		t.Errorf("EncodeCallback() of %d bytes = %v, want %v", len(over)+2, err, ErrCallbackTooLong)
+	}
+	if _, _, err := DecodeCallback("k:" + over); !errors.Is(err, ErrCallbackTooLong) {
+		t.Errorf("DecodeCallback() of %d bytes = %v, want %v", len(over)+2, err, ErrCallbackTooLong)
+	}
+	for _, tt := range []struct {
+		kind string
+		args []string
+	}{
+		{kind: ""},
+		{kind: "a:b"},
+		{kind: "k", args: []string{"1:2"}},
+	} {
+		if data, err := EncodeCallback(tt.kind, tt.args...); err == nil {
+			t.Errorf("EncodeCallback(%q, %q) = %q, want error", tt.kind, tt.args, data)
+		}
+	}
+	for _, data := range []string{"", ":", ":arg"} {
+		if _, _, err := DecodeCallback(data); err == nil {
+			t.Errorf("DecodeCallback(%q) = nil error, want error", data)
+		}
+	}
+}
+
+func TestParseLocationCallbackArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    *Location
+		wantErr bool
+	}{
+		{args: []string{"53.900000", "27.560000"}, want: &Location{53.9, 27.56}},
+		{args: []string{"53.9"}, wantErr: true},
+		{args: []string{"north", "27.56"}, wantErr: true},
+		{args: []string{"53.9", "east"}, wantErr: true},
+		{args: []string{"91", "27.56"}, wantErr: true},
+		{args: []string{"53.9", "181"}, wantErr: true},
+		{args: []string{"NaN", "27.56"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseLocationCallbackArgs(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLocationCallbackArgs(%q) error = %v, want error %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && *got != *tt.want {
+			t.Errorf("parseLocationCallbackArgs(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCallbackDeduper(t *testing.T) {
+	now := time.Now()
+	// query returns the callback query of the user tapping the button of the message
+	query := func(id string, userID int64, messageID int, data string) *tgbotapi.CallbackQuery {
+		return &tgbotapi.CallbackQuery{
+			ID:      id,
+			From:    &tgbotapi.User{ID: userID},
+			Message: &tgbotapi.Message{MessageID: messageID, Chat: &tgbotapi.Chat{ID: userID}},
+			Data:    data,
+		}
+	}
+	tests := []struct {
+		name   string
+		second *tgbotapi.CallbackQuery
+		after  time.Duration
+		want   bool
+	}{
+		{name: "same query", second: query("1", 1, 2, "details"), want: false},
+		{name: "redelivered to another message", second: query("1", 1, 3, "cleanUp"), want: false},
+		{name: "same tap", second: query("2", 1, 2, "details"), want: false},
+		{name: "tap within the window", second: query("2", 1, 2, "details"), after: callbackDedupWindow - time.Millisecond, want: false},
+		{name: "tap after the window", second: query("2", 1, 2, "details"), after: callbackDedupWindow, want: true},
+		{name: "other button", second: query("2", 1, 2, "cleanUp"), want: true},
+		{name: "other message", second: query("2", 1, 3, "details"), want: true},
+		{name: "other user", second: query("2", 4, 2, "details"), want: true},
+		{name: "no message", second: &tgbotapi.CallbackQuery{ID: "2", Data: "details"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d callbackDeduper
+			if !d.First(query("1", 1, 2, "details"), now) {
+				t.Fatal("First() = false for the first query")
+			}
+			if got := d.First(tt.second, now.Add(tt.after)); got != tt.want {
+				t.Errorf("First() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzDecodeCallback(f *testing.F) {
+	for _, seed := range []string{
+		"details", "cleanup", "notifyMe:53.900000:27.560000", "notifyMe:-33.868820:151.209296",
+		"details:NaN:NaN", "notifyMe:Inf:-Inf", "notifyMe:+Inf:0", "notifyMe:1e309:0", "notifyMe:91:0", "notifyMe:0:181",
+		"notifyMe:53.9", "notifyMe:53.9:27.56:1", "notifyMe::", "notifyMe:0x1p-2:0",
+		"", ":", "::", ":arg", "k:" + strings.Repeat("a", maxCallbackDataLen-2), "k:" + strings.Repeat("a", maxCallbackDataLen),
+		"\x00:\xff", "уведомить:53,9:27,56",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data string) {
+		kind, args, err := DecodeCallback(data)
+		if err != nil {
+			if kind != "" || args != nil {
+				t.Errorf("DecodeCallback(%q) = %q, %q with the error %v, want none", data, kind, args, err)
+			}
+			if len(data) > maxCallbackDataLen && !errors.Is(err, ErrCallbackTooLong) {
+				t.Errorf("DecodeCallback() of %d bytes = %v, want %v", len(data), err, ErrCallbackTooLong)
+			}
+			return
+		}
+		if kind == "" || len(data) > maxCallbackDataLen {
+			t.Fatalf("DecodeCallback(%q) = %q, %q, want an error", data, kind, args)
+		}
+		// the decoded data is encoded back as it was
+		if encoded, err := EncodeCallback(kind, args...); err != nil || encoded != data {
+			t.Errorf("EncodeCallback(%q, %q) = %q, %v, want %q", kind, args, encoded, err, data)
+		}
+
+		l, err := parseLocationCallbackArgs(args)
+		if err != nil {
+			if l != nil {
+				t.Errorf("parseLocationCallbackArgs(%q) = %v with the error %v, want nil", args, *l, err)
+			}
+			return
+		}
+		if math.IsNaN(l.Latitude) || math.IsNaN(l.Longitude) || !l.InRange() {
+			t.Errorf("parseLocationCallbackArgs(%q) = %v, want coordinates in range", args, *l)
+		}
+	})
+}
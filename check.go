@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+const checkUsageMsg = "Usage: /check <latitude> <longitude>, e.g. /check 53.9 27.56"
+
+// checkText reports the current AQI at the coordinates of the /check argument.
+// Nothing is stored: neither the session nor the DataPoint.
+func (bot *Bot) checkText(chatID int64, arg string, p *message.Printer) string {
+	location, err := ParseLocation(arg)
+	if err != nil {
+		return p.Sprintf(checkUsageMsg)
+	}
+	resp, err := bot.previewAirPollution(location)
+	if err == nil && len(resp.DP) == 0 {
+		err = errNoDataPoints
+	}
+	if err != nil {
+		log.Print("GetAirPollution: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	dp := &resp.DP[0]
+
+	msgText := []string{FormatAQIMessage(dp, p, "", ProfileGeneral, bot.scaleOf(chatID), bot.themeOf(chatID))}
+	if w := bot.coWarning(dp, p); w != "" {
+		msgText = append(msgText, "", w)
+	}
+	msgText = append(msgText, "", HumanizeSince(time.Unix(dp.Dt, 0), p))
+	return strings.Join(msgText, "\n")
+}
+
+// previewAirPollution gets the air pollution for the lookups storing nothing, like /check and /route,
+// by the AQIPreviewer if the AQIProvider is one
+func (bot *Bot) previewAirPollution(l *Location) (*ApiPollutionResponse, error) {
+	if previewer, ok := bot.wAPI.(AQIPreviewer); ok {
+		return previewer.PreviewAirPollution(l)
+	}
+	return bot.wAPI.GetAirPollution(l)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckCommandNoDBWrites(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		wantText string
+	}{
+		{name: "coordinates", text: "/check 53.9 27.56", wantText: ThemeEmoji.AQI(2)},
+		{name: "comma separated", text: "/check 53.9,27.56", wantText: ThemeEmoji.AQI(2)},
+		{name: "invalid coordinates", text: "/check 95 27.56", wantText: checkUsageMsg},
+		{name: "no coordinates", text: "/check", wantText: checkUsageMsg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			owma := newTestOWM(t, func(r *http.Request) (int, string) {
+				return http.StatusOK, `{"coord":{"lat":53.9,"lon":27.56},"list":[{"dt":1700000000,"main":{"aqi":2},"components":{"co":200}}]}`
+			})
+			// the raw responses of /check aren't captured either
+			owma.RawCapture = store
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: owma}, fake)
+			before := countRows(t, store, "SELECT total_changes()")
+
+			bot.handleMessage(newTestCommand(1, tt.text))
+
+			if got := fake.lastText(); !strings.Contains(got, tt.wantText) {
+				t.Errorf("reply = %q, want %q", got, tt.wantText)
+			}
+			if n := countRows(t, store, "SELECT total_changes()") - before; n != 0 {
+				t.Errorf("/check changed %d DB rows, want 0", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	helpHeaderText  = "Share your location to get the Air Quality Index and subscribe to its changes. Commands:"
+	helpLineTmpl    = "/%s - %s"
+	helpExampleTmpl = "    e.g. %s"
+	cmdDisabledMsg  = "This command is disabled"
+
+	airCmdDesc          = "get the Air Quality Index for your location"
+	subsriptionsCmdDesc = "list your subscriptions"
+	refreshCmdDesc      = "re-check your subscriptions now"
+	favoriteCmdDesc     = "save your location as a favorite"
+	subscribeAllCmdDesc = "subscribe to all your favorites"
+	restoreCmdDesc      = "bring back the subscriptions you removed"
+	topCmdDesc          = "your subscriptions with the worst AQI"
+	statsMeCmdDesc      = "your AQI statistics of the last week"
+	checkCmdDesc        = "AQI at coordinates without storing them"
+	nearbyCmdDesc       = "AQI around your location"
+	whereAmICmdDesc     = "the location your AQI is shown for"
+	routeCmdDesc        = "AQI along a route of waypoints"
+	componentsCmdDesc   = "pollutant concentrations at your location"
+	rawCmdDesc          = "the AQI number of the provider"
+	weatherCmdDesc      = "add the current weather to AQI messages"
+	unitsCmdDesc        = "metric or imperial units of the weather"
+	mapCmdDesc          = "attach a map pin to the AQI of a location"
+	scaleCmdDesc        = "the AQI scale: OWM, US EPA or AQHI"
+	themeCmdDesc        = "show the AQI with emoji or as plain text"
+	frequencyCmdDesc    = "how often your subscriptions are checked"
+	labelCmdDesc        = "name a subscription, like Home"
+	profileCmdDesc      = "health advice for your sensitivity profile"
+	snoozeCmdDesc       = "pause the notifications"
+	cooldownCmdDesc     = "minimal interval between the notifications"
+	testCmdDesc         = "send a test notification"
+	digestCmdDesc       = "a weekly digest instead of the alerts"
+	peaksCmdDesc        = "the AQI peaks of the previous day every morning"
+	exportCmdDesc       = "download your data"
+	forgetMeCmdDesc     = "delete your data"
+	privacyCmdDesc      = "what data is stored and for how long"
+	aboutCmdDesc        = "about the bot"
+	helpCmdDesc         = "the list of the commands"
+)
+
+// botCommand describes a command for /help and the command menu of Telegram
+type botCommand struct {
+	name        string
+	description string
+	// example is a usage example shown by /help. None if empty.
+	example string
+}
+
+// botCommands are the user commands in the order of /help. Admin commands are not listed.
+var botCommands = []botCommand{
+	{name: "air", description: airCmdDesc, example: "/air 53.9 27.56"},
+	{name: "subsriptions", description: subsriptionsCmdDesc},
+	{name: "refresh", description: refreshCmdDesc, example: "/refresh 2"},
+	{name: "favorite", description: favoriteCmdDesc, example: "/favorite add Home"},
+	{name: "subscribe_all", description: subscribeAllCmdDesc},
+	{name: "restore", description: restoreCmdDesc},
+	{name: "top", description: topCmdDesc},
+	{name: "stats_me", description: statsMeCmdDesc},
+	{name: "check", description: checkCmdDesc, example: "/check 53.9 27.56"},
+	{name: "nearby", description: nearbyCmdDesc},
+	{name: "whereami", description: whereAmICmdDesc},
+	{name: "route", description: routeCmdDesc, example: "/route 53.9 27.56 | 53.92 27.6"},
+	{name: "components", description: componentsCmdDesc},
+	{name: "raw", description: rawCmdDesc},
+	{name: "weather", description: weatherCmdDesc, example: "/weather on"},
+	{name: "units", description: unitsCmdDesc, example: "/units imperial"},
+	{name: "map", description: mapCmdDesc, example: "/map on"},
+	{name: "scale", description: scaleCmdDesc, example: "/scale epa"},
+	{name: "theme", description: themeCmdDesc, example: "/theme plain"},
+	{name: "frequency", description: frequencyCmdDesc, example: "/frequency hourly"},
+	{name: "label", description: labelCmdDesc, example: "/label 1 Home"},
+	{name: "profile", description: profileCmdDesc, example: "/profile children"},
+	{name: "snooze", description: snoozeCmdDesc, example: "/snooze 24h"},
+	{name: "cooldown", description: cooldownCmdDesc, example: "/cooldown 1h"},
+	{name: "test", description: testCmdDesc, example: "/test 1"},
+	{name: "digest", description: digestCmdDesc, example: "/digest on"},
+	{name: "peaks", description: peaksCmdDesc, example: "/peaks on"},
+	{name: "export", description: exportCmdDesc},
+	{name: "forgetme", description: forgetMeCmdDesc},
+	{name: "privacy", description: privacyCmdDesc},
+	{name: "about", description: aboutCmdDesc},
+	{name: "help", description: helpCmdDesc},
+}
+
+// commandAliases map the alternative names of the commands to the names in botCommands
+var commandAliases = map[string]string{"airQualityIndex": "air"}
+
+// commandEnabled reports whether the command, or the command it is an alias of, isn't disabled by the config
+func (bot *Bot) commandEnabled(name string) bool {
+	if alias, ok := commandAliases[name]; ok {
+		name = alias
+	}
+	return !bot.disabledCommands[name]
+}
+
+// enabledCommands returns botCommands without the disabled ones
+func (bot *Bot) enabledCommands() []botCommand {
+	cmds := make([]botCommand, 0, len(botCommands))
+	for _, cmd := range botCommands {
+		if bot.commandEnabled(cmd.name) {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+// commandMenuLanguages are the languages the command menu is translated to besides the default English one
+var commandMenuLanguages = []language.Tag{language.Russian, language.Make("be")}
+
+// helpText lists the commands with the descriptions and the examples
+func helpText(cmds []botCommand, p *message.Printer) string {
+	msgText := []string{p.Sprintf(helpHeaderText), ""}
+	for _, cmd := range cmds {
+		msgText = append(msgText, p.Sprintf(helpLineTmpl, cmd.name, p.Sprintf(cmd.description)))
+		if cmd.example != "" {
+			msgText = append(msgText, p.Sprintf(helpExampleTmpl, cmd.example))
+		}
+	}
+	return strings.Join(msgText, "\n")
+}
+
+// menuCommands returns the commands translated by the printer for setMyCommands
+func menuCommands(cmds []botCommand, p *message.Printer) []tgbotapi.BotCommand {
+	menu := make([]tgbotapi.BotCommand, 0, len(cmds))
+	for _, cmd := range cmds {
+		menu = append(menu, tgbotapi.BotCommand{Command: cmd.name, Description: p.Sprintf(cmd.description)})
+	}
+	return menu
+}
+
+// setMyCommands sets the command menu of Telegram to the enabled botCommands in English and commandMenuLanguages
+func (bot *Bot) setMyCommands() {
+	cmds := bot.enabledCommands()
+	if _, err := bot.tApi.Request(tgbotapi.NewSetMyCommands(menuCommands(cmds, message.NewPrinter(language.English))...)); err != nil {
+		log.Print("setMyCommands: ", err)
+	}
+	for _, lang := range commandMenuLanguages {
+		cfg := tgbotapi.NewSetMyCommandsWithScopeAndLanguage(tgbotapi.NewBotCommandScopeDefault(), lang.String(),
+			menuCommands(cmds, message.NewPrinter(lang))...)
+		if _, err := bot.tApi.Request(cfg); err != nil {
+			log.Printf("setMyCommands %s: %v", lang, err)
+		}
+	}
+}
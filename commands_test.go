@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestHelpText(t *testing.T) {
+	tests := []struct {
+		lang  language.Tag
+		lines []string
+	}{
+		{language.English, []string{
+			"Share your location to get the Air Quality Index and subscribe to its changes. Commands:",
+			"/air - get the Air Quality Index for your location",
+			"    e.g. /air 53.9 27.56",
+			"/subsriptions - list your subscriptions",
+			"/help - the list of the commands",
+		}},
+		{language.Russian, []string{
+			"Отправьте геопозицию, чтобы узнать Индекс Качества Воздуха и подписаться на его изменения. Команды:",
+			"/air - Индекс Качества Воздуха для вашего местоположения",
+			"    например /air 53.9 27.56",
+			"/help - список команд",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang.String(), func(t *testing.T) {
+			got := helpText(botCommands, message.NewPrinter(tt.lang))
+			lines := strings.Split(got, "\n")
+			for _, want := range tt.lines {
+				if !containsLine(lines, want) {
+					t.Errorf("helpText() has no line %q:\n%s", want, got)
+				}
+			}
+			for _, cmd := range botCommands {
+				if !strings.Contains(got, "/"+cmd.name+" - ") {
+					t.Errorf("helpText() doesn't describe /%s", cmd.name)
+				}
+			}
+		})
+	}
+}
+
+func TestHelpCommand(t *testing.T) {
+	store := newTestStore(t)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{disabledCommands: map[string]bool{"route": true}}, fake)
+
+	bot.handleMessage(newTestCommand(1, "/help"))
+	got := fake.lastText()
+	if want := helpText(bot.enabledCommands(), message.NewPrinter(language.English)); got != want {
+		t.Errorf("/help = %q, want %q", got, want)
+	}
+	if strings.Contains(got, "/route") {
+		t.Errorf("/help lists the disabled /route:\n%s", got)
+	}
+}
+
+func TestSetMyCommands(t *testing.T) {
+	store := newTestStore(t)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{disabledCommands: map[string]bool{"route": true}}, fake)
+
+	bot.setMyCommands()
+	sent := fake.sent("setMyCommands")
+	if len(sent) != 1+len(commandMenuLanguages) {
+		t.Fatalf("setMyCommands sent %d times, want %d", len(sent), 1+len(commandMenuLanguages))
+	}
+	langs := map[string]bool{}
+	for _, params := range sent {
+		langs[params.Get("language_code")] = true
+		if commands := params.Get("commands"); !strings.Contains(commands, `"help"`) || strings.Contains(commands, `"route"`) {
+			t.Errorf("commands = %s, want the enabled commands", commands)
+		}
+	}
+	for _, want := range []string{"", "ru", "be"} {
+		if !langs[want] {
+			t.Errorf("no command menu for the language %q", want)
+		}
+	}
+}
+
+func containsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDisabledCommands(t *testing.T) {
+	disabled := map[string]bool{"air": true, "check": true}
+	tests := []struct {
+		command      string
+		wantDisabled bool
+	}{
+		{command: "/check 53.9 27.56", wantDisabled: true},
+		{command: "/air", wantDisabled: true},
+		// disabling a command disables its aliases
+		{command: "/airQualityIndex", wantDisabled: true},
+		{command: "/about"},
+		{command: "/help"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			store := newTestStore(t)
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 2}, disabledCommands: disabled}, fake)
+
+			bot.handleMessage(newTestCommand(1, tt.command))
+			if got := fake.lastText() == cmdDisabledMsg; got != tt.wantDisabled {
+				t.Errorf("%s = %q, want disabled %v", tt.command, fake.lastText(), tt.wantDisabled)
+			}
+		})
+	}
+
+	bot := newTestBot(newTestStore(t), &botServices{disabledCommands: disabled})
+	for _, cmd := range bot.enabledCommands() {
+		if disabled[cmd.name] {
+			t.Errorf("enabledCommands() has the disabled /%s", cmd.name)
+		}
+	}
+	if got, want := len(bot.enabledCommands()), len(botCommands)-len(disabled); got != want {
+		t.Errorf("%d enabled commands, want %d", got, want)
+	}
+}
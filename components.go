@@ -0,0 +1,121 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	componentsHeaderText = "Pollutant concentrations:"
+	componentTmpl        = "%s: %.2f %s"
+
+	// componentUnit is the unit of the concentrations of OWM API
+	componentUnit = UnitMicrogramsPerCubicMeter
+)
+
+// componentInfo describes a DataPoint component
+type componentInfo struct {
+	key  string
+	name string
+	unit Unit
+	// bands are the upper bounds of the concentration of the OWM AQI levels 1 to 4. Higher ones are level 5.
+	// The level isn't known if there are none.
+	bands []float64
+}
+
+// componentRegistry keeps the known components in the order they are shown: particulate matter first, then gases.
+// The bands are from https://openweathermap.org/air-pollution-index-levels
+var componentRegistry = []componentInfo{
+	{key: "pm1", name: "PM1", unit: componentUnit},
+	{key: "pm2_5", name: "PM2.5", unit: componentUnit, bands: []float64{10, 25, 50, 75}},
+	{key: "pm10", name: "PM10", unit: componentUnit, bands: []float64{20, 50, 100, 200}},
+	{key: "o3", name: "O₃", unit: componentUnit, bands: []float64{60, 100, 140, 180}},
+	{key: "no2", name: "NO₂", unit: componentUnit, bands: []float64{40, 70, 150, 200}},
+	{key: "so2", name: "SO₂", unit: componentUnit, bands: []float64{20, 80, 250, 350}},
+	{key: "co", name: "CO", unit: componentUnit, bands: []float64{4400, 9400, 12400, 15400}},
+	{key: "nh3", name: "NH₃", unit: componentUnit},
+	{key: "no", name: "NO", unit: componentUnit},
+}
+
+// lookupComponent returns the componentInfo of the key. An unknown component gets its name from the key,
+// like PM0.1 for pm0_1, and no bands.
+func lookupComponent(key string) componentInfo {
+	for _, c := range componentRegistry {
+		if c.key == key {
+			return c
+		}
+	}
+	return componentInfo{key: key, name: strings.ToUpper(strings.ReplaceAll(key, "_", ".")), unit: componentUnit}
+}
+
+// level returns the OWM AQI level of the concentration. 0 if the component has no bands.
+func (c componentInfo) level(value float64) AirQualityIndex {
+	if len(c.bands) == 0 {
+		return 0
+	}
+	for i, bound := range c.bands {
+		if value < bound {
+			return AirQualityIndex(i + 1)
+		}
+	}
+	return AirQualityIndex(len(c.bands) + 1)
+}
+
+// componentLine renders the concentration of the component with the mark of its level in the theme, if it is known
+func componentLine(key string, value float64, theme Theme, p *message.Printer) string {
+	c := lookupComponent(key)
+	line := p.Sprintf(componentTmpl, c.name, value, c.unit.Localized(p))
+	if level := c.level(value); level.Valid() {
+		line += " " + theme.Level(level, p)
+	}
+	return line
+}
+
+// sortedComponents returns the keys of the components in the order of componentRegistry.
+// Unknown ones follow in alphabetical order.
+func sortedComponents(components map[string]float64) []string {
+	rank := make(map[string]int, len(componentRegistry))
+	for i, c := range componentRegistry {
+		rank[c.key] = i
+	}
+	keys := make([]string, 0, len(components))
+	for k := range components {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ri, iKnown := rank[keys[i]]
+		rj, jKnown := rank[keys[j]]
+		switch {
+		case iKnown && jKnown:
+			return ri < rj
+		case iKnown != jKnown:
+			return iKnown
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// componentsText reports the components of the latest DataPoint of the chat
+func (bot *Bot) componentsText(chatID int64, p *message.Printer) string {
+	dp, err := bot.store.GetLastPD(chatID)
+	if err != nil {
+		log.Print("GetLastPD: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if len(dp.Components) == 0 {
+		return p.Sprintf(nearbyNoSessionMsg)
+	}
+
+	theme := bot.themeOf(chatID)
+	msgText := []string{p.Sprintf(componentsHeaderText), ""}
+	for _, k := range sortedComponents(dp.Components) {
+		msgText = append(msgText, componentLine(k, dp.Components[k], theme, p))
+	}
+	msgText = append(msgText, "", HumanizeSince(time.Unix(dp.Dt, 0), p))
+	return strings.Join(msgText, "\n")
+}
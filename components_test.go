@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// testComponents are all the components of an OWM API response
+var testComponents = map[string]float64{
+	"co": 201.94, "no": 0.02, "no2": 0.77, "o3": 68.66, "so2": 0.64, "pm2_5": 0.5, "pm10": 0.54, "nh3": 0.12,
+}
+
+func TestSortedComponents(t *testing.T) {
+	tests := []struct {
+		name       string
+		components map[string]float64
+		want       []string
+	}{
+		{name: "none", components: nil, want: []string{}},
+		{name: "OWM API", components: testComponents, want: []string{"pm2_5", "pm10", "o3", "no2", "so2", "co", "nh3", "no"}},
+		{name: "unknown ones last", components: map[string]float64{"co": 1, "pm0_1": 2, "bc": 3, "pm1": 4},
+			want: []string{"pm1", "co", "bc", "pm0_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the order of the map iteration changes between the runs
+			for i := 0; i < 20; i++ {
+				if got := sortedComponents(tt.components); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("sortedComponents() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestComponentsText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	store := newTestStore(t)
+	addTestSession(t, store, 1, &Location{53.9, 27.56})
+	dps := []DataPoint{newTestDataPoint(2, time.Now(), testComponents)}
+	if err := store.AddDataPoint(1, &dps); err != nil {
+		t.Fatal(err)
+	}
+	bot := newTestBot(store, &botServices{})
+
+	got := bot.componentsText(1, p)
+	want := strings.Join([]string{
+		componentsHeaderText,
+		"",
+		"PM2.5: 0.50 μg/m³ 🟩",
+		"PM10: 0.54 μg/m³ 🟩",
+		"O₃: 68.66 μg/m³ 🟨",
+		"NO₂: 0.77 μg/m³ 🟩",
+		"SO₂: 0.64 μg/m³ 🟩",
+		"CO: 201.94 μg/m³ 🟩",
+		"NH₃: 0.12 μg/m³",
+		"NO: 0.02 μg/m³",
+		"",
+		"Measured just now",
+	}, "\n")
+	if got != want {
+		t.Errorf("componentsText() = %q, want %q", got, want)
+	}
+}
+
+func TestComponentLevel(t *testing.T) {
+	pm25 := lookupComponent("pm2_5")
+	tests := []struct {
+		value float64
+		want  AirQualityIndex
+	}{
+		{0, 1}, {9.99, 1}, {10, 2}, {25, 3}, {50, 4}, {74.9, 4}, {75, 5}, {500, 5},
+	}
+	for _, tt := range tests {
+		if got := pm25.level(tt.value); got != tt.want {
+			t.Errorf("level(%v) of PM2.5 = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+	if got := lookupComponent("nh3").level(100); got != 0 {
+		t.Errorf("level() of NH₃ without bands = %d, want 0", got)
+	}
+	if got := lookupComponent("pm0_1"); got.name != "PM0.1" {
+		t.Errorf("name of an unknown component = %q, want PM0.1", got.name)
+	}
+}
+
+func TestComponentLine(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	tests := []struct {
+		key   string
+		value float64
+		theme Theme
+		want  string
+	}{
+		{key: "pm2_5", value: 30, theme: ThemeEmoji, want: "PM2.5: 30.00 μg/m³ 🟧"},
+		{key: "pm2_5", value: 30, theme: ThemePlain, want: "PM2.5: 30.00 μg/m³ (Moderate)"},
+		{key: "pm1", value: 3.5, theme: ThemeEmoji, want: "PM1: 3.50 μg/m³"},
+		{key: "nh3", value: 0.12, theme: ThemeEmoji, want: "NH₃: 0.12 μg/m³"},
+		// unknown components are named after the key and shown without a level
+		{key: "pm0_1", value: 1.25, theme: ThemeEmoji, want: "PM0.1: 1.25 μg/m³"},
+		{key: "bc", value: 0.4, theme: ThemePlain, want: "BC: 0.40 μg/m³"},
+	}
+	for _, tt := range tests {
+		if got := componentLine(tt.key, tt.value, tt.theme, p); got != tt.want {
+			t.Errorf("componentLine(%q, %v, %s) = %q, want %q", tt.key, tt.value, tt.theme, got, tt.want)
+		}
+	}
+}
+
+func TestComponentsTextUnknownComponent(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	store := newTestStore(t)
+	addTestSession(t, store, 1, &Location{53.9, 27.56})
+	dps := []DataPoint{newTestDataPoint(2, time.Now(), map[string]float64{"pm2_5": 12, "pm1": 8, "pm0_1": 1.25, "co": 300})}
+	if err := store.AddDataPoint(1, &dps); err != nil {
+		t.Fatal(err)
+	}
+	bot := newTestBot(store, &botServices{})
+
+	want := strings.Join([]string{
+		componentsHeaderText,
+		"",
+		"PM1: 8.00 μg/m³",
+		"PM2.5: 12.00 μg/m³ 🟨",
+		"CO: 300.00 μg/m³ 🟩",
+		"PM0.1: 1.25 μg/m³",
+		"",
+		"Measured just now",
+	}, "\n")
+	if got := bot.componentsText(1, p); got != want {
+		t.Errorf("componentsText() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// Config keeps the settings of the bot. Each field is read from the config file key
+// in the `config` tag and the env variable in the `env` tag.
+type Config struct {
+	TelegramAPIToken    string        `config:"telegram_api_token" env:"TELEGRAM_API_TOKEN"`
+	TelegramAPITokens   []string      `config:"telegram_api_tokens" env:"TELEGRAM_API_TOKENS"`
+	OWMApiToken         string        `config:"owm_api_token" env:"OWM_API_TOKEN"`
+	DBPath              string        `config:"db_path" env:"DB_PATH"`
+	AdminID             int64         `config:"admin_id" env:"ADMIN_ID"`
+	CacheTime           time.Duration `config:"cache_time" env:"CACHE_TIME"`
+	CoordinatePrecision int           `config:"coordinate_precision" env:"COORDINATE_PRECISION"`
+	MaxSubscriptions    int           `config:"max_subscriptions" env:"MAX_SUBSCRIPTIONS"`
+	DuplicateDistance   float64       `config:"duplicate_distance" env:"DUPLICATE_DISTANCE"`
+	COThreshold         float64       `config:"co_threshold" env:"CO_THRESHOLD"`
+	DataPointsPerChat   int           `config:"data_points_per_chat" env:"DATA_POINTS_PER_CHAT"`
+	DataPointMinChange  float64       `config:"data_point_min_change" env:"DATA_POINT_MIN_CHANGE"`
+	DataPointRetention  time.Duration `config:"data_point_retention" env:"DATA_POINT_RETENTION"`
+	SpikeRatio          float64       `config:"spike_ratio" env:"SPIKE_RATIO"`
+	HysteresisMinDelta  int           `config:"hysteresis_min_delta" env:"HYSTERESIS_MIN_DELTA"`
+	OWMTimeout          time.Duration `config:"owm_timeout" env:"OWM_TIMEOUT"`
+	OWMApiEndpoint      string        `config:"owm_api_endpoint" env:"OWM_API_ENDPOINT"`
+	OWMFailureThreshold int           `config:"owm_failure_threshold" env:"OWM_FAILURE_THRESHOLD"`
+	CronInterval        time.Duration `config:"cron_interval" env:"CRON_INTERVAL"`
+	CleanupInterval     time.Duration `config:"cleanup_interval" env:"CLEANUP_INTERVAL"`
+	CronConcurrency     int           `config:"cron_concurrency" env:"CRON_CONCURRENCY"`
+	NotifyCooldown      time.Duration `config:"notification_cooldown" env:"NOTIFICATION_COOLDOWN"`
+	KeepAliveInterval   time.Duration `config:"keepalive_interval" env:"KEEPALIVE_INTERVAL"`
+	RawCaptureRetention time.Duration `config:"raw_capture_retention" env:"RAW_CAPTURE_RETENTION"`
+	DisabledCommands    []string      `config:"disabled_commands" env:"DISABLED_COMMANDS"`
+	ReferralCodes       []string      `config:"referral_codes" env:"REFERRAL_CODES"`
+	TranslationsDir     string        `config:"translations_dir" env:"TRANSLATIONS_DIR"`
+	DefaultLanguage     string        `config:"default_language" env:"DEFAULT_LANGUAGE"`
+	SupportedLanguages  []string      `config:"supported_languages" env:"SUPPORTED_LANGUAGES"`
+	HTTPAddr            string        `config:"http_addr" env:"HTTP_ADDR"`
+	BotMode             string        `config:"bot_mode" env:"BOT_MODE"`
+	WebhookURL          string        `config:"webhook_url" env:"WEBHOOK_URL"`
+	WebhookAddr         string        `config:"webhook_addr" env:"WEBHOOK_ADDR"`
+	WebhookSecret       string        `config:"webhook_secret" env:"WEBHOOK_SECRET"`
+	TraceExporter       string        `config:"trace_exporter" env:"TRACE_EXPORTER"`
+	Debug               bool          `config:"debug" env:"DEBUG"`
+}
+
+// DefaultConfig returns the Config used for the settings missing in all the sources
+func DefaultConfig() *Config {
+	return &Config{
+		DBPath:              DefaultDBPath,
+		CacheTime:           DefaultCacheTime,
+		MaxSubscriptions:    DefaultMaxSubscriptions,
+		DuplicateDistance:   DefaultDuplicateDistance,
+		COThreshold:         DefaultCOThreshold,
+		DataPointRetention:  DefaultDataPointRetention,
+		SpikeRatio:          DefaultSpikeRatio,
+		OWMTimeout:          DefaultHTTPTimeout,
+		OWMApiEndpoint:      OWMApiEndpoint,
+		OWMFailureThreshold: DefaultOWMFailureThreshold,
+		CronInterval:        30 * time.Minute,
+		CleanupInterval:     12 * time.Hour,
+		CronConcurrency:     DefaultCronConcurrency,
+		BotMode:             "polling",
+		WebhookAddr:         ":8443",
+	}
+}
+
+// LoadConfig reads the Config from the file at path, if it is not empty, and from env variables looked up by getenv.
+// Env variables override the file values. Settings missing in both keep the DefaultConfig values.
+func LoadConfig(path string, getenv func(string) string) (*Config, error) {
+	c := DefaultConfig()
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("opening config: %w", err)
+		}
+		defer f.Close()
+		if err := c.readFile(f); err != nil {
+			return nil, fmt.Errorf("reading config %s: %w", path, err)
+		}
+	}
+	if err := c.readEnv(getenv); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// webhookSecretRe matches the secret_token accepted by Telegram
+var webhookSecretRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// Validate checks the required settings are present and the values are consistent
+func (c *Config) Validate() error {
+	if c.TelegramAPIToken == "" {
+		return errors.New("telegram_api_token (TELEGRAM_API_TOKEN) is required")
+	}
+	if c.OWMApiToken == "" {
+		return errors.New("owm_api_token (OWM_API_TOKEN) is required")
+	}
+	switch c.BotMode {
+	case "polling":
+	case "webhook":
+		if c.WebhookURL == "" {
+			return errors.New("webhook_url (WEBHOOK_URL) is required in the webhook mode")
+		}
+		if c.WebhookSecret != "" && !webhookSecretRe.MatchString(c.WebhookSecret) {
+			return errors.New("webhook_secret must be 1-256 characters A-Z, a-z, 0-9, _ and -")
+		}
+	default:
+		return fmt.Errorf("unknown bot_mode %q", c.BotMode)
+	}
+	if len(c.TelegramAPITokens) > 0 && c.BotMode != "polling" {
+		return errors.New("telegram_api_tokens (TELEGRAM_API_TOKENS) are only supported in the polling mode")
+	}
+	tokens := map[string]bool{c.TelegramAPIToken: true}
+	for _, token := range c.TelegramAPITokens {
+		if tokens[token] {
+			return errors.New("telegram_api_tokens must differ from each other and from telegram_api_token")
+		}
+		tokens[token] = true
+	}
+	if c.CoordinatePrecision < 0 {
+		return errors.New("coordinate_precision must not be negative")
+	}
+	if c.DataPointMinChange < 0 {
+		return errors.New("data_point_min_change must not be negative")
+	}
+	if c.DataPointRetention < 0 {
+		return errors.New("data_point_retention must not be negative")
+	}
+	if c.SpikeRatio != 0 && c.SpikeRatio <= 1 {
+		return errors.New("spike_ratio must be above 1 or 0 to disable")
+	}
+	if c.DuplicateDistance <= 0 {
+		return errors.New("duplicate_distance must be positive")
+	}
+	if c.MaxSubscriptions < 0 {
+		return errors.New("max_subscriptions must not be negative")
+	}
+	if _, err := NewTracer(c.TraceExporter); err != nil {
+		return err
+	}
+	if u, err := url.Parse(c.OWMApiEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("owm_api_endpoint %q must be an absolute URL", c.OWMApiEndpoint)
+	}
+	if c.RawCaptureRetention < 0 {
+		return errors.New("raw_capture_retention must not be negative")
+	}
+	if c.CronInterval <= 0 || c.CleanupInterval <= 0 {
+		return errors.New("cron_interval and cleanup_interval must be positive")
+	}
+	if c.KeepAliveInterval < 0 {
+		return errors.New("keepalive_interval must not be negative")
+	}
+	if c.OWMFailureThreshold < 0 {
+		return errors.New("owm_failure_threshold must not be negative")
+	}
+	if c.NotifyCooldown < 0 {
+		return errors.New("notification_cooldown must not be negative")
+	}
+	if c.DefaultLanguage != "" {
+		if _, err := language.Parse(c.DefaultLanguage); err != nil {
+			return fmt.Errorf("default_language %q is not a language code: %w", c.DefaultLanguage, err)
+		}
+	}
+	for _, code := range c.SupportedLanguages {
+		if _, err := language.Parse(code); err != nil {
+			return fmt.Errorf("supported_languages: %q is not a language code: %w", code, err)
+		}
+	}
+	if c.TranslationsDir != "" {
+		if fi, err := os.Stat(c.TranslationsDir); err != nil || !fi.IsDir() {
+			return fmt.Errorf("translations_dir %q must be a directory", c.TranslationsDir)
+		}
+	}
+	return nil
+}
+
+// BotOptions returns the BotOptions of the Config. The Config is expected to be validated.
+func (c *Config) BotOptions() BotOptions {
+	tracer, err := NewTracer(c.TraceExporter)
+	if err != nil {
+		log.Print("NewTracer: ", err)
+	}
+	return BotOptions{
+		Tracer:              tracer,
+		TelegramAPIToken:    c.TelegramAPIToken,
+		OWMApiToken:         c.OWMApiToken,
+		DBPath:              c.DBPath,
+		CacheTime:           c.CacheTime,
+		CoordinatePrecision: c.CoordinatePrecision,
+		MaxSubscriptions:    c.MaxSubscriptions,
+		DuplicateDistance:   c.DuplicateDistance,
+		AdminID:             c.AdminID,
+		COThreshold:         c.COThreshold,
+		DataPointsPerChat:   c.DataPointsPerChat,
+		DataPointMinChange:  c.DataPointMinChange,
+		DataPointRetention:  c.DataPointRetention,
+		SpikeRatio:          c.SpikeRatio,
+		HysteresisMinDelta:  c.HysteresisMinDelta,
+		CronConcurrency:     c.CronConcurrency,
+		NotifyCooldown:      c.NotifyCooldown,
+		RawCaptureRetention: c.RawCaptureRetention,
+		CleanupInterval:     c.CleanupInterval,
+		DisabledCommands:    c.DisabledCommands,
+		ReferralCodes:       c.ReferralCodes,
+		HTTPTimeout:         c.OWMTimeout,
+		OWMApiEndpoint:      c.OWMApiEndpoint,
+		OWMFailureThreshold: c.OWMFailureThreshold,
+		Debug:               c.Debug,
+	}
+}
+
+// readFile parses a config file of flat `key: value` lines. The values are plain, "double quoted" with Go escapes
+// or 'single quoted', the lists are comma-separated values like in the env variables. Empty lines and # comments
+// are skipped. The rest of YAML, like nested or indented values, block and flow lists, is rejected.
+func (c *Config) readFile(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if raw := scanner.Text(); raw[0] == ' ' || raw[0] == '\t' {
+			return fmt.Errorf("line %d: indented values are not supported", n)
+		}
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			return fmt.Errorf("line %d: lists are comma-separated values, e.g. `key: a, b`", n)
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("line %d: expected `key: value`", n)
+		}
+		value, err := parseConfigValue(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		if err := c.set("config", strings.TrimSpace(key), value); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseConfigValue returns the value of a config file line without the quotes and the trailing # comment.
+// A # within the quotes is a part of the value.
+func parseConfigValue(value string) (string, error) {
+	var rest string
+	switch {
+	case value == "" || value[0] == '#':
+		return "", nil
+	case value[0] == '"':
+		quoted, err := strconv.QuotedPrefix(value)
+		if err != nil {
+			return "", errors.New("unterminated double quoted value")
+		}
+		rest = value[len(quoted):]
+		if value, err = strconv.Unquote(quoted); err != nil {
+			return "", err
+		}
+	case value[0] == '\'':
+		// '' is a single quote within a single quoted value
+		var b strings.Builder
+		i := 1
+		for ; i < len(value); i++ {
+			if value[i] != '\'' {
+				b.WriteByte(value[i])
+				continue
+			}
+			if i+1 < len(value) && value[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			break
+		}
+		if i == len(value) {
+			return "", errors.New("unterminated single quoted value")
+		}
+		value, rest = b.String(), value[i+1:]
+	case strings.ContainsRune("[{|>&*!", rune(value[0])):
+		return "", fmt.Errorf("unsupported value %q, lists are comma-separated values and the rest of YAML isn't parsed", value)
+	default:
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		return value, nil
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after the quoted value", rest)
+	}
+	return value, nil
+}
+
+func (c *Config) readEnv(getenv func(string) string) error {
+	t := reflect.TypeOf(c).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("env")
+		if v := getenv(key); v != "" {
+			if err := c.set("env", key, v); err != nil {
+				return fmt.Errorf("env variable %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// set parses the value into the field with the key in the tag
+func (c *Config) set(tag, key, value string) error {
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get(tag) != key {
+			continue
+		}
+		if err := setValue(v.Field(i), value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown key %q", key)
+}
+
+func setValue(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case int, int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case []string:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envOf returns a getenv of LoadConfig looking up the variables in the map
+func envOf(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestLoadConfigDBPath(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{name: "default", want: DefaultDBPath},
+		{name: "env", env: map[string]string{"DB_PATH": "/data/bot.db"}, want: "/data/bot.db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := LoadConfig("", envOf(tt.env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.DBPath != tt.want {
+				t.Errorf("DBPath = %q, want %q", c.DBPath, tt.want)
+			}
+			if got := c.BotOptions().DBPath; got != tt.want {
+				t.Errorf("BotOptions().DBPath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateWebhookSecret(t *testing.T) {
+	tests := []struct {
+		secret  string
+		wantErr bool
+	}{
+		{secret: ""},
+		{secret: "Abc_123-xyz"},
+		{secret: "with space", wantErr: true},
+		{secret: "semi;colon", wantErr: true},
+		{secret: strings.Repeat("a", 257), wantErr: true},
+	}
+	for _, tt := range tests {
+		c, err := LoadConfig("", envOf(map[string]string{
+			"TELEGRAM_API_TOKEN": "1:test",
+			"OWM_API_TOKEN":      "owm",
+			"BOT_MODE":           "webhook",
+			"WEBHOOK_URL":        "https://example.com/webhook",
+			"WEBHOOK_SECRET":     tt.secret,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() of secret %q = %v, want error %v", tt.secret, err, tt.wantErr)
+		}
+	}
+}
+
+// writeTestConfig writes the config file content into a temp dir and returns its path
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bot.conf")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeTestConfig(t, `
+# the file values are overridden by env and flags
+telegram_api_token: "file-token"
+owm_api_token: 'file-owm'
+db_path: /file/bot.db
+admin_id: 1 # the admin
+cache_time: 10m
+disabled_commands: check, route
+`)
+	c, err := LoadConfig(path, envOf(map[string]string{
+		"ADMIN_ID": "2",
+		"DB_PATH":  "/env/bot.db",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := flag.Set("db-path", "/flag/bot.db"); err != nil {
+		t.Fatal(err)
+	}
+	defer flag.Set("db-path", "")
+	applyFlags(c)
+
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"file", c.TelegramAPIToken, "file-token"},
+		{"single quoted file value", c.OWMApiToken, "file-owm"},
+		{"file duration", c.CacheTime, 10 * time.Minute},
+		{"file list", c.DisabledCommands, []string{"check", "route"}},
+		{"env over file", c.AdminID, int64(2)},
+		{"flag over env and file", c.DBPath, "/flag/bot.db"},
+		{"default", c.CronInterval, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestLoadConfigMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+	}{
+		{name: "no value", content: "telegram_api_token\n"},
+		{name: "unknown key", content: "telegram_token: x\n"},
+		{name: "invalid number", content: "admin_id: one\n"},
+		{name: "invalid duration", content: "cache_time: 10 minutes\n"},
+		{name: "block list", content: "disabled_commands:\n  - check\n  - route\n"},
+		{name: "dash list item", content: "- check\n"},
+		{name: "flow list", content: "disabled_commands: [check, route]\n"},
+		{name: "nested value", content: "owm:\n  timeout: 10s\n"},
+		{name: "unterminated quote", content: "db_path: \"/data/bot.db\n"},
+		{name: "unterminated single quote", content: "db_path: '/data/bot.db\n"},
+		{name: "text after quotes", content: "db_path: \"/data\"/bot.db\n"},
+		{name: "invalid env", env: map[string]string{"CRON_INTERVAL": "often"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfig(writeTestConfig(t, tt.content), envOf(tt.env)); err == nil {
+				t.Error("LoadConfig() = nil error, want error")
+			}
+		})
+	}
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.conf"), envOf(nil)); err == nil {
+		t.Error("LoadConfig() of a missing file = nil error, want error")
+	}
+}
+
+func TestLoadConfigFileValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *Config
+	}{
+		{name: "plain", content: "db_path: /data/bot.db", want: &Config{DBPath: "/data/bot.db"}},
+		{name: "comment", content: "db_path: /data/bot.db # the volume", want: &Config{DBPath: "/data/bot.db"}},
+		{name: "empty with comment", content: "db_path: # none", want: &Config{}},
+		{name: "# within plain value", content: "db_path: /data/bot#1.db", want: &Config{DBPath: "/data/bot#1.db"}},
+		{name: "quoted #", content: `db_path: "/data/bot #1.db" # the volume`, want: &Config{DBPath: "/data/bot #1.db"}},
+		{name: "single quoted #", content: `db_path: '/data/bot #1.db'`, want: &Config{DBPath: "/data/bot #1.db"}},
+		{name: "escaped single quote", content: `db_path: '/data/bot''s.db'`, want: &Config{DBPath: "/data/bot's.db"}},
+		{name: "escaped double quote", content: `db_path: "/data/\"bot\".db"`, want: &Config{DBPath: `/data/"bot".db`}},
+		{name: "list", content: "disabled_commands: check, route", want: &Config{DisabledCommands: []string{"check", "route"}}},
+		{name: "quoted list", content: `disabled_commands: "check, route" # the costly ones`,
+			want: &Config{DisabledCommands: []string{"check", "route"}}},
+		{name: "empty list", content: "disabled_commands:", want: &Config{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			if err := c.readFile(strings.NewReader(tt.content)); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c, tt.want) {
+				t.Errorf("readFile(%q) = %+v, want %+v", tt.content, c, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigDisabledCommands(t *testing.T) {
+	tests := []struct {
+		env  string
+		want []string
+	}{
+		{env: "", want: nil},
+		{env: "check", want: []string{"check"}},
+		{env: " check, /route ,,nearby,", want: []string{"check", "/route", "nearby"}},
+	}
+	for _, tt := range tests {
+		c, err := LoadConfig("", envOf(map[string]string{"DISABLED_COMMANDS": tt.env}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c.DisabledCommands, tt.want) {
+			t.Errorf("DISABLED_COMMANDS=%q: DisabledCommands = %q, want %q", tt.env, c.DisabledCommands, tt.want)
+		}
+		if got := c.BotOptions().DisabledCommands; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("BotOptions().DisabledCommands = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigKeepAlive(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    time.Duration
+		wantErr bool
+	}{
+		// disabled by default
+		{env: "", want: 0},
+		{env: "5m", want: 5 * time.Minute},
+		{env: "-1m", want: -time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		c, err := LoadConfig("", envOf(map[string]string{
+			"TELEGRAM_API_TOKEN": "1:test",
+			"OWM_API_TOKEN":      "owm",
+			"KEEPALIVE_INTERVAL": tt.env,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.KeepAliveInterval != tt.want {
+			t.Errorf("KEEPALIVE_INTERVAL=%q: KeepAliveInterval = %v, want %v", tt.env, c.KeepAliveInterval, tt.want)
+		}
+		if err := c.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("KEEPALIVE_INTERVAL=%q: Validate() = %v, want error %v", tt.env, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigDuplicateDistance(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    float64
+		wantErr bool
+	}{
+		{env: "", want: DefaultDuplicateDistance},
+		{env: "250", want: 250},
+		{env: "0", want: 0, wantErr: true},
+		{env: "-10", want: -10, wantErr: true},
+	}
+	for _, tt := range tests {
+		c, err := LoadConfig("", envOf(map[string]string{
+			"TELEGRAM_API_TOKEN": "1:test",
+			"OWM_API_TOKEN":      "owm",
+			"DUPLICATE_DISTANCE": tt.env,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.DuplicateDistance != tt.want || c.BotOptions().DuplicateDistance != tt.want {
+			t.Errorf("DUPLICATE_DISTANCE=%q: DuplicateDistance = %v, want %v", tt.env, c.DuplicateDistance, tt.want)
+		}
+		if err := c.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("DUPLICATE_DISTANCE=%q: Validate() = %v, want error %v", tt.env, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigDefaultLanguage(t *testing.T) {
+	tests := []struct {
+		env     string
+		wantErr bool
+	}{
+		{env: ""},
+		{env: "ru"},
+		{env: "be-BY"},
+		{env: "not a language", wantErr: true},
+	}
+	for _, tt := range tests {
+		c, err := LoadConfig("", envOf(map[string]string{
+			"TELEGRAM_API_TOKEN": "1:test",
+			"OWM_API_TOKEN":      "owm",
+			"DEFAULT_LANGUAGE":   tt.env,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.DefaultLanguage != tt.env {
+			t.Errorf("DEFAULT_LANGUAGE=%q: DefaultLanguage = %q", tt.env, c.DefaultLanguage)
+		}
+		if err := c.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("DEFAULT_LANGUAGE=%q: Validate() = %v, want error %v", tt.env, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigSpikeRatio(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    float64
+		wantErr bool
+	}{
+		{env: "", want: DefaultSpikeRatio},
+		{env: "1.5", want: 1.5},
+		// disabled
+		{env: "0", want: 0},
+		{env: "1", want: 1, wantErr: true},
+		{env: "-2", want: -2, wantErr: true},
+	}
+	for _, tt := range tests {
+		c, err := LoadConfig("", envOf(map[string]string{
+			"TELEGRAM_API_TOKEN": "1:test",
+			"OWM_API_TOKEN":      "owm",
+			"SPIKE_RATIO":        tt.env,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.SpikeRatio != tt.want {
+			t.Errorf("SPIKE_RATIO=%q: SpikeRatio = %v, want %v", tt.env, c.SpikeRatio, tt.want)
+		}
+		if err := c.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("SPIKE_RATIO=%q: Validate() = %v, want error %v", tt.env, err, tt.wantErr)
+		}
+		if got := c.BotOptions().SpikeRatio; got != tt.want {
+			t.Errorf("BotOptions().SpikeRatio = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigTelegramTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", env: map[string]string{}},
+		{name: "several", env: map[string]string{"TELEGRAM_API_TOKENS": "2:b, 3:c"}, want: []string{"2:b", "3:c"}},
+		{name: "the main token", env: map[string]string{"TELEGRAM_API_TOKENS": "1:a"}, want: []string{"1:a"}, wantErr: true},
+		{name: "duplicates", env: map[string]string{"TELEGRAM_API_TOKENS": "2:b,2:b"}, want: []string{"2:b", "2:b"}, wantErr: true},
+		{name: "webhook", env: map[string]string{"TELEGRAM_API_TOKENS": "2:b", "BOT_MODE": "webhook", "WEBHOOK_URL": "https://example.com/hook"},
+			want: []string{"2:b"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"TELEGRAM_API_TOKEN": "1:a", "OWM_API_TOKEN": "owm"}
+			for k, v := range tt.env {
+				env[k] = v
+			}
+			c, err := LoadConfig("", envOf(env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.TelegramAPITokens, tt.want) {
+				t.Errorf("TelegramAPITokens = %q, want %q", c.TelegramAPITokens, tt.want)
+			}
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	cooldownSetTmpl     = "OK. I will notify you about a subscription at most every %s"
+	cooldownOffText     = "OK. I will notify you about every AQI change"
+	cooldownDefaultTmpl = "OK. The default cooldown is used: %s"
+	cooldownUsageMsg    = "Usage: /cooldown <duration>, e.g. /cooldown 1h, /cooldown off or /cooldown default"
+)
+
+// parseCooldown parses the argument of the /cooldown command. nil means the cooldown of the bot, 0 disables it.
+func parseCooldown(arg string) (*time.Duration, error) {
+	var d time.Duration
+	switch arg = strings.ToLower(strings.TrimSpace(arg)); arg {
+	case "default":
+		return nil, nil
+	case "off":
+		return &d, nil
+	}
+	d, err := time.ParseDuration(arg)
+	if err != nil {
+		return nil, err
+	}
+	if d < 0 {
+		return nil, errors.New("cooldown must not be negative")
+	}
+	return &d, nil
+}
+
+// cooldownOf returns the minimal interval between the notifications of a subscription of the chat of the UserPrefs.
+// The one of UserPrefs overrides notifyCooldown of the bot.
+func (bot *Bot) cooldownOf(prefs *UserPrefs) time.Duration {
+	if prefs.Cooldown != nil {
+		return *prefs.Cooldown
+	}
+	return bot.notifyCooldown
+}
+
+// inCooldown reports whether the user was notified about the subscription less than the cooldown of the UserPrefs ago
+func (bot *Bot) inCooldown(s *AQISubscription, prefs *UserPrefs, now time.Time) bool {
+	cooldown := bot.cooldownOf(prefs)
+	return cooldown > 0 && !s.LastNotifiedAt.IsZero() && now.Sub(s.LastNotifiedAt) < cooldown
+}
+
+// cooldownText stores the cooldown of the /cooldown argument in UserPrefs
+func (bot *Bot) cooldownText(chatID int64, arg string, p *message.Printer) string {
+	cooldown, err := parseCooldown(arg)
+	if err != nil {
+		return p.Sprintf(cooldownUsageMsg)
+	}
+	prefs, err := bot.store.GetUserPrefs(chatID)
+	if err != nil {
+		log.Print("GetUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	prefs.Cooldown = cooldown
+	if err := bot.store.UpdateUserPrefs(prefs); err != nil {
+		log.Print("UpdateUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	switch {
+	case cooldown == nil:
+		return p.Sprintf(cooldownDefaultTmpl, bot.notifyCooldown)
+	case *cooldown == 0:
+		return p.Sprintf(cooldownOffText)
+	}
+	return p.Sprintf(cooldownSetTmpl, *cooldown)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestParseCooldown(t *testing.T) {
+	d := func(d time.Duration) *time.Duration { return &d }
+	tests := []struct {
+		arg     string
+		want    *time.Duration
+		wantErr bool
+	}{
+		{arg: "1h", want: d(time.Hour)},
+		{arg: " 90m ", want: d(90 * time.Minute)},
+		{arg: "1H", want: d(time.Hour)},
+		{arg: "0s", want: d(0)},
+		{arg: "off", want: d(0)},
+		{arg: "OFF", want: d(0)},
+		{arg: "default"},
+		{arg: "Default"},
+		{arg: "hourly", wantErr: true},
+		{arg: "", wantErr: true},
+		{arg: "-1h", wantErr: true},
+		{arg: "an hour", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parseCooldown(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCooldown() error = %v, want error %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("parseCooldown() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCooldownText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	hour, twoHours, off := time.Hour, 2*time.Hour, time.Duration(0)
+	tests := []struct {
+		arg  string
+		want string
+		// cooldown is the cooldown of UserPrefs after the command
+		cooldown *time.Duration
+	}{
+		{arg: "2h", want: p.Sprintf(cooldownSetTmpl, 2*time.Hour), cooldown: &twoHours},
+		{arg: "off", want: p.Sprintf(cooldownOffText), cooldown: &off},
+		{arg: "default", want: p.Sprintf(cooldownDefaultTmpl, 30*time.Minute)},
+		{arg: "hourly", want: p.Sprintf(cooldownUsageMsg), cooldown: &hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			store := newTestStore(t)
+			bot := newTestBot(store, &botServices{notifyCooldown: 30 * time.Minute})
+			if got := bot.cooldownText(1, "1h", p); got != p.Sprintf(cooldownSetTmpl, time.Hour) {
+				t.Fatalf("cooldownText() = %q", got)
+			}
+
+			if got := bot.cooldownText(1, tt.arg, p); got != tt.want {
+				t.Errorf("cooldownText(%q) = %q, want %q", tt.arg, got, tt.want)
+			}
+			prefs, err := store.GetUserPrefs(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := prefs.Cooldown; (got == nil) != (tt.cooldown == nil) || got != nil && *got != *tt.cooldown {
+				t.Errorf("cooldown = %v, want %v", got, tt.cooldown)
+			}
+		})
+	}
+}
+
+func TestCronCooldown(t *testing.T) {
+	tests := []struct {
+		name string
+		// notified is how long ago the subscription was notified, never if 0
+		notified time.Duration
+		// cooldown is the /cooldown argument of the user, the bot's hour if empty
+		cooldown string
+		want     bool
+	}{
+		{name: "never notified", want: true},
+		{name: "within cooldown", notified: 30 * time.Minute},
+		{name: "after cooldown", notified: 61 * time.Minute, want: true},
+		{name: "user cooldown shorter", notified: 30 * time.Minute, cooldown: "10m", want: true},
+		{name: "user cooldown longer", notified: 2 * time.Hour, cooldown: "3h"},
+		{name: "user cooldown off", notified: time.Minute, cooldown: "off", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 4}, notifyCooldown: time.Hour}, fake)
+			s := onlySubscription(t, store, 1)
+			if tt.notified > 0 {
+				if err := store.MarkSubscriptionNotified(s.ID, time.Now().Add(-tt.notified)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.cooldown != "" {
+				bot.cooldownText(1, tt.cooldown, message.NewPrinter(language.English))
+			}
+
+			bot.Cron()
+
+			if got := len(fake.sent("sendMessage")) == 1; got != tt.want {
+				t.Errorf("notified = %v, want %v", got, tt.want)
+			}
+			// the AQI is updated either way, so the change isn't notified once the cooldown is over
+			s = onlySubscription(t, store, 1)
+			if s.AirQualityIndex != 4 {
+				t.Errorf("AQI of the subscription = %v, want 4", s.AirQualityIndex)
+			}
+			if notified := time.Since(s.LastNotifiedAt) < time.Minute; notified != tt.want {
+				t.Errorf("last_notified_at = %v, want updated %v", s.LastNotifiedAt, tt.want)
+			}
+		})
+	}
+}
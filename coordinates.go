@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// coordinatePattern matches a coordinate in decimal degrees like -33.86 or 55.75N,
+// or in degrees, minutes and seconds like 55°45'20"N. %[1]s is the pattern of the decimal separator.
+const coordinatePattern = `([+-]?\d+(?:%[1]s\d+)?)\s*°?\s*(?:(\d+(?:%[1]s\d+)?)\s*'\s*)?(?:(\d+(?:%[1]s\d+)?)\s*"\s*)?([NSEW])?`
+
+var (
+	coordinatesRe = regexp.MustCompile(`^\s*` + fmt.Sprintf(coordinatePattern, `\.`) + `(?:\s*[,;]\s*|\s+)` +
+		fmt.Sprintf(coordinatePattern, `\.`) + `\s*$`)
+	// decimalCommaCoordinatesRe matches the coordinates with the decimal comma shown by localized printers, like 53,9.
+	// They have to be separated by a space or a semicolon.
+	decimalCommaCoordinatesRe = regexp.MustCompile(`^\s*` + fmt.Sprintf(coordinatePattern, `,`) + `(?:\s*;\s*|\s+)` +
+		fmt.Sprintf(coordinatePattern, `,`) + `\s*$`)
+)
+
+// coordinateMarks replaces the typographic primes and quotes with the ASCII ones used by coordinatePattern
+var coordinateMarks = strings.NewReplacer("′", "'", "’", "'", "″", `"`, "”", `"`, "''", `"`, "º", "°")
+
+// ParseLocation parses the latitude and the longitude separated by a space, a comma or a semicolon.
+// Coordinates with the decimal comma are separated by a space or a semicolon.
+// Each one is in decimal degrees, signed or with a hemisphere letter, or in degrees, minutes and seconds,
+// e.g. "53.9 27.56", "-33.86,151.21", "53,9;27,56" or `55°45'20"N 37°37'02"E`. Hemisphere letters allow any order.
+// Any other input, including the out of range coordinates, is an error.
+func ParseLocation(s string) (*Location, error) {
+	normalized := coordinateMarks.Replace(strings.ToUpper(s))
+	m := coordinatesRe.FindStringSubmatch(normalized)
+	if m == nil {
+		m = decimalCommaCoordinatesRe.FindStringSubmatch(normalized)
+	}
+	if m == nil {
+		return nil, fmt.Errorf("expected latitude and longitude, got %q", s)
+	}
+	first, err := parseCoordinate(m[1], m[2], m[3], m[4])
+	if err != nil {
+		return nil, err
+	}
+	second, err := parseCoordinate(m[5], m[6], m[7], m[8])
+	if err != nil {
+		return nil, err
+	}
+
+	lat, lon := first, second
+	switch h1, h2 := m[4], m[8]; {
+	case (h1 == "") != (h2 == ""):
+		return nil, errors.New("hemisphere letters are required for both coordinates")
+	case isLatitudeHemisphere(h1) == isLatitudeHemisphere(h2) && h1 != "":
+		return nil, fmt.Errorf("ambiguous hemispheres %s and %s", h1, h2)
+	case h1 != "" && !isLatitudeHemisphere(h1):
+		lat, lon = second, first
+	}
+
+	l := &Location{lat, lon}
+	if !l.InRange() {
+		return nil, fmt.Errorf("coordinates %f;%f are out of range", lat, lon)
+	}
+	return l, nil
+}
+
+// parseCoordinate returns the coordinate in decimal degrees. Southern and western ones are negative.
+func parseCoordinate(degrees, minutes, seconds, hemisphere string) (float64, error) {
+	d, err := parseDecimal(degrees)
+	if err != nil {
+		return 0, fmt.Errorf("parsing degrees: %w", err)
+	}
+	if hemisphere != "" && strings.ContainsAny(degrees, "+-") {
+		return 0, fmt.Errorf("both a sign and a hemisphere in %s%s", degrees, hemisphere)
+	}
+	if (minutes != "" || seconds != "") && strings.ContainsAny(degrees, ".,") {
+		return 0, fmt.Errorf("fractional degrees %s with minutes or seconds", degrees)
+	}
+	for _, part := range []struct {
+		value string
+		scale float64
+	}{{minutes, 60}, {seconds, 3600}} {
+		if part.value == "" {
+			continue
+		}
+		v, err := parseDecimal(part.value)
+		if err != nil {
+			return 0, fmt.Errorf("parsing %s: %w", part.value, err)
+		}
+		if v >= 60 {
+			return 0, fmt.Errorf("minutes or seconds %s are not below 60", part.value)
+		}
+		if strings.HasPrefix(degrees, "-") {
+			v = -v
+		}
+		d += v / part.scale
+	}
+	if hemisphere == "S" || hemisphere == "W" {
+		d = -d
+	}
+	return d, nil
+}
+
+// parseDecimal parses a number with the decimal dot or comma
+func parseDecimal(s string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+}
+
+func isLatitudeHemisphere(h string) bool {
+	return h == "N" || h == "S"
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Location
+		wantErr bool
+	}{
+		// decimal degrees
+		{in: "53.9 27.56", want: Location{53.9, 27.56}},
+		{in: "53.9,27.56", want: Location{53.9, 27.56}},
+		{in: " 53.9 ; 27.56 ", want: Location{53.9, 27.56}},
+		{in: "53 27", want: Location{53, 27}},
+		{in: "53,9 27,56", want: Location{53.9, 27.56}},
+		{in: "53,9;27,56", want: Location{53.9, 27.56}},
+		// signed
+		{in: "-33.86,151.21", want: Location{-33.86, 151.21}},
+		{in: "+40.71 -74.01", want: Location{40.71, -74.01}},
+		{in: "-90 -180", want: Location{-90, -180}},
+		// hemisphere letters
+		{in: "55.75N 37.62E", want: Location{55.75, 37.62}},
+		{in: "33.86S 151.21E", want: Location{-33.86, 151.21}},
+		{in: "40.71n 74.01w", want: Location{40.71, -74.01}},
+		{in: "74.01W 40.71N", want: Location{40.71, -74.01}},
+		// degrees, minutes and seconds
+		{in: `55°45'20"N 37°37'02"E`, want: Location{55 + 45.0/60 + 20.0/3600, 37 + 37.0/60 + 2.0/3600}},
+		{in: `33°51'54"S 151°12'34"E`, want: Location{-(33 + 51.0/60 + 54.0/3600), 151 + 12.0/60 + 34.0/3600}},
+		{in: `40°42'46"N 74°0'22"W`, want: Location{40 + 42.0/60 + 46.0/3600, -(74 + 22.0/3600)}},
+		{in: `55°45′20″N, 37°37′02″E`, want: Location{55 + 45.0/60 + 20.0/3600, 37 + 37.0/60 + 2.0/3600}},
+		{in: `55°45.5'N 37°37.25'E`, want: Location{55 + 45.5/60, 37 + 37.25/60}},
+		{in: `-33°51'54" 151°12'34"`, want: Location{-(33 + 51.0/60 + 54.0/3600), 151 + 12.0/60 + 34.0/3600}},
+		{in: `-0°30' 0°30'`, want: Location{-0.5, 0.5}},
+		// malformed
+		{in: "", wantErr: true},
+		{in: "53.9", wantErr: true},
+		{in: "5327", wantErr: true},
+		{in: "Minsk", wantErr: true},
+		{in: "53.9 27.56 10", wantErr: true},
+		{in: "53,9,27,56", wantErr: true},
+		{in: "91 27", wantErr: true},
+		{in: "53 181", wantErr: true},
+		{in: "55.75N 37.62", wantErr: true},
+		{in: "55.75N 37.62S", wantErr: true},
+		{in: "37.62E 55.75W", wantErr: true},
+		{in: "-55.75N 37.62E", wantErr: true},
+		{in: `55°60'N 37°37'E`, wantErr: true},
+		{in: `55°45'60"N 37°37'E`, wantErr: true},
+		{in: `55.5°45'N 37°37'E`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLocation(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLocation(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if math.Abs(got.Latitude-tt.want.Latitude) > 1e-9 || math.Abs(got.Longitude-tt.want.Longitude) > 1e-9 {
+				t.Errorf("ParseLocation(%q) = %+v, want %+v", tt.in, *got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParseCoordinates(f *testing.F) {
+	for _, seed := range []string{
+		"53.9 27.56", "-33.86,151.21", "53,9;27,56", `55°45'20"N 37°37'02"E`, "37.62E 55.75N",
+		"NaN NaN", "nan 27.56", "Inf -Inf", "+Inf 0", "1e309 0", "0x1p-2 0",
+		"90 180", "-90 -180", "90.0000001 0", "0 -180.0000001", "91 27", "53 181",
+		"", " ", ",", ";", "N S", "°'\"", "55°60'N 37°37'E", "-55.75N 37.62E",
+		strings.Repeat("9", 400) + " 0", strings.Repeat("1 ", 1000),
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		l, err := ParseLocation(s)
+		if err != nil {
+			if l != nil {
+				t.Errorf("ParseLocation(%q) = %v with the error %v, want nil", s, *l, err)
+			}
+			return
+		}
+		if l == nil || math.IsNaN(l.Latitude) || math.IsNaN(l.Longitude) || !l.InRange() {
+			t.Fatalf("ParseLocation(%q) = %v, want coordinates in range", s, l)
+		}
+		if !strings.ContainsAny(s, "0123456789") {
+			t.Errorf("ParseLocation(%q) = %v without digits, want an error", s, *l)
+		}
+		// the coordinates are parsed back from the format of the callbacks
+		again, err := ParseLocation(fmt.Sprintf("%f %f", l.Latitude, l.Longitude))
+		if err != nil {
+			t.Fatalf("ParseLocation() of the parsed %v = %v", *l, err)
+		}
+		if math.Abs(again.Latitude-l.Latitude) > 1e-6 || math.Abs(again.Longitude-l.Longitude) > 1e-6 {
+			t.Errorf("ParseLocation() of the parsed %v = %v", *l, *again)
+		}
+	})
+}
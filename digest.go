@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// digestPeriod is the period summarized by CronDigest
+	digestPeriod = 7 * 24 * time.Hour
+
+	digestHeaderText = "📅 Your weekly AQI digest"
+	digestEntryTmpl  = "Location: %f;%f. Average AQI: %.1f, peak: %s"
+	digestOnText     = "OK. You will get a weekly AQI digest instead of the alerts"
+	digestOffText    = "OK. I will notify you on AQI changes"
+	digestUsageMsg   = "Usage: /digest on|off"
+)
+
+// CronDigest runs weekly and sends every chat one summary of its digest subscriptions over digestPeriod
+func (bot *Bot) CronDigest() {
+	if bot.maintenance.Load() {
+		log.Print("CronDigest: maintenance mode, skipping")
+		return
+	}
+	entries, err := bot.store.ListDigestEntries(time.Now().Add(-digestPeriod))
+	if err != nil {
+		log.Print("CronDigest: ", err)
+		return
+	}
+
+	// entries are ordered by chat, so each chat gets a single message
+	var sent int
+	for i := 0; i < len(entries); {
+		chatID := entries[i].ChatID
+		p := newLangPrinter(entries[i].LanguageCode)
+		theme := bot.themeOf(chatID)
+		msgText := []string{p.Sprintf(digestHeaderText), ""}
+		for ; i < len(entries) && entries[i].ChatID == chatID; i++ {
+			e := entries[i]
+			msgText = append(msgText, p.Sprintf(digestEntryTmpl, e.Latitude, e.Longitude, e.AverageAQI, theme.LocalizedAQI(e.PeakAQI, p)))
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, strings.Join(msgText, "\n")))
+		sent++
+	}
+	log.Printf("CronDigest: sent %d digest(s)", sent)
+}
+
+// setDigestText switches the subscriptions of the chat between the weekly digest and the alerts
+func (bot *Bot) setDigestText(chatID int64, arg string, p *message.Printer) string {
+	var mode SubscriptionMode
+	switch strings.TrimSpace(arg) {
+	case "on":
+		mode = SubscriptionModeDigest
+	case "off":
+		mode = SubscriptionModeAlerts
+	default:
+		return p.Sprintf(digestUsageMsg)
+	}
+	if err := bot.store.SetSubscriptionsMode(chatID, mode); err != nil {
+		log.Print("SetSubscriptionsMode: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if mode == SubscriptionModeDigest {
+		return p.Sprintf(digestOnText)
+	}
+	return p.Sprintf(digestOffText)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// addTestHistory records the AQIs of the subscription checked ago
+func addTestHistory(t *testing.T, store *Store, subID int64, ago time.Duration, aqis ...AirQualityIndex) {
+	t.Helper()
+	for _, aqi := range aqis {
+		if err := store.AddAQIHistory(subID, aqi, time.Now().Add(-ago), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newTestDigestStore returns a store of the digest subscriptions of chat 1 and the alerts one of chat 2
+func newTestDigestStore(t *testing.T) (store *Store, minsk, brest int64) {
+	t.Helper()
+	store = newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	addTestSubscription(t, store, 1, &Location{52.1, 23.7}, 2)
+	addTestSubscription(t, store, 2, &Location{55.75, 37.62}, 2)
+	if err := store.SetSubscriptionsMode(1, SubscriptionModeDigest); err != nil {
+		t.Fatal(err)
+	}
+	subs, err := store.ListAQISubscriptions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	minsk, brest = (*subs)[0].ID, (*subs)[1].ID
+	addTestHistory(t, store, minsk, time.Hour, 1, 3, 2)
+	// the history before the period isn't summarized
+	addTestHistory(t, store, minsk, 8*24*time.Hour, 5)
+	addTestHistory(t, store, brest, time.Hour, 4)
+	addTestHistory(t, store, onlySubscription(t, store, 2).ID, time.Hour, 5)
+	return store, minsk, brest
+}
+
+func TestListDigestEntries(t *testing.T) {
+	store, minsk, brest := newTestDigestStore(t)
+
+	entries, err := store.ListDigestEntries(time.Now().Add(-digestPeriod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		id      int64
+		average float64
+		peak    AirQualityIndex
+		checks  int
+	}{
+		{minsk, 2, 3, 3},
+		{brest, 4, 4, 1},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("ListDigestEntries() = %+v, want %d entries", entries, len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.ID != w.id || e.AverageAQI != w.average || e.PeakAQI != w.peak || e.Checks != w.checks || e.ChatID != 1 {
+			t.Errorf("entry %d = %d: average %v, peak %d, %d checks of chat %d, want %d: %v, %d, %d of chat 1",
+				i, e.ID, e.AverageAQI, e.PeakAQI, e.Checks, e.ChatID, w.id, w.average, w.peak, w.checks)
+		}
+	}
+}
+
+func TestCronDigest(t *testing.T) {
+	store, _, _ := newTestDigestStore(t)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+
+	bot.CronDigest()
+
+	sent := fake.sent("sendMessage")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d digests, want 1", len(sent))
+	}
+	if got := sent[0].Get("chat_id"); got != "1" {
+		t.Errorf("digest is sent to chat %s, want 1", got)
+	}
+	text := sent[0].Get("text")
+	for _, want := range []string{digestHeaderText, "Average AQI: 2.0, peak: " + ThemeEmoji.AQI(3), "Average AQI: 4.0, peak: " + ThemeEmoji.AQI(4)} {
+		if !strings.Contains(text, want) {
+			t.Errorf("digest = %q, want %q", text, want)
+		}
+	}
+	// the history is kept for CronCleanup
+	if n := countRows(t, store, "SELECT COUNT(*) FROM aqi_history"); n != 6 {
+		t.Errorf("%d history rows after CronDigest, want 6", n)
+	}
+}
+
+func TestCleanupAQIHistory(t *testing.T) {
+	store, _, _ := newTestDigestStore(t)
+	bot := newTestBot(store, &botServices{})
+
+	bot.CronCleanup()
+
+	// the history before the period is deleted, the rest is still summarized
+	if n := countRows(t, store, "SELECT COUNT(*) FROM aqi_history WHERE created_at < ?", time.Now().Add(-digestPeriod).UTC()); n != 0 {
+		t.Errorf("%d history rows before the period are kept", n)
+	}
+	if n := countRows(t, store, "SELECT COUNT(*) FROM aqi_history"); n != 5 {
+		t.Errorf("%d history rows kept by CronCleanup, want 5", n)
+	}
+}
+
+func TestCronDigestSubscriptionsNotAlerted(t *testing.T) {
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 1)
+	if err := store.SetSubscriptionsMode(1, SubscriptionModeDigest); err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeTelegram{}
+	bot := newTestCronBot(t, store, &fakeAQI{aqi: 5}, fake)
+
+	bot.Cron()
+
+	if n := len(fake.sent("sendMessage")); n != 0 {
+		t.Errorf("%d alerts of a digest subscription, want 0", n)
+	}
+	// the checks are recorded for the digest
+	if n := countRows(t, store, "SELECT COUNT(*) FROM aqi_history"); n != 1 {
+		t.Errorf("%d history rows, want 1", n)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	// maxFavorites is the maximum number of the favorites of a chat
+	maxFavorites = 20
+
+	favoriteUsageMsg      = "Usage: /favorite add [label] saves your last shared location, /favorite remove N deletes favorite N, /favorite lists them"
+	favoritesHeaderTmpl   = "Your favorites: %d"
+	favoriteLineTmpl      = "%d. %.5f;%.5f"
+	favoriteLabeledTmpl   = "%d. %s: %.5f;%.5f"
+	favoritesEmptyText    = "You have no favorites. Share a location and save it with /favorite add Home"
+	favoritesHintText     = "/subscribe_all subscribes to all of them"
+	favoriteAddedTmpl     = "OK. Favorite %d is saved"
+	favoriteExistsText    = "This location is already a favorite"
+	favoritesFullTmpl     = "You can have at most %d favorites"
+	favoriteRemovedTmpl   = "OK. Favorite %d is removed"
+	subscribeAllTmpl      = "Subscribed to %d of %d favorite(s)"
+	subscribeAllExistTmpl = "Already subscribed: %d"
+)
+
+// favoriteText manages the favorites by the /favorite argument: "add [label]", "remove N" or none to list them
+func (bot *Bot) favoriteText(chatID int64, arg string, p *message.Printer) string {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		return bot.favoritesText(chatID, p)
+	}
+	switch fields[0] {
+	case "add":
+		return bot.addFavoriteText(chatID, strings.Join(fields[1:], " "), p)
+	case "remove":
+		if len(fields) != 2 {
+			return p.Sprintf(favoriteUsageMsg)
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return p.Sprintf(favoriteUsageMsg)
+		}
+		return bot.removeFavoriteText(chatID, n, p)
+	}
+	return p.Sprintf(favoriteUsageMsg)
+}
+
+// favoritesText lists the favorites of the chat in the order of their numbers
+func (bot *Bot) favoritesText(chatID int64, p *message.Printer) string {
+	favorites, err := bot.store.ListFavorites(chatID)
+	if err != nil {
+		log.Print("ListFavorites: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if len(favorites) == 0 {
+		return p.Sprintf(favoritesEmptyText)
+	}
+	msgText := []string{p.Sprintf(favoritesHeaderTmpl, len(favorites)), ""}
+	for i, f := range favorites {
+		if f.Label != "" {
+			msgText = append(msgText, p.Sprintf(favoriteLabeledTmpl, i+1, f.Label, f.Latitude, f.Longitude))
+		} else {
+			msgText = append(msgText, p.Sprintf(favoriteLineTmpl, i+1, f.Latitude, f.Longitude))
+		}
+	}
+	msgText = append(msgText, "", p.Sprintf(favoritesHintText))
+	return strings.Join(msgText, "\n")
+}
+
+// addFavoriteText saves the location of the UserSession as a favorite with the label
+func (bot *Bot) addFavoriteText(chatID int64, label string, p *message.Printer) string {
+	if utf8.RuneCountInString(label) > maxLabelLength {
+		return p.Sprintf(labelTooLongTmpl, maxLabelLength)
+	}
+	us, err := bot.store.GetSessionByChatID(chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p.Sprintf(nearbyNoSessionMsg)
+	}
+	if err != nil {
+		log.Print("GetSessionByChatID: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	favorites, err := bot.store.ListFavorites(chatID)
+	if err != nil {
+		log.Print("ListFavorites: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if len(favorites) >= maxFavorites {
+		return p.Sprintf(favoritesFullTmpl, maxFavorites)
+	}
+	switch err := bot.store.AddFavorite(chatID, us.Location(), label); {
+	case errors.Is(err, ErrFavoriteExists):
+		return p.Sprintf(favoriteExistsText)
+	case err != nil:
+		log.Print("AddFavorite: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	return p.Sprintf(favoriteAddedTmpl, len(favorites)+1)
+}
+
+// removeFavoriteText deletes the favorite number n of the list
+func (bot *Bot) removeFavoriteText(chatID int64, n int, p *message.Printer) string {
+	favorites, err := bot.store.ListFavorites(chatID)
+	if err != nil {
+		log.Print("ListFavorites: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if n < 1 || n > len(favorites) {
+		return p.Sprintf(favoriteUsageMsg)
+	}
+	if err := bot.store.DeleteFavorite(chatID, favorites[n-1].ID); err != nil {
+		log.Print("DeleteFavorite: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	return p.Sprintf(favoriteRemovedTmpl, n)
+}
+
+// subscribeAllText subscribes to the favorites of the chat. The subscribed ones are
+// skipped and subscribing stops at the subscription limit.
+func (bot *Bot) subscribeAllText(chatID int64, p *message.Printer) string {
+	favorites, err := bot.store.ListFavorites(chatID)
+	if err != nil {
+		log.Print("ListFavorites: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if len(favorites) == 0 {
+		return p.Sprintf(favoritesEmptyText)
+	}
+
+	var subscribed, existing, failed int
+	limited := false
+subscribing:
+	for _, f := range favorites {
+		// a subscription of an unknown AQI gets it on the next Cron run
+		var aqi AirQualityIndex
+		if resp, err := bot.wAPI.GetAirPollution(&f.Location); err == nil && len(resp.DP) > 0 {
+			aqi = latestDataPoint(resp.DP).GetAQI()
+		} else if err != nil {
+			log.Print("GetAirPollution: ", err)
+		}
+		switch err := bot.store.AddAQISubscriptionAt(chatID, &f.Location, aqi); {
+		case errors.Is(err, ErrNotificationExists):
+			existing++
+		case errors.Is(err, ErrSubscriptionLimitReached):
+			limited = true
+			break subscribing
+		case err != nil:
+			log.Print("AddAQISubscriptionAt: ", err)
+			failed++
+		default:
+			subscribed++
+		}
+	}
+
+	msgText := []string{p.Sprintf(subscribeAllTmpl, subscribed, len(favorites))}
+	if existing > 0 {
+		msgText = append(msgText, p.Sprintf(subscribeAllExistTmpl, existing))
+	}
+	if limited {
+		msgText = append(msgText, p.Sprintf(subsLimitTmpl, bot.store.MaxSubscriptions))
+	}
+	if failed > 0 {
+		msgText = append(msgText, p.Sprintf(safeToRetryErrMsg))
+	}
+	return strings.Join(msgText, "\n")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// favoriteLocations are the locations farther from each other than the duplicate distance
+var favoriteLocations = []*Location{{53.9, 27.56}, {52.1, 23.7}, {55.75, 37.62}, {51.51, -0.13}}
+
+// addTestFavorite saves the location as a favorite of the chat
+func addTestFavorite(t *testing.T, store *Store, chatID int64, l *Location, label string) {
+	t.Helper()
+	if err := store.AddFavorite(chatID, l, label); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFavorite(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	tests := []struct {
+		name string
+		// favorites are saved before the command
+		favorites []*Location
+		session   *Location
+		cmd       string
+		want      string
+		// left is the number of the favorites after the command
+		left int
+	}{
+		{name: "empty", cmd: "/favorite", want: favoritesEmptyText},
+		{name: "list", favorites: favoriteLocations[:2], cmd: "/favorite",
+			want: "Your favorites: 2\n\n1. Home: 53.90000;27.56000\n2. 52.10000;23.70000\n\n" + favoritesHintText, left: 2},
+		{name: "add", session: favoriteLocations[0], cmd: "/favorite add", want: "OK. Favorite 1 is saved", left: 1},
+		{name: "add labeled", session: favoriteLocations[2], favorites: favoriteLocations[:2], cmd: "/favorite add  Work ",
+			want: "OK. Favorite 3 is saved", left: 3},
+		{name: "add nearby", session: &Location{53.9005, 27.56}, favorites: favoriteLocations[:1], cmd: "/favorite add",
+			want: favoriteExistsText, left: 1},
+		{name: "add without session", cmd: "/favorite add", want: p.Sprintf(nearbyNoSessionMsg)},
+		{name: "add too long label", session: favoriteLocations[0], cmd: "/favorite add " + strings.Repeat("x", maxLabelLength+1),
+			want: p.Sprintf(labelTooLongTmpl, maxLabelLength)},
+		{name: "remove", favorites: favoriteLocations[:2], cmd: "/favorite remove 1", want: "OK. Favorite 1 is removed", left: 1},
+		{name: "remove unknown", favorites: favoriteLocations[:2], cmd: "/favorite remove 3", want: favoriteUsageMsg, left: 2},
+		{name: "remove not a number", favorites: favoriteLocations[:2], cmd: "/favorite remove first", want: favoriteUsageMsg, left: 2},
+		{name: "unknown action", cmd: "/favorite rename 1", want: favoriteUsageMsg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			for i, l := range tt.favorites {
+				label := ""
+				if i == 0 {
+					label = "Home"
+				}
+				addTestFavorite(t, store, 1, l, label)
+			}
+			if tt.session != nil {
+				addTestSession(t, store, 1, tt.session)
+			}
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+
+			bot.handleMessage(newTestCommand(1, tt.cmd))
+
+			if got := fake.lastText(); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.cmd, got, tt.want)
+			}
+			if n := countRows(t, store, "SELECT COUNT(*) FROM favorite WHERE chat_id=1"); n != tt.left {
+				t.Errorf("%d favorites, want %d", n, tt.left)
+			}
+		})
+	}
+
+	// the label is saved and the favorites are limited
+	store := newTestStore(t)
+	addTestSession(t, store, 1, favoriteLocations[0])
+	bot := newTestBot(store, &botServices{})
+	bot.favoriteText(1, "add  My  home ", p)
+	if favorites, err := store.ListFavorites(1); err != nil || len(favorites) != 1 || favorites[0].Label != "My home" {
+		t.Errorf("ListFavorites() = %+v, %v, want the label %q", favorites, err, "My home")
+	}
+	for i := 1; i < maxFavorites; i++ {
+		addTestFavorite(t, store, 1, &Location{float64(i), 0}, "")
+	}
+	addTestSession(t, store, 1, &Location{-10, -10})
+	if got, want := bot.favoriteText(1, "add", p), p.Sprintf(favoritesFullTmpl, maxFavorites); got != want {
+		t.Errorf("favoriteText() of %d favorites = %q, want %q", maxFavorites, got, want)
+	}
+}
+
+func TestSubscribeAll(t *testing.T) {
+	tests := []struct {
+		name      string
+		favorites int
+		// subscribed are the favorites subscribed before /subscribe_all
+		subscribed int
+		limit      int
+		want       []string
+		subs       int
+	}{
+		{name: "no favorites", want: []string{favoritesEmptyText}},
+		{name: "all", favorites: 3, want: []string{"Subscribed to 3 of 3 favorite(s)"}, subs: 3},
+		{name: "some subscribed", favorites: 3, subscribed: 2,
+			want: []string{"Subscribed to 1 of 3 favorite(s)", "Already subscribed: 2"}, subs: 3},
+		{name: "all subscribed", favorites: 2, subscribed: 2,
+			want: []string{"Subscribed to 0 of 2 favorite(s)", "Already subscribed: 2"}, subs: 2},
+		{name: "below the limit", favorites: 2, limit: 3, want: []string{"Subscribed to 2 of 2 favorite(s)"}, subs: 2},
+		{name: "at the limit", favorites: 4, limit: 2,
+			want: []string{"Subscribed to 2 of 4 favorite(s)", message.NewPrinter(language.English).Sprintf(subsLimitTmpl, 2)}, subs: 2},
+		{name: "limit of the subscribed", favorites: 4, subscribed: 1, limit: 2,
+			want: []string{"Subscribed to 1 of 4 favorite(s)", "Already subscribed: 1",
+				message.NewPrinter(language.English).Sprintf(subsLimitTmpl, 2)}, subs: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			// the favorites are saved from the session
+			addTestSession(t, store, 1, favoriteLocations[0])
+			for _, l := range favoriteLocations[:tt.favorites] {
+				addTestFavorite(t, store, 1, l, "")
+			}
+			for _, l := range favoriteLocations[:tt.subscribed] {
+				addTestSubscription(t, store, 1, l, 1)
+			}
+			store.MaxSubscriptions = tt.limit
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 3}}, fake)
+
+			bot.handleMessage(newTestCommand(1, "/subscribe_all"))
+
+			if got, want := fake.lastText(), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("/subscribe_all = %q, want %q", got, want)
+			}
+			if n := countRows(t, store, "SELECT COUNT(*) FROM subscription WHERE chat_id=1"); n != tt.subs {
+				t.Errorf("%d subscriptions, want %d", n, tt.subs)
+			}
+			// the new subscriptions get the current AQI
+			if n := countRows(t, store, "SELECT COUNT(*) FROM subscription WHERE chat_id=1 AND aqi=3"); n != tt.subs-tt.subscribed {
+				t.Errorf("%d subscriptions of the current AQI, want %d", n, tt.subs-tt.subscribed)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	measuredJustNowText  = "Measured just now"
+	measuredMinsAgoTmpl  = "Measured %d min ago"
+	measuredHoursAgoTmpl = "Measured %d h ago"
+	measuredDaysAgoTmpl  = "Measured %d day(s) ago"
+)
+
+// HumanizeSince returns a localized description of how long ago t was
+func HumanizeSince(t time.Time, p *message.Printer) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return p.Sprintf(measuredJustNowText)
+	case d < time.Hour:
+		return p.Sprintf(measuredMinsAgoTmpl, int(d/time.Minute))
+	case d < 24*time.Hour:
+		return p.Sprintf(measuredHoursAgoTmpl, int(d/time.Hour))
+	}
+	return p.Sprintf(measuredDaysAgoTmpl, int(d/(24*time.Hour)))
+}
+
+// FormatAQIMessage returns the AQI of the DataPoint in the scale and the theme with the health advice for the profile
+// under the header. The header is omitted if it is empty.
+func FormatAQIMessage(dp *DataPoint, p *message.Printer, header string, profile SensitivityProfile, scale AQIScale, theme Theme) string {
+	var msgText []string
+	if header != "" {
+		msgText = append(msgText, header, "")
+	}
+	msgText = append(msgText,
+		scale.Format(dp, theme, p),
+		"",
+		dp.Main.Aqi.LocalizedProfileDescription(profile, p),
+	)
+	return strings.Join(msgText, "\n")
+}
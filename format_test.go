@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestHumanizeSince(t *testing.T) {
+	tests := []struct {
+		ago  time.Duration
+		lang language.Tag
+		want string
+	}{
+		{0, language.English, "Measured just now"},
+		{59 * time.Second, language.English, "Measured just now"},
+		{time.Minute, language.English, "Measured 1 min ago"},
+		{59*time.Minute + 59*time.Second, language.English, "Measured 59 min ago"},
+		{time.Hour, language.English, "Measured 1 h ago"},
+		{23*time.Hour + 59*time.Minute, language.English, "Measured 23 h ago"},
+		{24 * time.Hour, language.English, "Measured 1 day(s) ago"},
+		{50 * time.Hour, language.English, "Measured 2 day(s) ago"},
+		{5 * time.Minute, language.Russian, "Измерено 5 мин. назад"},
+		{2 * time.Hour, language.Russian, "Измерено 2 ч. назад"},
+	}
+	for _, tt := range tests {
+		// the margin keeps the time within the boundary while the test runs
+		got := HumanizeSince(time.Now().Add(-tt.ago-time.Millisecond), message.NewPrinter(tt.lang))
+		if got != tt.want {
+			t.Errorf("HumanizeSince(%v ago) in %s = %q, want %q", tt.ago, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestLocalizedNumbers(t *testing.T) {
+	l := &Location{53.9, 27.56}
+	tests := []struct {
+		lang language.Tag
+		// component and location are the expected number parts of a component line and /subsriptions location
+		component, location string
+	}{
+		{language.English, "PM2.5: 12.50 ", "27.560000;53.900000"},
+		{language.Russian, "PM2.5: 12,50 ", "27,560000;53,900000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang.String(), func(t *testing.T) {
+			p := message.NewPrinter(tt.lang)
+			if got := componentLine("pm2_5", 12.5, ThemeEmoji, p); !strings.HasPrefix(got, tt.component) {
+				t.Errorf("componentLine() = %q, want the prefix %q", got, tt.component)
+			}
+
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, l, 2)
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+			msg := newTestCommand(1, "/subsriptions")
+			msg.From.LanguageCode = tt.lang.String()
+			bot.handleMessage(msg)
+			if got := fake.lastText(); !strings.Contains(got, tt.location) {
+				t.Errorf("/subsriptions = %q, want the location %q", got, tt.location)
+			}
+
+			// the shown coordinates can be passed back to the commands
+			got, err := ParseLocation(p.Sprintf("%f;%f", l.Latitude, l.Longitude))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != *l {
+				t.Errorf("ParseLocation() of the shown coordinates = %+v, want %+v", *got, *l)
+			}
+			// the query parameters don't depend on the language
+			if q := l.query().Encode(); q != "lat=53.900000&lon=27.560000" {
+				t.Errorf("query() = %q", q)
+			}
+		})
+	}
+}
+
+func TestFormatAQIMessage(t *testing.T) {
+	en := message.NewPrinter(language.English)
+	dp := newTestDataPoint(3, time.Now(), map[string]float64{"pm2_5": 30, "pm10": 40, "o3": 90})
+	tests := []struct {
+		name    string
+		header  string
+		profile SensitivityProfile
+		scale   AQIScale
+		theme   Theme
+		want    []string
+	}{
+		{name: "header", header: "AQI gets worse", profile: ProfileGeneral, scale: ScaleOWM, theme: ThemeEmoji, want: []string{
+			"AQI gets worse",
+			"",
+			"Air Quality Index: " + ThemeEmoji.AQI(3),
+			"",
+			AirQualityIndex(3).ProfileDescription(ProfileGeneral),
+		}},
+		{name: "no header", profile: ProfileGeneral, scale: ScaleOWM, theme: ThemeEmoji, want: []string{
+			"Air Quality Index: " + ThemeEmoji.AQI(3),
+			"",
+			AirQualityIndex(3).ProfileDescription(ProfileGeneral),
+		}},
+		{name: "plain theme and a profile", profile: ProfileChildren, scale: ScaleOWM, theme: ThemePlain, want: []string{
+			"Air Quality Index: Moderate",
+			"",
+			AirQualityIndex(3).ProfileDescription(ProfileChildren),
+		}},
+		{name: "multiline header", header: "AQI gets worse\n🏷 Home", profile: ProfileGeneral, scale: ScaleEPA, theme: ThemeEmoji, want: []string{
+			"AQI gets worse",
+			"🏷 Home",
+			"",
+			ScaleEPA.Format(&dp, ThemeEmoji, en),
+			"",
+			AirQualityIndex(3).ProfileDescription(ProfileGeneral),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatAQIMessage(&dp, en, tt.header, tt.profile, tt.scale, tt.theme)
+			if want := strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("FormatAQIMessage() = %q, want %q", got, want)
+			}
+		})
+	}
+
+	ru := message.NewPrinter(language.Russian)
+	if got := FormatAQIMessage(&dp, ru, "", ProfileGeneral, ScaleOWM, ThemeEmoji); got == FormatAQIMessage(&dp, en, "", ProfileGeneral, ScaleOWM, ThemeEmoji) {
+		t.Errorf("FormatAQIMessage() in Russian = %q, the same as in English", got)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// geocodeCacheTTL is how long a reverse geocoding result is served from geocode_cache. Places rarely change.
+const geocodeCacheTTL = 30 * 24 * time.Hour
+
+// cachedGeocoder serves the reverse geocoding from geocode_cache of the store, including the places not found.
+// Direct geocoding is passed to the Geocoder.
+type cachedGeocoder struct {
+	Geocoder
+	store *Store
+}
+
+func (g *cachedGeocoder) ReverseGeocode(l *Location) (*Place, error) {
+	// a broken cache doesn't stop the geocoding
+	place, err := g.store.GetGeocode(l, geocodeCacheTTL)
+	if err != nil {
+		log.Print("GetGeocode: ", err)
+	}
+	if place != nil {
+		if place.Name == "" {
+			return place, errPlaceNotFound
+		}
+		return place, nil
+	}
+
+	place, err = g.Geocoder.ReverseGeocode(l)
+	if err != nil && !errors.Is(err, errPlaceNotFound) {
+		return place, err
+	}
+	if err := g.store.SetGeocode(l, place); err != nil {
+		log.Print("SetGeocode: ", err)
+	}
+	return place, err
+}
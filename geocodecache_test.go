@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingGeocoder is a Geocoder counting the reverse geocoding requests
+type countingGeocoder struct {
+	fakeGeocoder
+	reverse int
+}
+
+func (g *countingGeocoder) ReverseGeocode(l *Location) (*Place, error) {
+	g.reverse++
+	return g.fakeGeocoder.ReverseGeocode(l)
+}
+
+func TestCachedGeocoder(t *testing.T) {
+	minsk := &Location{53.9, 27.56}
+	failure := errors.New("geocoding is down")
+	tests := []struct {
+		name string
+		err  error
+		// age of the cached result before the second request
+		age      time.Duration
+		wantName string
+		wantErr  error
+		// requests are the requests to the Geocoder of the two lookups
+		requests int
+	}{
+		{name: "hit", wantName: "Minsk", requests: 1},
+		{name: "expired", age: geocodeCacheTTL, wantName: "Minsk", requests: 2},
+		{name: "not found is cached", err: errPlaceNotFound, wantErr: errPlaceNotFound, requests: 1},
+		{name: "failure is not cached", err: failure, wantErr: failure, requests: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			geocoder := &countingGeocoder{fakeGeocoder: fakeGeocoder{place: Place{Name: "Minsk"}, err: tt.err}}
+			g := &cachedGeocoder{Geocoder: geocoder, store: store}
+
+			for i := 0; i < 2; i++ {
+				place, err := g.ReverseGeocode(minsk)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ReverseGeocode() #%d error = %v, want %v", i+1, err, tt.wantErr)
+				}
+				if err == nil && place.Name != tt.wantName {
+					t.Errorf("ReverseGeocode() #%d = %q, want %q", i+1, place.Name, tt.wantName)
+				}
+				if _, err := store.DB.Exec("UPDATE geocode_cache SET created_at=?", time.Now().Add(-tt.age).UTC()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if geocoder.reverse != tt.requests {
+				t.Errorf("%d geocoding request(s), want %d", geocoder.reverse, tt.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// HTTPHandler returns the handler for the service endpoints:
+// /healthz reports the process is alive, /readyz checks the DB and Telegram API are reachable,
+// /metrics serves the expvar counters of the bot like cron_outcomes as JSON.
+func (bot *Bot) HTTPHandler() http.Handler {
+	return bot.newServeMux()
+}
+
+func (bot *Bot) newServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", bot.handleReadyz)
+	mux.HandleFunc("/metrics", handleMetrics)
+	return mux
+}
+
+func (bot *Bot) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if err := bot.store.DB.Ping(); err != nil {
+		log.Print("readyz: DB: ", err)
+		http.Error(w, "DB is not reachable", http.StatusServiceUnavailable)
+		return
+	}
+	if _, err := bot.tApi.GetMe(); err != nil {
+		log.Print("readyz: Telegram API: ", err)
+		http.Error(w, "Telegram API is not reachable", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
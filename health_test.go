@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	tests := []struct {
+		name string
+		// closeDB makes the DB unreachable
+		closeDB   bool
+		failGetMe bool
+		path      string
+		want      int
+	}{
+		{name: "alive", path: "/healthz", want: http.StatusOK},
+		{name: "alive with unhealthy DB", closeDB: true, path: "/healthz", want: http.StatusOK},
+		{name: "ready", path: "/readyz", want: http.StatusOK},
+		{name: "unhealthy DB", closeDB: true, path: "/readyz", want: http.StatusServiceUnavailable},
+		{name: "unreachable Telegram API", failGetMe: true, path: "/readyz", want: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+			if tt.closeDB {
+				store.DB.Close()
+			}
+			fake.fail = map[string]bool{"getMe": tt.failGetMe}
+
+			rec := httptest.NewRecorder()
+			bot.HTTPHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("GET %s = %d, want %d: %s", tt.path, rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// inlineQueryMinLen is the shortest query geocoded. Telegram sends a query on every keystroke.
+	inlineQueryMinLen = 3
+	// inlineQueryInterval is the minimal interval between the lookups of a user. Queries sent sooner are dropped.
+	inlineQueryInterval = time.Second
+	// inlineCacheTime is how long Telegram caches the results of a query, in seconds
+	inlineCacheTime = 300
+
+	inlineResultTmpl = "%s: %s"
+)
+
+// Geocoder finds a Place by its name or coordinates
+type Geocoder interface {
+	Geocode(query string) (*Place, error)
+	ReverseGeocode(l *Location) (*Place, error)
+}
+
+// inlineLimiter drops inline queries of a user sent sooner than inlineQueryInterval after the previous lookup
+type inlineLimiter struct {
+	mu   sync.Mutex
+	last map[int64]time.Time
+}
+
+// Allow reports whether the user can make a lookup now and records it
+func (l *inlineLimiter) Allow(userID int64, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.last == nil {
+		l.last = map[int64]time.Time{}
+	}
+	if now.Sub(l.last[userID]) < inlineQueryInterval {
+		return false
+	}
+	l.last[userID] = now
+	return true
+}
+
+// handleInlineQuery answers "@bot <city>" queries with an article reporting the AQI of the city
+func (bot *Bot) handleInlineQuery(query *tgbotapi.InlineQuery) {
+	text := strings.TrimSpace(query.Query)
+	if len([]rune(text)) < inlineQueryMinLen || bot.inMaintenance(query.From.ID) || !bot.inlineLimiter.Allow(query.From.ID, time.Now()) {
+		return
+	}
+	p := newLangPrinter(query.From.LanguageCode)
+
+	place, err := bot.geocoder.Geocode(text)
+	if err != nil {
+		log.Print("Geocode: ", err)
+		return
+	}
+	resp, err := bot.wAPI.GetAirPollution(&place.Location)
+	if err == nil && len(resp.DP) == 0 {
+		err = errNoDataPoints
+	}
+	if err != nil {
+		log.Print("GetAirPollution: ", err)
+		return
+	}
+	aqi := resp.DP[0].GetAQI()
+
+	// the results are cached by Telegram for all the users, so the preferences of the user don't apply
+	article := tgbotapi.NewInlineQueryResultArticle("aqi", p.Sprintf(inlineResultTmpl, place.String(), aqi.LocalizedString(p)),
+		FormatAQIMessage(&resp.DP[0], p, place.String(), ProfileGeneral, ScaleOWM, ThemeEmoji))
+	article.Description = aqi.LocalizedDescription(p)
+
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       []interface{}{article},
+		CacheTime:     inlineCacheTime,
+	}
+	if _, err := bot.tApi.Request(answer); err != nil {
+		log.Print("answerInlineQuery: ", err)
+	}
+}
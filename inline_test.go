@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fakeGeocoder is a Geocoder finding the place for any query. It records the queries.
+type fakeGeocoder struct {
+	place   Place
+	err     error
+	queries []string
+}
+
+func (g *fakeGeocoder) Geocode(query string) (*Place, error) {
+	g.queries = append(g.queries, query)
+	if g.err != nil {
+		return &Place{}, g.err
+	}
+	place := g.place
+	return &place, nil
+}
+
+func (g *fakeGeocoder) ReverseGeocode(l *Location) (*Place, error) {
+	if g.err != nil {
+		return &Place{}, g.err
+	}
+	place := g.place
+	place.Location = *l
+	return &place, nil
+}
+
+func newTestInlineQuery(userID int64, query string) *tgbotapi.InlineQuery {
+	return &tgbotapi.InlineQuery{ID: "q1", From: &tgbotapi.User{ID: userID, LanguageCode: "en"}, Query: query}
+}
+
+func TestHandleInlineQuery(t *testing.T) {
+	minsk := Place{Name: "Minsk", Country: "BY", Location: Location{53.9, 27.56}}
+	tests := []struct {
+		name      string
+		query     string
+		geoErr    error
+		aqiErr    error
+		wantGeo   bool
+		wantTitle string
+	}{
+		{name: "city", query: "Minsk", wantGeo: true, wantTitle: "Minsk, BY: 🟨 (Fair)"},
+		{name: "short query", query: " Mi ", wantGeo: false},
+		{name: "place not found", query: "Nowhere", geoErr: errPlaceNotFound, wantGeo: true},
+		{name: "AQI error", query: "Minsk", aqiErr: errors.New("owm is down"), wantGeo: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			fake := &fakeTelegram{}
+			geo := &fakeGeocoder{place: minsk, err: tt.geoErr}
+			aqi := &fakeAQI{aqi: 2, err: tt.aqiErr}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: aqi, geocoder: geo}, fake)
+
+			bot.handleInlineQuery(newTestInlineQuery(1, tt.query))
+
+			if got := len(geo.queries) > 0; got != tt.wantGeo {
+				t.Errorf("geocoded = %v, want %v", got, tt.wantGeo)
+			}
+			answers := fake.sent("answerInlineQuery")
+			if tt.wantTitle == "" {
+				if len(answers) != 0 {
+					t.Errorf("answered %v, want no answer", answers)
+				}
+				return
+			}
+			if len(answers) != 1 {
+				t.Fatalf("answered %d times, want 1", len(answers))
+			}
+			if id := answers[0].Get("inline_query_id"); id != "q1" {
+				t.Errorf("inline_query_id = %q, want q1", id)
+			}
+			var results []struct {
+				Type  string `json:"type"`
+				Title string `json:"title"`
+			}
+			if err := json.Unmarshal([]byte(answers[0].Get("results")), &results); err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 || results[0].Type != "article" || results[0].Title != tt.wantTitle {
+				t.Errorf("results = %+v, want an article %q", results, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestHandleInlineQueryRateLimit(t *testing.T) {
+	store := newTestStore(t)
+	fake := &fakeTelegram{}
+	geo := &fakeGeocoder{place: Place{Name: "Minsk", Location: Location{53.9, 27.56}}}
+	bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 1}, geocoder: geo}, fake)
+
+	for _, q := range []string{"Min", "Mins", "Minsk"} {
+		bot.handleInlineQuery(newTestInlineQuery(1, q))
+	}
+	bot.handleInlineQuery(newTestInlineQuery(2, "London"))
+
+	if want := []string{"Min", "London"}; len(geo.queries) != len(want) || geo.queries[0] != want[0] || geo.queries[1] != want[1] {
+		t.Errorf("geocoded %q, want %q", geo.queries, want)
+	}
+	if got := len(fake.sent("answerInlineQuery")); got != 2 {
+		t.Errorf("answered %d times, want 2", got)
+	}
+}
+
+func TestInlineLimiter(t *testing.T) {
+	var l inlineLimiter
+	now := time.Now()
+	tests := []struct {
+		userID int64
+		at     time.Time
+		want   bool
+	}{
+		{1, now, true},
+		{1, now.Add(inlineQueryInterval / 2), false},
+		{2, now.Add(inlineQueryInterval / 2), true},
+		{1, now.Add(inlineQueryInterval), true},
+		{1, now.Add(inlineQueryInterval + time.Millisecond), false},
+	}
+	for i, tt := range tests {
+		if got := l.Allow(tt.userID, tt.at); got != tt.want {
+			t.Errorf("%d: Allow(%d) = %v, want %v", i, tt.userID, got, tt.want)
+		}
+	}
+}
+
+func TestGeocode(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    Place
+		wantErr error
+	}{
+		{name: "found", body: `[{"name":"Minsk","country":"BY","lat":53.9,"lon":27.56}]`,
+			want: Place{Name: "Minsk", Country: "BY", Location: Location{53.9, 27.56}}},
+		{name: "not found", body: `[]`, wantErr: errPlaceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			owma := newTestOWM(t, func(r *http.Request) (int, string) {
+				query = r.URL.Query().Get("q")
+				return http.StatusOK, tt.body
+			})
+			got, err := owma.Geocode("Minsk")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Geocode() error = %v, want %v", err, tt.wantErr)
+			}
+			if query != "Minsk" {
+				t.Errorf("q = %q, want Minsk", query)
+			}
+			if err == nil && *got != tt.want {
+				t.Errorf("Geocode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
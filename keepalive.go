@@ -0,0 +1,25 @@
+package main
+
+import (
+	"expvar"
+	"log"
+)
+
+// keepAlivePings counts the results of KeepAlive. It is served on /metrics.
+var keepAlivePings = expvar.NewMap("keepalive_pings")
+
+// KeepAlive checks the connection to Telegram by a getMe request. If it fails, the idle connections are closed,
+// so the next getUpdates dials a new connection instead of reusing one dropped by a proxy.
+// A getUpdates hung on the dropped connection isn't interrupted: it fails on the timeout of the Telegram client,
+// telegramHTTPTimeout by default, and Run retries it over a new connection.
+func (bot *Bot) KeepAlive() {
+	if _, err := bot.tApi.GetMe(); err != nil {
+		log.Print("KeepAlive: ", err)
+		keepAlivePings.Add("failed", 1)
+		if c, ok := bot.tApi.Client.(interface{ CloseIdleConnections() }); ok {
+			c.CloseIdleConnections()
+		}
+		return
+	}
+	keepAlivePings.Add("ok", 1)
+}
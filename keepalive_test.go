@@ -0,0 +1,136 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// closingTelegram is a fakeTelegram counting the calls of CloseIdleConnections
+type closingTelegram struct {
+	*fakeTelegram
+	closed int
+}
+
+func (c *closingTelegram) CloseIdleConnections() { c.closed++ }
+
+// keepAlivePingCount returns the count of the KeepAlive result
+func keepAlivePingCount(result string) int64 {
+	if v, ok := keepAlivePings.Get(result).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestKeepAlive(t *testing.T) {
+	tests := []struct {
+		name       string
+		fail       bool
+		wantResult string
+		wantClosed int
+	}{
+		{name: "ok", wantResult: "ok"},
+		{name: "failed ping", fail: true, wantResult: "failed", wantClosed: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeTelegram{}
+			client := &closingTelegram{fakeTelegram: fake}
+			bot := newFakeTelegramBot(t, newTestStore(t), &botServices{}, fake)
+			bot.tApi.Client = client
+			fake.fail = map[string]bool{"getMe": tt.fail}
+			ok, failed := keepAlivePingCount("ok"), keepAlivePingCount("failed")
+
+			bot.KeepAlive()
+
+			if got := len(fake.sent("getMe")); got != 2 {
+				t.Errorf("%d getMe requests, want the one of the bot and the ping", got)
+			}
+			wantOK, wantFailed := ok, failed+1
+			if tt.wantResult == "ok" {
+				wantOK, wantFailed = ok+1, failed
+			}
+			if got := keepAlivePingCount("ok"); got != wantOK {
+				t.Errorf("ok pings = %d, want %d", got, wantOK)
+			}
+			if got := keepAlivePingCount("failed"); got != wantFailed {
+				t.Errorf("failed pings = %d, want %d", got, wantFailed)
+			}
+			if client.closed != tt.wantClosed {
+				t.Errorf("idle connections closed %d times, want %d", client.closed, tt.wantClosed)
+			}
+		})
+	}
+}
+
+func TestKeepAliveRecovery(t *testing.T) {
+	var (
+		getMeFails atomic.Bool
+		polls      atomic.Int32
+		bot        *Bot
+	)
+	hung := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the closed connection cancels the context once the body is read
+		r.ParseForm()
+		switch path.Base(r.URL.Path) {
+		case "getMe":
+			if getMeFails.Load() {
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+				return
+			}
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`))
+		case "getUpdates":
+			switch polls.Add(1) {
+			case 1:
+				// the poll hangs on a dropped connection until the client times out
+				close(hung)
+				<-r.Context().Done()
+				return
+			case 2:
+				bot.Stop()
+				w.Write([]byte(`{"ok":true,"result":[{"update_id":1}]}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true,"result":[]}`))
+		default:
+			w.Write([]byte(`{"ok":true,"result":true}`))
+		}
+	}))
+	defer server.Close()
+	api, err := tgbotapi.NewBotAPIWithClient("1:test", server.URL+"/bot%s/%s", &http.Client{Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot = newTestBot(newTestStore(t), &botServices{})
+	bot.tApi = api
+	failed := keepAlivePingCount("failed")
+
+	done := make(chan struct{})
+	go func() {
+		bot.Run()
+		close(done)
+	}()
+	<-hung
+	getMeFails.Store(true)
+	bot.KeepAlive()
+
+	// the hung poll times out and is retried
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run doesn't recover from the hung poll")
+	}
+	if got := keepAlivePingCount("failed"); got != failed+1 {
+		t.Errorf("failed pings = %d, want %d", got, failed+1)
+	}
+	if got := polls.Load(); got != 2 {
+		t.Errorf("%d getUpdates requests, want the hung one and its retry", got)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	// maxLabelLength is the maximum number of characters of a subscription label
+	maxLabelLength = 32
+
+	labelUsageMsg         = "Usage: /label N <text>, where N is the number of the subscription in /subsriptions. The label is removed if the text is empty"
+	labelTooLongTmpl      = "The label is too long, at most %d characters are allowed"
+	labelSetTmpl          = "OK. Subscription %d is labeled: %s"
+	labelRemovedTmpl      = "OK. The label of subscription %d is removed"
+	subscriptionLabelTmpl = "🏷 %s"
+)
+
+// labelText sets the label of the subscription number N of the /label argument "N <text>".
+// Whitespace of the text is collapsed to single spaces.
+func (bot *Bot) labelText(chatID int64, arg string, p *message.Printer) string {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		return p.Sprintf(labelUsageMsg)
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return p.Sprintf(labelUsageMsg)
+	}
+	label := strings.Join(fields[1:], " ")
+	if utf8.RuneCountInString(label) > maxLabelLength {
+		return p.Sprintf(labelTooLongTmpl, maxLabelLength)
+	}
+
+	subs, err := bot.store.ListAQISubscriptions(chatID)
+	if err != nil {
+		log.Print("ListAQISubscriptions: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if n < 1 || n > len(*subs) {
+		return p.Sprintf(labelUsageMsg)
+	}
+	if err := bot.store.SetSubscriptionLabel((*subs)[n-1].ID, label); err != nil {
+		log.Print("SetSubscriptionLabel: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if label == "" {
+		return p.Sprintf(labelRemovedTmpl, n)
+	}
+	return p.Sprintf(labelSetTmpl, n, label)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestLabelText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	longest := strings.Repeat("д", maxLabelLength)
+	tests := []struct {
+		name string
+		arg  string
+		want string
+		// labels are the labels of the two subscriptions after the command
+		labels [2]string
+	}{
+		{name: "set", arg: "2 Office", want: fmt.Sprintf(labelSetTmpl, 2, "Office"), labels: [2]string{"Home", "Office"}},
+		{name: "whitespace collapsed", arg: " 2   My \t office ", want: fmt.Sprintf(labelSetTmpl, 2, "My office"), labels: [2]string{"Home", "My office"}},
+		{name: "replace", arg: "1 Cottage", want: fmt.Sprintf(labelSetTmpl, 1, "Cottage"), labels: [2]string{"Cottage"}},
+		{name: "remove", arg: "1", want: fmt.Sprintf(labelRemovedTmpl, 1)},
+		{name: "longest", arg: "2 " + longest, want: fmt.Sprintf(labelSetTmpl, 2, longest), labels: [2]string{"Home", longest}},
+		{name: "too long", arg: "2 " + longest + "д", want: fmt.Sprintf(labelTooLongTmpl, maxLabelLength), labels: [2]string{"Home"}},
+		{name: "no arguments", arg: "", want: labelUsageMsg, labels: [2]string{"Home"}},
+		{name: "not a number", arg: "Home Office", want: labelUsageMsg, labels: [2]string{"Home"}},
+		{name: "no subscription", arg: "3 Office", want: labelUsageMsg, labels: [2]string{"Home"}},
+		{name: "zero", arg: "0 Office", want: labelUsageMsg, labels: [2]string{"Home"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+			addTestSubscription(t, store, 1, &Location{51.51, -0.13}, 2)
+			bot := newTestBot(store, &botServices{})
+			if got := bot.labelText(1, "1 Home", p); got != fmt.Sprintf(labelSetTmpl, 1, "Home") {
+				t.Fatalf("labelText() = %q", got)
+			}
+
+			if got := bot.labelText(1, tt.arg, p); got != tt.want {
+				t.Errorf("labelText(%q) = %q, want %q", tt.arg, got, tt.want)
+			}
+			subs, err := store.ListAQISubscriptions(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, s := range *subs {
+				if s.Label != tt.labels[i] {
+					t.Errorf("label of subscription %d = %q, want %q", i+1, s.Label, tt.labels[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriptionLabelShown(t *testing.T) {
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 1)
+	if err := store.SetSubscriptionLabel(onlySubscription(t, store, 1).ID, "Home"); err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeTelegram{}
+	bot := newTestCronBot(t, store, &fakeAQI{aqi: 4}, fake)
+
+	bot.handleMessage(newTestCommand(1, "/subsriptions"))
+	if got := fake.lastText(); !strings.Contains(got, "🏷 Home\nLocation: ") {
+		t.Errorf("/subsriptions = %q, want the label above the location", got)
+	}
+	bot.Cron()
+	if got := fake.lastText(); !strings.Contains(got, "🏷 Home") {
+		t.Errorf("notification = %q, want the label", got)
+	}
+}
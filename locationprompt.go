@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// locationPromptWindow is how long the bot waits for the location requested by /air before explaining how to share it.
+// Telegram sends nothing if the user denies the permission.
+const locationPromptWindow = 2 * time.Minute
+
+const locationPromptText = "I haven't received your location. If your phone asks, allow Telegram to access the location, " +
+	"or attach it with 📎 → Location. You can also send the coordinates, e.g. /air 53.9 27.56"
+
+// pendingLocation is a location requested by /air and not shared yet
+type pendingLocation struct {
+	chatID       int64
+	languageCode string
+	requestedAt  time.Time
+}
+
+// pendingLocations tracks the chats requested to share the location
+type pendingLocations struct {
+	mu      sync.Mutex
+	pending map[int64]pendingLocation
+}
+
+// Request records the location request of the chat. A repeated request restarts the window.
+func (pl *pendingLocations) Request(chatID int64, languageCode string, now time.Time) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if pl.pending == nil {
+		pl.pending = map[int64]pendingLocation{}
+	}
+	pl.pending[chatID] = pendingLocation{chatID, languageCode, now}
+}
+
+// Resolve forgets the request of the chat once the location arrives
+func (pl *pendingLocations) Resolve(chatID int64) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	delete(pl.pending, chatID)
+}
+
+// Expired removes and returns the requests older than locationPromptWindow
+func (pl *pendingLocations) Expired(now time.Time) []pendingLocation {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	var expired []pendingLocation
+	for chatID, req := range pl.pending {
+		if now.Sub(req.requestedAt) >= locationPromptWindow {
+			expired = append(expired, req)
+			delete(pl.pending, chatID)
+		}
+	}
+	return expired
+}
+
+// CronLocationPrompts explains how to share the location to the chats not sharing it within locationPromptWindow
+func (bot *Bot) CronLocationPrompts() {
+	expired := bot.pendingLocations.Expired(time.Now())
+	for _, req := range expired {
+		p := newLangPrinter(req.languageCode)
+		bot.Send(tgbotapi.NewMessage(req.chatID, p.Sprintf(locationPromptText)))
+	}
+	if len(expired) > 0 {
+		log.Printf("CronLocationPrompts: prompted %d chat(s)", len(expired))
+	}
+}
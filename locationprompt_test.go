@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestPendingLocations(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		// requests are the ages of the requests of chats 1, 2, ...
+		requests []time.Duration
+		resolved []int64
+		want     []int64
+	}{
+		{name: "within the window", requests: []time.Duration{locationPromptWindow - time.Second}},
+		{name: "at the window", requests: []time.Duration{locationPromptWindow}, want: []int64{1}},
+		{name: "after the window", requests: []time.Duration{time.Hour, time.Second}, want: []int64{1}},
+		{name: "resolved", requests: []time.Duration{time.Hour, time.Hour}, resolved: []int64{1}, want: []int64{2}},
+		{name: "resolved unknown chat", requests: []time.Duration{time.Hour}, resolved: []int64{3}, want: []int64{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pl pendingLocations
+			for i, age := range tt.requests {
+				pl.Request(int64(i+1), "en", now.Add(-age))
+			}
+			for _, chatID := range tt.resolved {
+				pl.Resolve(chatID)
+			}
+
+			expired := pl.Expired(now)
+			if len(expired) != len(tt.want) {
+				t.Fatalf("Expired() = %v, want chats %v", expired, tt.want)
+			}
+			for i, req := range expired {
+				if req.chatID != tt.want[i] {
+					t.Errorf("Expired() = %v, want chats %v", expired, tt.want)
+				}
+			}
+			if again := pl.Expired(now); len(again) != 0 {
+				t.Errorf("Expired() again = %v, want none", again)
+			}
+		})
+	}
+
+	var pl pendingLocations
+	pl.Request(1, "en", now.Add(-time.Hour))
+	pl.Request(1, "en", now)
+	if expired := pl.Expired(now); len(expired) != 0 {
+		t.Errorf("Expired() = %v after a repeated request, want the window restarted", expired)
+	}
+}
+
+func TestCronLocationPrompts(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		// reply is sent after /air, none if nil
+		reply  func(bot *Bot)
+		prompt bool
+	}{
+		{name: "no location", lang: "en", prompt: true},
+		{name: "no location in Russian", lang: "ru", prompt: true},
+		{name: "location shared", lang: "en", reply: func(bot *Bot) {
+			bot.handleMessage(newTestLocationMessage(1, &Location{53.9, 27.56}))
+		}},
+		{name: "another command", lang: "en", reply: func(bot *Bot) {
+			bot.handleMessage(newTestCommand(1, "/subsriptions"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, newTestStore(t), &botServices{wAPI: &fakeAQI{aqi: 2}}, fake)
+			air := newTestCommand(1, "/air")
+			air.From.LanguageCode = tt.lang
+			bot.handleMessage(air)
+			if tt.reply != nil {
+				tt.reply(bot)
+			}
+
+			sent := len(fake.sent("sendMessage"))
+			bot.CronLocationPrompts()
+			if n := len(fake.sent("sendMessage")) - sent; n != 0 {
+				t.Fatalf("%d prompt(s) sent within the window, want 0", n)
+			}
+			// the window has passed
+			bot.pendingLocations.mu.Lock()
+			for chatID, req := range bot.pendingLocations.pending {
+				req.requestedAt = req.requestedAt.Add(-locationPromptWindow)
+				bot.pendingLocations.pending[chatID] = req
+			}
+			bot.pendingLocations.mu.Unlock()
+			bot.CronLocationPrompts()
+			bot.CronLocationPrompts()
+
+			prompts := fake.sent("sendMessage")[sent:]
+			if !tt.prompt {
+				if len(prompts) != 0 {
+					t.Errorf("prompts sent %v, want none", prompts)
+				}
+				return
+			}
+			want := message.NewPrinter(language.Make(tt.lang)).Sprintf(locationPromptText)
+			if len(prompts) != 1 || prompts[0].Get("chat_id") != "1" || prompts[0].Get("text") != want {
+				t.Errorf("prompts sent %v, want one %q", prompts, want)
+			}
+		})
+	}
+
+	if ru := message.NewPrinter(language.Russian).Sprintf(locationPromptText); ru == locationPromptText {
+		t.Errorf("%q is not translated into Russian", locationPromptText)
+	}
+}
+
+func TestAirCoordinates(t *testing.T) {
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, newTestStore(t), &botServices{wAPI: &fakeAQI{aqi: 2}}, fake)
+
+	bot.handleMessage(newTestCommand(1, "/air 53.9 27.56"))
+
+	if len(bot.pendingLocations.pending) != 0 {
+		t.Errorf("pending locations = %v, want none for /air with the coordinates", bot.pendingLocations.pending)
+	}
+	if got := fake.lastText(); got == message.NewPrinter(language.English).Sprintf("Share location!") {
+		t.Errorf("/air 53.9 27.56 = %q, want the AQI of the coordinates", got)
+	}
+}
@@ -1,36 +1,184 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/robfig/cron"
 )
 
-var dFlag = flag.Bool("debug", false, "increase verbosity")
+var (
+	dFlag      = flag.Bool("debug", false, "increase verbosity")
+	configFlag = flag.String("config", "", "path to a config file of key: value lines")
+	dbPathFlag = flag.String("db-path", "", "path to the SQLite DB file")
 
-func getEnvVarOrPanic(key string) string {
-	v := os.Getenv(key)
-	if v == "" {
-		log.Panic("env variable not found ", key)
+	exportSubsFlag = flag.String("export-subscriptions", "", "write the enabled subscriptions to the JSON file and exit")
+	importSubsFlag = flag.String("import-subscriptions", "", "add the subscriptions from the JSON file and exit")
+	cronOnceFlag   = flag.Bool("run-cron-once", false, "check the subscriptions once and exit, for external schedulers")
+)
+
+// applyFlags overrides the Config with the flags set in the command line
+func applyFlags(c *Config) {
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "debug":
+			c.Debug = *dFlag
+		case "db-path":
+			c.DBPath = *dbPathFlag
+		}
+	})
+}
+
+// migrateSubscriptions exports the subscriptions of the DB to the exportPath and imports them from the importPath, if set
+func migrateSubscriptions(dbPath, exportPath, importPath string) error {
+	store, err := OpenStore(dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.DB.Close()
+
+	if exportPath != "" {
+		data, err := store.ExportSubscriptions()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(exportPath, data, 0o600); err != nil {
+			return fmt.Errorf("writing subscriptions: %w", err)
+		}
+	}
+	if importPath != "" {
+		data, err := os.ReadFile(importPath)
+		if err != nil {
+			return fmt.Errorf("reading subscriptions: %w", err)
+		}
+		return store.ImportSubscriptions(data)
+	}
+	return nil
+}
+
+// newBots returns the bot of the options followed by the runners of the other tokens.
+// The runners share the OWM API client and the DB of the first bot.
+func newBots(opts BotOptions, tokens []string) ([]*Bot, func(), error) {
+	bot, cancel, err := NewBotWithOptions(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	bots := []*Bot{bot}
+	for _, token := range tokens {
+		runner, err := bot.NewRunner(token)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		bots = append(bots, runner)
+	}
+	return bots, cancel, nil
+}
+
+// runCronOnce checks the subscriptions of the bots once, without the scheduler and the long polling
+func runCronOnce(bots []*Bot) {
+	for _, b := range bots {
+		b.Cron()
 	}
-	return v
 }
 
 func main() {
 	flag.Parse()
 
-	botApiToken := getEnvVarOrPanic("TELEGRAM_API_TOKEN")
-	owmApiToken := getEnvVarOrPanic("OWM_API_TOKEN")
-
-	bot, cancel := NewBot(botApiToken, owmApiToken, *dFlag)
+	config, err := LoadConfig(*configFlag, os.Getenv)
+	if err != nil {
+		log.Fatal("loading config: ", err)
+	}
+	applyFlags(config)
+	if *exportSubsFlag != "" || *importSubsFlag != "" {
+		if err := migrateSubscriptions(config.DBPath, *exportSubsFlag, *importSubsFlag); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatal("invalid config: ", err)
+	}
+	if config.TranslationsDir != "" {
+		if err := loadTranslations(config.TranslationsDir); err != nil {
+			log.Fatal("loading translations: ", err)
+		}
+	}
+	// the languages are matched with the loaded translations
+	useLanguages(config.SupportedLanguages)
+	if config.DefaultLanguage != "" {
+		if err := setDefaultLanguage(config.DefaultLanguage); err != nil {
+			log.Fatal(err)
+		}
+	}
 
+	bots, cancel, err := newBots(config.BotOptions(), config.TelegramAPITokens)
+	if err != nil {
+		log.Fatal(err)
+	}
 	defer cancel()
+	bot := bots[0]
+	if *cronOnceFlag {
+		runCronOnce(bots)
+		return
+	}
+
 	c := cron.New()
-	c.AddFunc("@every 30m", bot.Cron)
-	c.AddFunc("@every 12h", bot.CronCleanup)
+	// the cleanup and the maintenance of the DB are run once for all the bots
+	c.AddFunc(fmt.Sprintf("@every %v", config.CleanupInterval), bot.CronCleanup)
+	c.AddFunc("@weekly", bot.CronMaintenance)
+	for _, b := range bots {
+		c.AddFunc(fmt.Sprintf("@every %v", config.CronInterval), b.Cron)
+		c.AddFunc("@weekly", b.CronDigest)
+		c.AddFunc("0 0 7 * * *", b.CronDailyPeaks)
+		c.AddFunc("@every 30s", b.CronLocationPrompts)
+		if config.KeepAliveInterval > 0 && config.BotMode == "polling" {
+			c.AddFunc(fmt.Sprintf("@every %v", config.KeepAliveInterval), b.KeepAlive)
+		}
+	}
 	c.Start()
 
-	bot.Run()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		log.Printf("received %v, shutting down", <-sig)
+		for _, b := range bots {
+			b.Stop()
+		}
+	}()
+
+	// the service endpoints are served in both modes, apart from the public webhook
+	var srv *http.Server
+	if config.HTTPAddr != "" {
+		srv = &http.Server{Addr: config.HTTPAddr, Handler: bot.HTTPHandler()}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal("HTTP server: ", err)
+			}
+		}()
+	}
+	switch config.BotMode {
+	case "polling":
+		RunAll(bots)
+	case "webhook":
+		if err := bot.RunWebhook(config.WebhookAddr, config.WebhookURL, config.WebhookSecret); err != nil {
+			log.Fatal("RunWebhook: ", err)
+		}
+	}
+
+	c.Stop()
+	if srv != nil {
+		ctx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdown()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Print("HTTP server shutdown: ", err)
+		}
+	}
 }
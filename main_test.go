@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunCronOnce(t *testing.T) {
+	fake := &fakeTelegram{}
+	opts := BotOptions{
+		TelegramAPIToken:   "1:first",
+		OWMApiToken:        "owm",
+		DBPath:             filepath.Join(t.TempDir(), "bot.db"),
+		HTTPClient:         fakeOWM(4),
+		TelegramHTTPClient: fake,
+	}
+	bots, cancel, err := newBots(opts, []string{"2:second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	if len(bots) != 2 {
+		t.Fatalf("newBots() = %d bots, want 2", len(bots))
+	}
+	for i, b := range bots {
+		addTestSubscription(t, b.store, int64(i+1), &Location{53.9, 27.56}, 1)
+	}
+
+	runCronOnce(bots)
+
+	notified := map[string]int{}
+	for _, r := range fake.requests {
+		switch r.method {
+		case "getUpdates":
+			t.Errorf("%s polled the updates", r.token)
+		case "sendMessage":
+			notified[r.token]++
+		}
+	}
+	for _, token := range []string{"1:first", "2:second"} {
+		if notified[token] != 1 {
+			t.Errorf("%s sent %d notification(s), want 1", token, notified[token])
+		}
+	}
+}
+
+func TestNewBotsInvalidToken(t *testing.T) {
+	fake := &fakeTelegram{invalidTokens: map[string]bool{"2:invalid": true}}
+	opts := BotOptions{
+		TelegramAPIToken:   "1:first",
+		OWMApiToken:        "owm",
+		DBPath:             filepath.Join(t.TempDir(), "bot.db"),
+		HTTPClient:         fakeOWM(1),
+		TelegramHTTPClient: fake,
+	}
+	if _, _, err := newBots(opts, []string{"2:invalid"}); !errors.Is(err, ErrInvalidTelegramToken) {
+		t.Errorf("newBots() error = %v, want %v", err, ErrInvalidTelegramToken)
+	}
+}
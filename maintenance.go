@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	maintenanceText = "The bot is under maintenance. Please try again later"
+
+	maintenanceOnMsg    = "Maintenance mode is on: users get the maintenance reply, no notifications are sent"
+	maintenanceOffMsg   = "Maintenance mode is off"
+	maintenanceUsageMsg = "Usage: /maintenance on|off"
+)
+
+// inMaintenance reports whether the request of the user is answered with maintenanceText.
+// The admin is served to be able to turn the maintenance mode off.
+func (bot *Bot) inMaintenance(userID int64) bool {
+	return bot.maintenance.Load() && !bot.isAdmin(userID)
+}
+
+// replyMaintenance answers the message with maintenanceText
+func (bot *Bot) replyMaintenance(msg *tgbotapi.Message) {
+	p := newLangPrinter(msg.From.LanguageCode)
+	tgMsg := tgbotapi.NewMessage(msg.Chat.ID, p.Sprintf(maintenanceText))
+	tgMsg.ReplyToMessageID = msg.MessageID
+	bot.Send(tgMsg)
+}
+
+// maintenanceCommandText switches the maintenance mode by the /maintenance argument for the admin.
+// The mode is stored in the DB to survive restarts.
+func (bot *Bot) maintenanceCommandText(arg string) string {
+	var on bool
+	switch strings.TrimSpace(arg) {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return maintenanceUsageMsg
+	}
+	if err := bot.store.SetMaintenance(on); err != nil {
+		log.Print("SetMaintenance: ", err)
+		return "Failed to switch the maintenance mode: " + err.Error()
+	}
+	bot.maintenance.Store(on)
+	log.Print("maintenance mode: ", arg)
+	if on {
+		return maintenanceOnMsg
+	}
+	return maintenanceOffMsg
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const testAdminID = 42
+
+func TestMaintenanceMode(t *testing.T) {
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 1)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 4}, adminID: testAdminID}, fake)
+
+	bot.handleMessage(newTestCommand(testAdminID, "/maintenance on"))
+	if got := fake.lastText(); got != maintenanceOnMsg {
+		t.Fatalf("/maintenance on = %q, want %q", got, maintenanceOnMsg)
+	}
+	if on, err := store.Maintenance(); err != nil || !on {
+		t.Errorf("Maintenance() = %v, %v, want it stored on", on, err)
+	}
+
+	ru := newTestCommand(1, "/subsriptions")
+	ru.From.LanguageCode = "ru"
+	tests := []struct {
+		name string
+		send func()
+		want string
+	}{
+		{name: "command", send: func() { bot.handleMessage(newTestCommand(1, "/start")) }, want: maintenanceText},
+		{name: "location", send: func() { bot.handleMessage(newTestLocationMessage(1, &Location{53.9, 27.56})) }, want: maintenanceText},
+		{name: "localized", send: func() { bot.handleMessage(ru) }, want: message.NewPrinter(language.Russian).Sprintf(maintenanceText)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := countRows(t, store, "SELECT total_changes()")
+			tt.send()
+			if got := fake.lastText(); got != tt.want {
+				t.Errorf("reply = %q, want %q", got, tt.want)
+			}
+			if n := countRows(t, store, "SELECT total_changes()") - changes; n != 0 {
+				t.Errorf("%d DB rows changed in maintenance, want 0", n)
+			}
+		})
+	}
+	if ru := message.NewPrinter(language.Russian).Sprintf(maintenanceText); ru == maintenanceText {
+		t.Errorf("%q is not translated into Russian", maintenanceText)
+	}
+
+	// the callbacks get the maintenance toast
+	bot.handleCallbackQuery(newTestCallbackQuery(t, 1, "en", callbackDetails))
+	answers := fake.sent("answerCallbackQuery")
+	if len(answers) != 1 || answers[0].Get("text") != maintenanceText {
+		t.Errorf("callback answers = %v, want the maintenance toast", answers)
+	}
+
+	// the admin is still served
+	bot.handleMessage(newTestCommand(testAdminID, "/start"))
+	if got := fake.lastText(); got == maintenanceText {
+		t.Error("the admin got the maintenance reply")
+	}
+
+	sent := len(fake.sent("sendMessage"))
+	bot.Cron()
+	if n := len(fake.sent("sendMessage")) - sent; n != 0 {
+		t.Errorf("Cron sent %d notification(s) in maintenance, want 0", n)
+	}
+
+	bot.handleMessage(newTestCommand(testAdminID, "/maintenance off"))
+	if got := fake.lastText(); got != maintenanceOffMsg {
+		t.Fatalf("/maintenance off = %q, want %q", got, maintenanceOffMsg)
+	}
+	bot.handleMessage(newTestCommand(1, "/start"))
+	if got := fake.lastText(); got == maintenanceText {
+		t.Error("maintenance reply after /maintenance off")
+	}
+	sent = len(fake.sent("sendMessage"))
+	bot.Cron()
+	if n := len(fake.sent("sendMessage")) - sent; n != 1 {
+		t.Errorf("Cron sent %d notification(s) after /maintenance off, want 1", n)
+	}
+}
+
+func TestMaintenanceCommand(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	tests := []struct {
+		name   string
+		userID int64
+		text   string
+		want   string
+		on     bool
+	}{
+		{name: "on", userID: testAdminID, text: "/maintenance on", want: maintenanceOnMsg, on: true},
+		{name: "off", userID: testAdminID, text: "/maintenance off", want: maintenanceOffMsg},
+		{name: "usage", userID: testAdminID, text: "/maintenance", want: maintenanceUsageMsg},
+		{name: "not the admin", userID: 1, text: "/maintenance on", want: p.Sprintf(unknownCmdMsg)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{adminID: testAdminID}, fake)
+
+			bot.handleMessage(newTestCommand(tt.userID, tt.text))
+			if got := fake.lastText(); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.text, got, tt.want)
+			}
+			if on, err := store.Maintenance(); err != nil || on != tt.on {
+				t.Errorf("Maintenance() = %v, %v, want %v", on, err, tt.on)
+			}
+		})
+	}
+}
+
+func TestMaintenanceSurvivesRestart(t *testing.T) {
+	opts := BotOptions{
+		TelegramAPIToken:   "1:test",
+		OWMApiToken:        "owm",
+		DBPath:             filepath.Join(t.TempDir(), "bot.db"),
+		HTTPClient:         fakeOWM(1),
+		TelegramHTTPClient: &fakeTelegram{},
+	}
+	bot, cleanUp, err := NewBotWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.maintenanceCommandText("on")
+	cleanUp()
+
+	bot, cleanUp, err = NewBotWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp()
+	if !bot.maintenance.Load() {
+		t.Error("maintenance mode is off after the restart")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// outcomes of a subscription in a Cron run counted by cronOutcomes
+const (
+	outcomeSent                 = "sent"
+	outcomeSendFailed           = "send_failed"
+	outcomeUnchanged            = "unchanged"
+	outcomeSkippedFrequency     = "skipped_frequency"
+	outcomeSuppressedSnooze     = "suppressed_snooze"
+	outcomeSuppressedHysteresis = "suppressed_hysteresis"
+	outcomeSuppressedCooldown   = "suppressed_cooldown"
+	outcomeDigest               = "digest"
+	outcomeFetchFailed          = "fetch_failed"
+	outcomeStoreFailed          = "store_failed"
+)
+
+// cronOutcomes counts the subscription outcomes of all Cron runs. It is served on /metrics.
+var cronOutcomes = expvar.NewMap("cron_outcomes")
+
+// dataPointsCompacted counts the DataPoints not stored by AddChangedDataPoints as unchanged
+var dataPointsCompacted = expvar.NewInt("data_points_compacted")
+
+// metricsVars are the expvar vars of the bot served on /metrics. cmdline and memstats published by expvar itself
+// aren't served, they expose the command line flags and the process internals.
+var metricsVars = []string{
+	"cron_outcomes",
+	"data_points_compacted",
+	"keepalive_pings",
+	"owm_consecutive_failures",
+	"owm_failure_alerts",
+	"start_referrals",
+}
+
+// handleMetrics serves metricsVars as a JSON object like expvar.Handler
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprint(w, "{\n")
+	for i, name := range metricsVars {
+		if i > 0 {
+			fmt.Fprint(w, ",\n")
+		}
+		fmt.Fprintf(w, "%q: %s", name, expvar.Get(name))
+	}
+	fmt.Fprint(w, "\n}\n")
+}
+
+// cronSummary counts the subscription outcomes of a Cron run
+type cronSummary map[string]int64
+
+func (s cronSummary) add(outcome string) {
+	s[outcome]++
+}
+
+// publish adds the counts to cronOutcomes and logs them
+func (s cronSummary) publish() {
+	outcomes := make([]string, 0, len(s))
+	for outcome := range s {
+		outcomes = append(outcomes, outcome)
+	}
+	sort.Strings(outcomes)
+
+	parts := make([]string, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		cronOutcomes.Add(outcome, s[outcome])
+		parts = append(parts, fmt.Sprintf("%s=%d", outcome, s[outcome]))
+	}
+	log.Print("Cron: ", strings.Join(parts, " "))
+}
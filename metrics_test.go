@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// cronOutcomeCounts returns the values of cronOutcomes
+func cronOutcomeCounts() map[string]int64 {
+	counts := map[string]int64{}
+	cronOutcomes.Do(func(kv expvar.KeyValue) {
+		counts[kv.Key] = kv.Value.(*expvar.Int).Value()
+	})
+	return counts
+}
+
+func TestHandleMetrics(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("GET /metrics = %s: %v", rec.Body, err)
+	}
+	// every var of the bot is served, the ones of expvar itself aren't
+	expvar.Do(func(kv expvar.KeyValue) {
+		_, ok := got[kv.Key]
+		if private := kv.Key == "cmdline" || kv.Key == "memstats"; ok == private {
+			t.Errorf("GET /metrics serves %q = %v", kv.Key, ok)
+		}
+	})
+	if len(got) != len(metricsVars) {
+		t.Errorf("GET /metrics = %d vars, want %d", len(got), len(metricsVars))
+	}
+}
+
+func TestCronOutcomes(t *testing.T) {
+	minsk := &Location{53.9, 27.56}
+	tests := []struct {
+		name string
+		// aqi is the AQI fetched for the subscription to AQI 2
+		aqi AirQualityIndex
+		// setup prepares the subscription of the chat 1 and the services
+		setup func(t *testing.T, store *Store, services *botServices, aqi *fakeAQI, fake *fakeTelegram)
+		want  string
+	}{
+		{name: "sent", aqi: 4, want: outcomeSent},
+		{name: "unchanged", aqi: 2, want: outcomeUnchanged},
+		{name: "send failed", aqi: 4, want: outcomeSendFailed,
+			setup: func(t *testing.T, store *Store, services *botServices, aqi *fakeAQI, fake *fakeTelegram) {
+				fake.fail = map[string]bool{"sendMessage": true}
+			}},
+		{name: "fetch failed", aqi: 4, want: outcomeFetchFailed,
+			setup: func(t *testing.T, store *Store, services *botServices, aqi *fakeAQI, fake *fakeTelegram) {
+				aqi.err = errors.New("owm is down")
+			}},
+		{name: "store failed", aqi: 4, want: outcomeStoreFailed,
+			setup: func(t *testing.T, store *Store, services *botServices, aqi *fakeAQI, fake *fakeTelegram) {
+				if _, err := store.DB.Exec(`CREATE TRIGGER fail_insert BEFORE INSERT ON data_point
+					BEGIN SELECT RAISE(ABORT, 'injected failure'); END`); err != nil {
+					t.Fatal(err)
+				}
+			}},
+		{name: "snoozed", aqi: 4, want: outcomeSuppressedSnooze,
+			setup: func(t *testing.T, store *Store, services *botServices, aqi *fakeAQI, fake *fakeTelegram) {
+				if err := store.SnoozeSubscriptions(1, time.Now().Add(time.Hour)); err != nil {
+					t.Fatal(err)
+				}
+			}},
+		{name: "checked recently", aqi: 4, want: outcomeSkippedFrequency,
+			setup: func(t *testing.T, store *Store, services *botServices, aqi *fakeAQI, fake *fakeTelegram) {
+				if err := store.SetSubscriptionsFrequency(1, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.MarkSubscriptionChecked(onlySubscription(t, store, 1).ID, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+			}},
+		{name: "hysteresis", aqi: 3, want: outcomeSuppressedHysteresis,
+			setup: func(t *testing.T, store *Store, services *botServices, aqi *fakeAQI, fake *fakeTelegram) {
+				services.hysteresisMinDelta = 2
+			}},
+		{name: "cooldown", aqi: 4, want: outcomeSuppressedCooldown,
+			setup: func(t *testing.T, store *Store, services *botServices, aqi *fakeAQI, fake *fakeTelegram) {
+				services.notifyCooldown = time.Hour
+				if err := store.MarkSubscriptionNotified(onlySubscription(t, store, 1).ID, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+			}},
+		{name: "digest", aqi: 4, want: outcomeDigest,
+			setup: func(t *testing.T, store *Store, services *botServices, aqi *fakeAQI, fake *fakeTelegram) {
+				if err := store.SetSubscriptionsMode(1, SubscriptionModeDigest); err != nil {
+					t.Fatal(err)
+				}
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, minsk, 2)
+			aqi := &fakeAQI{aqi: tt.aqi}
+			fake := &fakeTelegram{}
+			services := &botServices{wAPI: aqi, cronConcurrency: 2}
+			if tt.setup != nil {
+				tt.setup(t, store, services, aqi, fake)
+			}
+			bot := newFakeTelegramBot(t, store, services, fake)
+
+			before := cronOutcomeCounts()
+			bot.Cron()
+			after := cronOutcomeCounts()
+
+			for outcome, n := range after {
+				want := int64(0)
+				if outcome == tt.want {
+					want = 1
+				}
+				if got := n - before[outcome]; got != want {
+					t.Errorf("%s counted %d time(s), want %d", outcome, got, want)
+				}
+			}
+			if _, ok := after[tt.want]; !ok {
+				t.Errorf("%s is not counted", tt.want)
+			}
+		})
+	}
+}
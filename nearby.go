@@ -0,0 +1,91 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	// nearbyDistance is the distance in meters to the points checked by /nearby
+	nearbyDistance = 5000
+
+	nearbyHeaderTmpl   = "AQI within %d km of your location:"
+	nearbyHereText     = "📍 Here"
+	nearbyNorthText    = "⬆️ North"
+	nearbyEastText     = "➡️ East"
+	nearbySouthText    = "⬇️ South"
+	nearbyWestText     = "⬅️ West"
+	nearbyNoDataText   = "no data"
+	nearbyNoSessionMsg = "Share your location first: /airQualityIndex"
+)
+
+// nearbyPoint is a named Location checked by /nearby
+type nearbyPoint struct {
+	name     string
+	location *Location
+	aqi      AirQualityIndex
+	err      error
+}
+
+// nearbyText reports the AQI at the stored location of the chat and at the points nearbyDistance away in each direction
+func (bot *Bot) nearbyText(chatID int64, p *message.Printer) string {
+	us, err := bot.store.GetSessionByChatID(chatID)
+	if err != nil {
+		return p.Sprintf(nearbyNoSessionMsg)
+	}
+	center := us.Location()
+	points := []*nearbyPoint{
+		{name: p.Sprintf(nearbyHereText), location: center},
+		{name: p.Sprintf(nearbyNorthText), location: center.Destination(0, nearbyDistance)},
+		{name: p.Sprintf(nearbyEastText), location: center.Destination(90, nearbyDistance)},
+		{name: p.Sprintf(nearbySouthText), location: center.Destination(180, nearbyDistance)},
+		{name: p.Sprintf(nearbyWestText), location: center.Destination(270, nearbyDistance)},
+	}
+	bot.fetchAQI(points, bot.wAPI.GetAirPollution)
+
+	theme := bot.themeOf(chatID)
+	msgText := []string{p.Sprintf(nearbyHeaderTmpl, nearbyDistance/1000), ""}
+	for _, pt := range points {
+		value := p.Sprintf(nearbyNoDataText)
+		if pt.err == nil {
+			value = theme.LocalizedAQI(pt.aqi, p)
+		}
+		msgText = append(msgText, pt.name+": "+value)
+	}
+	return strings.Join(msgText, "\n")
+}
+
+// fetchAQI gets the current AQI for all the points by the get function with at most cronConcurrency concurrent
+// requests, like Cron. A failed point keeps its error.
+func (bot *Bot) fetchAQI(points []*nearbyPoint, get func(*Location) (*ApiPollutionResponse, error)) {
+	concurrency := bot.cronConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultCronConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, pt := range points {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(pt *nearbyPoint) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			resp, err := get(pt.location)
+			if err == nil && len(resp.DP) == 0 {
+				err = errNoDataPoints
+			}
+			if err != nil {
+				log.Print("GetAirPollution: ", err)
+				pt.err = err
+				return
+			}
+			pt.aqi = resp.DP[0].GetAQI()
+		}(pt)
+	}
+	wg.Wait()
+}
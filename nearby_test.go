@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestNearbyText(t *testing.T) {
+	center := &Location{53.9, 27.56}
+	// the AQI differs by the direction, the south fails
+	provider := aqiProviderFunc(func(l *Location) (*ApiPollutionResponse, error) {
+		switch {
+		case l.Latitude > center.Latitude+0.01:
+			return aqiResponse(l, 1), nil
+		case l.Latitude < center.Latitude-0.01:
+			return nil, errors.New("timeout")
+		case l.Longitude > center.Longitude+0.01:
+			return aqiResponse(l, 3), nil
+		case l.Longitude < center.Longitude-0.01:
+			return &ApiPollutionResponse{Location: *l}, nil
+		}
+		return aqiResponse(l, 2), nil
+	})
+	p := message.NewPrinter(language.English)
+
+	store := newTestStore(t)
+	addTestSession(t, store, 1, center)
+	bot := newTestBot(store, &botServices{wAPI: provider})
+
+	got := bot.nearbyText(1, p)
+	want := []string{
+		"📍 Here: " + ThemeEmoji.AQI(2),
+		"⬆️ North: " + ThemeEmoji.AQI(1),
+		"➡️ East: " + ThemeEmoji.AQI(3),
+		// a failed point and a point without DataPoints show what succeeded
+		"⬇️ South: no data",
+		"⬅️ West: no data",
+	}
+	for _, line := range want {
+		if !strings.Contains(got, line) {
+			t.Errorf("nearbyText() = %q, want line %q", got, line)
+		}
+	}
+
+	if got := bot.nearbyText(2, p); got != nearbyNoSessionMsg {
+		t.Errorf("nearbyText() without a location = %q, want %q", got, nearbyNoSessionMsg)
+	}
+}
+
+func TestFetchAQIConcurrency(t *testing.T) {
+	var (
+		mu                sync.Mutex
+		running, maxCalls int
+	)
+	get := func(l *Location) (*ApiPollutionResponse, error) {
+		mu.Lock()
+		running++
+		if running > maxCalls {
+			maxCalls = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return aqiResponse(l, 2), nil
+	}
+	bot := newTestBot(newTestStore(t), &botServices{cronConcurrency: 2})
+	var points []*nearbyPoint
+	for i := 0; i < 6; i++ {
+		points = append(points, &nearbyPoint{location: &Location{53.9, 27.56 + float64(i)}})
+	}
+
+	bot.fetchAQI(points, get)
+
+	if maxCalls > 2 {
+		t.Errorf("%d concurrent requests, want at most 2", maxCalls)
+	}
+	for i, pt := range points {
+		if pt.err != nil || pt.aqi != 2 {
+			t.Errorf("point %d = %v, %v, want AQI 2", i, pt.aqi, pt.err)
+		}
+	}
+}
@@ -2,22 +2,73 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	// OWMApiEndpoint is an base apiEndpoint
+	OWMApiEndpoint = "http://api.openweathermap.org/data/2.5/"
+	// OWMGeoEndpoint is the base endpoint of the geocoding API
+	OWMGeoEndpoint = "http://api.openweathermap.org/geo/1.0/"
+	// DefaultHTTPTimeout limits the time of a request to OWM API including reading the response
+	DefaultHTTPTimeout = 10 * time.Second
+	// maxResponseSize is the number of bytes of a response read from OWM API. The largest, the history
+	// of air pollution, has about 300 bytes per hour.
+	maxResponseSize = 4 << 20
+)
+
+// Version and BotName are set at build time, e.g. -ldflags "-X main.Version=1.2.0".
+// They identify the bot in the User-Agent of the requests to OWM API.
+var (
+	Version = "dev"
+	BotName = "airpollutionbot"
 )
 
-// OWMApiEndpoint is an base apiEndpoint
-const OWMApiEndpoint = "http://api.openweathermap.org/data/2.5/"
+// userAgent returns the User-Agent of the requests to OWM API
+func userAgent() string {
+	return fmt.Sprintf("%s/%s (+https://github.com/atsevan/airpollutionbot)", BotName, Version)
+}
+
+const (
+	unknownAQITmpl        = "Unknown (%d)"
+	unknownAQIEmoji       = "❔"
+	unknownAQILabel       = "Unknown"
+	unknownAQIHexColor    = "#808080"
+	unknownAQIDescription = "There is no information about the air quality."
+)
 
 var (
-	aqiDesc = map[AirQualityIndex]string{
-		1: "🟩 (Good)",
-		2: "🟨 (Fair)",
-		3: "🟧 (Moderate)",
-		4: "🟥 (Poor)",
-		5: "⬛ (Very Poor)",
+	aqiEmoji = map[AirQualityIndex]string{
+		1: "🟩",
+		2: "🟨",
+		3: "🟧",
+		4: "🟥",
+		5: "⬛",
+	}
+	aqiLabel = map[AirQualityIndex]string{
+		1: "Good",
+		2: "Fair",
+		3: "Moderate",
+		4: "Poor",
+		5: "Very Poor",
+	}
+	aqiHexColor = map[AirQualityIndex]string{
+		1: "#00E400",
+		2: "#FFFF00",
+		3: "#FF7E00",
+		4: "#FF0000",
+		5: "#7E0023",
 	}
 	aqiDescription = map[AirQualityIndex]string{
 		1: "No health implications.",
@@ -28,82 +79,361 @@ var (
 	}
 )
 
+// errNoDataPoints is returned if a response has no DataPoints
+var errNoDataPoints = errors.New("no data points in the response")
+
+// ErrInvalidOWMToken is returned if OWM API rejects the token
+var ErrInvalidOWMToken = errors.New("OWM_API_TOKEN is invalid or missing")
+
+// ErrResponseTooLarge is returned if a response of OWM API exceeds maxResponseSize
+var ErrResponseTooLarge = errors.New("OWM API response is too large")
+
+// ErrUnexpectedStatus is returned if OWM API responds with a status other than 2xx, e.g. 429 or 500
+var ErrUnexpectedStatus = errors.New("unexpected OWM API response status")
+
 // HTTPClient is the type needed for the bot to perform HTTP requests.
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// RawResponseRecorder stores the raw bodies of air pollution responses for debugging
+type RawResponseRecorder interface {
+	AddRawResponse(l *Location, body []byte, t time.Time) error
+}
+
 // OpenWheatherMapApi keeps the infromation for openweathermap.org API communication
 type OpenWheatherMapApi struct {
 	token       string
 	httpClient  HTTPClient
 	Debug       bool
 	apiEndpoint string
+	geoEndpoint string
+	// Tracer traces the requests. Tracing is disabled if nil.
+	Tracer Tracer
+	// RawCapture records the raw air pollution responses. Disabled if nil.
+	RawCapture RawResponseRecorder
+	// Failures monitors the air pollution requests failed in a row. Disabled if nil.
+	Failures *FailureMonitor
 }
 
-// NewOpenWheatherMapApi creates a new clinet for OpenWheatherMapApi
+// NewOpenWheatherMapApi creates a new clinet for OpenWheatherMapApi with DefaultHTTPTimeout
 func NewOpenWheatherMapApi(token string) (*OpenWheatherMapApi, error) {
-	return &OpenWheatherMapApi{token, &http.Client{}, false, OWMApiEndpoint}, nil
+	return NewOpenWheatherMapApiWithClient(token, &http.Client{Timeout: DefaultHTTPTimeout})
+}
+
+// NewOpenWheatherMapApiWithClient creates a new clinet for OpenWheatherMapApi performing requests by httpClient
+func NewOpenWheatherMapApiWithClient(token string, httpClient HTTPClient) (*OpenWheatherMapApi, error) {
+	if httpClient == nil {
+		return nil, errors.New("httpClient is nil")
+	}
+	return &OpenWheatherMapApi{token, httpClient, false, OWMApiEndpoint, OWMGeoEndpoint, noopTracer{}, nil, nil}, nil
 }
 
-func (owma *OpenWheatherMapApi) makeRequest(path string) ([]byte, error) {
-	url := fmt.Sprintf("%s/%s&appid=%s", owma.apiEndpoint, path, owma.token)
+// SetAPIEndpoint sets the base URL of the air pollution and weather requests like OWMApiEndpoint,
+// e.g. to use another version of OWM API
+func (owma *OpenWheatherMapApi) SetAPIEndpoint(endpoint string) error {
+	if _, err := url.Parse(endpoint); err != nil {
+		return fmt.Errorf("invalid OWM API endpoint: %w", err)
+	}
+	owma.apiEndpoint = endpoint
+	return nil
+}
+
+// makeRequest GETs the path relative to the endpoint with the query and the API token
+func (owma *OpenWheatherMapApi) makeRequest(endpoint, path string, query url.Values) (body []byte, err error) {
+	if owma.Tracer != nil {
+		span := owma.Tracer.Start("owm " + path)
+		defer func() {
+			span.SetError(err)
+			span.End()
+		}()
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return []byte{}, fmt.Errorf("invalid OWM API endpoint: %w", err)
+	}
+	u = u.JoinPath(path)
+	u.RawQuery = query.Encode()
 	if owma.Debug {
-		log.Printf("air_pollution url: %q", url)
+		log.Printf("%s url: %q", path, u)
 	}
-	req, err := http.NewRequest("GET", url, nil)
+	// the token is added after logging to keep it out of the logs
+	q := u.Query()
+	q.Set("appid", owma.token)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return []byte{}, err
 	}
+	req.Header.Set("User-Agent", userAgent())
 	resp, err := owma.httpClient.Do(req)
 	if err != nil {
 		return []byte{}, err
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return []byte{}, ErrInvalidOWMToken
+	}
+	// the error bodies like {"cod":429,"message":"..."} aren't responses of the path
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return []byte{}, fmt.Errorf("%s: %w %s", path, ErrUnexpectedStatus, resp.Status)
+	}
+	// a byte over the limit is read to tell a response of exactly maxResponseSize from a larger one
+	body, err = io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
 	if err != nil {
 		return []byte{}, err
 	}
+	if len(body) > maxResponseSize {
+		return []byte{}, fmt.Errorf("%s: %w", path, ErrResponseTooLarge)
+	}
 	return body, nil
 }
 
+// CheckToken requests the air pollution at 0;0 to find out whether OWM API accepts the token.
+// It returns ErrInvalidOWMToken if the token is empty or rejected.
+func (owma *OpenWheatherMapApi) CheckToken() error {
+	if owma.token == "" {
+		return ErrInvalidOWMToken
+	}
+	_, err := owma.makeRequest(owma.apiEndpoint, "air_pollution", (&Location{}).query())
+	return err
+}
+
 // GetAirPollution gets the current information about air pollution for the coordintes.
 // returns ApiPollutionResponse or Error
 func (owma *OpenWheatherMapApi) GetAirPollution(l *Location) (*ApiPollutionResponse, error) {
-	path := fmt.Sprintf("air_pollution?lat=%f&lon=%f", l.Latitude, l.Longitude)
-	data, err := owma.makeRequest(path)
+	return owma.getAirPollution(l, true)
+}
+
+// PreviewAirPollution gets the air pollution like GetAirPollution without recording the raw response by RawCapture
+func (owma *OpenWheatherMapApi) PreviewAirPollution(l *Location) (*ApiPollutionResponse, error) {
+	return owma.getAirPollution(l, false)
+}
+
+func (owma *OpenWheatherMapApi) getAirPollution(l *Location, capture bool) (_ *ApiPollutionResponse, err error) {
+	if owma.Failures != nil {
+		defer func() { owma.Failures.Record(err) }()
+	}
+	data, err := owma.makeRequest(owma.apiEndpoint, "air_pollution", l.query())
 	if err != nil {
 		return &ApiPollutionResponse{}, err
 	}
+	// the body is recorded before parsing to keep the invalid ones too
+	if capture && owma.RawCapture != nil {
+		if err := owma.RawCapture.AddRawResponse(l, data, time.Now()); err != nil {
+			log.Print("RawCapture: ", err)
+		}
+	}
 	var apiResp ApiPollutionResponse
 	err = json.Unmarshal(data, &apiResp)
 	if err != nil {
 		return &ApiPollutionResponse{}, err
 	}
+	if err := apiResp.Validate(); err != nil {
+		return &ApiPollutionResponse{}, fmt.Errorf("invalid air_pollution response: %w", err)
+	}
 	if owma.Debug {
 		log.Printf("air_pollution response: %v", &apiResp)
 	}
 	return &apiResp, nil
 }
 
+// GetCurrentWeather gets the current weather for the coordinates in metric units.
+// returns Weather or Error
+func (owma *OpenWheatherMapApi) GetCurrentWeather(l *Location) (*Weather, error) {
+	query := l.query()
+	query.Set("units", "metric")
+	data, err := owma.makeRequest(owma.apiEndpoint, "weather", query)
+	if err != nil {
+		return &Weather{}, err
+	}
+	var w Weather
+	if err := json.Unmarshal(data, &w); err != nil {
+		return &Weather{}, err
+	}
+	if err := w.Validate(); err != nil {
+		return &Weather{}, fmt.Errorf("invalid weather response: %w", err)
+	}
+	if owma.Debug {
+		log.Printf("weather response: %v", &w)
+	}
+	return &w, nil
+}
+
+// Geocode gets the most relevant Place for the query like "Minsk" or "London,GB".
+// returns errPlaceNotFound if there is none
+func (owma *OpenWheatherMapApi) Geocode(query string) (*Place, error) {
+	data, err := owma.makeRequest(owma.geoEndpoint, "direct", url.Values{"q": {query}, "limit": {"1"}})
+	if err != nil {
+		return &Place{}, err
+	}
+	var places []Place
+	if err := json.Unmarshal(data, &places); err != nil {
+		return &Place{}, err
+	}
+	if owma.Debug {
+		log.Printf("geocoding response: %v", places)
+	}
+	if len(places) == 0 {
+		return &Place{}, errPlaceNotFound
+	}
+	return &places[0], nil
+}
+
+// ReverseGeocode gets the Place at the coordinates.
+// returns errPlaceNotFound if there is none
+func (owma *OpenWheatherMapApi) ReverseGeocode(l *Location) (*Place, error) {
+	query := l.query()
+	query.Set("limit", "1")
+	data, err := owma.makeRequest(owma.geoEndpoint, "reverse", query)
+	if err != nil {
+		return &Place{}, err
+	}
+	var places []Place
+	if err := json.Unmarshal(data, &places); err != nil {
+		return &Place{}, err
+	}
+	if owma.Debug {
+		log.Printf("reverse geocoding response: %v", places)
+	}
+	if len(places) == 0 {
+		return &Place{}, errPlaceNotFound
+	}
+	return &places[0], nil
+}
+
+// errPlaceNotFound is returned if geocoding finds nothing
+var errPlaceNotFound = errors.New("place not found")
+
+// Place is a geocoding result
+// see https://openweathermap.org/api/geocoding-api#direct_name_fields
+type Place struct {
+	Name    string `json:"name"`
+	Country string `json:"country"`
+	State   string `json:"state"`
+	Location
+}
+
+// String returns the name of the Place with the state and the country if they are known
+func (pl *Place) String() string {
+	parts := []string{pl.Name}
+	for _, s := range []string{pl.State, pl.Country} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
 // Location keeps coordinates for the result
 type Location struct {
 	Latitude  float64 `json:"lat"`
 	Longitude float64 `json:"lon"`
 }
 
+// query returns the lat and lon query parameters of OWM API with 6 decimals, about 0.1 m
+func (l *Location) query() url.Values {
+	return url.Values{
+		"lat": {strconv.FormatFloat(l.Latitude, 'f', 6, 64)},
+		"lon": {strconv.FormatFloat(l.Longitude, 'f', 6, 64)},
+	}
+}
+
+// InRange reports whether the latitude is from -90 to 90 and the longitude is from -180 to 180.
+// NaN coordinates, e.g. parsed from untrusted input, are out of range.
+func (l *Location) InRange() bool {
+	return l.Latitude >= -90 && l.Latitude <= 90 && l.Longitude >= -180 && l.Longitude <= 180
+}
+
+// Round returns the Location with coordinates rounded to the number of decimals.
+// 2 decimals are about 1 km, which keeps AQI essentially the same.
+func (l *Location) Round(decimals int) *Location {
+	scale := math.Pow(10, float64(decimals))
+	return &Location{math.Round(l.Latitude*scale) / scale, math.Round(l.Longitude*scale) / scale}
+}
+
+// earthRadius is the mean radius of the Earth in meters
+const earthRadius = 6371008.8
+
+// DistanceTo returns the great-circle distance to the other Location in meters using the haversine formula
+func (l *Location) DistanceTo(other *Location) float64 {
+	lat1, lat2 := l.Latitude*math.Pi/180, other.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (other.Longitude - l.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Destination returns the Location at distance meters from l in the direction of bearing degrees clockwise from north
+func (l *Location) Destination(bearing, distance float64) *Location {
+	lat1, lon1 := l.Latitude*math.Pi/180, l.Longitude*math.Pi/180
+	b := bearing * math.Pi / 180
+	d := distance / earthRadius
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+	lon2 := lon1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return &Location{lat2 * 180 / math.Pi, math.Remainder(lon2*180/math.Pi, 360)}
+}
+
 // AirQualityIndex is the current level of Air Quality
 type AirQualityIndex int
 
+// String returns the Air Quality Index level in ThemeEmoji
 func (aqi AirQualityIndex) String() string {
-	return aqiDesc[aqi]
+	return ThemeEmoji.AQI(aqi)
+}
+
+// Valid reports whether the Air Quality Index is one of the known levels 1-5
+func (aqi AirQualityIndex) Valid() bool {
+	_, ok := aqiLabel[aqi]
+	return ok
+}
+
+// Emoji returns a colored square of the Air Quality Index level
+func (aqi AirQualityIndex) Emoji() string {
+	if !aqi.Valid() {
+		return unknownAQIEmoji
+	}
+	return aqiEmoji[aqi]
+}
+
+// Label returns a short name of the Air Quality Index level
+func (aqi AirQualityIndex) Label() string {
+	if !aqi.Valid() {
+		return unknownAQILabel
+	}
+	return aqiLabel[aqi]
+}
+
+// HexColor returns the color of the Air Quality Index level as #RRGGBB
+func (aqi AirQualityIndex) HexColor() string {
+	if !aqi.Valid() {
+		return unknownAQIHexColor
+	}
+	return aqiHexColor[aqi]
 }
 
 // Description returns a longer description of the Air Quality Index level
 func (aqi AirQualityIndex) Description() string {
+	if !aqi.Valid() {
+		log.Printf("unknown AQI value %d", aqi)
+		return unknownAQIDescription
+	}
 	return aqiDescription[aqi]
 }
 
+// LocalizedString returns the Air Quality Index level in ThemeEmoji translated by the printer
+func (aqi AirQualityIndex) LocalizedString(p *message.Printer) string {
+	return ThemeEmoji.LocalizedAQI(aqi, p)
+}
+
+// LocalizedDescription returns the description of the Air Quality Index level translated by the printer
+func (aqi AirQualityIndex) LocalizedDescription(p *message.Printer) string {
+	return p.Sprintf(aqi.Description())
+}
+
 // DataPoint keeps the AirPollutionIndex measurement
 type DataPoint struct {
 	Dt   int64 `json:"dt"`
@@ -113,6 +443,20 @@ type DataPoint struct {
 	Components map[string]float64 `json:"components"` // Components keeps concentration of each component in μg/m3
 }
 
+// Validate checks the DataPoint has a known AQI, the time and the components
+func (dp *DataPoint) Validate() error {
+	if !dp.Main.Aqi.Valid() {
+		return fmt.Errorf("unknown AQI %d", dp.Main.Aqi)
+	}
+	if dp.Dt <= 0 {
+		return errors.New("no time of the data point")
+	}
+	if len(dp.Components) == 0 {
+		return errors.New("no components of the data point")
+	}
+	return nil
+}
+
 // GetAQI returns the AirQualityIndex for the DataPoint
 func (dp *DataPoint) GetAQI() AirQualityIndex {
 	return dp.Main.Aqi
@@ -123,4 +467,85 @@ func (dp *DataPoint) GetAQI() AirQualityIndex {
 type ApiPollutionResponse struct {
 	Location Location    `json:"coord"`
 	DP       []DataPoint `json:"list"`
+	// hasCoord is set by UnmarshalJSON if the coordinates are present, missing ones would read as 0;0
+	hasCoord bool
+}
+
+// UnmarshalJSON decodes the response noting whether it has the coordinates
+func (r *ApiPollutionResponse) UnmarshalJSON(data []byte) error {
+	// response has the fields of ApiPollutionResponse without UnmarshalJSON to decode them by default
+	type response ApiPollutionResponse
+	var v struct {
+		response
+		Coord *Location `json:"coord"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = ApiPollutionResponse(v.response)
+	if v.Coord != nil {
+		r.Location, r.hasCoord = *v.Coord, true
+	}
+	return nil
+}
+
+// Validate checks the coordinates are present and in range and all the DataPoints are valid.
+// A response without DataPoints is invalid.
+func (r *ApiPollutionResponse) Validate() error {
+	if !r.hasCoord {
+		return errors.New("no coordinates")
+	}
+	if !r.Location.InRange() {
+		return fmt.Errorf("coordinates %f;%f are out of range", r.Location.Latitude, r.Location.Longitude)
+	}
+	if len(r.DP) == 0 {
+		return errNoDataPoints
+	}
+	for i := range r.DP {
+		if err := r.DP[i].Validate(); err != nil {
+			return fmt.Errorf("data point %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Weather keeps the current weather conditions affecting air pollution
+// see https://openweathermap.org/current#fields_json
+type Weather struct {
+	Main struct {
+		Temp     float64 `json:"temp"`     // Temp is the temperature in Celsius
+		Humidity float64 `json:"humidity"` // Humidity is in %
+	} `json:"main"`
+	Wind struct {
+		Speed float64 `json:"speed"` // Speed is in meter/sec
+	} `json:"wind"`
+	// hasMain is set by UnmarshalJSON if the main block is present, a missing one would read as 0°C
+	hasMain bool
+}
+
+// UnmarshalJSON decodes the Weather noting whether it has the main block
+func (w *Weather) UnmarshalJSON(data []byte) error {
+	// weather has the fields of Weather without UnmarshalJSON to decode them by default
+	type weather Weather
+	var v struct {
+		weather
+		Main json.RawMessage `json:"main"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*w = Weather(v.weather)
+	if len(v.Main) == 0 || string(v.Main) == "null" {
+		return nil
+	}
+	w.hasMain = true
+	return json.Unmarshal(v.Main, &w.Main)
+}
+
+// Validate checks the Weather has the main block with the temperature and the humidity
+func (w *Weather) Validate() error {
+	if !w.hasMain {
+		return errors.New("no main block")
+	}
+	return nil
 }
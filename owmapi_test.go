@@ -0,0 +1,561 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestLocalizedAQI(t *testing.T) {
+	en, ru := message.NewPrinter(language.English), message.NewPrinter(language.Russian)
+	tests := []struct {
+		name string
+		text func(aqi AirQualityIndex, p *message.Printer) string
+	}{
+		{"LocalizedString", AirQualityIndex.LocalizedString},
+		{"LocalizedDescription", AirQualityIndex.LocalizedDescription},
+		{"ThemePlain", func(aqi AirQualityIndex, p *message.Printer) string { return ThemePlain.LocalizedAQI(aqi, p) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for aqi := AirQualityIndex(1); aqi <= 5; aqi++ {
+				if got := tt.text(aqi, ru); got == tt.text(aqi, en) {
+					t.Errorf("%d in Russian = %q, the same as in English", aqi, got)
+				}
+			}
+		})
+	}
+}
+
+func TestAQIDescriptionIsEnglish(t *testing.T) {
+	en := message.NewPrinter(language.English)
+	for aqi := AirQualityIndex(1); aqi <= 5; aqi++ {
+		if got, want := aqi.LocalizedDescription(en), aqi.Description(); got != want {
+			t.Errorf("LocalizedDescription(%d) in English = %q, want %q", aqi, got, want)
+		}
+	}
+}
+
+// newTestOWM returns an OpenWheatherMapApi of the respond of the HTTP status and the body of the requests
+func newTestOWM(t *testing.T, respond func(r *http.Request) (int, string)) *OpenWheatherMapApi {
+	t.Helper()
+	owma, err := NewOpenWheatherMapApiWithClient("owm", httpClientFunc(func(r *http.Request) (*http.Response, error) {
+		status, body := respond(r)
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return owma
+}
+
+func TestGetCurrentWeather(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantTemp  float64
+		wantHum   float64
+		wantSpeed float64
+		wantErr   error
+	}{
+		{
+			name:   "weather",
+			status: http.StatusOK,
+			body: `{"coord":{"lon":27.56,"lat":53.9},"weather":[{"id":800,"main":"Clear"}],` +
+				`"main":{"temp":21.5,"feels_like":21,"humidity":40},"wind":{"speed":3.6,"deg":200},"name":"Minsk"}`,
+			wantTemp: 21.5, wantHum: 40, wantSpeed: 3.6,
+		},
+		{name: "no wind", status: http.StatusOK, body: `{"main":{"temp":-3,"humidity":80}}`, wantTemp: -3, wantHum: 80},
+		{name: "missing fields", status: http.StatusOK, body: `{}`, wantErr: errors.New("invalid weather response: no main block")},
+		{name: "null main", status: http.StatusOK, body: `{"main":null,"wind":{"speed":1}}`, wantErr: errors.New("invalid weather response: no main block")},
+		{name: "malformed", status: http.StatusOK, body: `{"main":`, wantErr: errors.New("unexpected end of JSON input")},
+		{name: "invalid token", status: http.StatusUnauthorized, body: `{"cod":401}`, wantErr: ErrInvalidOWMToken},
+		// the error bodies are valid JSON without the weather
+		{name: "rate limited", status: http.StatusTooManyRequests, body: `{"cod":429,"message":"limit exceeded"}`, wantErr: ErrUnexpectedStatus},
+		{name: "server error", status: http.StatusInternalServerError, body: `{"cod":500,"message":"Internal error"}`, wantErr: ErrUnexpectedStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			owma := newTestOWM(t, func(r *http.Request) (int, string) {
+				if !strings.HasSuffix(r.URL.Path, "/weather") {
+					t.Errorf("request path = %s, want the weather endpoint", r.URL.Path)
+				}
+				query = r.URL.Query()
+				return tt.status, tt.body
+			})
+
+			w, err := owma.GetCurrentWeather(&Location{53.9, 27.56})
+			if tt.wantErr != nil {
+				if err == nil || !errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error() {
+					t.Fatalf("GetCurrentWeather() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Main.Temp != tt.wantTemp || w.Main.Humidity != tt.wantHum || w.Wind.Speed != tt.wantSpeed {
+				t.Errorf("GetCurrentWeather() = %+v, want temp %v, humidity %v, wind %v", w, tt.wantTemp, tt.wantHum, tt.wantSpeed)
+			}
+			if query.Get("units") != "metric" || query.Get("lat") == "" || query.Get("lon") == "" {
+				t.Errorf("query = %v, want metric units at the coordinates", query)
+			}
+		})
+	}
+}
+
+// roundTripperFunc is an http.RoundTripper of a function
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestHTTPClientTimeout(t *testing.T) {
+	owma, err := NewOpenWheatherMapApi("owm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := owma.httpClient.(*http.Client); !ok || c.Timeout != DefaultHTTPTimeout {
+		t.Errorf("default client = %#v, want the timeout of %v", owma.httpClient, DefaultHTTPTimeout)
+	}
+	if _, err := NewOpenWheatherMapApiWithClient("owm", nil); err == nil {
+		t.Error("NewOpenWheatherMapApiWithClient(nil) = nil error, want error")
+	}
+
+	// the transport stalls until the request is canceled by the timeout of the client
+	stalled := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		select {
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		case <-time.After(10 * time.Second):
+			return nil, errors.New("the request is not canceled")
+		}
+	})
+	owma, err = NewOpenWheatherMapApiWithClient("owm", &http.Client{Timeout: 50 * time.Millisecond, Transport: stalled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = owma.GetAirPollution(&Location{53.9, 27.56})
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("GetAirPollution() error = %v, want a timeout", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("GetAirPollution() returned after %v", d)
+	}
+}
+
+func TestAirQualityIndexParts(t *testing.T) {
+	tests := []struct {
+		aqi                           AirQualityIndex
+		emoji, label, color, wantText string
+	}{
+		{0, "❔", "Unknown", "#808080", "Unknown (0)"},
+		{1, "🟩", "Good", "#00E400", "🟩 (Good)"},
+		{2, "🟨", "Fair", "#FFFF00", "🟨 (Fair)"},
+		{3, "🟧", "Moderate", "#FF7E00", "🟧 (Moderate)"},
+		{4, "🟥", "Poor", "#FF0000", "🟥 (Poor)"},
+		{5, "⬛", "Very Poor", "#7E0023", "⬛ (Very Poor)"},
+		{6, "❔", "Unknown", "#808080", "Unknown (6)"},
+	}
+	for _, tt := range tests {
+		if got := tt.aqi.Emoji(); got != tt.emoji {
+			t.Errorf("%d.Emoji() = %q, want %q", tt.aqi, got, tt.emoji)
+		}
+		if got := tt.aqi.Label(); got != tt.label {
+			t.Errorf("%d.Label() = %q, want %q", tt.aqi, got, tt.label)
+		}
+		if got := tt.aqi.HexColor(); got != tt.color {
+			t.Errorf("%d.HexColor() = %q, want %q", tt.aqi, got, tt.color)
+		}
+		if got := tt.aqi.String(); got != tt.wantText {
+			t.Errorf("%d.String() = %q, want %q", tt.aqi, got, tt.wantText)
+		}
+		if got := tt.aqi.Valid(); got != (tt.label != "Unknown") {
+			t.Errorf("%d.Valid() = %v", tt.aqi, got)
+		}
+	}
+}
+
+func TestUnknownAQI(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	ru := message.NewPrinter(language.Russian)
+
+	for _, aqi := range []AirQualityIndex{0, 6, -1} {
+		logs.Reset()
+		if got, want := aqi.String(), fmt.Sprintf("Unknown (%d)", aqi); got != want {
+			t.Errorf("%d.String() = %q, want %q", aqi, got, want)
+		}
+		if got := aqi.Description(); got != unknownAQIDescription {
+			t.Errorf("%d.Description() = %q, want %q", aqi, got, unknownAQIDescription)
+		}
+		if !strings.Contains(logs.String(), fmt.Sprintf("unknown AQI value %d", aqi)) {
+			t.Errorf("unknown AQI %d is not logged: %q", aqi, logs.String())
+		}
+		if got := aqi.LocalizedString(ru); got == "" || got == aqi.String() {
+			t.Errorf("%d.LocalizedString() in Russian = %q", aqi, got)
+		}
+	}
+}
+
+func TestDistanceTo(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Location
+		// want is the distance in km
+		want, tolerance float64
+	}{
+		{"same point", Location{53.9, 27.56}, Location{53.9, 27.56}, 0, 0.001},
+		{"Minsk-Moscow", Location{53.9, 27.5667}, Location{55.7558, 37.6173}, 675, 5},
+		{"London-Paris", Location{51.5074, -0.1278}, Location{48.8566, 2.3522}, 344, 3},
+		{"New York-Los Angeles", Location{40.7128, -74.006}, Location{34.0522, -118.2437}, 3936, 20},
+		{"across the antimeridian", Location{0, 179.5}, Location{0, -179.5}, 111.2, 1},
+		{"antipodes", Location{0, 0}, Location{0, 180}, math.Pi * earthRadius / 1000, 0.001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.a.DistanceTo(&tt.b) / 1000
+			if math.Abs(got-tt.want) > tt.tolerance {
+				t.Errorf("DistanceTo() = %.1f km, want %.1f±%.1f km", got, tt.want, tt.tolerance)
+			}
+			if back := tt.b.DistanceTo(&tt.a) / 1000; math.Abs(back-got) > 0.001 {
+				t.Errorf("DistanceTo() back = %.3f km, want %.3f km", back, got)
+			}
+		})
+	}
+}
+
+func TestDestination(t *testing.T) {
+	from := &Location{53.9, 27.56}
+	for _, bearing := range []float64{0, 90, 180, 270} {
+		to := from.Destination(bearing, 5000)
+		if d := from.DistanceTo(to); math.Abs(d-5000) > 1 {
+			t.Errorf("Destination(%v, 5000) is %.1f m away", bearing, d)
+		}
+	}
+}
+
+func TestGetAirPollutionValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "valid", body: `{"coord":{"lon":27.56,"lat":53.9},"list":[{"main":{"aqi":2},"components":{"co":201.94,"pm2_5":0.5},"dt":1700000000}]}`},
+		{name: "empty list", body: `{"coord":{"lon":27.56,"lat":53.9},"list":[]}`, wantErr: true},
+		{name: "no list", body: `{"coord":{"lon":27.56,"lat":53.9}}`, wantErr: true},
+		{name: "null main", body: `{"coord":{"lon":27.56,"lat":53.9},"list":[{"main":null,"components":{"co":1},"dt":1700000000}]}`, wantErr: true},
+		{name: "AQI out of range", body: `{"coord":{"lon":27.56,"lat":53.9},"list":[{"main":{"aqi":6},"components":{"co":1},"dt":1700000000}]}`, wantErr: true},
+		{name: "no time", body: `{"coord":{"lon":27.56,"lat":53.9},"list":[{"main":{"aqi":2},"components":{"co":1}}]}`, wantErr: true},
+		{name: "no components", body: `{"coord":{"lon":27.56,"lat":53.9},"list":[{"main":{"aqi":2},"dt":1700000000}]}`, wantErr: true},
+		{name: "missing coord", body: `{"list":[{"main":{"aqi":2},"components":{"co":1},"dt":1700000000}]}`, wantErr: true},
+		{name: "null coord", body: `{"coord":null,"list":[{"main":{"aqi":2},"components":{"co":1},"dt":1700000000}]}`, wantErr: true},
+		{name: "coordinates out of range", body: `{"coord":{"lon":200,"lat":53.9},"list":[{"main":{"aqi":2},"components":{"co":1},"dt":1700000000}]}`, wantErr: true},
+		{name: "second data point invalid", body: `{"coord":{"lon":27.56,"lat":53.9},"list":[` +
+			`{"main":{"aqi":2},"components":{"co":1},"dt":1700000000},{"main":{"aqi":0},"components":{"co":1},"dt":1700003600}]}`, wantErr: true},
+		{name: "not JSON", body: `<html>Bad Gateway</html>`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owma := newTestOWM(t, func(r *http.Request) (int, string) { return http.StatusOK, tt.body })
+			resp, err := owma.GetAirPollution(&Location{53.9, 27.56})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetAirPollution() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (len(resp.DP) != 1 || resp.DP[0].GetAQI() != 2) {
+				t.Errorf("GetAirPollution() = %+v, want a DataPoint of AQI 2", resp)
+			}
+		})
+	}
+}
+
+func TestGetAirPollutionStatus(t *testing.T) {
+	const body = `{"coord":{"lon":27.56,"lat":53.9},"list":[{"main":{"aqi":2},"components":{"co":1},"dt":1700000000}]}`
+	tests := []struct {
+		status  int
+		body    string
+		wantErr error
+	}{
+		{status: http.StatusOK, body: body},
+		{status: http.StatusTooManyRequests, body: `{"cod":429,"message":"limit exceeded"}`, wantErr: ErrUnexpectedStatus},
+		{status: http.StatusInternalServerError, body: `{"cod":500,"message":"Internal error"}`, wantErr: ErrUnexpectedStatus},
+		// a valid body of an error status isn't used either
+		{status: http.StatusBadGateway, body: body, wantErr: ErrUnexpectedStatus},
+		{status: http.StatusUnauthorized, body: `{"cod":401}`, wantErr: ErrInvalidOWMToken},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			owma := newTestOWM(t, func(r *http.Request) (int, string) { return tt.status, tt.body })
+			_, err := owma.GetAirPollution(&Location{53.9, 27.56})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetAirPollution() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDataPointValidate(t *testing.T) {
+	dataPoint := func(aqi AirQualityIndex, dt int64, components map[string]float64) DataPoint {
+		dp := DataPoint{Dt: dt, Components: components}
+		dp.Main.Aqi = aqi
+		return dp
+	}
+	tests := []struct {
+		name    string
+		dp      DataPoint
+		wantErr bool
+	}{
+		{name: "valid", dp: dataPoint(1, 1, map[string]float64{"co": 1})},
+		{name: "AQI 0", dp: dataPoint(0, 1, map[string]float64{"co": 1}), wantErr: true},
+		{name: "AQI 6", dp: dataPoint(6, 1, map[string]float64{"co": 1}), wantErr: true},
+		{name: "no time", dp: dataPoint(1, 0, map[string]float64{"co": 1}), wantErr: true},
+		{name: "negative time", dp: dataPoint(1, -1, map[string]float64{"co": 1}), wantErr: true},
+		{name: "empty components", dp: dataPoint(1, 1, map[string]float64{}), wantErr: true},
+		{name: "no components", dp: dataPoint(1, 1, nil), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dp.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRawCapture(t *testing.T) {
+	const body = `{"coord":{"lon":27.56,"lat":53.9},"list":[{"main":{"aqi":2},"components":{"co":201.94},"dt":1700000000}]}`
+	tests := []struct {
+		name    string
+		request func(owma *OpenWheatherMapApi, l *Location) (*ApiPollutionResponse, error)
+		want    int
+	}{
+		{name: "GetAirPollution", request: (*OpenWheatherMapApi).GetAirPollution, want: 1},
+		{name: "PreviewAirPollution", request: (*OpenWheatherMapApi).PreviewAirPollution, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			owma := newTestOWM(t, func(r *http.Request) (int, string) { return http.StatusOK, body })
+			owma.RawCapture = store
+			if _, err := tt.request(owma, &Location{53.9, 27.56}); err != nil {
+				t.Fatal(err)
+			}
+			got, err := store.ListRawResponses(nil, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("%d raw response(s) captured, want %d", len(got), tt.want)
+			}
+			if tt.want > 0 && (got[0].Body != body || got[0].Location != (Location{53.9, 27.56})) {
+				t.Errorf("captured %+v, want the body for 53.9;27.56", got[0])
+			}
+		})
+	}
+}
+
+func TestRequestURL(t *testing.T) {
+	const body = `{"coord":{"lon":27.56,"lat":53.9},"list":[{"main":{"aqi":2},"components":{"co":1},"dt":1700000000}]}`
+	tests := []struct {
+		name     string
+		endpoint string
+		want     string
+	}{
+		{name: "default", want: "http://api.openweathermap.org/data/2.5/air_pollution"},
+		{name: "another version", endpoint: "https://api.openweathermap.org/data/3.0/", want: "https://api.openweathermap.org/data/3.0/air_pollution"},
+		{name: "without a trailing slash", endpoint: "https://owm.example.com/data/3.0", want: "https://owm.example.com/data/3.0/air_pollution"},
+		{name: "port", endpoint: "http://localhost:8080/", want: "http://localhost:8080/air_pollution"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *url.URL
+			owma := newTestOWM(t, func(r *http.Request) (int, string) {
+				got = r.URL
+				return http.StatusOK, body
+			})
+			if tt.endpoint != "" {
+				if err := owma.SetAPIEndpoint(tt.endpoint); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := owma.GetAirPollution(&Location{53.9, 27.56}); err != nil {
+				t.Fatal(err)
+			}
+			u := *got
+			u.RawQuery = ""
+			if u.String() != tt.want {
+				t.Errorf("URL = %q, want %q", u.String(), tt.want)
+			}
+			if want := "appid=owm&lat=53.900000&lon=27.560000"; got.RawQuery != want {
+				t.Errorf("query = %q, want %q", got.RawQuery, want)
+			}
+		})
+	}
+
+	owma := newTestOWM(t, func(r *http.Request) (int, string) { return http.StatusOK, body })
+	if err := owma.SetAPIEndpoint("http://[::1"); err == nil {
+		t.Error("SetAPIEndpoint() of an invalid URL = nil, want an error")
+	}
+}
+
+func TestRequestQueryEscaping(t *testing.T) {
+	var got url.Values
+	var rawQuery string
+	owma, err := NewOpenWheatherMapApiWithClient("to&ken=1 2", httpClientFunc(func(r *http.Request) (*http.Response, error) {
+		got, rawQuery = r.URL.Query(), r.URL.RawQuery
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`[]`)), Header: http.Header{}}, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{"São Paulo,BR", "a&appid=stolen", "100% #1 ?x=y", "Minsk"} {
+		if _, err := owma.Geocode(query); !errors.Is(err, errPlaceNotFound) {
+			t.Fatalf("Geocode(%q) error = %v, want %v", query, err, errPlaceNotFound)
+		}
+		if got.Get("q") != query || len(got["appid"]) != 1 || got.Get("appid") != "to&ken=1 2" || got.Get("limit") != "1" {
+			t.Errorf("Geocode(%q) query = %q, decoded %v", query, rawQuery, got)
+		}
+	}
+}
+
+func TestLocationQuery(t *testing.T) {
+	tests := []struct {
+		l    Location
+		want string
+	}{
+		{Location{53.9, 27.56}, "lat=53.900000&lon=27.560000"},
+		{Location{-33.8688, -151.2093}, "lat=-33.868800&lon=-151.209300"},
+		{Location{0, 0}, "lat=0.000000&lon=0.000000"},
+		{Location{1e-7, 179.9999999}, "lat=0.000000&lon=180.000000"},
+		{Location{90, -180}, "lat=90.000000&lon=-180.000000"},
+	}
+	for _, tt := range tests {
+		if got := tt.l.query().Encode(); got != tt.want {
+			t.Errorf("query() of %+v = %q, want %q", tt.l, got, tt.want)
+		}
+	}
+
+	// a localized printer formats the numbers with the decimal comma, the query doesn't depend on it
+	if p := message.NewPrinter(language.Russian); p.Sprintf("%f", 53.9) == "53.900000" {
+		t.Fatal("the Russian printer uses the decimal dot")
+	}
+	if got := (&Location{53.9, 27.56}).query().Get("lat"); got != "53.900000" {
+		t.Errorf("lat = %q, want 53.900000", got)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	defer func(version, name string) { Version, BotName = version, name }(Version, BotName)
+
+	var got []string
+	owma := newTestOWM(t, func(r *http.Request) (int, string) {
+		got = append(got, r.Header.Get("User-Agent"))
+		return http.StatusOK, `[]`
+	})
+	// each request of OWM API has the header
+	requests := []struct {
+		name string
+		do   func()
+	}{
+		{name: "air pollution", do: func() { owma.GetAirPollution(&Location{53.9, 27.56}) }},
+		{name: "weather", do: func() { owma.GetCurrentWeather(&Location{53.9, 27.56}) }},
+		{name: "geocode", do: func() { owma.Geocode("Minsk") }},
+		{name: "reverse geocode", do: func() { owma.ReverseGeocode(&Location{53.9, 27.56}) }},
+	}
+	tests := []struct {
+		version, name string
+		want          string
+	}{
+		{version: "dev", name: "airpollutionbot", want: "airpollutionbot/dev (+https://github.com/atsevan/airpollutionbot)"},
+		{version: "1.2.0", name: "mybot", want: "mybot/1.2.0 (+https://github.com/atsevan/airpollutionbot)"},
+	}
+	for _, tt := range tests {
+		Version, BotName = tt.version, tt.name
+		for _, r := range requests {
+			got = nil
+			r.do()
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("%s: User-Agent = %q, want %q", r.name, got, tt.want)
+			}
+		}
+	}
+}
+
+// endlessReader is an endless body of spaces counting the bytes read
+type endlessReader struct{ n int }
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = ' '
+	}
+	r.n += len(p)
+	return len(p), nil
+}
+
+func TestResponseSizeLimit(t *testing.T) {
+	const body = `{"coord":{"lon":27.56,"lat":53.9},"list":[{"main":{"aqi":2},"components":{"co":1},"dt":1700000000}]}`
+	padded := func(size int) io.Reader {
+		return strings.NewReader(body + strings.Repeat(" ", size-len(body)))
+	}
+	tests := []struct {
+		name    string
+		body    func() io.Reader
+		wantErr bool
+	}{
+		{name: "small", body: func() io.Reader { return strings.NewReader(body) }},
+		{name: "at the limit", body: func() io.Reader { return padded(maxResponseSize) }},
+		{name: "over the limit", body: func() io.Reader { return padded(maxResponseSize + 1) }, wantErr: true},
+		{name: "endless", body: func() io.Reader { return io.MultiReader(strings.NewReader(body), &endlessReader{}) }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owma, err := NewOpenWheatherMapApiWithClient("owm", httpClientFunc(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(tt.body()), Header: http.Header{}}, nil
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = owma.GetAirPollution(&Location{53.9, 27.56})
+
+			if errors.Is(err, ErrResponseTooLarge) != tt.wantErr {
+				t.Errorf("GetAirPollution() error = %v, want %v: %v", err, ErrResponseTooLarge, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("GetAirPollution() = %v, want no error", err)
+			}
+		})
+	}
+
+	// the endless body is read only up to the limit
+	endless := &endlessReader{}
+	owma, err := NewOpenWheatherMapApiWithClient("owm", httpClientFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(endless), Header: http.Header{}}, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := owma.Geocode("Minsk"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Geocode() error = %v, want %v", err, ErrResponseTooLarge)
+	}
+	if endless.n > 2*maxResponseSize {
+		t.Errorf("%d bytes read, want about the limit of %d", endless.n, maxResponseSize)
+	}
+}
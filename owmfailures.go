@@ -0,0 +1,61 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// DefaultOWMFailureThreshold is the number of OWM API failures in a row alerting the operator
+const DefaultOWMFailureThreshold = 10
+
+var (
+	// owmConsecutiveFailures is the number of the last OWM API air pollution requests failed in a row
+	owmConsecutiveFailures = expvar.NewInt("owm_consecutive_failures")
+	// owmFailureAlerts counts the alerts of FailureMonitor
+	owmFailureAlerts = expvar.NewInt("owm_failure_alerts")
+)
+
+// FailureMonitor counts the requests failed in a row and calls OnAlert once their number reaches Threshold.
+// A successful request resets the count and arms the alert again.
+type FailureMonitor struct {
+	Threshold int
+	// OnAlert is called with the number of the failures and the last error. It must not block.
+	OnAlert func(failures int, err error)
+
+	mu       sync.Mutex
+	failures int
+}
+
+// Record counts the outcome of a request
+func (m *FailureMonitor) Record(err error) {
+	m.mu.Lock()
+	if err == nil {
+		m.failures = 0
+	} else {
+		m.failures++
+	}
+	failures := m.failures
+	m.mu.Unlock()
+
+	owmConsecutiveFailures.Set(int64(failures))
+	if err != nil && failures == m.Threshold {
+		owmFailureAlerts.Add(1)
+		if m.OnAlert != nil {
+			m.OnAlert(failures, err)
+		}
+	}
+}
+
+// alertOWMFailures is the OnAlert of FailureMonitor. It logs the failures and messages the admin, if there is one.
+func (bot *Bot) alertOWMFailures(failures int, err error) {
+	log.Printf("ERROR: %d OWM API requests failed in a row, the last error: %v", failures, err)
+	if bot.adminID == 0 {
+		return
+	}
+	// the admin's private chat has the ID of the admin
+	go bot.Send(tgbotapi.NewMessage(bot.adminID, fmt.Sprintf("⚠️ %d OWM API requests failed in a row. The last error: %v", failures, err)))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFailureMonitor(t *testing.T) {
+	failure := errors.New("owm is down")
+	tests := []struct {
+		name string
+		// outcomes are the recorded requests, true is a failed one
+		outcomes []bool
+		alerts   int
+		// failures is the count of the monitor after the outcomes
+		failures int
+	}{
+		{name: "below threshold", outcomes: []bool{true, true}, failures: 2},
+		{name: "at threshold", outcomes: []bool{true, true, true}, alerts: 1, failures: 3},
+		{name: "once above threshold", outcomes: []bool{true, true, true, true, true, true, true}, alerts: 1, failures: 7},
+		{name: "reset by success", outcomes: []bool{true, true, false, true, true}, failures: 2},
+		{name: "armed again after success", outcomes: []bool{true, true, true, false, true, true, true}, alerts: 2, failures: 3},
+		{name: "successes", outcomes: []bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var alerts []int
+			m := &FailureMonitor{Threshold: 3, OnAlert: func(failures int, err error) {
+				if !errors.Is(err, failure) {
+					t.Errorf("OnAlert() error = %v, want %v", err, failure)
+				}
+				alerts = append(alerts, failures)
+			}}
+			before := owmFailureAlerts.Value()
+
+			for _, failed := range tt.outcomes {
+				var err error
+				if failed {
+					err = failure
+				}
+				m.Record(err)
+			}
+
+			if len(alerts) != tt.alerts {
+				t.Errorf("OnAlert() called %d time(s), want %d", len(alerts), tt.alerts)
+			}
+			for _, n := range alerts {
+				if n != m.Threshold {
+					t.Errorf("OnAlert() of %d failures, want %d", n, m.Threshold)
+				}
+			}
+			if n := owmFailureAlerts.Value() - before; n != int64(tt.alerts) {
+				t.Errorf("owm_failure_alerts = %d, want %d", n, tt.alerts)
+			}
+			if n := owmConsecutiveFailures.Value(); n != int64(tt.failures) {
+				t.Errorf("owm_consecutive_failures = %d, want %d", n, tt.failures)
+			}
+		})
+	}
+}
+
+func TestOWMFailureAlert(t *testing.T) {
+	down := true
+	owma := newTestOWM(t, func(r *http.Request) (int, string) {
+		if down {
+			return http.StatusInternalServerError, `{"cod":500,"message":"Internal error"}`
+		}
+		return http.StatusOK, `{"coord":{"lat":53.9,"lon":27.56},"list":[{"dt":1700000000,"main":{"aqi":2},"components":{"co":200}}]}`
+	})
+	store := newTestStore(t)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{wAPI: owma, adminID: 42}, fake)
+	owma.Failures = &FailureMonitor{Threshold: 3, OnAlert: bot.alertOWMFailures}
+	minsk := &Location{53.9, 27.56}
+
+	for i := 0; i < 5; i++ {
+		if _, err := owma.GetAirPollution(minsk); err == nil {
+			t.Fatal("GetAirPollution() = nil error, want the failure")
+		}
+	}
+	// the admin is messaged asynchronously
+	deadline := time.Now().Add(time.Second)
+	for len(fake.sent("sendMessage")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	sent := fake.sent("sendMessage")
+	if len(sent) != 1 || sent[0].Get("chat_id") != "42" {
+		t.Fatalf("alerts sent %v, want one to the admin", sent)
+	}
+	if got := sent[0].Get("text"); !strings.HasPrefix(got, "⚠️ 3 OWM API requests failed in a row") {
+		t.Errorf("alert = %q, want the number of the failures", got)
+	}
+
+	down = false
+	if _, err := owma.GetAirPollution(minsk); err != nil {
+		t.Fatal(err)
+	}
+	if n := owmConsecutiveFailures.Value(); n != 0 {
+		t.Errorf("owm_consecutive_failures = %d after a success, want 0", n)
+	}
+}
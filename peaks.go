@@ -0,0 +1,133 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	peaksHeaderTmpl = "📈 AQI peaks of %s"
+	peaksEntryTmpl  = "Location: %f;%f. Peak: %s at %s"
+	peaksNoDataTmpl = "Location: %f;%f. No checks that day"
+	peaksOnText     = "OK. You will get the AQI peaks of the previous day every morning"
+	peaksOffText    = "OK. I won't send the daily AQI peaks"
+	peaksUsageMsg   = "Usage: /peaks on|off"
+
+	peaksDateLayout = "2006-01-02"
+	peaksTimeLayout = "15:04"
+)
+
+// peakOfPeriod returns the entry with the highest AQI recorded in [from, to). The earliest one wins a tie.
+// It returns false if there are no entries in the period.
+func peakOfPeriod(entries []AQIHistoryEntry, from, to time.Time) (AQIHistoryEntry, bool) {
+	var (
+		peak  AQIHistoryEntry
+		found bool
+	)
+	for _, e := range entries {
+		if e.CreatedAt.Before(from) || !e.CreatedAt.Before(to) {
+			continue
+		}
+		if !found || e.AQI > peak.AQI {
+			peak, found = e, true
+		}
+	}
+	return peak, found
+}
+
+// CronDailyPeaks runs in the morning and sends the chats opted in to /peaks the AQI peaks of the previous day.
+// The peaks are taken from the AQI history recorded by Cron, so each subscription has its own.
+func (bot *Bot) CronDailyPeaks() {
+	if bot.maintenance.Load() {
+		log.Print("CronDailyPeaks: maintenance mode, skipping")
+		return
+	}
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	from := to.AddDate(0, 0, -1)
+
+	chatIDs, err := bot.store.ListDailyPeaksChatIDs()
+	if err != nil {
+		log.Print("CronDailyPeaks: ", err)
+		return
+	}
+	var sent int
+	for _, chatID := range chatIDs {
+		msgText, err := bot.dailyPeaksText(chatID, from, to)
+		if err != nil {
+			log.Print("CronDailyPeaks: ", err)
+			continue
+		}
+		if msgText == "" {
+			continue
+		}
+		if err := bot.Send(tgbotapi.NewMessage(chatID, msgText)); err == nil {
+			sent++
+		}
+	}
+	log.Printf("CronDailyPeaks: sent %d summary(ies)", sent)
+}
+
+// dailyPeaksText reports the AQI peak of each subscription of the chat in [from, to).
+// It is empty if the chat has no subscriptions.
+func (bot *Bot) dailyPeaksText(chatID int64, from, to time.Time) (string, error) {
+	subs, err := bot.store.ListAQISubscriptions(chatID)
+	if err != nil {
+		return "", err
+	}
+	if len(*subs) == 0 {
+		return "", nil
+	}
+
+	p := newLangPrinter((*subs)[0].LanguageCode)
+	theme := bot.themeOf(chatID)
+	msgText := []string{p.Sprintf(peaksHeaderTmpl, from.Format(peaksDateLayout)), ""}
+	for _, s := range *subs {
+		if s.Label != "" {
+			msgText = append(msgText, p.Sprintf(subscriptionLabelTmpl, s.Label))
+		}
+		entries, err := bot.store.ListAQIHistory(s.ID, from)
+		if err != nil {
+			return "", err
+		}
+		peak, ok := peakOfPeriod(entries, from, to)
+		if !ok {
+			msgText = append(msgText, p.Sprintf(peaksNoDataTmpl, s.Latitude, s.Longitude))
+			continue
+		}
+		msgText = append(msgText, p.Sprintf(peaksEntryTmpl, s.Latitude, s.Longitude,
+			theme.LocalizedAQI(peak.AQI, p), peak.CreatedAt.In(from.Location()).Format(peaksTimeLayout)))
+	}
+	return strings.Join(msgText, "\n"), nil
+}
+
+// setPeaksText opts the chat in to or out of the daily AQI peaks
+func (bot *Bot) setPeaksText(chatID int64, arg string, p *message.Printer) string {
+	var on bool
+	switch strings.TrimSpace(arg) {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return p.Sprintf(peaksUsageMsg)
+	}
+	prefs, err := bot.store.GetUserPrefs(chatID)
+	if err != nil {
+		log.Print("GetUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	prefs.DailyPeaks = on
+	if err := bot.store.UpdateUserPrefs(prefs); err != nil {
+		log.Print("UpdateUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if on {
+		return p.Sprintf(peaksOnText)
+	}
+	return p.Sprintf(peaksOffText)
+}
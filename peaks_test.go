@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// hourlyHistory returns the entries of the AQIs checked every hour from the start
+func hourlyHistory(start time.Time, aqis ...AirQualityIndex) []AQIHistoryEntry {
+	entries := make([]AQIHistoryEntry, 0, len(aqis))
+	for i, aqi := range aqis {
+		entries = append(entries, AQIHistoryEntry{AQI: aqi, CreatedAt: start.Add(time.Duration(i) * time.Hour)})
+	}
+	return entries
+}
+
+func TestPeakOfPeriod(t *testing.T) {
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	next := day.AddDate(0, 0, 1)
+	tests := []struct {
+		name    string
+		entries []AQIHistoryEntry
+		want    time.Time
+		wantAQI AirQualityIndex
+		wantOK  bool
+	}{
+		{name: "no entries"},
+		{name: "hourly", entries: hourlyHistory(day, 1, 1, 2, 2, 2, 3, 4, 3, 2, 2, 1, 1, 1, 1, 1, 2, 2, 2, 3, 3, 2, 1, 1, 1),
+			want: day.Add(6 * time.Hour), wantAQI: 4, wantOK: true},
+		{name: "the earliest of a tie", entries: hourlyHistory(day.Add(8*time.Hour), 2, 5, 3, 5),
+			want: day.Add(9 * time.Hour), wantAQI: 5, wantOK: true},
+		// the entries of the evening before and the next day are out of the period
+		{name: "bounds", entries: hourlyHistory(day.Add(-2*time.Hour), 5, 5, 1, 2, 1),
+			want: day.Add(time.Hour), wantAQI: 2, wantOK: true},
+		{name: "at the end", entries: hourlyHistory(next.Add(-time.Hour), 3, 5),
+			want: next.Add(-time.Hour), wantAQI: 3, wantOK: true},
+		{name: "no entries that day", entries: hourlyHistory(next, 4, 5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := peakOfPeriod(tt.entries, day, next)
+			if ok != tt.wantOK {
+				t.Fatalf("peakOfPeriod() found %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (got.AQI != tt.wantAQI || !got.CreatedAt.Equal(tt.want)) {
+				t.Errorf("peakOfPeriod() = %v at %v, want %v at %v", got.AQI, got.CreatedAt, tt.wantAQI, tt.want)
+			}
+		})
+	}
+}
+
+func TestDailyPeaksText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	from := to.AddDate(0, 0, -1)
+
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	addTestSubscription(t, store, 1, &Location{52.1, 23.7}, 2)
+	subs, err := store.ListAQISubscriptions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetSubscriptionLabel((*subs)[0].ID, "Home"); err != nil {
+		t.Fatal(err)
+	}
+	// the first subscription is checked hourly the day before and today, the second one only today
+	for _, e := range hourlyHistory(from.Add(-3*time.Hour), 5, 5, 5, 1, 2, 2, 4, 3, 4, 2, 1) {
+		if err := store.AddAQIHistory((*subs)[0].ID, e.AQI, e.CreatedAt, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.AddAQIHistory((*subs)[1].ID, 5, to.Add(time.Minute), true); err != nil {
+		t.Fatal(err)
+	}
+	bot := newTestBot(store, &botServices{})
+
+	got, err := bot.dailyPeaksText(1, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"📈 AQI peaks of " + from.Format(peaksDateLayout),
+		"",
+		"🏷 Home",
+		"Location: 53.900000;27.560000. Peak: " + ThemeEmoji.LocalizedAQI(4, p) + " at " + from.Add(3*time.Hour).Format(peaksTimeLayout),
+		"Location: 52.100000;23.700000. No checks that day",
+	}, "\n")
+	if got != want {
+		t.Errorf("dailyPeaksText() = %q, want %q", got, want)
+	}
+
+	if got, err := bot.dailyPeaksText(2, from, to); err != nil || got != "" {
+		t.Errorf("dailyPeaksText() without subscriptions = %q, %v, want empty", got, err)
+	}
+}
+
+func TestCronDailyPeaks(t *testing.T) {
+	store := newTestStore(t)
+	p := message.NewPrinter(language.English)
+	for _, chatID := range []int64{1, 2, 3} {
+		addTestSubscription(t, store, chatID, &Location{53.9, 27.56}, 2)
+		addTestHistory(t, store, onlySubscription(t, store, chatID).ID, 24*time.Hour, 3)
+	}
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+	// the chat 3 opts out after opting in
+	for chatID, arg := range map[int64]string{1: "on", 3: "on"} {
+		if got := bot.setPeaksText(chatID, arg, p); got != peaksOnText {
+			t.Fatalf("setPeaksText(%q) = %q", arg, got)
+		}
+	}
+	if got := bot.setPeaksText(3, " off ", p); got != peaksOffText {
+		t.Errorf("setPeaksText(off) = %q, want %q", got, peaksOffText)
+	}
+	if got := bot.setPeaksText(2, "daily", p); got != peaksUsageMsg {
+		t.Errorf("setPeaksText(daily) = %q, want %q", got, peaksUsageMsg)
+	}
+
+	bot.CronDailyPeaks()
+
+	sent := fake.sent("sendMessage")
+	if len(sent) != 1 || sent[0].Get("chat_id") != "1" {
+		t.Fatalf("CronDailyPeaks() sent %v, want a summary to the chat 1 only", sent)
+	}
+	if got := sent[0].Get("text"); !strings.HasPrefix(got, "📈 AQI peaks of ") {
+		t.Errorf("summary = %q, want the peaks", got)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	privacyIntroText               = "I store only the data needed to report the air quality to you:"
+	privacySessionText             = "• your last shared location and your language, until you delete them with /forgetme"
+	privacyRoundedTmpl             = "• the coordinates are rounded to %d decimal places"
+	privacySubsText                = "• your subscriptions with their last AQI, until you unsubscribe"
+	privacyFavoritesText           = "• your favorite locations, until you remove them"
+	privacySubsCleanupTmpl         = "• your subscriptions with their last AQI. Unsubscribed ones are deleted within %s"
+	privacyDataPointsTmpl          = "• the last %d air measurements of your locations"
+	privacyDataPointsText          = "• the air measurements of your locations"
+	privacyDataPointsRetentionTmpl = "• the air measurements are deleted after %s"
+	privacyHistoryTmpl             = "• the AQI history of your subscriptions for up to %s, for the digests and the statistics"
+	privacyRawTmpl                 = "• the raw responses of the air quality provider for your coordinates for %s"
+	privacyControlText             = "/export downloads all your data, /forgetme deletes it."
+	retentionDaysTmpl              = "%d day(s)"
+	retentionHoursTmpl             = "%d h"
+)
+
+// formatRetention returns the localized duration in whole days or hours if possible
+func formatRetention(d time.Duration, p *message.Printer) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return p.Sprintf(retentionDaysTmpl, int(d/(24*time.Hour)))
+	case d%time.Hour == 0:
+		return p.Sprintf(retentionHoursTmpl, int(d/time.Hour))
+	}
+	return d.String()
+}
+
+// privacyText describes the stored data and how long it is kept. The periods come from the configuration of the bot.
+func (bot *Bot) privacyText(p *message.Printer) string {
+	msgText := []string{p.Sprintf(privacyIntroText), "", p.Sprintf(privacySessionText)}
+	if bot.store.CoordinatePrecision > 0 {
+		msgText = append(msgText, p.Sprintf(privacyRoundedTmpl, bot.store.CoordinatePrecision))
+	}
+	if bot.cleanupInterval > 0 {
+		// CronCleanup keeps the unsubscribed ones for restoreWindow
+		msgText = append(msgText, p.Sprintf(privacySubsCleanupTmpl, formatRetention(restoreWindow+bot.cleanupInterval, p)))
+	} else {
+		msgText = append(msgText, p.Sprintf(privacySubsText))
+	}
+	msgText = append(msgText, p.Sprintf(privacyFavoritesText))
+	if bot.dataPointsPerChat > 0 {
+		msgText = append(msgText, p.Sprintf(privacyDataPointsTmpl, bot.dataPointsPerChat))
+	} else {
+		msgText = append(msgText, p.Sprintf(privacyDataPointsText))
+	}
+	if bot.dataPointRetention > 0 {
+		msgText = append(msgText, p.Sprintf(privacyDataPointsRetentionTmpl, formatRetention(bot.dataPointRetention, p)))
+	}
+	// CronCleanup deletes the history older than digestPeriod
+	msgText = append(msgText, p.Sprintf(privacyHistoryTmpl, formatRetention(digestPeriod+bot.cleanupInterval, p)))
+	if bot.rawCaptureRetention > 0 {
+		msgText = append(msgText, p.Sprintf(privacyRawTmpl, formatRetention(bot.rawCaptureRetention, p)))
+	}
+	msgText = append(msgText, "", p.Sprintf(privacyControlText))
+	return strings.Join(msgText, "\n")
+}
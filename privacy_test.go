@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestFormatRetention(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 24 * time.Hour, want: "1 day(s)"},
+		{d: 14 * 24 * time.Hour, want: "14 day(s)"},
+		{d: 36 * time.Hour, want: "36 h"},
+		{d: time.Hour, want: "1 h"},
+		{d: 90 * time.Minute, want: "1h30m0s"},
+	}
+	for _, tt := range tests {
+		if got := formatRetention(tt.d, p); got != tt.want {
+			t.Errorf("formatRetention(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestPrivacyText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	tests := []struct {
+		name     string
+		services *botServices
+		// precision is CoordinatePrecision of the store
+		precision int
+		want      []string
+		notWant   []string
+	}{
+		{name: "defaults", want: []string{
+			privacySessionText,
+			privacySubsText,
+			privacyDataPointsText,
+			"• the AQI history of your subscriptions for up to 7 day(s), for the digests and the statistics",
+			privacyControlText,
+		}, notWant: []string{"rounded", "raw responses", "deleted after"}},
+		{name: "configured retention", precision: 3,
+			services: &botServices{cleanupInterval: 24 * time.Hour, dataPointsPerChat: 48, dataPointRetention: 7 * 24 * time.Hour,
+				rawCaptureRetention: 72 * time.Hour},
+			want: []string{
+				"• the coordinates are rounded to 3 decimal places",
+				"• your subscriptions with their last AQI. Unsubscribed ones are deleted within 36 h",
+				"• the last 48 air measurements of your locations",
+				"• the air measurements are deleted after 7 day(s)",
+				"• the AQI history of your subscriptions for up to 8 day(s), for the digests and the statistics",
+				"• the raw responses of the air quality provider for your coordinates for 3 day(s)",
+			}},
+		{name: "retention in hours", services: &botServices{cleanupInterval: 12 * time.Hour, rawCaptureRetention: 6 * time.Hour},
+			want: []string{
+				"• your subscriptions with their last AQI. Unsubscribed ones are deleted within 1 day(s)",
+				"• the raw responses of the air quality provider for your coordinates for 6 h",
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			store.CoordinatePrecision = tt.precision
+			services := tt.services
+			if services == nil {
+				services = &botServices{}
+			}
+			bot := newTestBot(store, services)
+
+			got := bot.privacyText(p)
+			lines := strings.Split(got, "\n")
+			for _, want := range tt.want {
+				if !containsLine(lines, want) {
+					t.Errorf("privacyText() = %q, want the line %q", got, want)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("privacyText() = %q, want no %q", got, s)
+				}
+			}
+		})
+	}
+
+	bot := newTestBot(newTestStore(t), &botServices{rawCaptureRetention: 72 * time.Hour})
+	ru := message.NewPrinter(language.Russian)
+	if got := bot.privacyText(ru); got == bot.privacyText(p) || !strings.Contains(got, ru.Sprintf(retentionDaysTmpl, 3)) {
+		t.Errorf("privacyText() in Russian = %q, want the translation with the retention", got)
+	}
+}
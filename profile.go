@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"golang.org/x/text/message"
+)
+
+// SensitivityProfile selects the health advice of the AQI messages
+type SensitivityProfile string
+
+const (
+	ProfileGeneral     SensitivityProfile = "general"
+	ProfileChildren    SensitivityProfile = "children"
+	ProfileRespiratory SensitivityProfile = "respiratory"
+	ProfileElderly     SensitivityProfile = "elderly"
+)
+
+const (
+	profileSetTmpl     = "OK. Health advice is given for the profile: %s"
+	profileUsageMsg    = "Usage: /profile general|children|respiratory|elderly"
+	profileCurrentTmpl = "Current profile: %s"
+)
+
+// profileDescription keeps the health advice of the sensitive profiles. They get warnings at lower AQI levels.
+// ProfileGeneral uses aqiDescription.
+var profileDescription = map[SensitivityProfile]map[AirQualityIndex]string{
+	ProfileChildren: {
+		1: "No health implications. A good time for outdoor play.",
+		2: "Children with asthma should watch for symptoms during active play.",
+		3: "Children should take breaks during long or intense outdoor activities. Children with asthma should keep their reliever inhaler at hand.",
+		4: "Children should avoid long or intense outdoor activities and play indoors where possible.",
+		5: "Children should stay indoors and keep the windows closed.",
+	},
+	ProfileRespiratory: {
+		1: "No health implications.",
+		2: "People with asthma or lung disease may notice symptoms. Keep your medication at hand.",
+		3: "Reduce intense outdoor activities. Follow your action plan if symptoms appear.",
+		4: "Avoid outdoor activities, keep the windows closed and follow your action plan.",
+		5: "Stay indoors and contact your doctor if the symptoms get worse.",
+	},
+	ProfileElderly: {
+		1: "No health implications.",
+		2: "Older people with heart or lung disease may notice slight effects.",
+		3: "Older people should reduce long or intense outdoor activities.",
+		4: "Older people should avoid outdoor activities and watch for chest pain or shortness of breath.",
+		5: "Older people should stay indoors and seek medical advice if they feel unwell.",
+	},
+}
+
+// parseProfile parses the argument of the /profile command
+func parseProfile(arg string) (SensitivityProfile, bool) {
+	profile := SensitivityProfile(strings.ToLower(strings.TrimSpace(arg)))
+	switch profile {
+	case ProfileGeneral, ProfileChildren, ProfileRespiratory, ProfileElderly:
+		return profile, true
+	}
+	return "", false
+}
+
+// ProfileDescription returns the health advice of the Air Quality Index level for the profile.
+// The general description is returned for ProfileGeneral and unknown profiles.
+func (aqi AirQualityIndex) ProfileDescription(profile SensitivityProfile) string {
+	descriptions, ok := profileDescription[profile]
+	if !ok || !aqi.Valid() {
+		return aqi.Description()
+	}
+	return descriptions[aqi]
+}
+
+// LocalizedProfileDescription returns ProfileDescription translated by the printer
+func (aqi AirQualityIndex) LocalizedProfileDescription(profile SensitivityProfile, p *message.Printer) string {
+	return p.Sprintf(aqi.ProfileDescription(profile))
+}
+
+// setProfileText stores the SensitivityProfile of the /profile argument. The current profile is shown without an argument.
+func (bot *Bot) setProfileText(chatID int64, arg string, p *message.Printer) string {
+	prefs, err := bot.store.GetUserPrefs(chatID)
+	if err != nil {
+		log.Print("GetUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if strings.TrimSpace(arg) == "" {
+		return p.Sprintf(profileCurrentTmpl, prefs.Profile) + "\n" + p.Sprintf(profileUsageMsg)
+	}
+	profile, ok := parseProfile(arg)
+	if !ok {
+		return p.Sprintf(profileUsageMsg)
+	}
+	prefs.Profile = profile
+	if err := bot.store.UpdateUserPrefs(prefs); err != nil {
+		log.Print("UpdateUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	return p.Sprintf(profileSetTmpl, profile)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestProfileDescription(t *testing.T) {
+	profiles := []SensitivityProfile{ProfileGeneral, ProfileChildren, ProfileRespiratory, ProfileElderly}
+	// the same AQI of the warnings gives different advice per profile
+	for _, aqi := range []AirQualityIndex{2, 3, 4, 5} {
+		seen := map[string]SensitivityProfile{}
+		for _, profile := range profiles {
+			d := aqi.ProfileDescription(profile)
+			if d == "" {
+				t.Errorf("%d.ProfileDescription(%s) is empty", aqi, profile)
+			}
+			if other, ok := seen[d]; ok {
+				t.Errorf("%d.ProfileDescription() of %s and %s are the same: %q", aqi, other, profile, d)
+			}
+			seen[d] = profile
+		}
+	}
+
+	tests := []struct {
+		name    string
+		aqi     AirQualityIndex
+		profile SensitivityProfile
+		want    string
+	}{
+		{"general", 3, ProfileGeneral, aqiDescription[3]},
+		{"unknown profile", 3, SensitivityProfile("athletes"), aqiDescription[3]},
+		{"empty profile", 3, "", aqiDescription[3]},
+		{"unknown AQI", 0, ProfileChildren, unknownAQIDescription},
+		{"children", 4, ProfileChildren, profileDescription[ProfileChildren][4]},
+	}
+	for _, tt := range tests {
+		if got := tt.aqi.ProfileDescription(tt.profile); got != tt.want {
+			t.Errorf("%s: ProfileDescription() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLocalizedProfileDescription(t *testing.T) {
+	en, ru := message.NewPrinter(language.English), message.NewPrinter(language.Russian)
+	for profile := range profileDescription {
+		for aqi := AirQualityIndex(1); aqi <= 5; aqi++ {
+			if got := aqi.LocalizedProfileDescription(profile, ru); got == aqi.LocalizedProfileDescription(profile, en) {
+				t.Errorf("%d.LocalizedProfileDescription(%s) in Russian = %q, the same as in English", aqi, profile, got)
+			}
+		}
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	tests := []struct {
+		arg    string
+		want   SensitivityProfile
+		wantOK bool
+	}{
+		{"children", ProfileChildren, true},
+		{" Elderly ", ProfileElderly, true},
+		{"GENERAL", ProfileGeneral, true},
+		{"athletes", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := parseProfile(tt.arg); got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseProfile(%q) = %q, %v, want %q, %v", tt.arg, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
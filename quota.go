@@ -0,0 +1,34 @@
+package main
+
+import (
+	"log"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	subsQuotaTmpl = "You have %d of %d subscriptions"
+	subsLimitTmpl = "You have reached the limit of %d subscriptions. Remove them with /subsriptions to add new ones"
+)
+
+// quotaText returns the number of the subscriptions of the n ones of the limit. Empty if the subscriptions aren't limited.
+func (bot *Bot) quotaText(n int, p *message.Printer) string {
+	if bot.store.MaxSubscriptions <= 0 {
+		return ""
+	}
+	return p.Sprintf(subsQuotaTmpl, n, bot.store.MaxSubscriptions)
+}
+
+// atSubscriptionLimit reports whether the chat can't add more subscriptions
+func (bot *Bot) atSubscriptionLimit(chatID int64) bool {
+	if bot.store.MaxSubscriptions <= 0 {
+		return false
+	}
+	subs, err := bot.store.ListAQISubscriptions(chatID)
+	if err != nil {
+		// the subscribe button is shown, the limit is checked again on subscribing
+		log.Print("ListAQISubscriptions: ", err)
+		return false
+	}
+	return len(*subs) >= bot.store.MaxSubscriptions
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestSubscriptionsQuota(t *testing.T) {
+	locations := []*Location{{53.9, 27.56}, {52.1, 23.7}, {55.75, 37.62}}
+	tests := []struct {
+		name  string
+		limit int
+		subs  int
+		// footer is the last line of /subsriptions, none if empty
+		footer     string
+		notifyMeOn bool
+	}{
+		{name: "no subscriptions", limit: 3, subs: 0, footer: "You have 0 of 3 subscriptions", notifyMeOn: true},
+		{name: "below the limit", limit: 3, subs: 2, footer: "You have 2 of 3 subscriptions", notifyMeOn: true},
+		{name: "at the limit", limit: 3, subs: 3, footer: "You have 3 of 3 subscriptions"},
+		{name: "not limited", limit: 0, subs: 3, notifyMeOn: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			for _, l := range locations[:tt.subs] {
+				addTestSubscription(t, store, 1, l, 2)
+			}
+			store.MaxSubscriptions = tt.limit
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 2}}, fake)
+
+			bot.handleMessage(newTestCommand(1, "/subsriptions"))
+			got := fake.lastText()
+			lines := strings.Split(got, "\n")
+			if last := lines[len(lines)-1]; tt.footer != "" && last != tt.footer {
+				t.Errorf("/subsriptions = %q, want the footer %q", got, tt.footer)
+			}
+			if tt.footer == "" && strings.Contains(got, " of ") {
+				t.Errorf("/subsriptions = %q, want no footer", got)
+			}
+
+			// the subscribe button is hidden at the limit
+			bot.handleMessage(newTestLocationMessage(1, &Location{51.51, -0.13}))
+			if notifyMeOn := len(notifyMeCallbacks(t, fake)) == 1; notifyMeOn != tt.notifyMeOn {
+				t.Errorf("notifyMe button shown %v, want %v", notifyMeOn, tt.notifyMeOn)
+			}
+		})
+	}
+
+	ru := message.NewPrinter(language.Russian)
+	bot := newTestBot(newTestStore(t), &botServices{})
+	bot.store.MaxSubscriptions = 10
+	if got, en := bot.quotaText(8, ru), bot.quotaText(8, message.NewPrinter(language.English)); got == en || !strings.Contains(got, "8") || !strings.Contains(got, "10") {
+		t.Errorf("quotaText() in Russian = %q, want a translation of %q", got, en)
+	}
+}
+
+func TestSubscribeAtLimit(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	store := newTestStore(t)
+	store.MaxSubscriptions = 1
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 2}}, fake)
+	// a button sent before the limit was reached
+	store.MaxSubscriptions = 2
+	bot.handleMessage(newTestLocationMessage(1, &Location{51.51, -0.13}))
+	query := newTestCallbackQuery(t, 1, "en", callbackNotifyMe)
+	query.Data = notifyMeCallbacks(t, fake)[0]
+	store.MaxSubscriptions = 1
+
+	bot.handleCallbackQuery(query)
+
+	if got, want := fake.lastText(), p.Sprintf(subsLimitTmpl, 1); got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+	if n := countRows(t, store, "SELECT COUNT(*) FROM subscription WHERE chat_id=1"); n != 1 {
+		t.Errorf("%d subscriptions, want 1", n)
+	}
+}
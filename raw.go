@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	rawAQITmpl      = "OWM AQI: %d of 5, %s"
+	rawDominantTmpl = "The highest pollutant level: %s, level %d"
+	rawScaleText    = "OWM rates the air from 1 to 5 by the concentrations of the pollutants. Apps using the US EPA scale from 0 to 500 show other numbers for the same air."
+)
+
+// dominantComponent returns the key and the level of the component with the highest OWM AQI level.
+// The first one in the order of sortedComponents wins a tie. The level is 0 if no component has bands.
+func dominantComponent(components map[string]float64) (string, AirQualityIndex) {
+	var (
+		key   string
+		level AirQualityIndex
+	)
+	for _, k := range sortedComponents(components) {
+		if l := lookupComponent(k).level(components[k]); l > level {
+			key, level = k, l
+		}
+	}
+	return key, level
+}
+
+// rawText reports the AQI integer of OWM API of the latest DataPoint of the chat and the component driving it
+func (bot *Bot) rawText(chatID int64, p *message.Printer) string {
+	dp, err := bot.store.GetLastPD(chatID)
+	if err != nil {
+		log.Print("GetLastPD: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if !dp.GetAQI().Valid() {
+		return p.Sprintf(nearbyNoSessionMsg)
+	}
+
+	msgText := []string{p.Sprintf(rawAQITmpl, int(dp.GetAQI()), bot.themeOf(chatID).LocalizedAQI(dp.GetAQI(), p))}
+	if key, level := dominantComponent(dp.Components); level.Valid() {
+		msgText = append(msgText, p.Sprintf(rawDominantTmpl, lookupComponent(key).name, int(level)))
+	}
+	msgText = append(msgText,
+		HumanizeSince(time.Unix(dp.Dt, 0), p),
+		"",
+		p.Sprintf(rawScaleText),
+	)
+	return strings.Join(msgText, "\n")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestDominantComponent(t *testing.T) {
+	tests := []struct {
+		name       string
+		components map[string]float64
+		wantKey    string
+		wantLevel  AirQualityIndex
+	}{
+		{name: "no components"},
+		{name: "no bands", components: map[string]float64{"nh3": 500, "no": 30}},
+		{name: "particulate matter", components: map[string]float64{"pm2_5": 60, "o3": 68.66, "co": 201.94}, wantKey: "pm2_5", wantLevel: 4},
+		{name: "gas", components: map[string]float64{"pm2_5": 5, "no2": 210}, wantKey: "no2", wantLevel: 5},
+		{name: "tie wins the first", components: map[string]float64{"so2": 30, "pm10": 30}, wantKey: "pm10", wantLevel: 2},
+		{name: "test components", components: testComponents, wantKey: "o3", wantLevel: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if key, level := dominantComponent(tt.components); key != tt.wantKey || level != tt.wantLevel {
+				t.Errorf("dominantComponent() = %q, %v, want %q, %v", key, level, tt.wantKey, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestRawText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	tests := []struct {
+		name       string
+		aqi        AirQualityIndex
+		components map[string]float64
+		want       []string
+	}{
+		{name: "dominant component", aqi: 4, components: map[string]float64{"pm2_5": 60, "o3": 68.66},
+			want: []string{"OWM AQI: 4 of 5, " + ThemeEmoji.LocalizedAQI(4, p), p.Sprintf(rawDominantTmpl, "PM2.5", 4)}},
+		{name: "no bands", aqi: 1, components: map[string]float64{"nh3": 0.12},
+			want: []string{"OWM AQI: 1 of 5, " + ThemeEmoji.LocalizedAQI(1, p)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSession(t, store, 1, &Location{53.9, 27.56})
+			dps := []DataPoint{newTestDataPoint(tt.aqi, time.Now(), tt.components)}
+			if err := store.AddDataPoint(1, &dps); err != nil {
+				t.Fatal(err)
+			}
+			bot := newTestBot(store, &botServices{})
+
+			got := bot.rawText(1, p)
+			lines := strings.Split(got, "\n")
+			for i, want := range tt.want {
+				if i >= len(lines) || lines[i] != want {
+					t.Errorf("rawText() = %q, want the line %d %q", got, i+1, want)
+				}
+			}
+			if strings.Contains(got, "The highest pollutant level:") != (len(tt.want) > 1) {
+				t.Errorf("rawText() = %q, want the dominant component %v", got, len(tt.want) > 1)
+			}
+			if !strings.HasSuffix(got, "\n\n"+p.Sprintf(rawScaleText)) {
+				t.Errorf("rawText() = %q, want the note on the scale last", got)
+			}
+		})
+	}
+
+	bot := newTestBot(newTestStore(t), &botServices{})
+	if got := bot.rawText(1, p); got != p.Sprintf(nearbyNoSessionMsg) {
+		t.Errorf("rawText() without a DataPoint = %q, want %q", got, nearbyNoSessionMsg)
+	}
+}
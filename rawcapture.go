@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// rawResponsesLimit is the number of the raw responses listed by /owmraw
+const rawResponsesLimit = 5
+
+// rawResponsesText lists the most recent raw OWM API responses for the admin.
+// The argument optionally filters them by the coordinates.
+func (bot *Bot) rawResponsesText(arg string) string {
+	if bot.rawCaptureRetention <= 0 {
+		return "Raw capture is disabled. Set RAW_CAPTURE_RETENTION to enable it."
+	}
+	var near *Location
+	if strings.TrimSpace(arg) != "" {
+		l, err := ParseLocation(arg)
+		if err != nil {
+			return "Usage: /owmraw [latitude longitude]"
+		}
+		near = l
+	}
+	responses, err := bot.store.ListRawResponses(near, rawResponsesLimit)
+	if err != nil {
+		log.Print("ListRawResponses: ", err)
+		return safeToRetryErrMsg
+	}
+	if len(responses) == 0 {
+		return "No raw responses"
+	}
+	var msgText []string
+	for _, r := range responses {
+		msgText = append(msgText,
+			fmt.Sprintf("%s %f;%f", r.CreatedAt.Format(lastCheckedLayout), r.Latitude, r.Longitude),
+			r.Body,
+			"",
+		)
+	}
+	return strings.Join(msgText, "\n")
+}
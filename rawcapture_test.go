@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRawResponsesText(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.AddRawResponse(&Location{53.9, 27.56}, []byte(`{"list":[]}`), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		retention time.Duration
+		arg       string
+		want      string
+	}{
+		{name: "disabled", arg: "", want: "Raw capture is disabled"},
+		{name: "all", retention: time.Hour, arg: "", want: `53.900000;27.560000` + "\n" + `{"list":[]}`},
+		{name: "near", retention: time.Hour, arg: "53.9 27.56", want: `{"list":[]}`},
+		{name: "nothing near", retention: time.Hour, arg: "51.51 -0.13", want: "No raw responses"},
+		{name: "usage", retention: time.Hour, arg: "Minsk", want: "Usage: /owmraw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot := newTestBot(store, &botServices{rawCaptureRetention: tt.retention})
+			if got := bot.rawResponsesText(tt.arg); !strings.Contains(got, tt.want) {
+				t.Errorf("rawResponsesText(%q) = %q, want it to contain %q", tt.arg, got, tt.want)
+			}
+		})
+	}
+}
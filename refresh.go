@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	refreshUsageMsg   = "Usage: /refresh [N], where N is the number of the subscription in /subsriptions"
+	refreshNoSubsMsg  = "You have no subscriptions to refresh"
+	refreshedTmpl     = "%d. Location: %f;%f. AQI: %s"
+	refreshFailedTmpl = "%d. Location: %f;%f. Failed to get AQI, retry later"
+)
+
+// refreshText fetches the current AQI of the subscription number arg of the chat, or of all of them if arg is empty.
+// The cache is bypassed and the AQI stored in the subscription is updated.
+func (bot *Bot) refreshText(chatID int64, arg string, p *message.Printer) string {
+	subs, err := bot.store.ListAQISubscriptions(chatID)
+	if err != nil {
+		log.Print("ListAQISubscriptions: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if len(*subs) == 0 {
+		return p.Sprintf(refreshNoSubsMsg)
+	}
+
+	first, last := 1, len(*subs)
+	if arg = strings.TrimSpace(arg); arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(*subs) {
+			return p.Sprintf(refreshUsageMsg)
+		}
+		first, last = n, n
+	}
+
+	theme := bot.themeOf(chatID)
+	var msgText []string
+	for n := first; n <= last; n++ {
+		s := (*subs)[n-1]
+		aqi, err := bot.refreshSubscription(&s)
+		if err != nil {
+			log.Print("refreshSubscription: ", err)
+			msgText = append(msgText, p.Sprintf(refreshFailedTmpl, n, s.Latitude, s.Longitude))
+			continue
+		}
+		msgText = append(msgText, p.Sprintf(refreshedTmpl, n, s.Latitude, s.Longitude, theme.LocalizedAQI(aqi, p)))
+	}
+	return strings.Join(msgText, "\n")
+}
+
+// refreshSubscription gets the current AQI of the subscription from AQIProvider and stores it
+func (bot *Bot) refreshSubscription(s *AQISubscription) (AirQualityIndex, error) {
+	resp, err := bot.wAPI.GetAirPollution(&Location{s.Latitude, s.Longitude})
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.DP) == 0 {
+		return 0, errNoDataPoints
+	}
+	aqi := resp.DP[0].GetAQI()
+	if err := bot.store.UpdateSubscriptionAQI(s.ID, aqi); err != nil {
+		return 0, err
+	}
+	if err := bot.store.MarkSubscriptionChecked(s.ID, time.Now()); err != nil {
+		log.Print("MarkSubscriptionChecked: ", err)
+	}
+	return aqi, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestRefreshText(t *testing.T) {
+	a, b := &Location{53.9, 27.56}, &Location{52.1, 23.7}
+	p := message.NewPrinter(language.English)
+	tests := []struct {
+		name string
+		arg  string
+		// wantAQIs are the stored AQIs of the subscriptions after the refresh
+		wantAQIs     []AirQualityIndex
+		wantRequests int
+		wantText     []string
+	}{
+		{
+			name:         "all",
+			wantAQIs:     []AirQualityIndex{4, 1},
+			wantRequests: 2,
+			wantText: []string{
+				p.Sprintf(refreshedTmpl, 1, a.Latitude, a.Longitude, ThemeEmoji.AQI(4)),
+				p.Sprintf(refreshFailedTmpl, 2, b.Latitude, b.Longitude),
+			},
+		},
+		{
+			name:         "one",
+			arg:          "1",
+			wantAQIs:     []AirQualityIndex{4, 1},
+			wantRequests: 1,
+			// the latitude goes first like in the shared locations
+			wantText: []string{"1. Location: 53.900000;27.560000. AQI: " + ThemeEmoji.AQI(4)},
+		},
+		{name: "out of range", arg: "3", wantAQIs: []AirQualityIndex{1, 1}, wantText: []string{refreshUsageMsg}},
+		{name: "not a number", arg: "first", wantAQIs: []AirQualityIndex{1, 1}, wantText: []string{refreshUsageMsg}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, a, 1)
+			addTestSubscription(t, store, 1, b, 1)
+			// the cached DataPoint is bypassed
+			dps := []DataPoint{newTestDataPoint(1, time.Now(), nil)}
+			if err := store.AddDataPoint(1, &dps); err != nil {
+				t.Fatal(err)
+			}
+			var requests int
+			provider := aqiProviderFunc(func(l *Location) (*ApiPollutionResponse, error) {
+				requests++
+				if *l == *b {
+					return nil, errors.New("timeout")
+				}
+				return aqiResponse(l, 4), nil
+			})
+			bot := newTestBot(store, &botServices{wAPI: provider})
+
+			got := bot.refreshText(1, tt.arg, p)
+
+			if want := strings.Join(tt.wantText, "\n"); got != want {
+				t.Errorf("refreshText() = %q, want %q", got, want)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("%d OWM API requests, want %d", requests, tt.wantRequests)
+			}
+			subs, err := store.ListAQISubscriptions(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var aqis []AirQualityIndex
+			for _, s := range *subs {
+				aqis = append(aqis, s.AirQualityIndex)
+			}
+			if fmt.Sprint(aqis) != fmt.Sprint(tt.wantAQIs) {
+				t.Errorf("stored AQIs = %v, want %v", aqis, tt.wantAQIs)
+			}
+		})
+	}
+}
+
+func TestRefreshTextNoSubscriptions(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	bot := newTestBot(newTestStore(t), &botServices{wAPI: &fakeAQI{aqi: 2}})
+	if got := bot.refreshText(1, "", p); got != refreshNoSubsMsg {
+		t.Errorf("refreshText() = %q, want %q", got, refreshNoSubsMsg)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+// restoreWindow is how long the subscriptions removed by the cleanup button can be restored.
+// CronCleanup keeps them for it.
+const restoreWindow = 12 * time.Hour
+
+const (
+	restoredTmpl    = "OK. Restored subscriptions: %d"
+	restoreNoneText = "There are no recently removed subscriptions to restore"
+	restoreHintText = "Removed by mistake? /restore brings them back"
+)
+
+// restoreText enables again the subscriptions of the chat removed within restoreWindow
+func (bot *Bot) restoreText(chatID int64, p *message.Printer) string {
+	n, err := bot.store.RestoreRecentlyDisabled(chatID, time.Now().Add(-restoreWindow))
+	if err != nil {
+		log.Print("RestoreRecentlyDisabled: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if n == 0 {
+		return p.Sprintf(restoreNoneText)
+	}
+	return p.Sprintf(restoredTmpl, n)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestore(t *testing.T) {
+	store := newTestStore(t)
+	locations := []*Location{{53.9, 27.56}, {52.1, 23.7}}
+	for _, l := range locations {
+		addTestSubscription(t, store, 1, l, 2)
+	}
+	addTestSubscription(t, store, 2, locations[0], 2)
+	fake := &fakeTelegram{}
+	bot := newFakeTelegramBot(t, store, &botServices{}, fake)
+
+	bot.handleCallbackQuery(newTestCallbackQuery(t, 1, "en", callbackCleanup))
+	if got := fake.lastText(); !strings.HasSuffix(got, "\n"+restoreHintText) {
+		t.Errorf("cleanup reply = %q, want the hint %q", got, restoreHintText)
+	}
+	if n := countRows(t, store, "SELECT COUNT(*) FROM subscription WHERE chat_id=1 AND enabled=1"); n != 0 {
+		t.Fatalf("%d subscriptions after the cleanup, want 0", n)
+	}
+
+	for _, want := range []string{fmt.Sprintf(restoredTmpl, 2), restoreNoneText} {
+		bot.handleMessage(newTestCommand(1, "/restore"))
+		if got := fake.lastText(); got != want {
+			t.Errorf("/restore = %q, want %q", got, want)
+		}
+		if n := countRows(t, store, "SELECT COUNT(*) FROM subscription WHERE chat_id=1 AND enabled=1"); n != 2 {
+			t.Errorf("%d subscriptions after /restore, want 2", n)
+		}
+	}
+	// the subscriptions of another chat aren't touched
+	if n := countRows(t, store, "SELECT COUNT(*) FROM subscription WHERE chat_id=2 AND enabled=1"); n != 1 {
+		t.Errorf("%d subscriptions of another chat, want 1", n)
+	}
+}
+
+func TestCleanupDataPointRetention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention time.Duration
+		want      int
+	}{
+		{name: "old deleted", retention: 24 * time.Hour, want: 1},
+		{name: "all kept", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			dps := []DataPoint{newTestDataPoint(2, time.Now().Add(-48*time.Hour), nil), newTestDataPoint(3, time.Now(), nil)}
+			if err := store.AddDataPoint(1, &dps); err != nil {
+				t.Fatal(err)
+			}
+			bot := newTestBot(store, &botServices{dataPointRetention: tt.retention})
+
+			bot.CronCleanup()
+
+			if n := countRows(t, store, "SELECT COUNT(*) FROM data_point WHERE chat_id=1"); n != tt.want {
+				t.Errorf("%d data points kept by CronCleanup, want %d", n, tt.want)
+			}
+			if dp, err := store.GetLastPD(1); err != nil || dp.GetAQI() != 3 {
+				t.Errorf("GetLastPD() = %+v, %v, want the recent data point", dp, err)
+			}
+		})
+	}
+}
+
+func TestCleanupKeepsRestorable(t *testing.T) {
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	addTestSubscription(t, store, 1, &Location{52.1, 23.7}, 2)
+	addTestSubscription(t, store, 1, &Location{55.75, 37.62}, 2)
+	bot := newTestBot(store, &botServices{})
+	if err := store.DeleteAQISubscriptions(1); err != nil {
+		t.Fatal(err)
+	}
+	// disabled within the window, before it and before disabled_at was recorded
+	for lat, disabledAt := range map[float64]interface{}{
+		53.9:  time.Now().Add(-restoreWindow + time.Hour),
+		52.1:  time.Now().Add(-restoreWindow - time.Hour),
+		55.75: nil,
+	} {
+		if _, err := store.DB.Exec("UPDATE subscription SET disabled_at=? WHERE latitude=?", disabledAt, lat); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bot.CronCleanup()
+
+	if n := countRows(t, store, "SELECT COUNT(*) FROM subscription WHERE chat_id=1"); n != 1 {
+		t.Errorf("%d subscriptions kept by CronCleanup, want the one within the restore window", n)
+	}
+	if got := bot.restoreText(1, newLangPrinter("en")); got != fmt.Sprintf(restoredTmpl, 1) {
+		t.Errorf("restoreText() = %q, want %q", got, fmt.Sprintf(restoredTmpl, 1))
+	}
+	if sub := onlySubscription(t, store, 1); sub.Latitude != 53.9 {
+		t.Errorf("restored %v, want the one disabled within the window", sub.Location())
+	}
+}
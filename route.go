@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	// maxRouteWaypoints limits the OWM API requests of a /route command
+	maxRouteWaypoints = 10
+
+	routeUsageMsg      = "Usage: /route followed by 2 to %d waypoints, one per line or separated by |, e.g. /route 53.9 27.56 | 53.92 27.6"
+	routeHeaderText    = "AQI along the route:"
+	routeWaypointTmpl  = "%d. %.4f;%.4f: %s"
+	routeWorstTmpl     = "Worst: waypoint %d, %s"
+	routeAllFailedText = "No data for the waypoints. Please, retry!"
+)
+
+// parseWaypoints parses the waypoints of the /route argument. They are separated by new lines or |.
+func parseWaypoints(arg string) ([]*Location, error) {
+	fields := strings.FieldsFunc(arg, func(r rune) bool { return r == '\n' || r == '|' })
+	var waypoints []*Location
+	for _, f := range fields {
+		if strings.TrimSpace(f) == "" {
+			continue
+		}
+		l, err := ParseLocation(f)
+		if err != nil {
+			return nil, fmt.Errorf("waypoint %d: %w", len(waypoints)+1, err)
+		}
+		waypoints = append(waypoints, l)
+	}
+	if len(waypoints) < 2 || len(waypoints) > maxRouteWaypoints {
+		return nil, fmt.Errorf("expected 2 to %d waypoints, got %d", maxRouteWaypoints, len(waypoints))
+	}
+	return waypoints, nil
+}
+
+// routeText reports the current AQI at each waypoint of the /route argument and the worst one.
+// Waypoints failed to fetch are reported without data. Nothing is stored.
+func (bot *Bot) routeText(arg string, p *message.Printer) string {
+	waypoints, err := parseWaypoints(arg)
+	if err != nil {
+		return p.Sprintf(routeUsageMsg, maxRouteWaypoints)
+	}
+	points := make([]*nearbyPoint, len(waypoints))
+	for i, l := range waypoints {
+		points[i] = &nearbyPoint{location: l}
+	}
+	bot.fetchAQI(points, bot.previewAirPollution)
+
+	msgText := []string{p.Sprintf(routeHeaderText), ""}
+	worst := -1
+	for i, pt := range points {
+		value := p.Sprintf(nearbyNoDataText)
+		if pt.err == nil {
+			value = pt.aqi.LocalizedString(p)
+			if worst < 0 || pt.aqi > points[worst].aqi {
+				worst = i
+			}
+		}
+		msgText = append(msgText, p.Sprintf(routeWaypointTmpl, i+1, pt.location.Latitude, pt.location.Longitude, value))
+	}
+	if worst < 0 {
+		return p.Sprintf(routeAllFailedText)
+	}
+	msgText = append(msgText, "", p.Sprintf(routeWorstTmpl, worst+1, points[worst].aqi.LocalizedString(p)))
+	return strings.Join(msgText, "\n")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestParseWaypoints(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    []Location
+		wantErr bool
+	}{
+		{arg: "53.9 27.56 | 53.92 27.6", want: []Location{{53.9, 27.56}, {53.92, 27.6}}},
+		{arg: "53.9 27.56\n53.92 27.6\n\n53.95,27.7", want: []Location{{53.9, 27.56}, {53.92, 27.6}, {53.95, 27.7}}},
+		{arg: "53.9 27.56 || 53.92 27.6 | ", want: []Location{{53.9, 27.56}, {53.92, 27.6}}},
+		{arg: "", wantErr: true},
+		{arg: "53.9 27.56", wantErr: true},
+		{arg: "53.9 27.56 | Minsk", wantErr: true},
+		{arg: "53.9 27.56 | 91 27.6", wantErr: true},
+		{arg: strings.Repeat("53.9 27.56 | ", maxRouteWaypoints+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parseWaypoints(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWaypoints() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseWaypoints() = %d waypoints, want %d", len(got), len(tt.want))
+			}
+			for i, l := range got {
+				if *l != tt.want[i] {
+					t.Errorf("waypoint %d = %+v, want %+v", i+1, *l, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRouteText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	// the AQI of a waypoint is its longitude above 27, the ones at 27.9 fail
+	provider := aqiProviderFunc(func(l *Location) (*ApiPollutionResponse, error) {
+		if l.Longitude > 27.85 {
+			return nil, errors.New("timeout")
+		}
+		return aqiResponse(l, AirQualityIndex((l.Longitude-27)*10+0.5)), nil
+	})
+	aqi := func(i AirQualityIndex) string { return i.LocalizedString(p) }
+	tests := []struct {
+		name string
+		arg  string
+		want []string
+	}{
+		{name: "waypoints", arg: "53.9 27.1 | 53.9 27.3 | 53.9 27.2", want: []string{
+			"AQI along the route:",
+			"",
+			"1. 53.9000;27.1000: " + aqi(1),
+			"2. 53.9000;27.3000: " + aqi(3),
+			"3. 53.9000;27.2000: " + aqi(2),
+			"",
+			"Worst: waypoint 2, " + aqi(3),
+		}},
+		{name: "partial failure", arg: "53.9 27.9 | 53.9 27.4 | 53.9 27.5", want: []string{
+			"AQI along the route:",
+			"",
+			"1. 53.9000;27.9000: no data",
+			"2. 53.9000;27.4000: " + aqi(4),
+			"3. 53.9000;27.5000: " + aqi(5),
+			"",
+			"Worst: waypoint 3, " + aqi(5),
+		}},
+		{name: "the first worst", arg: "53.9 27.2 | 53.9 27.2", want: []string{
+			"AQI along the route:",
+			"",
+			"1. 53.9000;27.2000: " + aqi(2),
+			"2. 53.9000;27.2000: " + aqi(2),
+			"",
+			"Worst: waypoint 1, " + aqi(2),
+		}},
+		{name: "all failed", arg: "53.9 27.9 | 53.95 27.9", want: []string{routeAllFailedText}},
+		{name: "usage", arg: "53.9 27.1", want: []string{fmt.Sprintf(routeUsageMsg, maxRouteWaypoints)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot := newTestBot(newTestStore(t), &botServices{wAPI: provider, cronConcurrency: 2})
+			if got, want := bot.routeText(tt.arg, p), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("routeText() = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"golang.org/x/text/message"
+)
+
+// AQIScale selects the scale of the AQI in the messages
+type AQIScale string
+
+const (
+	// ScaleOWM is the AQI of OWM API from 1 to 5
+	ScaleOWM AQIScale = "owm"
+	// ScaleEPA is the AQI of US EPA from 0 to 500
+	ScaleEPA AQIScale = "epa"
+	// ScaleAQHI is the Air Quality Health Index of Canada from 1 to 10+
+	ScaleAQHI AQIScale = "aqhi"
+)
+
+const (
+	aqiScaleTmpl  = "%s (%s): %s"
+	scaleSetTmpl  = "OK. The AQI is shown in the %s scale"
+	scaleUsageMsg = "Usage: /scale owm|epa|aqhi"
+
+	epaGoodText      = "Good"
+	epaModerateText  = "Moderate"
+	epaSensitiveText = "Unhealthy for Sensitive Groups"
+	epaUnhealthyText = "Unhealthy"
+	epaVeryUnhealthy = "Very Unhealthy"
+	epaHazardousText = "Hazardous"
+	aqhiLowRiskText  = "Low risk"
+	aqhiModerateText = "Moderate risk"
+	aqhiHighRiskText = "High risk"
+	aqhiVeryHighText = "Very high risk"
+)
+
+const (
+	// epaTopValue is the US EPA AQI above the top breakpoint
+	epaTopValue = 500
+	// epaNoComponents is returned by EPAIndex without the pollutants of EPA
+	epaNoComponents = -1
+	// aqhiTopValue is the highest AQHI shown as a number. Higher ones are shown as 10+.
+	aqhiTopValue = 10
+
+	// molarVolume is the volume in liters of a mole of a gas at 25 °C and 1 atm
+	molarVolume  = 24.45
+	molarMassO3  = 48.00
+	molarMassNO2 = 46.01
+	molarMassSO2 = 64.07
+	molarMassCO  = 28.01
+)
+
+// epaBreakpoint is the upper concentration of an US EPA AQI category and the AQI of it.
+// The AQI is interpolated linearly between the breakpoints starting from 0;0.
+type epaBreakpoint struct {
+	concentration float64
+	aqi           float64
+}
+
+// epaComponent converts the concentration of a component in μg/m³ to the unit of the breakpoints
+type epaComponent struct {
+	convert     func(float64) float64
+	breakpoints []epaBreakpoint
+}
+
+// ugm3ToPPB converts the concentration of a gas of the molar mass from μg/m³ to ppb
+func ugm3ToPPB(c, molarMass float64) float64 {
+	return c * molarVolume / molarMass
+}
+
+func identity(c float64) float64 { return c }
+
+// epaComponents are the breakpoints of US EPA of 2024. The current concentrations are used instead of the averages
+// of the EPA rules, as OWM API provides those: 24h for PM, 8h for O₃ and CO, 1h for NO₂ and SO₂.
+var epaComponents = map[string]epaComponent{
+	"pm2_5": {identity, []epaBreakpoint{{9, 50}, {35.4, 100}, {55.4, 150}, {125.4, 200}, {225.4, 300}, {325.4, 500}}},
+	"pm10":  {identity, []epaBreakpoint{{54, 50}, {154, 100}, {254, 150}, {354, 200}, {424, 300}, {604, 500}}},
+	"o3": {func(c float64) float64 { return ugm3ToPPB(c, molarMassO3) / 1000 },
+		[]epaBreakpoint{{0.054, 50}, {0.070, 100}, {0.085, 150}, {0.105, 200}, {0.200, 300}, {0.604, 500}}},
+	"no2": {func(c float64) float64 { return ugm3ToPPB(c, molarMassNO2) },
+		[]epaBreakpoint{{53, 50}, {100, 100}, {360, 150}, {649, 200}, {1249, 300}, {2049, 500}}},
+	"so2": {func(c float64) float64 { return ugm3ToPPB(c, molarMassSO2) },
+		[]epaBreakpoint{{35, 50}, {75, 100}, {185, 150}, {304, 200}, {604, 300}, {1004, 500}}},
+	"co": {func(c float64) float64 { return ugm3ToPPB(c, molarMassCO) / 1000 },
+		[]epaBreakpoint{{4.4, 50}, {9.4, 100}, {12.4, 150}, {15.4, 200}, {30.4, 300}, {50.4, 500}}},
+}
+
+// index returns the US EPA AQI of the concentration in μg/m³. It is 500 above the top breakpoint.
+func (ec epaComponent) index(c float64) float64 {
+	c = ec.convert(c)
+	var lo epaBreakpoint
+	for _, hi := range ec.breakpoints {
+		if c <= hi.concentration {
+			return lo.aqi + (hi.aqi-lo.aqi)*(c-lo.concentration)/(hi.concentration-lo.concentration)
+		}
+		lo = hi
+	}
+	return epaTopValue
+}
+
+// EPAIndex returns the US EPA AQI of the components: the highest index of the pollutants.
+// It is epaNoComponents if none of the pollutants of EPA is in the components.
+func EPAIndex(components map[string]float64) int {
+	aqi := float64(epaNoComponents)
+	for k, c := range components {
+		if ec, ok := epaComponents[k]; ok {
+			aqi = math.Max(aqi, ec.index(math.Max(c, 0)))
+		}
+	}
+	return int(math.Round(aqi))
+}
+
+// AQHIIndex returns the Air Quality Health Index of Canada of the O₃, NO₂ and PM2.5 components.
+// The current concentrations are used instead of the 3h averages. ok is false without all of them.
+func AQHIIndex(components map[string]float64) (aqhi int, ok bool) {
+	o3, okO3 := components["o3"]
+	no2, okNO2 := components["no2"]
+	pm25, okPM := components["pm2_5"]
+	if !okO3 || !okNO2 || !okPM {
+		return 0, false
+	}
+	v := 1000 / 10.4 * ((math.Exp(0.000871*ugm3ToPPB(no2, molarMassNO2)) - 1) +
+		(math.Exp(0.000537*ugm3ToPPB(o3, molarMassO3)) - 1) +
+		(math.Exp(0.000487*pm25) - 1))
+	aqhi = int(math.Round(v))
+	if aqhi < 1 {
+		aqhi = 1
+	}
+	return aqhi, true
+}
+
+// epaCategory returns the emoji and the category of the US EPA AQI
+func epaCategory(aqi int) (string, string) {
+	switch {
+	case aqi <= 50:
+		return "🟢", epaGoodText
+	case aqi <= 100:
+		return "🟡", epaModerateText
+	case aqi <= 150:
+		return "🟠", epaSensitiveText
+	case aqi <= 200:
+		return "🔴", epaUnhealthyText
+	case aqi <= 300:
+		return "🟣", epaVeryUnhealthy
+	}
+	return "🟤", epaHazardousText
+}
+
+// aqhiCategory returns the emoji and the risk category of the AQHI
+func aqhiCategory(aqhi int) (string, string) {
+	switch {
+	case aqhi <= 3:
+		return "🟢", aqhiLowRiskText
+	case aqhi <= 6:
+		return "🟡", aqhiModerateText
+	case aqhi <= aqhiTopValue:
+		return "🔴", aqhiHighRiskText
+	}
+	return "🟣", aqhiVeryHighText
+}
+
+// Name returns the name of the scale
+func (sc AQIScale) Name() string {
+	switch sc {
+	case ScaleEPA:
+		return "US EPA"
+	case ScaleAQHI:
+		return "AQHI"
+	}
+	return "OWM"
+}
+
+// Format returns the AQI line of the DataPoint in the scale and the theme, e.g. "Air Quality Index (US EPA): 🟡 72 (Moderate)".
+// The AQI of OWM API is shown if the scale is OWM or the DataPoint lacks the components of the scale.
+func (sc AQIScale) Format(dp *DataPoint, theme Theme, p *message.Printer) string {
+	switch sc {
+	case ScaleEPA:
+		if aqi := EPAIndex(dp.Components); aqi != epaNoComponents {
+			emoji, category := epaCategory(aqi)
+			return p.Sprintf(aqiScaleTmpl, p.Sprintf(aqiText), sc.Name(), theme.Category(emoji, fmt.Sprint(aqi), p.Sprintf(category)))
+		}
+	case ScaleAQHI:
+		if aqhi, ok := AQHIIndex(dp.Components); ok {
+			emoji, category := aqhiCategory(aqhi)
+			value := fmt.Sprint(aqhi)
+			if aqhi > aqhiTopValue {
+				value = fmt.Sprint(aqhiTopValue, "+")
+			}
+			return p.Sprintf(aqiScaleTmpl, p.Sprintf(aqiText), sc.Name(), theme.Category(emoji, value, p.Sprintf(category)))
+		}
+	}
+	return p.Sprintf(aqiText) + ": " + theme.LocalizedAQI(dp.GetAQI(), p)
+}
+
+func parseAQIScale(arg string) (AQIScale, error) {
+	switch sc := AQIScale(strings.ToLower(strings.TrimSpace(arg))); sc {
+	case ScaleOWM, ScaleEPA, ScaleAQHI:
+		return sc, nil
+	}
+	return "", fmt.Errorf("unknown AQI scale %q", arg)
+}
+
+// scaleOf returns the AQIScale of UserPrefs of the chat
+func (bot *Bot) scaleOf(chatID int64) AQIScale {
+	prefs, err := bot.store.GetUserPrefs(chatID)
+	if err != nil {
+		log.Print("GetUserPrefs: ", err)
+	}
+	return prefs.Scale
+}
+
+// scaleText stores the AQIScale of the /scale argument in UserPrefs
+func (bot *Bot) scaleText(chatID int64, arg string, p *message.Printer) string {
+	scale, err := parseAQIScale(arg)
+	if err != nil {
+		return p.Sprintf(scaleUsageMsg)
+	}
+	prefs, err := bot.store.GetUserPrefs(chatID)
+	if err != nil {
+		log.Print("GetUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	prefs.Scale = scale
+	if err := bot.store.UpdateUserPrefs(prefs); err != nil {
+		log.Print("UpdateUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	return p.Sprintf(scaleSetTmpl, scale.Name())
+}
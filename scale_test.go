@@ -0,0 +1,171 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestEPAIndex(t *testing.T) {
+	tests := []struct {
+		name       string
+		components map[string]float64
+		want       int
+	}{
+		{name: "no pollutants of EPA", components: map[string]float64{"nh3": 10, "no": 5}, want: epaNoComponents},
+		{name: "zero", components: map[string]float64{"pm2_5": 0}, want: 0},
+		{name: "negative", components: map[string]float64{"pm2_5": -5}, want: 0},
+		{name: "at a breakpoint", components: map[string]float64{"pm2_5": 9}, want: 50},
+		{name: "interpolated", components: map[string]float64{"pm2_5": 22.2}, want: 75},
+		{name: "at the top breakpoint", components: map[string]float64{"pm10": 604}, want: 500},
+		{name: "above the top breakpoint", components: map[string]float64{"pm2_5": 1000}, want: epaTopValue},
+		{name: "gas in ppb", components: map[string]float64{"no2": 100 * molarMassNO2 / molarVolume}, want: 100},
+		{name: "gas in ppm", components: map[string]float64{"co": 9400 * molarMassCO / molarVolume}, want: 100},
+		{name: "the highest pollutant", components: map[string]float64{"pm2_5": 9, "pm10": 154, "nh3": 1000}, want: 100},
+		{name: "test components", components: testComponents, want: 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EPAIndex(tt.components); got != tt.want {
+				t.Errorf("EPAIndex() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAQHIIndex(t *testing.T) {
+	tests := []struct {
+		name       string
+		components map[string]float64
+		want       int
+		wantOK     bool
+	}{
+		{name: "no NO₂", components: map[string]float64{"o3": 68.66, "pm2_5": 0.5}},
+		{name: "clean air", components: map[string]float64{"o3": 0, "no2": 0, "pm2_5": 0}, want: 1, wantOK: true},
+		{name: "test components", components: testComponents, want: 2, wantOK: true},
+		{name: "particulate matter", components: map[string]float64{"o3": 0, "no2": 0, "pm2_5": 100}, want: 5, wantOK: true},
+		{name: "high", components: map[string]float64{"o3": 120, "no2": 80, "pm2_5": 60}, want: 10, wantOK: true},
+		{name: "above the top", components: map[string]float64{"o3": 300, "no2": 300, "pm2_5": 300}, want: 38, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := AQHIIndex(tt.components)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("AQHIIndex() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAQIScaleFormat(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	// the same DataPoint is rendered in each scale
+	dp := newTestDataPoint(2, time.Now(), map[string]float64{"o3": 120, "no2": 80, "pm2_5": 60, "co": 300})
+	high := newTestDataPoint(5, time.Now(), map[string]float64{"o3": 300, "no2": 300, "pm2_5": 300})
+	noComponents := newTestDataPoint(3, time.Now(), map[string]float64{"nh3": 1})
+	tests := []struct {
+		name  string
+		scale AQIScale
+		dp    *DataPoint
+		theme Theme
+		want  string
+	}{
+		{name: "owm", scale: ScaleOWM, dp: &dp, theme: ThemeEmoji, want: "Air Quality Index: " + ThemeEmoji.LocalizedAQI(2, p)},
+		{name: "default", dp: &dp, theme: ThemeEmoji, want: "Air Quality Index: " + ThemeEmoji.LocalizedAQI(2, p)},
+		{name: "epa", scale: ScaleEPA, dp: &dp, theme: ThemeEmoji, want: "Air Quality Index (US EPA): 🔴 153 (Unhealthy)"},
+		{name: "epa plain", scale: ScaleEPA, dp: &dp, theme: ThemePlain, want: "Air Quality Index (US EPA): 153 (Unhealthy)"},
+		{name: "aqhi", scale: ScaleAQHI, dp: &dp, theme: ThemeEmoji, want: "Air Quality Index (AQHI): 🔴 10 (High risk)"},
+		{name: "aqhi above the top", scale: ScaleAQHI, dp: &high, theme: ThemeEmoji, want: "Air Quality Index (AQHI): 🟣 10+ (Very high risk)"},
+		{name: "epa without the components", scale: ScaleEPA, dp: &noComponents, theme: ThemeEmoji, want: "Air Quality Index: " + ThemeEmoji.LocalizedAQI(3, p)},
+		{name: "aqhi without the components", scale: ScaleAQHI, dp: &noComponents, theme: ThemeEmoji, want: "Air Quality Index: " + ThemeEmoji.LocalizedAQI(3, p)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scale.Format(tt.dp, tt.theme, p); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	ru := message.NewPrinter(language.Russian)
+	if got := ScaleEPA.Format(&dp, ThemeEmoji, ru); got == ScaleEPA.Format(&dp, ThemeEmoji, p) {
+		t.Errorf("Format() in Russian = %q, the same as in English", got)
+	}
+}
+
+func TestEPACategory(t *testing.T) {
+	tests := []struct {
+		aqi      int
+		emoji    string
+		category string
+	}{
+		{0, "🟢", epaGoodText}, {50, "🟢", epaGoodText},
+		{51, "🟡", epaModerateText}, {100, "🟡", epaModerateText},
+		{101, "🟠", epaSensitiveText}, {150, "🟠", epaSensitiveText},
+		{151, "🔴", epaUnhealthyText}, {200, "🔴", epaUnhealthyText},
+		{201, "🟣", epaVeryUnhealthy}, {300, "🟣", epaVeryUnhealthy},
+		{301, "🟤", epaHazardousText}, {500, "🟤", epaHazardousText},
+	}
+	for _, tt := range tests {
+		if emoji, category := epaCategory(tt.aqi); emoji != tt.emoji || category != tt.category {
+			t.Errorf("epaCategory(%d) = %s %q, want %s %q", tt.aqi, emoji, category, tt.emoji, tt.category)
+		}
+	}
+}
+
+func TestScaleText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	store := newTestStore(t)
+	bot := newTestBot(store, &botServices{})
+	tests := []struct {
+		arg   string
+		want  string
+		scale AQIScale
+	}{
+		{arg: "epa", want: "OK. The AQI is shown in the US EPA scale", scale: ScaleEPA},
+		{arg: " AQHI ", want: "OK. The AQI is shown in the AQHI scale", scale: ScaleAQHI},
+		{arg: "caqi", want: scaleUsageMsg, scale: ScaleAQHI},
+		{arg: "owm", want: "OK. The AQI is shown in the OWM scale", scale: ScaleOWM},
+	}
+	for _, tt := range tests {
+		if got := bot.scaleText(1, tt.arg, p); got != tt.want {
+			t.Errorf("scaleText(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+		if got := bot.scaleOf(1); got != tt.scale {
+			t.Errorf("scale after %q = %q, want %q", tt.arg, got, tt.scale)
+		}
+	}
+}
+
+func TestScaleOnDemandAndCron(t *testing.T) {
+	components := map[string]float64{"o3": 120, "no2": 80, "pm2_5": 60}
+	for _, tt := range []struct {
+		scale AQIScale
+		want  string
+	}{
+		{scale: ScaleOWM, want: "Air Quality Index: "},
+		{scale: ScaleEPA, want: "Air Quality Index (US EPA): 🔴 153 (Unhealthy)"},
+		{scale: ScaleAQHI, want: "Air Quality Index (AQHI): 🔴 10 (High risk)"},
+	} {
+		t.Run(string(tt.scale), func(t *testing.T) {
+			store := newTestStore(t)
+			aqi := &fakeAQI{aqi: 2, components: components}
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: aqi}, fake)
+			bot.scaleText(1, string(tt.scale), message.NewPrinter(language.English))
+
+			bot.handleMessage(newTestLocationMessage(1, &Location{53.9, 27.56}))
+			if got := fake.lastText(); !strings.Contains(got, tt.want) {
+				t.Errorf("AQI message = %q, want %q", got, tt.want)
+			}
+			addTestSubscription(t, store, 1, &Location{52.1, 23.7}, 1)
+			bot.Cron()
+			if got := fake.lastText(); !strings.Contains(got, tt.want) {
+				t.Errorf("notification = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	snoozeSetTmpl  = "OK. Notifications are paused until %s"
+	snoozeOffText  = "OK. Notifications are resumed"
+	snoozeUsageMsg = "Usage: /snooze <duration>, e.g. /snooze 24h, or /snooze off"
+)
+
+// parseSnooze parses the argument of the /snooze command. 0 means resuming the notifications.
+func parseSnooze(arg string) (time.Duration, error) {
+	arg = strings.TrimSpace(arg)
+	if arg == "off" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(arg)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("snooze duration must be positive")
+	}
+	return d, nil
+}
+
+// snoozeText pauses the notifications of the subscriptions of the chat for the duration of the /snooze argument
+func (bot *Bot) snoozeText(chatID int64, arg string, p *message.Printer) string {
+	d, err := parseSnooze(arg)
+	if err != nil {
+		return p.Sprintf(snoozeUsageMsg)
+	}
+	var until time.Time
+	if d > 0 {
+		until = time.Now().Add(d)
+	}
+	if err := bot.store.SnoozeSubscriptions(chatID, until); err != nil {
+		log.Print("SnoozeSubscriptions: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if until.IsZero() {
+		return p.Sprintf(snoozeOffText)
+	}
+	return p.Sprintf(snoozeSetTmpl, until.Format(lastCheckedLayout))
+}
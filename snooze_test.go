@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestParseSnooze(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{arg: "24h", want: 24 * time.Hour},
+		{arg: " 90m ", want: 90 * time.Minute},
+		{arg: "off", want: 0},
+		{arg: "", wantErr: true},
+		{arg: "tomorrow", wantErr: true},
+		{arg: "0s", wantErr: true},
+		{arg: "-1h", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSnooze(tt.arg)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseSnooze(%q) = %v, %v, want %v, error %v", tt.arg, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCronSnooze(t *testing.T) {
+	tests := []struct {
+		name string
+		// until is the snooze of the subscription from now. Not snoozed if zero.
+		until       time.Duration
+		wantChecked bool
+	}{
+		{name: "not snoozed", wantChecked: true},
+		{name: "active snooze", until: time.Hour},
+		{name: "expired snooze", until: -time.Minute, wantChecked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+			if tt.until != 0 {
+				if err := store.SnoozeSubscriptions(1, time.Now().Add(tt.until)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			aqi := &fakeAQI{aqi: 4}
+			fake := &fakeTelegram{}
+			bot := newTestCronBot(t, store, aqi, fake)
+
+			bot.Cron()
+
+			if checked := aqi.requests() > 0; checked != tt.wantChecked {
+				t.Errorf("subscription checked = %v, want %v", checked, tt.wantChecked)
+			}
+			if notified := len(fake.sent("sendMessage")) > 0; notified != tt.wantChecked {
+				t.Errorf("notified = %v, want %v", notified, tt.wantChecked)
+			}
+		})
+	}
+}
+
+func TestSnoozeText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	bot := newTestBot(store, &botServices{})
+
+	if got := bot.snoozeText(1, "later", p); got != snoozeUsageMsg {
+		t.Errorf("snoozeText(later) = %q, want %q", got, snoozeUsageMsg)
+	}
+	bot.snoozeText(1, "24h", p)
+	if until := onlySubscription(t, store, 1).SnoozedUntil; time.Until(until) < 23*time.Hour || time.Until(until) > 24*time.Hour {
+		t.Errorf("snoozed until %v, want in 24h", until)
+	}
+	if got := bot.snoozeText(1, "off", p); got != snoozeOffText {
+		t.Errorf("snoozeText(off) = %q, want %q", got, snoozeOffText)
+	}
+	if until := onlySubscription(t, store, 1).SnoozedUntil; !until.IsZero() {
+		t.Errorf("snoozed until %v after off, want zero", until)
+	}
+}
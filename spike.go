@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/text/message"
+)
+
+// DefaultSpikeRatio is the rise of a pollutant between two checks of a subscription notified as a spike
+const DefaultSpikeRatio = 2
+
+const (
+	spikeHeaderMsg = "📈 A sharp rise of a pollutant"
+	spikeRiseTmpl  = "%s rose from %.2f to %.2f %s"
+)
+
+// spikedComponent returns the key of the pollutant with the highest rise from the prev components to the cur ones
+// of at least ratio times. Only the pollutants of the OWM AQI rising above the good level are considered,
+// so the noise of the clean air isn't notified. ok is false if there is no such pollutant.
+func spikedComponent(prev, cur map[string]float64, ratio float64) (key string, ok bool) {
+	var highest float64
+	for _, k := range sortedComponents(cur) {
+		old, v := prev[k], cur[k]
+		if old <= 0 || v < ratio*old || lookupComponent(k).level(v) <= 1 {
+			continue
+		}
+		if rise := v / old; rise > highest {
+			key, highest = k, rise
+		}
+	}
+	return key, key != ""
+}
+
+// spikeOf returns the key of the pollutant of the DataPoint spiked since the last check of the subscription
+func (bot *Bot) spikeOf(s *AQISubscription, dp *DataPoint) (string, bool) {
+	if bot.spikeRatio <= 0 || s.Components == nil {
+		return "", false
+	}
+	return spikedComponent(s.Components, dp.Components, bot.spikeRatio)
+}
+
+// formatSpikeNotification returns the text Cron notifies about the spike of the pollutant of the subscription
+// within the same AQI in the style of the UserPrefs of the chat
+func (bot *Bot) formatSpikeNotification(s *AQISubscription, dp *DataPoint, key string, prefs *UserPrefs, p *message.Printer) string {
+	c := lookupComponent(key)
+	header := p.Sprintf(spikeHeaderMsg)
+	if s.Label != "" {
+		header += "\n" + p.Sprintf(subscriptionLabelTmpl, s.Label)
+	}
+	header += "\n" + p.Sprintf(spikeRiseTmpl, c.name, s.Components[key], dp.Components[key], c.unit.Localized(p))
+	msgText := []string{FormatAQIMessage(dp, p, header, prefs.Profile, prefs.Scale, prefs.Theme)}
+	if w := bot.coWarning(dp, p); w != "" {
+		msgText = append(msgText, "", w)
+	}
+	return strings.Join(msgText, "\n")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpikedComponent(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur map[string]float64
+		ratio     float64
+		want      string
+	}{
+		{name: "doubled", prev: map[string]float64{"pm2_5": 12}, cur: map[string]float64{"pm2_5": 24}, ratio: 2, want: "pm2_5"},
+		{name: "below the ratio", prev: map[string]float64{"pm2_5": 12}, cur: map[string]float64{"pm2_5": 23.9}, ratio: 2},
+		{name: "fell", prev: map[string]float64{"pm2_5": 40}, cur: map[string]float64{"pm2_5": 12}, ratio: 2},
+		{name: "within the good level", prev: map[string]float64{"pm2_5": 2}, cur: map[string]float64{"pm2_5": 9}, ratio: 2},
+		{name: "no bands", prev: map[string]float64{"nh3": 1}, cur: map[string]float64{"nh3": 100}, ratio: 2},
+		{name: "new pollutant", prev: map[string]float64{"pm10": 10}, cur: map[string]float64{"pm2_5": 30, "pm10": 10}, ratio: 2},
+		{name: "zero before", prev: map[string]float64{"pm2_5": 0}, cur: map[string]float64{"pm2_5": 30}, ratio: 2},
+		{name: "the highest rise", prev: map[string]float64{"pm2_5": 12, "no2": 20, "o3": 50},
+			cur: map[string]float64{"pm2_5": 30, "no2": 80, "o3": 70}, ratio: 2, want: "no2"},
+		{name: "lower ratio", prev: map[string]float64{"o3": 50}, cur: map[string]float64{"o3": 76}, ratio: 1.5, want: "o3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := spikedComponent(tt.prev, tt.cur, tt.ratio)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("spikedComponent() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronSpike(t *testing.T) {
+	tests := []struct {
+		name  string
+		ratio float64
+		// prev are the components of the last check, none if nil
+		prev map[string]float64
+		cur  map[string]float64
+		// aqi is the AQI of the check, the subscribed one is 2
+		aqi  AirQualityIndex
+		want []string
+	}{
+		{name: "pm2.5 spike within the AQI", ratio: 2, prev: map[string]float64{"pm2_5": 12}, cur: map[string]float64{"pm2_5": 30}, aqi: 2,
+			want: []string{spikeHeaderMsg, "PM2.5 rose from 12.00 to 30.00"}},
+		{name: "below the ratio", ratio: 2, prev: map[string]float64{"pm2_5": 12}, cur: map[string]float64{"pm2_5": 20}, aqi: 2},
+		{name: "disabled", prev: map[string]float64{"pm2_5": 12}, cur: map[string]float64{"pm2_5": 30}, aqi: 2},
+		{name: "first check", ratio: 2, cur: map[string]float64{"pm2_5": 30}, aqi: 2},
+		{name: "AQI changed", ratio: 2, prev: map[string]float64{"pm2_5": 12}, cur: map[string]float64{"pm2_5": 30}, aqi: 3,
+			want: []string{"Air Quality Index"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+			sub := onlySubscription(t, store, 1)
+			if tt.prev != nil {
+				if err := store.SetSubscriptionComponents(sub.ID, tt.prev); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: tt.aqi, components: tt.cur}, spikeRatio: tt.ratio}, fake)
+
+			bot.Cron()
+
+			texts := fake.texts()
+			if len(tt.want) == 0 {
+				if len(texts) != 0 {
+					t.Errorf("notifications %q, want none", texts)
+				}
+			} else if len(texts) != 1 {
+				t.Fatalf("notifications %q, want one", texts)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(texts[0], want) {
+					t.Errorf("notification = %q, want %q", texts[0], want)
+				}
+			}
+			if tt.aqi == 3 && strings.Contains(texts[0], spikeHeaderMsg) {
+				t.Errorf("notification = %q, want the AQI change, not the spike", texts[0])
+			}
+			// the next check is compared with this one
+			if got := onlySubscription(t, store, 1).Components; got["pm2_5"] != tt.cur["pm2_5"] {
+				t.Errorf("stored components = %v, want %v", got, tt.cur)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// startLocationPrefix starts the payload of a location, e.g. loc_5390000_2756000 for 53.9;27.56
+	startLocationPrefix = "loc_"
+	// startReferralPrefix starts the payload of a referral code, e.g. ref_channel
+	startReferralPrefix = "ref_"
+	// startCoordinateScale is the factor of the integer coordinates of a location payload. Payloads can't contain dots.
+	startCoordinateScale = 1e5
+)
+
+// startPayloadRe matches the characters Telegram allows in the payload of t.me/Bot?start=PAYLOAD
+var startPayloadRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// startReferrals counts the /start commands by the referral code
+var startReferrals = expvar.NewMap("start_referrals")
+
+const (
+	// maxReferralCodes is the number of the distinct referral codes counted if none are configured
+	maxReferralCodes = 100
+	// otherReferral counts the referral codes not configured or above maxReferralCodes
+	otherReferral = "other"
+)
+
+// referralCounter counts the /start commands by the referral code in startReferrals. The codes are sent by users,
+// so only the configured ones are counted by name, or the first maxReferralCodes ones if none are configured.
+// The rest are counted as otherReferral.
+type referralCounter struct {
+	codes map[string]bool
+	mu    sync.Mutex
+	// counted is the number of the keys of startReferrals
+	counted int
+}
+
+// Count adds a /start command of the referral code
+func (rc *referralCounter) Count(code string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	switch {
+	case len(rc.codes) > 0 && !rc.codes[code]:
+		code = otherReferral
+	case len(rc.codes) == 0 && startReferrals.Get(code) == nil && rc.counted >= maxReferralCodes:
+		code = otherReferral
+	}
+	if startReferrals.Get(code) == nil {
+		rc.counted++
+	}
+	startReferrals.Add(code, 1)
+}
+
+// startPayload is the argument of /start sent by a deep link
+type startPayload struct {
+	location *Location
+	referral string
+}
+
+// parseStartPayload parses the /start argument. It returns nil if there is no payload.
+func parseStartPayload(payload string) (*startPayload, error) {
+	if payload == "" {
+		return nil, nil
+	}
+	if !startPayloadRe.MatchString(payload) {
+		return nil, fmt.Errorf("invalid start payload %q", payload)
+	}
+	switch {
+	case strings.HasPrefix(payload, startLocationPrefix):
+		lat, lon, ok := strings.Cut(strings.TrimPrefix(payload, startLocationPrefix), "_")
+		if !ok {
+			return nil, fmt.Errorf("no longitude in start payload %q", payload)
+		}
+		l, err := parseScaledLocation(lat, lon)
+		if err != nil {
+			return nil, fmt.Errorf("start payload %q: %w", payload, err)
+		}
+		return &startPayload{location: l}, nil
+	case strings.HasPrefix(payload, startReferralPrefix):
+		code := strings.TrimPrefix(payload, startReferralPrefix)
+		if code == "" {
+			return nil, errors.New("empty referral code")
+		}
+		return &startPayload{referral: code}, nil
+	}
+	return nil, fmt.Errorf("unknown start payload %q", payload)
+}
+
+// parseScaledLocation returns the location of the coordinates multiplied by startCoordinateScale
+func parseScaledLocation(lat, lon string) (*Location, error) {
+	la, err := strconv.ParseInt(lat, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("latitude: %w", err)
+	}
+	lo, err := strconv.ParseInt(lon, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("longitude: %w", err)
+	}
+	l := &Location{float64(la) / startCoordinateScale, float64(lo) / startCoordinateScale}
+	if !l.InRange() {
+		return nil, fmt.Errorf("coordinates %f;%f are out of range", l.Latitude, l.Longitude)
+	}
+	return l, nil
+}
+
+// locationMessage returns a copy of the msg sharing the location, as if the user sent it
+func locationMessage(msg *tgbotapi.Message, l *Location) *tgbotapi.Message {
+	m := *msg
+	m.Text = ""
+	m.Entities = nil
+	m.Location = &tgbotapi.Location{Latitude: l.Latitude, Longitude: l.Longitude}
+	return &m
+}
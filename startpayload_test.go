@@ -0,0 +1,152 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseStartPayload(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    *startPayload
+		wantErr bool
+	}{
+		{payload: ""},
+		{payload: "loc_5390000_2756000", want: &startPayload{location: &Location{53.9, 27.56}}},
+		{payload: "loc_-3386880_1842330", want: &startPayload{location: &Location{-33.8688, 18.4233}}},
+		{payload: "loc_9000000_-18000000", want: &startPayload{location: &Location{90, -180}}},
+		{payload: "ref_channel", want: &startPayload{referral: "channel"}},
+		{payload: "ref_summer-2024", want: &startPayload{referral: "summer-2024"}},
+		{payload: "loc_5390000", wantErr: true},
+		{payload: "loc_53.9_27.56", wantErr: true},
+		{payload: "loc_9000001_0", wantErr: true},
+		{payload: "loc_0_18000001", wantErr: true},
+		{payload: "loc_minsk_city", wantErr: true},
+		{payload: "ref_", wantErr: true},
+		{payload: "ref channel", wantErr: true},
+		{payload: "ref_" + strings.Repeat("a", 61), wantErr: true},
+		{payload: "promo", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.payload, func(t *testing.T) {
+			got, err := parseStartPayload(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStartPayload() error = %v, want error %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("parseStartPayload() = %+v, want %+v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			if got.referral != tt.want.referral {
+				t.Errorf("referral = %q, want %q", got.referral, tt.want.referral)
+			}
+			if (got.location == nil) != (tt.want.location == nil) ||
+				got.location != nil && *got.location != *tt.want.location {
+				t.Errorf("location = %+v, want %+v", got.location, tt.want.location)
+			}
+		})
+	}
+}
+
+func TestStartCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		// location is the location of the session after the command, no session if nil
+		location *Location
+		referral string
+	}{
+		{name: "no payload", command: "/start"},
+		{name: "location", command: "/start loc_5390000_2756000", location: &Location{53.9, 27.56}},
+		{name: "referral", command: "/start ref_start_test", referral: "start_test"},
+		{name: "invalid payload", command: "/start loc_minsk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			fake := &fakeTelegram{}
+			bot := newTestCronBot(t, store, &fakeAQI{aqi: 2}, fake)
+			var before int64
+			if tt.referral != "" {
+				before = startReferralCount(tt.referral)
+			}
+
+			bot.handleMessage(newTestCommand(1, tt.command))
+
+			sent := fake.sent("sendMessage")
+			if len(sent) == 0 || !strings.Contains(sent[0].Get("text"), helpAQICmdMsg) {
+				t.Fatalf("%s sent %v, want the help first", tt.command, sent)
+			}
+			session, err := store.GetSessionByChatID(1)
+			if tt.location == nil {
+				if len(sent) != 1 {
+					t.Errorf("%s sent %d messages, want only the help", tt.command, len(sent))
+				}
+				if err == nil {
+					t.Errorf("%s stored the session %+v, want none", tt.command, session)
+				}
+			} else {
+				if len(sent) != 2 || !strings.Contains(sent[1].Get("text"), aqiText) {
+					t.Errorf("%s sent %v, want the help and the AQI of the location", tt.command, sent)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if l := session.Location(); *l != *tt.location {
+					t.Errorf("%s stored the session of %+v, want %+v", tt.command, *l, *tt.location)
+				}
+			}
+			if tt.referral != "" {
+				if n := startReferralCount(tt.referral) - before; n != 1 {
+					t.Errorf("%s counted the referral %d time(s), want 1", tt.command, n)
+				}
+			}
+		})
+	}
+}
+
+// startReferralCount returns the number of the /start commands of the referral code in startReferrals
+func startReferralCount(code string) int64 {
+	v := startReferrals.Get(code)
+	if v == nil {
+		return 0
+	}
+	return v.(*expvar.Int).Value()
+}
+
+func TestReferralCounter(t *testing.T) {
+	configured := &referralCounter{codes: map[string]bool{"counter_channel": true}}
+	other := startReferralCount(otherReferral)
+	configured.Count("counter_channel")
+	configured.Count("counter_unknown")
+	if n := startReferralCount("counter_channel"); n != 1 {
+		t.Errorf("configured code counted %d time(s), want 1", n)
+	}
+	if startReferrals.Get("counter_unknown") != nil {
+		t.Error("not configured code is counted by name")
+	}
+	if n := startReferralCount(otherReferral) - other; n != 1 {
+		t.Errorf("%s counted %d time(s), want 1", otherReferral, n)
+	}
+
+	// without configured codes only the first maxReferralCodes ones are counted by name
+	bounded := &referralCounter{}
+	other = startReferralCount(otherReferral)
+	for i := 0; i < maxReferralCodes+10; i++ {
+		bounded.Count(fmt.Sprintf("counter_%d", i))
+	}
+	bounded.Count("counter_0")
+	if n := startReferralCount("counter_0"); n != 2 {
+		t.Errorf("counter_0 counted %d time(s), want 2", n)
+	}
+	if startReferrals.Get(fmt.Sprintf("counter_%d", maxReferralCodes)) != nil {
+		t.Errorf("code above maxReferralCodes is counted by name")
+	}
+	if n := startReferralCount(otherReferral) - other; n != 10 {
+		t.Errorf("%s counted %d time(s), want 10", otherReferral, n)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	statsMeHeaderTmpl   = "📊 Your AQI over the last %d days"
+	statsMeNoSubsMsg    = "You have no subscriptions yet. Share your location and subscribe to get statistics"
+	statsMeLocationTmpl = "Location: %f;%f"
+	statsMeAQITmpl      = "Average AQI: %.1f, peak: %s, checks: %d"
+	statsMeAlertsTmpl   = "Alerts: %d"
+	statsMeStreakTmpl   = "Longest good air streak: %d h"
+	statsMeNoDataText   = "No checks in this period yet"
+)
+
+// aqiHistoryStats aggregates the AQI history of a subscription
+type aqiHistoryStats struct {
+	Checks     int
+	AverageAQI float64
+	PeakAQI    AirQualityIndex
+	Alerts     int
+	// GoodStreak is the longest time between the first and the last of the checks in a row with the good AQI
+	GoodStreak time.Duration
+}
+
+// summarizeAQIHistory aggregates the entries ordered by time
+func summarizeAQIHistory(entries []AQIHistoryEntry) aqiHistoryStats {
+	var (
+		st        aqiHistoryStats
+		sum       int
+		goodSince time.Time
+	)
+	for _, e := range entries {
+		st.Checks++
+		sum += int(e.AQI)
+		if e.AQI > st.PeakAQI {
+			st.PeakAQI = e.AQI
+		}
+		if e.Alerted {
+			st.Alerts++
+		}
+
+		if e.AQI != 1 {
+			goodSince = time.Time{}
+			continue
+		}
+		if goodSince.IsZero() {
+			goodSince = e.CreatedAt
+		}
+		if streak := e.CreatedAt.Sub(goodSince); streak > st.GoodStreak {
+			st.GoodStreak = streak
+		}
+	}
+	if st.Checks > 0 {
+		st.AverageAQI = float64(sum) / float64(st.Checks)
+	}
+	return st
+}
+
+// statsMeText summarizes the AQI history of each subscription of the chat over digestPeriod.
+// The history is kept by CronCleanup for digestPeriod.
+func (bot *Bot) statsMeText(chatID int64, p *message.Printer) string {
+	subs, err := bot.store.ListAQISubscriptions(chatID)
+	if err != nil {
+		log.Print("ListAQISubscriptions: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if len(*subs) == 0 {
+		return p.Sprintf(statsMeNoSubsMsg)
+	}
+
+	since := time.Now().Add(-digestPeriod)
+	theme := bot.themeOf(chatID)
+	msgText := []string{p.Sprintf(statsMeHeaderTmpl, int(digestPeriod/(24*time.Hour)))}
+	for _, s := range *subs {
+		msgText = append(msgText, "")
+		if s.Label != "" {
+			msgText = append(msgText, p.Sprintf(subscriptionLabelTmpl, s.Label))
+		}
+		msgText = append(msgText, p.Sprintf(statsMeLocationTmpl, s.Latitude, s.Longitude))
+
+		entries, err := bot.store.ListAQIHistory(s.ID, since)
+		if err != nil {
+			log.Print("ListAQIHistory: ", err)
+			return p.Sprintf(safeToRetryErrMsg)
+		}
+		if len(entries) == 0 {
+			msgText = append(msgText, p.Sprintf(statsMeNoDataText))
+			continue
+		}
+		st := summarizeAQIHistory(entries)
+		msgText = append(msgText,
+			p.Sprintf(statsMeAQITmpl, st.AverageAQI, theme.LocalizedAQI(st.PeakAQI, p), st.Checks),
+			p.Sprintf(statsMeAlertsTmpl, st.Alerts),
+			p.Sprintf(statsMeStreakTmpl, int(st.GoodStreak/time.Hour)),
+		)
+	}
+	return strings.Join(msgText, "\n")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestSummarizeAQIHistory(t *testing.T) {
+	start := time.Now().Add(-72 * time.Hour)
+	// entry returns an entry of the AQI checked h hours after the start
+	entry := func(h int, aqi AirQualityIndex, alerted bool) AQIHistoryEntry {
+		return AQIHistoryEntry{AQI: aqi, CreatedAt: start.Add(time.Duration(h) * time.Hour), Alerted: alerted}
+	}
+	tests := []struct {
+		name    string
+		entries []AQIHistoryEntry
+		want    aqiHistoryStats
+	}{
+		{name: "no entries", want: aqiHistoryStats{}},
+		{name: "single good check", entries: []AQIHistoryEntry{entry(0, 1, false)},
+			want: aqiHistoryStats{Checks: 1, AverageAQI: 1, PeakAQI: 1}},
+		{name: "across days", entries: []AQIHistoryEntry{
+			entry(0, 1, false), entry(6, 1, false), entry(12, 1, false),
+			entry(24, 4, true), entry(30, 2, true),
+			entry(48, 1, true), entry(60, 1, false), entry(72, 1, false),
+		}, want: aqiHistoryStats{Checks: 8, AverageAQI: 12.0 / 8, PeakAQI: 4, Alerts: 3, GoodStreak: 24 * time.Hour}},
+		{name: "no good air", entries: []AQIHistoryEntry{entry(0, 3, true), entry(24, 5, true), entry(48, 3, true)},
+			want: aqiHistoryStats{Checks: 3, AverageAQI: 11.0 / 3, PeakAQI: 5, Alerts: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizeAQIHistory(tt.entries); got != tt.want {
+				t.Errorf("summarizeAQIHistory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsMeText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	addTestSubscription(t, store, 1, &Location{52.1, 23.7}, 2)
+	subs, err := store.ListAQISubscriptions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	minsk, brest := (*subs)[0].ID, (*subs)[1].ID
+	if err := store.SetSubscriptionLabel(minsk, "Home"); err != nil {
+		t.Fatal(err)
+	}
+	// a good day and a polluted one, the history before the period isn't summarized
+	for _, e := range []struct {
+		ago     time.Duration
+		aqi     AirQualityIndex
+		alerted bool
+	}{
+		{8 * 24 * time.Hour, 5, true},
+		{3 * 24 * time.Hour, 1, false},
+		{3*24*time.Hour - 10*time.Hour, 1, false},
+		{2 * 24 * time.Hour, 4, true},
+		{24 * time.Hour, 3, true},
+	} {
+		if err := store.AddAQIHistory(minsk, e.aqi, time.Now().Add(-e.ago), e.alerted); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// brest has only a check before the period
+	addTestHistory(t, store, brest, 8*24*time.Hour, 5)
+	bot := newTestBot(store, &botServices{})
+
+	want := strings.Join([]string{
+		"📊 Your AQI over the last 7 days",
+		"",
+		"🏷 Home",
+		"Location: 53.900000;27.560000",
+		"Average AQI: 2.2, peak: " + ThemeEmoji.AQI(4) + ", checks: 4",
+		"Alerts: 2",
+		"Longest good air streak: 10 h",
+		"",
+		"Location: 52.100000;23.700000",
+		statsMeNoDataText,
+	}, "\n")
+	if got := bot.statsMeText(1, p); got != want {
+		t.Errorf("statsMeText() = %q, want %q", got, want)
+	}
+
+	if got := bot.statsMeText(2, p); got != statsMeNoSubsMsg {
+		t.Errorf("statsMeText() without subscriptions = %q, want %q", got, statsMeNoSubsMsg)
+	}
+	ru := message.NewPrinter(language.Russian)
+	if got := bot.statsMeText(1, ru); got == bot.statsMeText(1, p) {
+		t.Errorf("statsMeText() in Russian = %q, the same as in English", got)
+	}
+}
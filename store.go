@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 var sqlSchema = `
@@ -30,6 +33,8 @@ CREATE TABLE IF NOT EXISTS "data_point" (
 	FOREIGN KEY("chat_id") REFERENCES user_session("chatid")
 );
 
+CREATE INDEX IF NOT EXISTS "data_point_chat_id_created_at" ON "data_point" ("chat_id", "created_at");
+
 CREATE TABLE IF NOT EXISTS "subscription" (
 	"id" INTEGER PRIMARY KEY AUTOINCREMENT,
 	"chat_id" INTEGER,
@@ -40,8 +45,86 @@ CREATE TABLE IF NOT EXISTS "subscription" (
 	"enabled" INTEGER,
 	"created_at" DATE
 ); 
+
+CREATE TABLE IF NOT EXISTS "user_prefs" (
+	"chat_id" INTEGER PRIMARY KEY,
+	"weather" INTEGER DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS "aqi_history" (
+	"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+	"subscription_id" INTEGER,
+	"aqi" INT,
+	"created_at" DATE,
+	FOREIGN KEY("subscription_id") REFERENCES subscription("id")
+);
+
+CREATE TABLE IF NOT EXISTS "setting" (
+	"key" TEXT PRIMARY KEY,
+	"value" TEXT
+);
+
+CREATE TABLE IF NOT EXISTS "geocode_cache" (
+	"latitude" REAL,
+	"longitude" REAL,
+	"place" JSON,
+	"created_at" DATE,
+	PRIMARY KEY ("latitude", "longitude")
+);
+
+CREATE TABLE IF NOT EXISTS "favorite" (
+	"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+	"chat_id" INTEGER,
+	"label" TEXT DEFAULT '',
+	"latitude" REAL,
+	"longitude" REAL,
+	"created_at" DATE
+);
+
+CREATE TABLE IF NOT EXISTS "raw_response" (
+	"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+	"longitude" REAL,
+	"latitude" REAL,
+	"body" TEXT,
+	"created_at" DATE
+);
 `
 
+// sqlMigrations add columns to the tables created by sqlSchema. Already applied ones fail with "duplicate column name".
+var sqlMigrations = []string{
+	`ALTER TABLE "subscription" ADD COLUMN "frequency" INTEGER DEFAULT 0`,
+	`ALTER TABLE "subscription" ADD COLUMN "last_checked_at" DATE NULL`,
+	`ALTER TABLE "user_session" ADD COLUMN "last_message_id" INTEGER NULL`,
+	`ALTER TABLE "user_session" ADD COLUMN "last_message_at" DATE NULL`,
+	`ALTER TABLE "user_prefs" ADD COLUMN "profile" TEXT DEFAULT 'general'`,
+	`ALTER TABLE "subscription" ADD COLUMN "mode" TEXT DEFAULT 'alerts'`,
+	`ALTER TABLE "subscription" ADD COLUMN "pending_aqi" INT DEFAULT 0`,
+	`ALTER TABLE "subscription" ADD COLUMN "snoozed_until" DATE NULL`,
+	`ALTER TABLE "subscription" ADD COLUMN "label" TEXT DEFAULT ''`,
+	`ALTER TABLE "aqi_history" ADD COLUMN "alerted" INTEGER DEFAULT 0`,
+	`ALTER TABLE "subscription" ADD COLUMN "last_notified_at" DATE NULL`,
+	`ALTER TABLE "user_prefs" ADD COLUMN "cooldown" INTEGER NULL`,
+	`ALTER TABLE "user_prefs" ADD COLUMN "units" TEXT DEFAULT 'metric'`,
+	`ALTER TABLE "user_prefs" ADD COLUMN "daily_peaks" INTEGER DEFAULT 0`,
+	`ALTER TABLE "user_prefs" ADD COLUMN "scale" TEXT DEFAULT 'owm'`,
+	`ALTER TABLE "user_prefs" ADD COLUMN "map" INTEGER DEFAULT 0`,
+	`ALTER TABLE "subscription" ADD COLUMN "components" TEXT DEFAULT ''`,
+	`ALTER TABLE "subscription" ADD COLUMN "disabled_at" DATE NULL`,
+	`ALTER TABLE "user_prefs" ADD COLUMN "theme" TEXT DEFAULT 'emoji'`,
+	`ALTER TABLE "subscription" ADD COLUMN "bot_id" INTEGER DEFAULT 0`,
+	`ALTER TABLE "data_point" ADD COLUMN "latitude" REAL NULL`,
+	`ALTER TABLE "data_point" ADD COLUMN "longitude" REAL NULL`,
+}
+
+// DefaultDuplicateDistance is the distance in meters below which two subscriptions are the same location
+const DefaultDuplicateDistance = 150
+
+// ErrSubscriptionLimitReached is returned on attempt to add a subscription above Store.MaxSubscriptions
+var ErrSubscriptionLimitReached = errors.New("subscription limit reached")
+
+// ErrFavoriteExists is returned on attempt to add a favorite location near an existing one
+var ErrFavoriteExists = errors.New("location is already a favorite")
+
 // ErrNotificationExists is returted on attempt to add an existing location
 var ErrNotificationExists = errors.New("location is already subscribed")
 
@@ -61,42 +144,309 @@ func (us *UserSession) SetLocation(l *Location) {
 	us.Longitude = l.Longitude
 }
 
+// Location returns the location of the UserSession
+func (us *UserSession) Location() *Location {
+	return &Location{us.Latitude, us.Longitude}
+}
+
+// UserPrefs keeps the preferences of a chat
+type UserPrefs struct {
+	ChatID int64
+	// Weather adds the current weather to AQI messages
+	Weather bool
+	// Profile selects the health advice of AQI messages
+	Profile SensitivityProfile
+	// Units selects the units of the weather in AQI messages
+	Units UnitSystem
+	// Scale selects the scale of the AQI in AQI messages
+	Scale AQIScale
+	// Theme selects the style of the AQI in AQI messages
+	Theme Theme
+	// Map attaches a venue with the AQI to the AQI messages of the shared locations
+	Map bool
+	// DailyPeaks sends the AQI peaks of the previous day of the subscriptions every morning
+	DailyPeaks bool
+	// Cooldown overrides the minimal interval between the notifications of a subscription. The bot's one is used if nil.
+	Cooldown *time.Duration
+}
+
 // Store keeps an UserSessions, DataPoints and Subscriptions
 type Store struct {
 	DB        *sql.DB
 	CacheTime time.Duration
+	// CoordinatePrecision is the number of decimals the stored coordinates are rounded to. Not rounded if 0.
+	CoordinatePrecision int
+	// MaxSubscriptions is the number of the enabled subscriptions a chat may have. Not limited if 0.
+	MaxSubscriptions int
+	// DataPointMinChange is the relative change of a component below which AddChangedDataPoints skips
+	// a DataPoint of the same AQI, e.g. 0.1 for 10%. All DataPoints are stored if 0.
+	DataPointMinChange float64
+	// DuplicateDistance is the distance in meters below which two subscriptions of a chat are the same location.
+	// DefaultDuplicateDistance if 0.
+	DuplicateDistance float64
+	// BotID is the Telegram ID of the bot the subscriptions of the Store are notified by. Sessions, preferences
+	// and DataPoints of a chat are shared by all the bots of the DB. 0 is the primary bot, also of the subscriptions
+	// stored before other bots were added.
+	BotID int64
+}
+
+// ForBot returns a copy of the Store sharing the DB with the subscriptions of the bot of the Telegram ID
+func (s *Store) ForBot(botID int64) *Store {
+	botStore := *s
+	botStore.BotID = botID
+	return &botStore
+}
+
+// duplicateDistance returns DuplicateDistance or DefaultDuplicateDistance if it isn't set
+func (s *Store) duplicateDistance() float64 {
+	if s.DuplicateDistance > 0 {
+		return s.DuplicateDistance
+	}
+	return DefaultDuplicateDistance
+}
+
+// OpenStore opens the SQLite DB file at the path and initializes it. The directory of the file is created if needed.
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating DB directory: %w", err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("creating DB client: %w", err)
+	}
+	store := &Store{DB: db, CacheTime: DefaultCacheTime}
+	if err := store.Init(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cannot init DB: %w", err)
+	}
+	return store, nil
 }
 
 func (s *Store) Init() error {
 	_, err := s.DB.Exec(sqlSchema)
 	if err != nil {
-		return err
+		return fmt.Errorf("creating DB schema: %w", err)
+	}
+	for _, m := range sqlMigrations {
+		_, err := s.DB.Exec(m)
+		if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("migrating DB: %w", err)
+		}
 	}
 	return nil
 }
 
-// UpdateUserSession replaces the UserSession in a DB
+// UpdateUserSession replaces the UserSession in a DB. Other columns of the chat, like the last message, are kept.
+// Coordinates are rounded to CoordinatePrecision if it is set. Subscriptions copy the rounded ones.
 func (s *Store) UpdateUserSession(n *UserSession) error {
-	_, err := s.DB.Exec("REPLACE INTO user_session (userid, chatid, language, longitude, latitude, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+	if s.CoordinatePrecision > 0 {
+		rounded := *n
+		rounded.SetLocation(n.Location().Round(s.CoordinatePrecision))
+		n = &rounded
+	}
+	_, err := s.DB.Exec(`INSERT INTO user_session (userid, chatid, language, longitude, latitude, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(chatid) DO UPDATE SET userid=excluded.userid, language=excluded.language,
+		longitude=excluded.longitude, latitude=excluded.latitude, created_at=excluded.created_at`,
 		n.UserID, n.ChatID, n.LanguageCode, n.Longitude, n.Latitude, time.Now())
 	if err != nil {
-		return fmt.Errorf("UpdateUserSession: %v", err)
+		return fmt.Errorf("UpdateUserSession: %w", err)
+	}
+	return nil
+}
+
+// GetUserPrefs returns UserPrefs for the ChatID. Default UserPrefs if none are stored
+func (s *Store) GetUserPrefs(chatID int64) (*UserPrefs, error) {
+	prefs := UserPrefs{ChatID: chatID, Profile: ProfileGeneral, Units: UnitsMetric, Scale: ScaleOWM, Theme: ThemeEmoji}
+	var cooldown sql.NullInt64
+	err := s.DB.QueryRow("SELECT weather, profile, units, scale, theme, map, daily_peaks, cooldown FROM user_prefs WHERE chat_id=?", chatID).
+		Scan(&prefs.Weather, &prefs.Profile, &prefs.Units, &prefs.Scale, &prefs.Theme, &prefs.Map, &prefs.DailyPeaks, &cooldown)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return &UserPrefs{ChatID: chatID, Profile: ProfileGeneral, Units: UnitsMetric, Scale: ScaleOWM, Theme: ThemeEmoji}, fmt.Errorf("GetUserPrefs: %w", err)
+	}
+	if cooldown.Valid {
+		d := time.Duration(cooldown.Int64) * time.Second
+		prefs.Cooldown = &d
+	}
+	return &prefs, nil
+}
+
+// UpdateUserPrefs replaces the UserPrefs in a DB
+func (s *Store) UpdateUserPrefs(prefs *UserPrefs) error {
+	var cooldown sql.NullInt64
+	if prefs.Cooldown != nil {
+		cooldown = sql.NullInt64{Int64: int64(*prefs.Cooldown / time.Second), Valid: true}
+	}
+	_, err := s.DB.Exec("REPLACE INTO user_prefs (chat_id, weather, profile, units, scale, theme, map, daily_peaks, cooldown) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		prefs.ChatID, prefs.Weather, prefs.Profile, prefs.Units, prefs.Scale, prefs.Theme, prefs.Map, prefs.DailyPeaks, cooldown)
+	if err != nil {
+		return fmt.Errorf("UpdateUserPrefs: %w", err)
 	}
 	return nil
 }
 
-// AddDataPoint adds a DataPoint for the ChatID into DB for caching purposes. Returns an error or nil
+// SetLastMessage stores the ID and the time of the last AQI message sent to the chat
+func (s *Store) SetLastMessage(chatID int64, messageID int, t time.Time) error {
+	_, err := s.DB.Exec("UPDATE user_session SET last_message_id=?, last_message_at=? WHERE chatid=?", messageID, t, chatID)
+	if err != nil {
+		return fmt.Errorf("SetLastMessage: %w", err)
+	}
+	return nil
+}
+
+// GetLastMessage returns the ID and the time of the last AQI message sent to the chat. 0 if there is none
+func (s *Store) GetLastMessage(chatID int64) (int, time.Time, error) {
+	var (
+		messageID sql.NullInt64
+		sentAt    sql.NullTime
+	)
+	err := s.DB.QueryRow("SELECT last_message_id, last_message_at FROM user_session WHERE chatid=?", chatID).Scan(&messageID, &sentAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, time.Time{}, nil
+		}
+		return 0, time.Time{}, fmt.Errorf("GetLastMessage: %w", err)
+	}
+	return int(messageID.Int64), sentAt.Time, nil
+}
+
+// dataPointsPerInsert limits the rows of a multi-row INSERT of AddDataPointAt
+// to stay below the SQLite limit of 999 variables of a statement
+const dataPointsPerInsert = 300
+
+// dataPointLocationDecimals is the number of decimals the locations of the DataPoints are rounded to, about 100 m.
+// The DataPoints of a location are looked up by the rounded coordinates.
+const dataPointLocationDecimals = 3
+
+// dataPointLocation returns the location rounded to dataPointLocationDecimals or to CoordinatePrecision if it is coarser
+func (s *Store) dataPointLocation(l *Location) *Location {
+	decimals := dataPointLocationDecimals
+	if s.CoordinatePrecision > 0 && s.CoordinatePrecision < decimals {
+		decimals = s.CoordinatePrecision
+	}
+	return l.Round(decimals)
+}
+
+// AddDataPoint adds DataPoints for the ChatID into DB for caching purposes like AddDataPointAt without the location.
+// Returns an error or nil
 func (s *Store) AddDataPoint(chatID int64, dps *[]DataPoint) error {
-	for _, dp := range *dps {
-		dataPoint, err := json.Marshal(dp)
-		if err != nil {
-			return fmt.Errorf("marshaling DP: %v ", err)
+	return s.AddDataPointAt(chatID, nil, dps)
+}
+
+// AddDataPointAt adds DataPoints of the location for the ChatID into DB. Either all of them are added or none.
+// They are inserted by multi-row INSERTs of up to dataPointsPerInsert rows. The location isn't stored if nil.
+// Returns an error or nil
+func (s *Store) AddDataPointAt(chatID int64, location *Location, dps *[]DataPoint) error {
+	var lat, lon sql.NullFloat64
+	if location != nil {
+		rounded := s.dataPointLocation(location)
+		lat = sql.NullFloat64{Float64: rounded.Latitude, Valid: true}
+		lon = sql.NullFloat64{Float64: rounded.Longitude, Valid: true}
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(*dps); start += dataPointsPerInsert {
+		end := start + dataPointsPerInsert
+		if end > len(*dps) {
+			end = len(*dps)
+		}
+		rows := make([]string, 0, end-start)
+		args := make([]interface{}, 0, 5*(end-start))
+		for _, dp := range (*dps)[start:end] {
+			dataPoint, err := json.Marshal(dp)
+			if err != nil {
+				return fmt.Errorf("marshaling DP: %w", err)
+			}
+			rows = append(rows, "(?, ?, ?, ?, ?)")
+			args = append(args, chatID, dataPoint, time.Unix(dp.Dt, 0), lat, lon)
 		}
-		_, err = s.DB.Exec("INSERT into `data_point` (`chat_id`, `data`, `created_at`) VALUES(?, ?, ?)", chatID, dataPoint, time.Unix(dp.Dt, 0))
+		_, err = tx.Exec("INSERT into `data_point` (`chat_id`, `data`, `created_at`, `latitude`, `longitude`) VALUES "+
+			strings.Join(rows, ", "), args...)
 		if err != nil {
-			return fmt.Errorf("updating DB: %v", err)
+			return fmt.Errorf("updating DB: %w", err)
 		}
 	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing data points: %w", err)
+	}
+	return nil
+}
+
+// AddChangedDataPoints adds the DataPoints of the location for the ChatID differing from the previous one by the AQI
+// or by a component changed more than DataPointMinChange. The first one is compared with the last stored DataPoint
+// of the same location.
+// Returns the number of the stored DataPoints or an error.
+func (s *Store) AddChangedDataPoints(chatID int64, location *Location, dps *[]DataPoint) (int, error) {
+	if s.DataPointMinChange <= 0 {
+		return len(*dps), s.AddDataPointAt(chatID, location, dps)
+	}
+	prev, err := s.GetLastPDAt(chatID, location)
+	if err != nil {
+		// a corrupt DataPoint is not compared with
+		log.Print("AddChangedDataPoints: ", err)
+	}
+	var changed []DataPoint
+	for i := range *dps {
+		dp := &(*dps)[i]
+		if dataPointChanged(prev, dp, s.DataPointMinChange) {
+			changed = append(changed, *dp)
+			prev = dp
+		}
+	}
+	dataPointsCompacted.Add(int64(len(*dps) - len(changed)))
+	if len(changed) == 0 {
+		return 0, nil
+	}
+	return len(changed), s.AddDataPointAt(chatID, location, &changed)
+}
+
+// dataPointChanged reports whether the DataPoint differs from the previous one by the AQI, the set of the components
+// or a component changed more than minChange relatively
+func dataPointChanged(prev, dp *DataPoint, minChange float64) bool {
+	if prev.GetAQI() != dp.GetAQI() || len(prev.Components) != len(dp.Components) {
+		return true
+	}
+	for k, v := range dp.Components {
+		old, ok := prev.Components[k]
+		if !ok || math.Abs(v-old) > minChange*math.Abs(old) {
+			return true
+		}
+	}
+	return false
+}
+
+// latestDataPoint returns the most recently measured of the DataPoints. It panics if there are none.
+func latestDataPoint(dps []DataPoint) *DataPoint {
+	latest := &dps[0]
+	for i := range dps {
+		if dps[i].Dt > latest.Dt {
+			latest = &dps[i]
+		}
+	}
+	return latest
+}
+
+// TrimDataPoints keeps only the most recent keep DataPoints for the ChatID and deletes the rest
+func (s *Store) TrimDataPoints(chatID int64, keep int) error {
+	_, err := s.DB.Exec(`DELETE FROM data_point WHERE chat_id=? AND id NOT IN (
+		SELECT id FROM data_point WHERE chat_id=? ORDER BY created_at DESC, id DESC LIMIT ?
+	)`, chatID, chatID, keep)
+	if err != nil {
+		return fmt.Errorf("TrimDataPoints: %w", err)
+	}
+	return nil
+}
+
+// DeleteDataPointsBefore deletes the DataPoints of all the chats measured before the time
+func (s *Store) DeleteDataPointsBefore(t time.Time) error {
+	// created_at is stored in the local time zone by AddDataPointAt like in GetDataPoints
+	_, err := s.DB.Exec("DELETE FROM data_point WHERE created_at < ?", t.Local())
+	if err != nil {
+		return fmt.Errorf("DeleteDataPointsBefore: %w", err)
+	}
 	return nil
 }
 
@@ -113,7 +463,7 @@ func (s *Store) GetSessionByChatID(chatID int64) (*UserSession, error) {
 	)
 	if err != nil {
 		log.Print("GetSessionByChatID: ", err)
-		return &UserSession{}, err
+		return &UserSession{}, fmt.Errorf("GetSessionByChatID: %w", err)
 	}
 	return &us, nil
 }
@@ -122,130 +472,1009 @@ func (s *Store) GetSessionByChatID(chatID int64) (*UserSession, error) {
 func (s *Store) GetLastPD(chatID int64) (*DataPoint, error) {
 
 	var dp DataPoint
-	var data []byte
-	err := s.DB.QueryRow("SELECT data FROM data_point WHERE chat_id=? ORDER BY created_at DESC LIMIT 1", chatID).Scan(&data)
+	var (
+		id   int64
+		data []byte
+	)
+	err := s.DB.QueryRow("SELECT id, data FROM data_point WHERE chat_id=? ORDER BY created_at DESC, id DESC LIMIT 1", chatID).Scan(&id, &data)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return &DataPoint{}, nil
 		}
-		return &DataPoint{}, err
+		return &DataPoint{}, fmt.Errorf("GetLastPD: %w", err)
+	}
+	if err := json.Unmarshal(data, &dp); err != nil {
+		log.Printf("GetLastPD: data_point %d of chat %d is corrupt: %q", id, chatID, data)
+		return &DataPoint{}, fmt.Errorf("GetLastPD: unmarshaling DP %d: %w", id, err)
 	}
-	json.Unmarshal(data, &dp)
 
 	return &dp, nil
 }
 
+// GetLastPDAt returns latest DataPoint of the location for the ChatID. The location is compared rounded the way
+// AddDataPointAt stores it. An empty DataPoint if there is none
+func (s *Store) GetLastPDAt(chatID int64, location *Location) (*DataPoint, error) {
+	rounded := s.dataPointLocation(location)
+	var (
+		dp   DataPoint
+		id   int64
+		data []byte
+	)
+	err := s.DB.QueryRow("SELECT id, data FROM data_point WHERE chat_id=? AND latitude=? AND longitude=? ORDER BY created_at DESC, id DESC LIMIT 1",
+		chatID, rounded.Latitude, rounded.Longitude).Scan(&id, &data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &DataPoint{}, nil
+		}
+		return &DataPoint{}, fmt.Errorf("GetLastPDAt: %w", err)
+	}
+	if err := json.Unmarshal(data, &dp); err != nil {
+		log.Printf("GetLastPDAt: data_point %d of chat %d is corrupt: %q", id, chatID, data)
+		return &DataPoint{}, fmt.Errorf("GetLastPDAt: unmarshaling DP %d: %w", id, err)
+	}
+	return &dp, nil
+}
+
+// GetDataPoints returns the DataPoints for the ChatID measured from the time to the time inclusive, the oldest first.
+// An empty slice if there are none
+func (s *Store) GetDataPoints(chatID int64, from, to time.Time) ([]DataPoint, error) {
+	// created_at is stored in the local time zone by AddDataPoint, the bounds have to be in it to be compared
+	rows, err := s.DB.Query("SELECT data FROM data_point WHERE chat_id=? AND created_at >= ? AND created_at <= ? ORDER BY created_at, id",
+		chatID, from.Local(), to.Local())
+	if err != nil {
+		return []DataPoint{}, fmt.Errorf("GetDataPoints: %w", err)
+	}
+	defer rows.Close()
+
+	dps := []DataPoint{}
+	for rows.Next() {
+		var (
+			data []byte
+			dp   DataPoint
+		)
+		if err := rows.Scan(&data); err != nil {
+			return []DataPoint{}, fmt.Errorf("GetDataPoints: %w", err)
+		}
+		if err := json.Unmarshal(data, &dp); err != nil {
+			return []DataPoint{}, fmt.Errorf("GetDataPoints: unmarshaling DP: %w", err)
+		}
+		dps = append(dps, dp)
+	}
+	if err := rows.Err(); err != nil {
+		return []DataPoint{}, fmt.Errorf("GetDataPoints: %w", err)
+	}
+	return dps, nil
+}
+
 // AQISubscription represents a Users subscription to AQI updates
 type AQISubscription struct {
 	ID int64
 	UserSession
 	AirQualityIndex
+	// Frequency is the minimal interval between checks of the subscription. 0 checks on every Cron run.
+	Frequency     time.Duration
+	LastCheckedAt time.Time
+	// Mode is how the user is informed about the AQI of the subscription
+	Mode SubscriptionMode
+	// PendingAQI is the changed AQI waiting for the confirmation by the next Cron run. 0 if there is none.
+	PendingAQI AirQualityIndex
+	// SnoozedUntil is the time Cron skips the subscription until. Zero if it is not snoozed.
+	SnoozedUntil time.Time
+	// Label is the name of the subscription given by the user, like "Home". Empty if there is none.
+	Label string
+	// LastNotifiedAt is the time of the last notification sent by Cron. Zero if there is none.
+	LastNotifiedAt time.Time
+	// Components are the pollutants of the last check by Cron. nil if there is none.
+	Components map[string]float64
+}
+
+// SubscriptionMode is how the user is informed about the AQI of a subscription
+type SubscriptionMode string
+
+const (
+	// SubscriptionModeAlerts notifies on every AQI change
+	SubscriptionModeAlerts SubscriptionMode = "alerts"
+	// SubscriptionModeDigest sends a weekly summary instead of the alerts
+	SubscriptionModeDigest SubscriptionMode = "digest"
+)
+
+// subscriptionColumns are selected by the queries scanned with scanSubscription
+const subscriptionColumns = "id, chat_id, language, longitude, latitude, aqi, created_at, frequency, last_checked_at, mode, pending_aqi, snoozed_until, label, last_notified_at, components"
+
+func scanSubscription(rows *sql.Rows) (AQISubscription, error) {
+	var (
+		sub            AQISubscription
+		frequency      sql.NullInt64
+		lastCheckedAt  sql.NullTime
+		mode           sql.NullString
+		pendingAQI     sql.NullInt64
+		snoozedUntil   sql.NullTime
+		label          sql.NullString
+		lastNotifiedAt sql.NullTime
+		components     sql.NullString
+	)
+	err := rows.Scan(&sub.ID, &sub.ChatID, &sub.LanguageCode, &sub.Longitude, &sub.Latitude, &sub.AirQualityIndex, &sub.CreatedAt,
+		&frequency, &lastCheckedAt, &mode, &pendingAQI, &snoozedUntil, &label, &lastNotifiedAt, &components)
+	if err != nil {
+		return AQISubscription{}, fmt.Errorf("scanning subscription: %w", err)
+	}
+	sub.Frequency = time.Duration(frequency.Int64) * time.Second
+	sub.LastCheckedAt = lastCheckedAt.Time
+	sub.PendingAQI = AirQualityIndex(pendingAQI.Int64)
+	sub.SnoozedUntil = snoozedUntil.Time
+	sub.Label = label.String
+	sub.LastNotifiedAt = lastNotifiedAt.Time
+	if components.String != "" {
+		// corrupt components are only logged, the next check replaces them
+		if err := json.Unmarshal([]byte(components.String), &sub.Components); err != nil {
+			log.Printf("scanning subscription %d components: %v", sub.ID, err)
+		}
+	}
+	sub.Mode = SubscriptionMode(mode.String)
+	if sub.Mode == "" {
+		sub.Mode = SubscriptionModeAlerts
+	}
+	return sub, nil
 }
 
 // AddNotification gathers the latest data for the chatID and create a new AQISubscription record
 func (s *Store) AddAQISubscription(chatID int64) error {
 	us, err := s.GetSessionByChatID(chatID)
 	if err != nil {
-		return err
+		return fmt.Errorf("addAQISubscription: %w", err)
+	}
+	dp, err := s.GetLastPD(chatID)
+	if err != nil {
+		return fmt.Errorf("addAQISubscription: %w", err)
+	}
+	return s.AddAQISubscriptionAt(chatID, us.Location(), dp.GetAQI())
+}
+
+// AddAQISubscriptionAt creates a new AQISubscription record of the location with the current aqi.
+// The language is taken from the session of the chatID.
+func (s *Store) AddAQISubscriptionAt(chatID int64, location *Location, aqi AirQualityIndex) error {
+	us, err := s.GetSessionByChatID(chatID)
+	if err != nil {
+		return fmt.Errorf("addAQISubscription: %w", err)
+	}
+	if s.CoordinatePrecision > 0 {
+		location = location.Round(s.CoordinatePrecision)
 	}
 
 	// duplicate check
+	dupDistance := s.duplicateDistance()
 	subs, err := s.ListAQISubscriptions(chatID)
 	if err != nil {
-		return err
+		return fmt.Errorf("addAQISubscription: %w", err)
 	}
 	for _, s := range *subs {
-		if math.Abs(s.Latitude-us.Latitude) < 0.0009 || math.Abs(s.Longitude-us.Longitude) < 0.0009 {
+		if s.Location().DistanceTo(location) < dupDistance {
 			log.Print("notificaiton already exists")
 			return ErrNotificationExists
 		}
 	}
+	if s.MaxSubscriptions > 0 && len(*subs) >= s.MaxSubscriptions {
+		return ErrSubscriptionLimitReached
+	}
 
-	dp, err := s.GetLastPD(chatID)
+	// a disabled subscription of the location is enabled again instead of adding a duplicate
+	disabledID, err := s.findDisabledSubscription(chatID, location)
 	if err != nil {
-		return err
+		return fmt.Errorf("addAQISubscription: %w", err)
 	}
-	_, err = s.DB.Exec("INSERT INTO subscription (chat_id, language, longitude, latitude, aqi, enabled, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
-		us.ChatID, us.LanguageCode, us.Longitude, us.Latitude, dp.GetAQI(), 1, time.Now())
+	if disabledID != 0 {
+		_, err = s.DB.Exec("UPDATE subscription SET enabled=1, language=?, aqi=?, pending_aqi=0, snoozed_until=NULL, disabled_at=NULL WHERE id=?",
+			us.LanguageCode, aqi, disabledID)
+		if err != nil {
+			return fmt.Errorf("addAQISubscription: %w", err)
+		}
+		return nil
+	}
+
+	_, err = s.DB.Exec("INSERT INTO subscription (chat_id, bot_id, language, longitude, latitude, aqi, enabled, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		chatID, s.BotID, us.LanguageCode, location.Longitude, location.Latitude, aqi, 1, time.Now())
 	if err != nil {
-		return fmt.Errorf("addAQISubscription: %v", err)
+		return fmt.Errorf("addAQISubscription: %w", err)
 	}
 	return nil
 }
 
-// ListAQISubscriptions returns AQISubscriptions for the chatID. And error on DB errors
+// Favorite is a location saved by a chat to subscribe to with /subscribe_all
+type Favorite struct {
+	ID     int64
+	ChatID int64
+	Label  string
+	Location
+	CreatedAt time.Time
+}
+
+// AddFavorite saves the location as a favorite of the chat. It returns ErrFavoriteExists if the chat has
+// a favorite closer than the duplicate distance.
+func (s *Store) AddFavorite(chatID int64, location *Location, label string) error {
+	if s.CoordinatePrecision > 0 {
+		location = location.Round(s.CoordinatePrecision)
+	}
+	favorites, err := s.ListFavorites(chatID)
+	if err != nil {
+		return fmt.Errorf("AddFavorite: %w", err)
+	}
+	for _, f := range favorites {
+		if f.Location.DistanceTo(location) < s.duplicateDistance() {
+			return ErrFavoriteExists
+		}
+	}
+	_, err = s.DB.Exec("INSERT INTO favorite (chat_id, label, latitude, longitude, created_at) VALUES (?, ?, ?, ?, ?)",
+		chatID, label, location.Latitude, location.Longitude, time.Now())
+	if err != nil {
+		return fmt.Errorf("AddFavorite: %w", err)
+	}
+	return nil
+}
+
+// ListFavorites returns the favorites of the chat, the oldest first
+func (s *Store) ListFavorites(chatID int64) ([]Favorite, error) {
+	rows, err := s.DB.Query("SELECT id, chat_id, label, latitude, longitude, created_at FROM favorite WHERE chat_id=? ORDER BY id", chatID)
+	if err != nil {
+		return []Favorite{}, fmt.Errorf("ListFavorites: %w", err)
+	}
+	defer rows.Close()
+	favorites := []Favorite{}
+	for rows.Next() {
+		var f Favorite
+		if err := rows.Scan(&f.ID, &f.ChatID, &f.Label, &f.Latitude, &f.Longitude, &f.CreatedAt); err != nil {
+			return []Favorite{}, fmt.Errorf("ListFavorites: %w", err)
+		}
+		favorites = append(favorites, f)
+	}
+	if err := rows.Err(); err != nil {
+		return []Favorite{}, fmt.Errorf("ListFavorites: %w", err)
+	}
+	return favorites, nil
+}
+
+// DeleteFavorite deletes the favorite of the chat by the ID
+func (s *Store) DeleteFavorite(chatID, id int64) error {
+	if _, err := s.DB.Exec("DELETE FROM favorite WHERE chat_id=? AND id=?", chatID, id); err != nil {
+		return fmt.Errorf("DeleteFavorite: %w", err)
+	}
+	return nil
+}
+
+// findDisabledSubscription returns the ID of a disabled AQISubscription of the chat closer than
+// the duplicate distance to the location. 0 if there is none
+func (s *Store) findDisabledSubscription(chatID int64, l *Location) (int64, error) {
+	rows, err := s.DB.Query("SELECT "+subscriptionColumns+" FROM subscription WHERE chat_id=? AND bot_id=? AND enabled=0 ORDER BY id DESC", chatID, s.BotID)
+	if err != nil {
+		return 0, fmt.Errorf("findDisabledSubscription: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return 0, fmt.Errorf("findDisabledSubscription: %w", err)
+		}
+		if sub.Location().DistanceTo(l) < s.duplicateDistance() {
+			return sub.ID, nil
+		}
+	}
+	return 0, rows.Err()
+}
+
+// ListAQISubscriptions returns AQISubscriptions for the chatID, the oldest first, so their numbers in /subsriptions
+// stay the same for /label, /refresh, /top and /test. And error on DB errors
 func (s *Store) ListAQISubscriptions(chatID int64) (*[]AQISubscription, error) {
 	var uss []AQISubscription
-	rows, err := s.DB.Query("SELECT chat_id, language, longitude, latitude, aqi, created_at FROM subscription WHERE chat_id=? AND enabled=1", chatID)
+	rows, err := s.DB.Query("SELECT "+subscriptionColumns+" FROM subscription WHERE chat_id=? AND bot_id=? AND enabled=1 ORDER BY id", chatID, s.BotID)
 	if err != nil {
-		return &[]AQISubscription{}, err
+		return &[]AQISubscription{}, fmt.Errorf("ListAQISubscriptions: %w", err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		subs := AQISubscription{}
-
-		err := rows.Scan(&subs.ChatID, &subs.LanguageCode, &subs.Longitude, &subs.Latitude, &subs.AirQualityIndex, &subs.CreatedAt)
+		subs, err := scanSubscription(rows)
 		if err != nil {
-			return &[]AQISubscription{}, err
+			return &[]AQISubscription{}, fmt.Errorf("ListAQISubscriptions: %w", err)
 		}
 		uss = append(uss, subs)
 	}
+	if err := rows.Err(); err != nil {
+		return &[]AQISubscription{}, fmt.Errorf("ListAQISubscriptions: %w", err)
+	}
 
 	return &uss, nil
 }
 
-// DeleteAQISubscriptions disabled all AQISubscriptions for the chatID
+// DeleteAQISubscriptions disabled all AQISubscriptions for the chatID. They are kept with the time they were disabled
+// at until ClenupAQISubscriptions, so RestoreRecentlyDisabled can enable them again.
 func (s *Store) DeleteAQISubscriptions(chatID int64) error {
-	_, err := s.DB.Exec("UPDATE subscription SET enabled=0 WHERE chat_id=?", chatID)
+	_, err := s.DB.Exec("UPDATE subscription SET enabled=0, disabled_at=? WHERE chat_id=? AND bot_id=? AND enabled=1", time.Now(), chatID, s.BotID)
 	if err != nil {
-		return err
+		return fmt.Errorf("DeleteAQISubscriptions: %w", err)
 	}
 	return nil
 }
 
-// ListEnabledSubscriptions returns all active AQISubscriptions
+// RestoreRecentlyDisabled enables again the AQISubscriptions of the chat disabled since the time, the most recent first.
+// The ones closer than the duplicate distance to an enabled subscription are skipped and at most MaxSubscriptions
+// are enabled in total. Returns the number of the restored subscriptions.
+func (s *Store) RestoreRecentlyDisabled(chatID int64, since time.Time) (int, error) {
+	subs, err := s.ListAQISubscriptions(chatID)
+	if err != nil {
+		return 0, fmt.Errorf("RestoreRecentlyDisabled: %w", err)
+	}
+	var enabled []*Location
+	for _, sub := range *subs {
+		enabled = append(enabled, sub.Location())
+	}
+
+	rows, err := s.DB.Query("SELECT "+subscriptionColumns+" FROM subscription WHERE chat_id=? AND bot_id=? AND enabled=0 AND disabled_at>=? ORDER BY disabled_at DESC, id DESC",
+		chatID, s.BotID, since)
+	if err != nil {
+		return 0, fmt.Errorf("RestoreRecentlyDisabled: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("RestoreRecentlyDisabled: %w", err)
+		}
+		if s.MaxSubscriptions > 0 && len(enabled) >= s.MaxSubscriptions {
+			break
+		}
+		if isNearAny(sub.Location(), enabled, s.duplicateDistance()) {
+			continue
+		}
+		enabled = append(enabled, sub.Location())
+		ids = append(ids, sub.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("RestoreRecentlyDisabled: %w", err)
+	}
+
+	for _, id := range ids {
+		_, err := s.DB.Exec("UPDATE subscription SET enabled=1, pending_aqi=0, disabled_at=NULL WHERE id=?", id)
+		if err != nil {
+			return 0, fmt.Errorf("RestoreRecentlyDisabled: %w", err)
+		}
+	}
+	return len(ids), nil
+}
+
+// ListEnabledSubscriptions returns all active AQISubscriptions of the BotID
 func (s *Store) ListEnabledSubscriptions() (*[]AQISubscription, error) {
 	var subs []AQISubscription
-	rows, err := s.DB.Query("SELECT id, chat_id, language, longitude, latitude, aqi, created_at FROM subscription WHERE enabled=1")
+	rows, err := s.DB.Query("SELECT "+subscriptionColumns+" FROM subscription WHERE bot_id=? AND enabled=1", s.BotID)
 	if err != nil {
-		return &[]AQISubscription{}, err
+		return &[]AQISubscription{}, fmt.Errorf("ListEnabledSubscriptions: %w", err)
 	}
+	defer rows.Close()
 	for rows.Next() {
-		sub := AQISubscription{}
-
-		err := rows.Scan(&sub.ID, &sub.ChatID, &sub.LanguageCode, &sub.Longitude, &sub.Latitude, &sub.AirQualityIndex, &sub.CreatedAt)
+		sub, err := scanSubscription(rows)
 		if err != nil {
-			return &[]AQISubscription{}, err
+			return &[]AQISubscription{}, fmt.Errorf("ListEnabledSubscriptions: %w", err)
 		}
 		subs = append(subs, sub)
 	}
+	if err := rows.Err(); err != nil {
+		return &[]AQISubscription{}, fmt.Errorf("ListEnabledSubscriptions: %w", err)
+	}
 
 	return &subs, nil
 }
 
+// SetSubscriptionsFrequency sets the check Frequency of all AQISubscriptions for the chatID
+func (s *Store) SetSubscriptionsFrequency(chatID int64, frequency time.Duration) error {
+	_, err := s.DB.Exec("UPDATE subscription SET frequency=? WHERE chat_id=? AND bot_id=?", int64(frequency/time.Second), chatID, s.BotID)
+	if err != nil {
+		return fmt.Errorf("SetSubscriptionsFrequency: %w", err)
+	}
+	return nil
+}
+
+// SetSubscriptionsMode sets the SubscriptionMode of all AQISubscriptions for the chatID
+func (s *Store) SetSubscriptionsMode(chatID int64, mode SubscriptionMode) error {
+	_, err := s.DB.Exec("UPDATE subscription SET mode=? WHERE chat_id=? AND bot_id=?", mode, chatID, s.BotID)
+	if err != nil {
+		return fmt.Errorf("SetSubscriptionsMode: %w", err)
+	}
+	return nil
+}
+
+// SnoozeSubscriptions makes Cron skip all AQISubscriptions for the chatID until the time. Zero time resumes them.
+func (s *Store) SnoozeSubscriptions(chatID int64, until time.Time) error {
+	snoozedUntil := sql.NullTime{Time: until, Valid: !until.IsZero()}
+	_, err := s.DB.Exec("UPDATE subscription SET snoozed_until=? WHERE chat_id=? AND bot_id=?", snoozedUntil, chatID, s.BotID)
+	if err != nil {
+		return fmt.Errorf("SnoozeSubscriptions: %w", err)
+	}
+	return nil
+}
+
+// SetSubscriptionLabel sets the Label of the subscription. An empty label removes it.
+func (s *Store) SetSubscriptionLabel(id int64, label string) error {
+	_, err := s.DB.Exec("UPDATE subscription SET label=? WHERE id=?", label, id)
+	if err != nil {
+		return fmt.Errorf("SetSubscriptionLabel: %w", err)
+	}
+	return nil
+}
+
+// SetSubscriptionPendingAQI sets the changed AQI of the subscription waiting for the confirmation. 0 clears it.
+func (s *Store) SetSubscriptionPendingAQI(id int64, aqi AirQualityIndex) error {
+	_, err := s.DB.Exec("UPDATE subscription SET pending_aqi=? WHERE id=?", aqi, id)
+	if err != nil {
+		return fmt.Errorf("SetSubscriptionPendingAQI: %w", err)
+	}
+	return nil
+}
+
+// MarkSubscriptionNotified sets the time the user was last notified about the subscription by Cron
+func (s *Store) MarkSubscriptionNotified(id int64, t time.Time) error {
+	_, err := s.DB.Exec("UPDATE subscription SET last_notified_at=? WHERE id=?", t, id)
+	if err != nil {
+		return fmt.Errorf("MarkSubscriptionNotified: %w", err)
+	}
+	return nil
+}
+
+// SetSubscriptionComponents sets the pollutants of the last check of the subscription by Cron
+func (s *Store) SetSubscriptionComponents(id int64, components map[string]float64) error {
+	b, err := json.Marshal(components)
+	if err != nil {
+		return fmt.Errorf("SetSubscriptionComponents: %w", err)
+	}
+	_, err = s.DB.Exec("UPDATE subscription SET components=? WHERE id=?", string(b), id)
+	if err != nil {
+		return fmt.Errorf("SetSubscriptionComponents: %w", err)
+	}
+	return nil
+}
+
+// MarkSubscriptionChecked sets the time the subscription was last checked by Cron
+func (s *Store) MarkSubscriptionChecked(id int64, t time.Time) error {
+	_, err := s.DB.Exec("UPDATE subscription SET last_checked_at=? WHERE id=?", t, id)
+	if err != nil {
+		return fmt.Errorf("MarkSubscriptionChecked: %w", err)
+	}
+	return nil
+}
+
+// exportDataPointsLimit is the number of the most recent DataPoints included into ExportUserData
+const exportDataPointsLimit = 100
+
+// UserData keeps all the data stored for a chat
+type UserData struct {
+	Session       *UserSession      `json:"session"`
+	Prefs         *UserPrefs        `json:"prefs"`
+	Subscriptions []AQISubscription `json:"subscriptions"`
+	Favorites     []Favorite        `json:"favorites"`
+	DataPoints    []DataPoint       `json:"data_points"`
+}
+
+// ExportUserData returns JSON encoded UserData of the chat: the session, preferences, subscriptions, favorites
+// and recent DataPoints
+func (s *Store) ExportUserData(chatID int64) ([]byte, error) {
+	ud := UserData{Subscriptions: []AQISubscription{}, Favorites: []Favorite{}, DataPoints: []DataPoint{}}
+
+	us, err := s.GetSessionByChatID(chatID)
+	switch {
+	case err == nil:
+		ud.Session = us
+	case !errors.Is(err, sql.ErrNoRows):
+		return []byte{}, fmt.Errorf("exporting session: %w", err)
+	}
+
+	prefs, err := s.GetUserPrefs(chatID)
+	if err != nil {
+		return []byte{}, fmt.Errorf("exporting prefs: %w", err)
+	}
+	ud.Prefs = prefs
+
+	subs, err := s.ListAQISubscriptions(chatID)
+	if err != nil {
+		return []byte{}, fmt.Errorf("exporting subscriptions: %w", err)
+	}
+	ud.Subscriptions = append(ud.Subscriptions, *subs...)
+
+	favorites, err := s.ListFavorites(chatID)
+	if err != nil {
+		return []byte{}, fmt.Errorf("exporting favorites: %w", err)
+	}
+	ud.Favorites = append(ud.Favorites, favorites...)
+
+	rows, err := s.DB.Query("SELECT data FROM data_point WHERE chat_id=? ORDER BY created_at DESC, id DESC LIMIT ?", chatID, exportDataPointsLimit)
+	if err != nil {
+		return []byte{}, fmt.Errorf("exporting data points: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			data []byte
+			dp   DataPoint
+		)
+		if err := rows.Scan(&data); err != nil {
+			return []byte{}, fmt.Errorf("exporting data points: %w", err)
+		}
+		if err := json.Unmarshal(data, &dp); err != nil {
+			return []byte{}, fmt.Errorf("exporting data points: %w", err)
+		}
+		ud.DataPoints = append(ud.DataPoints, dp)
+	}
+	if err := rows.Err(); err != nil {
+		return []byte{}, fmt.Errorf("exporting data points: %w", err)
+	}
+
+	return json.MarshalIndent(ud, "", "  ")
+}
+
+// ExportSubscriptions returns all enabled AQISubscriptions JSON encoded to be imported by ImportSubscriptions
+func (s *Store) ExportSubscriptions() ([]byte, error) {
+	subs, err := s.ListEnabledSubscriptions()
+	if err != nil {
+		return []byte{}, fmt.Errorf("ExportSubscriptions: %w", err)
+	}
+	return json.MarshalIndent(append([]AQISubscription{}, *subs...), "", "  ")
+}
+
+// ImportSubscriptions adds the AQISubscriptions encoded by ExportSubscriptions in a single transaction.
+// IDs are reassigned. Subscriptions closer than the duplicate distance to an existing one of the chat are skipped.
+func (s *Store) ImportSubscriptions(data []byte) error {
+	var subs []AQISubscription
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return fmt.Errorf("ImportSubscriptions: %w", err)
+	}
+	existing, err := s.ListEnabledSubscriptions()
+	if err != nil {
+		return fmt.Errorf("ImportSubscriptions: %w", err)
+	}
+	locations := map[int64][]*Location{}
+	for _, sub := range *existing {
+		locations[sub.ChatID] = append(locations[sub.ChatID], sub.Location())
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("ImportSubscriptions: %w", err)
+	}
+	defer tx.Rollback()
+
+	var imported, skipped int
+	for _, sub := range subs {
+		if isNearAny(sub.Location(), locations[sub.ChatID], s.duplicateDistance()) {
+			skipped++
+			continue
+		}
+		if sub.Mode == "" {
+			sub.Mode = SubscriptionModeAlerts
+		}
+		_, err := tx.Exec(`INSERT INTO subscription (chat_id, bot_id, language, longitude, latitude, aqi, enabled, created_at, frequency, mode, label)
+			VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?, ?, ?)`,
+			sub.ChatID, s.BotID, sub.LanguageCode, sub.Longitude, sub.Latitude, sub.AirQualityIndex, sub.CreatedAt,
+			int64(sub.Frequency/time.Second), sub.Mode, sub.Label)
+		if err != nil {
+			return fmt.Errorf("ImportSubscriptions: %w", err)
+		}
+		locations[sub.ChatID] = append(locations[sub.ChatID], sub.Location())
+		imported++
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ImportSubscriptions: %w", err)
+	}
+	log.Printf("imported %d subscription(s), skipped %d duplicate(s)", imported, skipped)
+	return nil
+}
+
+// isNearAny reports whether the Location is closer than the distance in meters to any of the others
+func isNearAny(l *Location, others []*Location, distance float64) bool {
+	for _, o := range others {
+		if l.DistanceTo(o) < distance {
+			return true
+		}
+	}
+	return false
+}
+
+// PurgeUser deletes the session, preferences, DataPoints and AQISubscriptions of the chat of all the bots
+// in a single transaction
+func (s *Store) PurgeUser(chatID int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("PurgeUser: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, q := range []string{
+		"DELETE FROM aqi_history WHERE subscription_id IN (SELECT id FROM subscription WHERE chat_id=?)",
+		"DELETE FROM data_point WHERE chat_id=?",
+		"DELETE FROM subscription WHERE chat_id=?",
+		"DELETE FROM favorite WHERE chat_id=?",
+		"DELETE FROM user_prefs WHERE chat_id=?",
+		"DELETE FROM user_session WHERE chatid=?",
+	} {
+		if _, err := tx.Exec(q, chatID); err != nil {
+			return fmt.Errorf("PurgeUser: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DigestEntry summarizes the AQI of a digest AQISubscription over a period
+type DigestEntry struct {
+	AQISubscription
+	AverageAQI float64
+	PeakAQI    AirQualityIndex
+	Checks     int
+}
+
+// AQIHistoryEntry is the AQI of a subscription checked by Cron
+type AQIHistoryEntry struct {
+	AQI       AirQualityIndex
+	CreatedAt time.Time
+	// Alerted is whether the user was alerted about the AQI change
+	Alerted bool
+}
+
+// AddAQIHistory records the AQI of the subscription checked at t and whether the user was alerted about it
+func (s *Store) AddAQIHistory(subID int64, aqi AirQualityIndex, t time.Time, alerted bool) error {
+	_, err := s.DB.Exec("INSERT INTO aqi_history (subscription_id, aqi, created_at, alerted) VALUES (?, ?, ?, ?)", subID, aqi, t.UTC(), alerted)
+	if err != nil {
+		return fmt.Errorf("AddAQIHistory: %w", err)
+	}
+	return nil
+}
+
+// ListDigestEntries aggregates the AQI history recorded since the time for every enabled digest AQISubscription
+func (s *Store) ListDigestEntries(since time.Time) ([]DigestEntry, error) {
+	rows, err := s.DB.Query(`SELECT s.id, s.chat_id, s.language, s.longitude, s.latitude, AVG(h.aqi), MAX(h.aqi), COUNT(*)
+		FROM subscription s JOIN aqi_history h ON h.subscription_id = s.id
+		WHERE s.bot_id=? AND s.enabled=1 AND s.mode=? AND h.created_at >= ?
+		GROUP BY s.id ORDER BY s.chat_id, s.id`, s.BotID, SubscriptionModeDigest, since.UTC())
+	if err != nil {
+		return []DigestEntry{}, fmt.Errorf("ListDigestEntries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []DigestEntry
+	for rows.Next() {
+		var e DigestEntry
+		err := rows.Scan(&e.ID, &e.ChatID, &e.LanguageCode, &e.Longitude, &e.Latitude, &e.AverageAQI, &e.PeakAQI, &e.Checks)
+		if err != nil {
+			return []DigestEntry{}, fmt.Errorf("ListDigestEntries: %w", err)
+		}
+		e.Mode = SubscriptionModeDigest
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return []DigestEntry{}, fmt.Errorf("ListDigestEntries: %w", err)
+	}
+	return entries, nil
+}
+
+// ListAQIHistory returns the AQI history of the subscription recorded since the time, the oldest first
+func (s *Store) ListAQIHistory(subID int64, since time.Time) ([]AQIHistoryEntry, error) {
+	rows, err := s.DB.Query("SELECT aqi, created_at, alerted FROM aqi_history WHERE subscription_id=? AND created_at >= ? ORDER BY created_at, id",
+		subID, since.UTC())
+	if err != nil {
+		return []AQIHistoryEntry{}, fmt.Errorf("ListAQIHistory: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AQIHistoryEntry{}
+	for rows.Next() {
+		var (
+			e       AQIHistoryEntry
+			alerted sql.NullBool
+		)
+		if err := rows.Scan(&e.AQI, &e.CreatedAt, &alerted); err != nil {
+			return []AQIHistoryEntry{}, fmt.Errorf("ListAQIHistory: %w", err)
+		}
+		e.Alerted = alerted.Bool
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return []AQIHistoryEntry{}, fmt.Errorf("ListAQIHistory: %w", err)
+	}
+	return entries, nil
+}
+
+// DeleteAQIHistoryBefore deletes the AQI history recorded before the time
+func (s *Store) DeleteAQIHistoryBefore(t time.Time) error {
+	_, err := s.DB.Exec("DELETE FROM aqi_history WHERE created_at < ?", t.UTC())
+	if err != nil {
+		return fmt.Errorf("DeleteAQIHistoryBefore: %w", err)
+	}
+	return nil
+}
+
+// RawResponse is a raw body of an OWM API air pollution response
+type RawResponse struct {
+	Location
+	Body      string
+	CreatedAt time.Time
+}
+
+// AddRawResponse stores the raw body of the air pollution response for the location received at t
+func (s *Store) AddRawResponse(l *Location, body []byte, t time.Time) error {
+	_, err := s.DB.Exec("INSERT INTO raw_response (longitude, latitude, body, created_at) VALUES (?, ?, ?, ?)",
+		l.Longitude, l.Latitude, string(body), t.UTC())
+	if err != nil {
+		return fmt.Errorf("AddRawResponse: %w", err)
+	}
+	return nil
+}
+
+// ListRawResponses returns up to limit most recent RawResponses. If near is not nil,
+// only the ones within the duplicate distance of it are returned.
+func (s *Store) ListRawResponses(near *Location, limit int) ([]RawResponse, error) {
+	rows, err := s.DB.Query("SELECT longitude, latitude, body, created_at FROM raw_response ORDER BY created_at DESC, id DESC")
+	if err != nil {
+		return []RawResponse{}, fmt.Errorf("ListRawResponses: %w", err)
+	}
+	defer rows.Close()
+
+	var responses []RawResponse
+	for rows.Next() && len(responses) < limit {
+		var r RawResponse
+		if err := rows.Scan(&r.Longitude, &r.Latitude, &r.Body, &r.CreatedAt); err != nil {
+			return []RawResponse{}, fmt.Errorf("ListRawResponses: %w", err)
+		}
+		if near != nil && near.DistanceTo(&r.Location) >= s.duplicateDistance() {
+			continue
+		}
+		responses = append(responses, r)
+	}
+	if err := rows.Err(); err != nil {
+		return []RawResponse{}, fmt.Errorf("ListRawResponses: %w", err)
+	}
+	return responses, nil
+}
+
+// DeleteRawResponsesBefore deletes the RawResponses received before the time
+func (s *Store) DeleteRawResponsesBefore(t time.Time) error {
+	_, err := s.DB.Exec("DELETE FROM raw_response WHERE created_at < ?", t.UTC())
+	if err != nil {
+		return fmt.Errorf("DeleteRawResponsesBefore: %w", err)
+	}
+	return nil
+}
+
+// ListSubscribedChatIDs returns unique ChatIDs having at least one enabled AQISubscription of any bot
+func (s *Store) ListSubscribedChatIDs() ([]int64, error) {
+	var chatIDs []int64
+	rows, err := s.DB.Query("SELECT DISTINCT chat_id FROM subscription WHERE enabled=1")
+	if err != nil {
+		return []int64{}, fmt.Errorf("ListSubscribedChatIDs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var chatID int64
+		if err := rows.Scan(&chatID); err != nil {
+			return []int64{}, fmt.Errorf("ListSubscribedChatIDs: %w", err)
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+
+	return chatIDs, rows.Err()
+}
+
+// ListDailyPeaksChatIDs returns the chats with enabled subscriptions opted in to the daily peaks
+func (s *Store) ListDailyPeaksChatIDs() ([]int64, error) {
+	var chatIDs []int64
+	rows, err := s.DB.Query(`SELECT DISTINCT s.chat_id FROM subscription s JOIN user_prefs p ON p.chat_id = s.chat_id
+		WHERE s.bot_id=? AND s.enabled=1 AND p.daily_peaks=1`, s.BotID)
+	if err != nil {
+		return []int64{}, fmt.Errorf("ListDailyPeaksChatIDs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var chatID int64
+		if err := rows.Scan(&chatID); err != nil {
+			return []int64{}, fmt.Errorf("ListDailyPeaksChatIDs: %w", err)
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+
+	return chatIDs, rows.Err()
+}
+
+// Stats keeps aggregated usage numbers of the bot
+type Stats struct {
+	Users                int
+	SubscribedChats      int
+	EnabledSubscriptions int
+	DataPoints           int
+	SubscriptionsByAQI   map[AirQualityIndex]int
+}
+
+// Stats returns aggregated counters of users and DataPoints of all the bots and of the subscriptions of the BotID.
+// Returns an error on DB error
+func (s *Store) Stats() (*Stats, error) {
+	st := &Stats{SubscriptionsByAQI: map[AirQualityIndex]int{}}
+	err := s.DB.QueryRow("SELECT COUNT(*) FROM user_session").Scan(&st.Users)
+	if err != nil {
+		return &Stats{}, fmt.Errorf("counting users: %w", err)
+	}
+	err = s.DB.QueryRow("SELECT COUNT(*) FROM data_point").Scan(&st.DataPoints)
+	if err != nil {
+		return &Stats{}, fmt.Errorf("counting data points: %w", err)
+	}
+	err = s.DB.QueryRow("SELECT COUNT(DISTINCT chat_id) FROM subscription WHERE bot_id=? AND enabled=1", s.BotID).Scan(&st.SubscribedChats)
+	if err != nil {
+		return &Stats{}, fmt.Errorf("counting subscribed chats: %w", err)
+	}
+
+	rows, err := s.DB.Query("SELECT aqi, COUNT(*) FROM subscription WHERE bot_id=? AND enabled=1 GROUP BY aqi", s.BotID)
+	if err != nil {
+		return &Stats{}, fmt.Errorf("counting subscriptions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			aqi AirQualityIndex
+			n   int
+		)
+		if err := rows.Scan(&aqi, &n); err != nil {
+			return &Stats{}, fmt.Errorf("counting subscriptions: %w", err)
+		}
+		st.SubscriptionsByAQI[aqi] = n
+		st.EnabledSubscriptions += n
+	}
+
+	return st, rows.Err()
+}
+
 // UpdateSubscriptionAQI sets the AirQualityIndex for a subcription. Returns an error on DB error
 func (s *Store) UpdateSubscriptionAQI(subID int64, aqi AirQualityIndex) error {
-	_, err := s.DB.Exec("UPDATE subscription SET aqi=? WHERE id=?", aqi, subID)
+	_, err := s.DB.Exec("UPDATE subscription SET aqi=?, pending_aqi=0 WHERE id=?", aqi, subID)
+	if err != nil {
+		return fmt.Errorf("UpdateSubscriptionAQI: %w", err)
+	}
+	return nil
+}
+
+// UpdateSubscriptionAQIBatch sets the AirQualityIndex for several subcriptions by ID in a single transaction.
+// Returns an error on DB error
+func (s *Store) UpdateSubscriptionAQIBatch(aqis map[int64]AirQualityIndex) error {
+	if len(aqis) == 0 {
+		return nil
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("UPDATE subscription SET aqi=?, pending_aqi=0 WHERE id=?")
+	if err != nil {
+		return fmt.Errorf("preparing statement: %w", err)
+	}
+	defer stmt.Close()
+	for id, aqi := range aqis {
+		if _, err := stmt.Exec(aqi, id); err != nil {
+			return fmt.Errorf("updating subscription %d: %w", id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing subscriptions: %w", err)
+	}
+	return nil
+}
+
+// ClenupAQISubscriptions cleans up AQISubscriptions of all the bots disabled before the time, so the more recent ones
+// can still be restored. The ones disabled before disabled_at was recorded are cleaned up too.
+// Returns an error on DB error
+func (s *Store) ClenupAQISubscriptions(before time.Time) error {
+	_, err := s.DB.Exec("DELETE FROM subscription WHERE enabled=0 AND (disabled_at IS NULL OR disabled_at < ?)", before)
 	if err != nil {
-		return err
+		return fmt.Errorf("ClenupAQISubscriptions: %w", err)
 	}
 	return nil
 }
 
-// ClenupAQISubscriptions cleans up disabled AQISubscriptions. Returns an error on DB error
-func (s *Store) ClenupAQISubscriptions() error {
-	_, err := s.DB.Exec("DELETE subscription WHERE enabled=0")
+// geocodeCachePrecision is the number of decimals of the coordinates geocode_cache is keyed by, about 110 m
+const geocodeCachePrecision = 3
+
+// GetGeocode returns the Place cached for the coordinates less than maxAge ago. nil if there is none.
+// A Place without a name means the geocoding found nothing.
+func (s *Store) GetGeocode(l *Location, maxAge time.Duration) (*Place, error) {
+	key := l.Round(geocodeCachePrecision)
+	var (
+		data      []byte
+		createdAt time.Time
+	)
+	err := s.DB.QueryRow("SELECT place, created_at FROM geocode_cache WHERE latitude=? AND longitude=?", key.Latitude, key.Longitude).
+		Scan(&data, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetGeocode: %w", err)
+	}
+	if time.Since(createdAt) >= maxAge {
+		return nil, nil
+	}
+	var place Place
+	if err := json.Unmarshal(data, &place); err != nil {
+		return nil, fmt.Errorf("GetGeocode: %w", err)
+	}
+	return &place, nil
+}
+
+// SetGeocode caches the Place of the coordinates replacing the cached one
+func (s *Store) SetGeocode(l *Location, place *Place) error {
+	key := l.Round(geocodeCachePrecision)
+	data, err := json.Marshal(place)
 	if err != nil {
-		return err
+		return fmt.Errorf("SetGeocode: %w", err)
+	}
+	_, err = s.DB.Exec("REPLACE INTO geocode_cache (latitude, longitude, place, created_at) VALUES (?, ?, ?, ?)",
+		key.Latitude, key.Longitude, data, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("SetGeocode: %w", err)
 	}
 	return nil
 }
 
-// ClenupDataPoint deletes DataPoints older than 12 hours
-func (s *Store) ClenupDataPoint() error {
-	_, err := s.DB.Exec("DELETE data_point WHERE created_at <= datetime('now', '-12 hour')")
+// settingMaintenance is the key of the maintenance mode in the setting table
+const settingMaintenance = "maintenance"
+
+// Maintenance reports whether the bot is in the maintenance mode
+func (s *Store) Maintenance() (bool, error) {
+	var value string
+	err := s.DB.QueryRow("SELECT value FROM setting WHERE key=?", settingMaintenance).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
 	if err != nil {
-		return err
+		return false, fmt.Errorf("Maintenance: %w", err)
+	}
+	return value == "on", nil
+}
+
+// SetMaintenance stores the maintenance mode of the bot
+func (s *Store) SetMaintenance(on bool) error {
+	value := "off"
+	if on {
+		value = "on"
+	}
+	if _, err := s.DB.Exec("REPLACE INTO setting (key, value) VALUES (?, ?)", settingMaintenance, value); err != nil {
+		return fmt.Errorf("SetMaintenance: %w", err)
 	}
 	return nil
 }
+
+// Size returns the size of the DB file in bytes
+func (s *Store) Size() (int64, error) {
+	var pages, pageSize int64
+	if err := s.DB.QueryRow("PRAGMA page_count").Scan(&pages); err != nil {
+		return 0, fmt.Errorf("Size: %w", err)
+	}
+	if err := s.DB.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("Size: %w", err)
+	}
+	return pages * pageSize, nil
+}
+
+// Maintain rebuilds the DB file to give back the space of the deleted rows and updates the statistics of the query planner.
+// It runs only for the SQLite driver. It returns the size of the DB before and after.
+func (s *Store) Maintain() (int64, int64, error) {
+	if _, ok := s.DB.Driver().(*sqlite3.SQLiteDriver); !ok {
+		return 0, 0, errors.New("Maintain: not an SQLite DB")
+	}
+	before, err := s.Size()
+	if err != nil {
+		return 0, 0, fmt.Errorf("Maintain: %w", err)
+	}
+	for _, stmt := range []string{"VACUUM", "ANALYZE"} {
+		if _, err := s.DB.Exec(stmt); err != nil {
+			return 0, 0, fmt.Errorf("Maintain: %s: %w", stmt, err)
+		}
+	}
+	after, err := s.Size()
+	if err != nil {
+		return 0, 0, fmt.Errorf("Maintain: %w", err)
+	}
+	return before, after, nil
+}
@@ -0,0 +1,1419 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestStore returns a Store of an initialized in-memory SQLite DB closed at the end of the test.
+// The DB is kept on a single connection, as each connection to ":memory:" opens a new DB.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	store := &Store{DB: db, CacheTime: DefaultCacheTime}
+	if err := store.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// addTestSession stores the session of the chat at the location
+func addTestSession(t *testing.T, store *Store, chatID int64, l *Location) {
+	t.Helper()
+	us := &UserSession{ChatID: chatID, UserID: chatID, LanguageCode: "en"}
+	us.SetLocation(l)
+	if err := store.UpdateUserSession(us); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addTestSubscription subscribes the chat to the location with the AQI, adding the session of the chat if needed
+func addTestSubscription(t *testing.T, store *Store, chatID int64, l *Location, aqi AirQualityIndex) {
+	t.Helper()
+	if _, err := store.GetSessionByChatID(chatID); err != nil {
+		addTestSession(t, store, chatID, l)
+	}
+	if err := store.AddAQISubscriptionAt(chatID, l, aqi); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestDataPoint returns a DataPoint of the AQI measured at the time with the components
+func newTestDataPoint(aqi AirQualityIndex, t time.Time, components map[string]float64) DataPoint {
+	dp := DataPoint{Dt: t.Unix(), Components: components}
+	dp.Main.Aqi = aqi
+	return dp
+}
+
+func TestListSubscribedChatIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		subs map[int64][]*Location
+		// disabled are the chats unsubscribed from all the locations
+		disabled []int64
+		want     []int64
+	}{
+		{name: "none", want: nil},
+		{
+			name: "overlapping chats",
+			subs: map[int64][]*Location{
+				1: {{53.9, 27.56}, {52.1, 23.7}, {55.75, 37.62}},
+				2: {{53.9, 27.56}},
+				3: {{51.5, -0.12}, {48.85, 2.35}},
+			},
+			want: []int64{1, 2, 3},
+		},
+		{
+			name: "unsubscribed chat",
+			subs: map[int64][]*Location{
+				1: {{53.9, 27.56}, {52.1, 23.7}},
+				2: {{53.9, 27.56}},
+			},
+			disabled: []int64{1},
+			want:     []int64{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			for chatID, locations := range tt.subs {
+				for _, l := range locations {
+					addTestSubscription(t, store, chatID, l, 1)
+				}
+			}
+			for _, chatID := range tt.disabled {
+				if err := store.DeleteAQISubscriptions(chatID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := store.ListSubscribedChatIDs()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListSubscribedChatIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	store := newTestStore(t)
+	addTestSession(t, store, 4, &Location{53.9, 27.56})
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 1)
+	addTestSubscription(t, store, 1, &Location{52.1, 23.7}, 3)
+	addTestSubscription(t, store, 2, &Location{53.9, 27.56}, 3)
+	addTestSubscription(t, store, 3, &Location{51.5, -0.12}, 5)
+	// the unsubscribed chat is counted as a user only
+	if err := store.DeleteAQISubscriptions(3); err != nil {
+		t.Fatal(err)
+	}
+	// the subscriptions of another bot aren't counted
+	addTestSubscription(t, store.ForBot(2), 5, &Location{50.45, 30.52}, 4)
+	dps := []DataPoint{newTestDataPoint(1, time.Now(), nil), newTestDataPoint(2, time.Now(), nil)}
+	if err := store.AddDataPoint(1, &dps); err != nil {
+		t.Fatal(err)
+	}
+	dps = dps[:1]
+	if err := store.AddDataPoint(2, &dps); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Stats{
+		Users:                5,
+		SubscribedChats:      2,
+		EnabledSubscriptions: 3,
+		DataPoints:           3,
+		SubscriptionsByAQI:   map[AirQualityIndex]int{1: 1, 3: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestOpenStoreCustomPath(t *testing.T) {
+	// the missing directories of the path are created
+	path := filepath.Join(t.TempDir(), "data", "bot.db")
+	store, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.DB.Close()
+	addTestSession(t, store, 1, &Location{53.9, 27.56})
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("DB file %s: %v", path, err)
+	}
+	if fi.Size() == 0 {
+		t.Errorf("DB file %s is empty", path)
+	}
+	if _, err := os.Stat(DefaultDBPath); err == nil {
+		t.Errorf("DB file %s is created instead of %s", DefaultDBPath, path)
+	}
+}
+
+func TestExportUserData(t *testing.T) {
+	store := newTestStore(t)
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	addTestSubscription(t, store, 1, &Location{52.1, 23.7}, 3)
+	if err := store.AddFavorite(1, &Location{53.9, 27.56}, "home"); err != nil {
+		t.Fatal(err)
+	}
+	dps := []DataPoint{newTestDataPoint(2, time.Now(), map[string]float64{"co": 200})}
+	if err := store.AddDataPoint(1, &dps); err != nil {
+		t.Fatal(err)
+	}
+	// the data of the other chat is not exported
+	addTestSubscription(t, store, 2, &Location{51.5, -0.12}, 4)
+	if err := store.AddFavorite(2, &Location{51.5, -0.12}, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddDataPoint(2, &dps); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		chatID int64
+		// want are the lengths of the exported lists
+		wantSubscriptions, wantFavorites, wantDataPoints int
+		wantSession                                      bool
+	}{
+		{name: "user", chatID: 1, wantSubscriptions: 2, wantFavorites: 1, wantDataPoints: 1, wantSession: true},
+		{name: "unknown user", chatID: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := store.ExportUserData(tt.chatID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]json.RawMessage
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("exported data is not a JSON object: %v", err)
+			}
+			for _, key := range []string{"session", "prefs", "subscriptions", "favorites", "data_points"} {
+				if _, ok := got[key]; !ok {
+					t.Errorf("exported data has no %q", key)
+				}
+			}
+
+			var ud UserData
+			if err := json.Unmarshal(data, &ud); err != nil {
+				t.Fatal(err)
+			}
+			if (ud.Session != nil) != tt.wantSession {
+				t.Errorf("session = %+v, want session %v", ud.Session, tt.wantSession)
+			}
+			if ud.Session != nil && ud.Session.ChatID != tt.chatID {
+				t.Errorf("session of chat %d, want %d", ud.Session.ChatID, tt.chatID)
+			}
+			if len(ud.Subscriptions) != tt.wantSubscriptions || len(ud.Favorites) != tt.wantFavorites || len(ud.DataPoints) != tt.wantDataPoints {
+				t.Errorf("exported %d subscriptions, %d favorites, %d data points, want %d, %d, %d",
+					len(ud.Subscriptions), len(ud.Favorites), len(ud.DataPoints), tt.wantSubscriptions, tt.wantFavorites, tt.wantDataPoints)
+			}
+			for _, s := range ud.Subscriptions {
+				if s.ChatID != tt.chatID {
+					t.Errorf("subscription of chat %d is exported", s.ChatID)
+				}
+			}
+		})
+	}
+}
+
+// countRows returns the count of the rows of the query
+func countRows(t *testing.T, store *Store, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := store.DB.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// addTestUserData stores the data of the chat in all the tables of it
+func addTestUserData(t *testing.T, store *Store, chatID int64) {
+	t.Helper()
+	l := &Location{53.9 + float64(chatID), 27.56}
+	addTestSubscription(t, store, chatID, l, 2)
+	subs, err := store.ListAQISubscriptions(chatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddAQIHistory((*subs)[0].ID, 2, time.Now(), false); err != nil {
+		t.Fatal(err)
+	}
+	dps := []DataPoint{newTestDataPoint(2, time.Now(), nil)}
+	if err := store.AddDataPoint(chatID, &dps); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddFavorite(chatID, l, "home"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateUserPrefs(&UserPrefs{ChatID: chatID, Weather: true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPurgeUser(t *testing.T) {
+	store := newTestStore(t)
+	addTestUserData(t, store, 1)
+	addTestUserData(t, store, 2)
+
+	if err := store.PurgeUser(1); err != nil {
+		t.Fatal(err)
+	}
+
+	queries := map[string]string{
+		"user_session": "SELECT COUNT(*) FROM user_session WHERE chatid=?",
+		"user_prefs":   "SELECT COUNT(*) FROM user_prefs WHERE chat_id=?",
+		"data_point":   "SELECT COUNT(*) FROM data_point WHERE chat_id=?",
+		"subscription": "SELECT COUNT(*) FROM subscription WHERE chat_id=?",
+		"favorite":     "SELECT COUNT(*) FROM favorite WHERE chat_id=?",
+		"aqi_history":  "SELECT COUNT(*) FROM aqi_history WHERE subscription_id IN (SELECT id FROM subscription WHERE chat_id=?)",
+	}
+	for table, query := range queries {
+		if n := countRows(t, store, query, 1); n != 0 {
+			t.Errorf("%s keeps %d rows of the purged chat", table, n)
+		}
+		if n := countRows(t, store, query, 2); n == 0 {
+			t.Errorf("%s rows of the other chat are purged", table)
+		}
+	}
+	// the orphaned history of the purged subscriptions is deleted too
+	if n := countRows(t, store, "SELECT COUNT(*) FROM aqi_history"); n != 1 {
+		t.Errorf("aqi_history keeps %d rows, want 1", n)
+	}
+}
+
+func TestExportUserDataPrefs(t *testing.T) {
+	store := newTestStore(t)
+	addTestUserData(t, store, 1)
+
+	data, err := store.ExportUserData(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ud UserData
+	if err := json.Unmarshal(data, &ud); err != nil {
+		t.Fatal(err)
+	}
+	if ud.Prefs == nil || !ud.Prefs.Weather {
+		t.Errorf("exported prefs = %+v, want Weather", ud.Prefs)
+	}
+}
+
+func TestTrimDataPoints(t *testing.T) {
+	tests := []struct {
+		name string
+		keep int
+		want int
+	}{
+		{name: "keep 10", keep: 10, want: 10},
+		{name: "keep more than stored", keep: 200, want: 100},
+		{name: "keep none", keep: 0, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			start := time.Now().Add(-100 * time.Hour)
+			var dps []DataPoint
+			for i := 0; i < 100; i++ {
+				dps = append(dps, newTestDataPoint(2, start.Add(time.Duration(i)*time.Hour), nil))
+			}
+			if err := store.AddDataPoint(1, &dps); err != nil {
+				t.Fatal(err)
+			}
+			// the DataPoints of the other chat are kept
+			other := dps[:5]
+			if err := store.AddDataPoint(2, &other); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := store.TrimDataPoints(1, tt.keep); err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := store.DB.Query("SELECT data FROM data_point WHERE chat_id=1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var kept []int64
+			for rows.Next() {
+				var (
+					data []byte
+					dp   DataPoint
+				)
+				if err := rows.Scan(&data); err != nil {
+					t.Fatal(err)
+				}
+				if err := json.Unmarshal(data, &dp); err != nil {
+					t.Fatal(err)
+				}
+				kept = append(kept, dp.Dt)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if len(kept) != tt.want {
+				t.Fatalf("kept %d data points, want %d", len(kept), tt.want)
+			}
+			// the most recent ones are kept
+			recent := dps[len(dps)-tt.want:]
+			for _, dt := range kept {
+				if len(recent) > 0 && dt < recent[0].Dt {
+					t.Errorf("data point of %v is kept", time.Unix(dt, 0))
+				}
+			}
+			rows.Close()
+			if n := countRows(t, store, "SELECT COUNT(*) FROM data_point WHERE chat_id=2"); n != len(other) {
+				t.Errorf("other chat keeps %d data points, want %d", n, len(other))
+			}
+		})
+	}
+}
+
+func TestDeleteDataPointsBefore(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Now().Truncate(time.Second)
+	for chatID, ages := range map[int64][]time.Duration{1: {48 * time.Hour, 25 * time.Hour, time.Hour}, 2: {30 * time.Hour}} {
+		var dps []DataPoint
+		for _, age := range ages {
+			dps = append(dps, newTestDataPoint(2, now.Add(-age), nil))
+		}
+		if err := store.AddDataPoint(chatID, &dps); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.DeleteDataPointsBefore(now.Add(-24 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the old ones of all the chats are deleted, the recent one is kept
+	if n := countRows(t, store, "SELECT COUNT(*) FROM data_point"); n != 1 {
+		t.Errorf("%d data points kept, want 1", n)
+	}
+	if dp, err := store.GetLastPD(1); err != nil || dp.Dt != now.Add(-time.Hour).Unix() {
+		t.Errorf("GetLastPD() = %+v, %v, want the recent data point", dp, err)
+	}
+}
+
+func TestAddDataPointAllOrNothing(t *testing.T) {
+	tests := []struct {
+		name string
+		// broken makes the DataPoint fail to be added
+		broken func(t *testing.T, store *Store, dp *DataPoint)
+	}{
+		{
+			name: "marshaling",
+			broken: func(t *testing.T, store *Store, dp *DataPoint) {
+				dp.Components = map[string]float64{"co": math.NaN()}
+			},
+		},
+		{
+			name: "inserting",
+			broken: func(t *testing.T, store *Store, dp *DataPoint) {
+				dp.Components = map[string]float64{"broken": 1}
+				_, err := store.DB.Exec(`CREATE TRIGGER fail_insert BEFORE INSERT ON data_point
+					WHEN NEW.data LIKE '%"broken"%' BEGIN SELECT RAISE(ABORT, 'injected failure'); END`)
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			var dps []DataPoint
+			for i := 0; i < 2*dataPointsPerInsert; i++ {
+				dps = append(dps, newTestDataPoint(2, time.Now(), map[string]float64{"co": 200}))
+			}
+			// the failure is in the second INSERT, after the first one succeeded
+			tt.broken(t, store, &dps[dataPointsPerInsert+1])
+
+			if err := store.AddDataPoint(1, &dps); err == nil {
+				t.Fatal("AddDataPoint() = nil, want error")
+			}
+			if n := countRows(t, store, "SELECT COUNT(*) FROM data_point"); n != 0 {
+				t.Errorf("%d data points are committed, want 0", n)
+			}
+		})
+	}
+}
+
+func TestAddAQISubscriptionDuplicateDistance(t *testing.T) {
+	// a degree of longitude is about 35 km at the latitude of Murmansk
+	base := &Location{68.97, 33.07}
+	tests := []struct {
+		name    string
+		l       *Location
+		wantErr error
+	}{
+		{name: "same location", l: base, wantErr: ErrNotificationExists},
+		{name: "500 m east", l: base.Destination(90, 500), wantErr: ErrNotificationExists},
+		{name: "500 m north", l: base.Destination(0, 500), wantErr: ErrNotificationExists},
+		{name: "0.02 degrees of longitude east", l: &Location{base.Latitude, base.Longitude + 0.02}, wantErr: ErrNotificationExists},
+		{name: "2 km east", l: base.Destination(90, 2000)},
+		{name: "2 km north", l: base.Destination(0, 2000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			store.DuplicateDistance = 1000
+			addTestSubscription(t, store, 1, base, 2)
+
+			if err := store.AddAQISubscriptionAt(1, tt.l, 2); !errors.Is(err, tt.wantErr) {
+				t.Errorf("AddAQISubscriptionAt() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDuplicateDistanceRadius(t *testing.T) {
+	base := &Location{53.9, 27.56}
+	for _, radius := range []float64{0, 150, 500} {
+		configured := radius
+		if configured == 0 {
+			configured = DefaultDuplicateDistance
+		}
+		for _, bearing := range []float64{0, 90, 225} {
+			tests := []struct {
+				name     string
+				distance float64
+				wantErr  error
+			}{
+				{name: "just inside", distance: configured - 1, wantErr: ErrNotificationExists},
+				{name: "just outside", distance: configured + 1},
+			}
+			for _, tt := range tests {
+				t.Run(fmt.Sprintf("%v m %s at %v°", radius, tt.name, bearing), func(t *testing.T) {
+					store := newTestStore(t)
+					store.DuplicateDistance = radius
+					addTestSubscription(t, store, 1, base, 2)
+					l := base.Destination(bearing, tt.distance)
+
+					if err := store.AddAQISubscriptionAt(1, l, 2); !errors.Is(err, tt.wantErr) {
+						t.Errorf("AddAQISubscriptionAt() %.1f m away = %v, want %v", base.DistanceTo(l), err, tt.wantErr)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestLastMessage(t *testing.T) {
+	store := newTestStore(t)
+	addTestSession(t, store, 1, &Location{53.9, 27.56})
+
+	tests := []struct {
+		name   string
+		chatID int64
+		// set stores the message of the chat if not zero
+		set    int
+		wantID int
+	}{
+		{name: "none", chatID: 1},
+		{name: "set", chatID: 1, set: 10, wantID: 10},
+		{name: "replaced", chatID: 1, set: 11, wantID: 11},
+		{name: "unknown chat", chatID: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentAt := time.Now().Truncate(time.Second)
+			if tt.set != 0 {
+				if err := store.SetLastMessage(tt.chatID, tt.set, sentAt); err != nil {
+					t.Fatal(err)
+				}
+			}
+			id, at, err := store.GetLastMessage(tt.chatID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.wantID {
+				t.Errorf("GetLastMessage() = %d, want %d", id, tt.wantID)
+			}
+			if tt.set != 0 && !at.Equal(sentAt) {
+				t.Errorf("GetLastMessage() time = %v, want %v", at, sentAt)
+			}
+			if tt.wantID == 0 && !at.IsZero() {
+				t.Errorf("GetLastMessage() time = %v, want zero", at)
+			}
+		})
+	}
+}
+
+func TestUpdateSubscriptionAQIBatch(t *testing.T) {
+	store := newTestStore(t)
+	locations := []*Location{{53.9, 27.56}, {52.1, 23.7}, {55.75, 37.62}, {51.5, -0.12}}
+	for _, l := range locations {
+		addTestSubscription(t, store, 1, l, 1)
+	}
+	subs, err := store.ListAQISubscriptions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the pending AQI is reset by the update
+	if err := store.SetSubscriptionPendingAQI((*subs)[0].ID, 3); err != nil {
+		t.Fatal(err)
+	}
+	// the last subscription isn't updated
+	aqis := map[int64]AirQualityIndex{(*subs)[0].ID: 2, (*subs)[1].ID: 4, (*subs)[2].ID: 5}
+	if err := store.UpdateSubscriptionAQIBatch(aqis); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateSubscriptionAQIBatch(nil); err != nil {
+		t.Errorf("UpdateSubscriptionAQIBatch(nil) = %v", err)
+	}
+
+	updated, err := store.ListAQISubscriptions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range *updated {
+		want, ok := aqis[s.ID]
+		if !ok {
+			want = 1
+		}
+		if s.AirQualityIndex != want {
+			t.Errorf("subscription %d AQI = %d, want %d", s.ID, s.AirQualityIndex, want)
+		}
+		if s.PendingAQI != 0 {
+			t.Errorf("subscription %d pending AQI = %d, want 0", s.ID, s.PendingAQI)
+		}
+	}
+}
+
+func TestStoreErrorsWrapSentinels(t *testing.T) {
+	store := newTestStore(t)
+	store.MaxSubscriptions = 2
+	addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+	addTestSubscription(t, store, 1, &Location{52.1, 23.7}, 2)
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "duplicate subscription", err: store.AddAQISubscriptionAt(1, &Location{53.9, 27.56}, 2), want: ErrNotificationExists},
+		{name: "subscription limit", err: store.AddAQISubscriptionAt(1, &Location{55.75, 37.62}, 2), want: ErrSubscriptionLimitReached},
+		{name: "subscription of unknown chat", err: store.AddAQISubscriptionAt(2, &Location{55.75, 37.62}, 2), want: sql.ErrNoRows},
+		{name: "duplicate favorite", err: func() error {
+			if err := store.AddFavorite(1, &Location{53.9, 27.56}, ""); err != nil {
+				return err
+			}
+			return store.AddFavorite(1, &Location{53.9, 27.56}, "")
+		}(), want: ErrFavoriteExists},
+		{name: "unknown session", err: func() error {
+			_, err := store.GetSessionByChatID(2)
+			return err
+		}(), want: sql.ErrNoRows},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: error = %v, want errors.Is %v", tt.name, tt.err, tt.want)
+		}
+	}
+}
+
+func TestCoordinatePrecision(t *testing.T) {
+	exact := &Location{53.901234, 27.567891}
+	tests := []struct {
+		name      string
+		precision int
+		want      Location
+	}{
+		{name: "exact", precision: 0, want: *exact},
+		{name: "2 decimals", precision: 2, want: Location{53.9, 27.57}},
+		{name: "4 decimals", precision: 4, want: Location{53.9012, 27.5679}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			store.CoordinatePrecision = tt.precision
+			addTestSubscription(t, store, 1, exact, 2)
+
+			us, err := store.GetSessionByChatID(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := *us.Location(); got != tt.want {
+				t.Errorf("stored session location = %v, want %v", got, tt.want)
+			}
+			sub := onlySubscription(t, store, 1)
+			if got := *sub.Location(); got != tt.want {
+				t.Errorf("stored subscription location = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportImportSubscriptions(t *testing.T) {
+	src := newTestStore(t)
+	minsk, london := &Location{53.9, 27.56}, &Location{51.51, -0.13}
+	addTestSubscription(t, src, 1, minsk, 2)
+	addTestSubscription(t, src, 1, london, 3)
+	addTestSubscription(t, src, 2, minsk, 4)
+	addTestSubscription(t, src, 3, london, 1)
+	if err := src.SetSubscriptionsFrequency(1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SetSubscriptionsMode(2, SubscriptionModeDigest); err != nil {
+		t.Fatal(err)
+	}
+	subs, err := src.ListAQISubscriptions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SetSubscriptionLabel((*subs)[0].ID, "Home"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.DeleteAQISubscriptions(3); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := src.ExportSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := newTestStore(t)
+	addTestSubscription(t, dst, 2, &Location{40.71, -74.01}, 1)
+	if err := dst.ImportSubscriptions(data); err != nil {
+		t.Fatal(err)
+	}
+
+	type exported struct {
+		ChatID    int64
+		Location  Location
+		AQI       AirQualityIndex
+		Frequency time.Duration
+		Mode      SubscriptionMode
+		Label     string
+	}
+	summary := func(store *Store) []exported {
+		t.Helper()
+		subs, err := store.ListEnabledSubscriptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []exported
+		for i := range *subs {
+			s := &(*subs)[i]
+			got = append(got, exported{s.ChatID, *s.Location(), s.AirQualityIndex, s.Frequency, s.Mode, s.Label})
+		}
+		sort.Slice(got, func(i, j int) bool {
+			if got[i].ChatID != got[j].ChatID {
+				return got[i].ChatID < got[j].ChatID
+			}
+			return got[i].Location.Latitude < got[j].Location.Latitude
+		})
+		return got
+	}
+	want := []exported{
+		{1, *london, 3, time.Hour, SubscriptionModeAlerts, ""},
+		{1, *minsk, 2, time.Hour, SubscriptionModeAlerts, "Home"},
+		{2, Location{40.71, -74.01}, 1, 0, SubscriptionModeAlerts, ""},
+		{2, *minsk, 4, 0, SubscriptionModeDigest, ""},
+	}
+	if got := summary(dst); !reflect.DeepEqual(got, want) {
+		t.Errorf("imported subscriptions = %+v, want %+v", got, want)
+	}
+
+	// importing again skips the duplicates
+	if err := dst.ImportSubscriptions(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := summary(dst); !reflect.DeepEqual(got, want) {
+		t.Errorf("imported again = %+v, want %+v", got, want)
+	}
+}
+
+func TestImportSubscriptionsMalformed(t *testing.T) {
+	for _, data := range []string{"", "{", `{"ChatID":1}`, `[{"ChatID":"one"}]`} {
+		store := newTestStore(t)
+		if err := store.ImportSubscriptions([]byte(data)); err == nil {
+			t.Errorf("ImportSubscriptions(%q) = nil, want an error", data)
+		}
+		if n := countRows(t, store, "SELECT COUNT(*) FROM subscription"); n != 0 {
+			t.Errorf("ImportSubscriptions(%q) added %d subscription(s)", data, n)
+		}
+	}
+}
+
+func TestRawResponses(t *testing.T) {
+	store := newTestStore(t)
+	minsk, london := &Location{53.9, 27.56}, &Location{51.51, -0.13}
+	now := time.Now().Truncate(time.Second)
+	for i, r := range []struct {
+		l    *Location
+		body string
+	}{{minsk, "minsk 1"}, {london, "london 1"}, {minsk, "minsk 2"}, {minsk, "minsk 3"}} {
+		if err := store.AddRawResponse(r.l, []byte(r.body), now.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bodies := func(responses []RawResponse) []string {
+		var got []string
+		for _, r := range responses {
+			got = append(got, r.Body)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name  string
+		near  *Location
+		limit int
+		want  []string
+	}{
+		{name: "all", limit: 10, want: []string{"minsk 3", "minsk 2", "london 1", "minsk 1"}},
+		{name: "limit", limit: 2, want: []string{"minsk 3", "minsk 2"}},
+		{name: "near", near: &Location{53.9001, 27.5601}, limit: 10, want: []string{"minsk 3", "minsk 2", "minsk 1"}},
+		{name: "near with limit", near: london, limit: 1, want: []string{"london 1"}},
+		{name: "nothing near", near: &Location{40.71, -74.01}, limit: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.ListRawResponses(tt.near, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(bodies(got), tt.want) {
+				t.Errorf("ListRawResponses() = %q, want %q", bodies(got), tt.want)
+			}
+		})
+	}
+
+	got, err := store.ListRawResponses(nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Location != *minsk || !got[0].CreatedAt.Equal(now.Add(3*time.Minute)) {
+		t.Errorf("ListRawResponses() = %+v, want the one of %v at %v", got, *minsk, now.Add(3*time.Minute))
+	}
+
+	if err := store.DeleteRawResponsesBefore(now.Add(2 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.ListRawResponses(nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"minsk 3", "minsk 2"}; !reflect.DeepEqual(bodies(got), want) {
+		t.Errorf("after DeleteRawResponsesBefore() = %q, want %q", bodies(got), want)
+	}
+}
+
+func TestGetDataPoints(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Now().Truncate(time.Hour).Add(-10 * time.Hour)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+	// stored out of order, hour 2 twice
+	dps := []DataPoint{
+		newTestDataPoint(3, at(3), nil),
+		newTestDataPoint(1, at(1), nil),
+		newTestDataPoint(2, at(2), nil),
+		newTestDataPoint(4, at(2), nil),
+		newTestDataPoint(5, at(5), nil),
+	}
+	if err := store.AddDataPoint(1, &dps); err != nil {
+		t.Fatal(err)
+	}
+	other := []DataPoint{newTestDataPoint(1, at(2), nil)}
+	if err := store.AddDataPoint(2, &other); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []AirQualityIndex
+	}{
+		{name: "all", from: at(0), to: at(10), want: []AirQualityIndex{1, 2, 4, 3, 5}},
+		{name: "inclusive bounds", from: at(2), to: at(3), want: []AirQualityIndex{2, 4, 3}},
+		{name: "single instant", from: at(5), to: at(5), want: []AirQualityIndex{5}},
+		{name: "between the points", from: at(3).Add(time.Second), to: at(5).Add(-time.Second), want: []AirQualityIndex{}},
+		{name: "bounds in UTC", from: at(1).UTC(), to: at(2).UTC(), want: []AirQualityIndex{1, 2, 4}},
+		{name: "reversed", from: at(5), to: at(1), want: []AirQualityIndex{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.GetDataPoints(1, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == nil {
+				t.Fatal("GetDataPoints() = nil, want a slice")
+			}
+			aqis := []AirQualityIndex{}
+			for _, dp := range got {
+				aqis = append(aqis, dp.GetAQI())
+			}
+			if !reflect.DeepEqual(aqis, tt.want) {
+				t.Errorf("GetDataPoints() AQIs = %v, want %v", aqis, tt.want)
+			}
+		})
+	}
+
+	got, err := store.GetDataPoints(3, at(0), at(10))
+	if err != nil || got == nil || len(got) != 0 {
+		t.Errorf("GetDataPoints() of a chat without data points = %v, %v, want an empty slice", got, err)
+	}
+}
+
+func TestGetLastPD(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    AirQualityIndex
+		wantErr bool
+	}{
+		{name: "valid", data: `{"dt":1700000000,"main":{"aqi":3},"components":{"co":1}}`, want: 3},
+		{name: "invalid JSON", data: `{"dt":1700000000,"main":`, wantErr: true},
+		{name: "wrong type", data: `{"dt":"yesterday"}`, wantErr: true},
+		{name: "empty", data: ``, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			older := []DataPoint{newTestDataPoint(1, time.Now().Add(-time.Hour), nil)}
+			if err := store.AddDataPoint(1, &older); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.DB.Exec("INSERT INTO data_point (chat_id, data, created_at) VALUES (?, ?, ?)",
+				1, []byte(tt.data), time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			dp, err := store.GetLastPD(1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetLastPD() error = %v, want error %v", err, tt.wantErr)
+			}
+			if dp == nil {
+				t.Fatal("GetLastPD() = nil")
+			}
+			if !tt.wantErr && dp.GetAQI() != tt.want {
+				t.Errorf("GetLastPD() AQI = %v, want %v", dp.GetAQI(), tt.want)
+			}
+		})
+	}
+
+	store := newTestStore(t)
+	if dp, err := store.GetLastPD(1); err != nil || dp.Dt != 0 {
+		t.Errorf("GetLastPD() without data points = %+v, %v, want an empty DataPoint", dp, err)
+	}
+}
+
+func TestSetSubscriptionLabel(t *testing.T) {
+	store := newTestStore(t)
+	for i := 0; i < 3; i++ {
+		addTestSubscription(t, store, 1, &Location{53.9, 27.56 + float64(i)}, 2)
+	}
+	subs, err := store.ListAQISubscriptions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the label and the check don't change the order of the subscriptions
+	if err := store.SetSubscriptionLabel((*subs)[1].ID, "Office"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkSubscriptionChecked((*subs)[0].ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateSubscriptionAQI((*subs)[0].ID, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.ListAQISubscriptions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range *got {
+		if s.ID != (*subs)[i].ID {
+			t.Errorf("subscription %d ID = %d, want %d", i+1, s.ID, (*subs)[i].ID)
+		}
+		if i > 0 && s.ID < (*got)[i-1].ID {
+			t.Errorf("subscription %d ID %d is before %d", i+1, s.ID, (*got)[i-1].ID)
+		}
+		if want := map[bool]string{true: "Office"}[i == 1]; s.Label != want {
+			t.Errorf("subscription %d Label = %q, want %q", i+1, s.Label, want)
+		}
+	}
+
+	if err := store.SetSubscriptionLabel((*subs)[1].ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.ListAQISubscriptions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if label := (*got)[1].Label; label != "" {
+		t.Errorf("Label after removing = %q, want none", label)
+	}
+}
+
+func TestResubscribeEnablesDisabledSubscription(t *testing.T) {
+	minsk := &Location{53.9, 27.56}
+	tests := []struct {
+		name string
+		// again is the location subscribed to after unsubscribing
+		again    *Location
+		wantRows int
+	}{
+		{name: "same location", again: minsk, wantRows: 1},
+		{name: "duplicate distance", again: &Location{53.9001, 27.5601}, wantRows: 1},
+		{name: "another location", again: &Location{52.1, 23.7}, wantRows: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, minsk, 2)
+			id := onlySubscription(t, store, 1).ID
+			if err := store.SnoozeSubscriptions(1, time.Now().Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SetSubscriptionPendingAQI(id, 3); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.DeleteAQISubscriptions(1); err != nil {
+				t.Fatal(err)
+			}
+			// subscribing twice is retry safe
+			for i := 0; i < 2; i++ {
+				err := store.AddAQISubscriptionAt(1, tt.again, 4)
+				if i == 1 && errors.Is(err, ErrNotificationExists) {
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if n := countRows(t, store, "SELECT COUNT(*) FROM subscription WHERE chat_id=?", 1); n != tt.wantRows {
+				t.Errorf("%d subscription rows, want %d", n, tt.wantRows)
+			}
+			subs, err := store.ListAQISubscriptions(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*subs) != 1 {
+				t.Fatalf("%d enabled subscriptions, want 1", len(*subs))
+			}
+			s := (*subs)[0]
+			if reused := s.ID == id; reused != (tt.wantRows == 1) {
+				t.Errorf("subscription ID = %d, the disabled one is %d", s.ID, id)
+			}
+			if s.AirQualityIndex != 4 || s.PendingAQI != 0 || !s.SnoozedUntil.IsZero() {
+				t.Errorf("subscription AQI = %v, PendingAQI = %v, SnoozedUntil = %v, want AQI 4 without a pending AQI and a snooze",
+					s.AirQualityIndex, s.PendingAQI, s.SnoozedUntil)
+			}
+		})
+	}
+}
+
+// newTestForecast returns n hourly DataPoints starting at the time
+func newTestForecast(start time.Time, n int) []DataPoint {
+	dps := make([]DataPoint, 0, n)
+	for i := 0; i < n; i++ {
+		dps = append(dps, newTestDataPoint(AirQualityIndex(1+i%5), start.Add(time.Duration(i)*time.Hour), map[string]float64{"co": 200, "pm2_5": float64(i)}))
+	}
+	return dps
+}
+
+func TestAddDataPointBulk(t *testing.T) {
+	start := time.Now().Truncate(time.Hour)
+	for _, n := range []int{0, 1, 48, dataPointsPerInsert, dataPointsPerInsert + 1} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			store := newTestStore(t)
+			dps := newTestForecast(start, n)
+			if err := store.AddDataPoint(1, &dps); err != nil {
+				t.Fatal(err)
+			}
+			if got := countRows(t, store, "SELECT COUNT(*) FROM data_point WHERE chat_id=?", 1); got != n {
+				t.Errorf("%d rows, want %d", got, n)
+			}
+			got, err := store.GetDataPoints(1, start, start.Add(time.Duration(n)*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, dps) {
+				t.Errorf("GetDataPoints() = %d data points, want the %d added in order", len(got), n)
+			}
+		})
+	}
+}
+
+func BenchmarkAddDataPointForecast(b *testing.B) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	store := &Store{DB: db}
+	if err := store.Init(); err != nil {
+		b.Fatal(err)
+	}
+	dps := newTestForecast(time.Now(), 48)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := store.AddDataPoint(int64(i), &dps); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMaintainAfterBulkDeletes(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store := &Store{DB: db, CacheTime: DefaultCacheTime}
+	if err := store.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for chatID := int64(1); chatID <= 10; chatID++ {
+		dps := newTestForecast(time.Now().Add(-30*24*time.Hour), 500)
+		if err := store.AddDataPoint(chatID, &dps); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.DB.Exec("DELETE FROM data_point WHERE chat_id<>1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.TrimDataPoints(1, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	before, after, err := store.Maintain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after >= before {
+		t.Errorf("Maintain() size %d -> %d bytes, want the space of the deleted rows given back", before, after)
+	}
+	if size, err := store.Size(); err != nil || size != after {
+		t.Errorf("Size() = %d, %v, want %d", size, err, after)
+	}
+	// the data is kept and the DB is usable after the rebuild
+	if n := countRows(t, store, "SELECT COUNT(*) FROM data_point"); n != 10 {
+		t.Errorf("%d DataPoints after Maintain(), want 10", n)
+	}
+	if n := countRows(t, store, "SELECT COUNT(*) FROM sqlite_stat1"); n == 0 {
+		t.Error("no statistics of the query planner after Maintain()")
+	}
+	dps := newTestForecast(time.Now(), 1)
+	if err := store.AddDataPoint(1, &dps); err != nil {
+		t.Fatal(err)
+	}
+
+	// maintaining a compact DB doesn't fail
+	if _, _, err := store.Maintain(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDataPointChanged(t *testing.T) {
+	now := time.Now()
+	prev := newTestDataPoint(2, now, map[string]float64{"co": 200, "pm2_5": 10})
+	tests := []struct {
+		name       string
+		dp         DataPoint
+		minChange  float64
+		wantChange bool
+	}{
+		{name: "same", dp: newTestDataPoint(2, now, map[string]float64{"co": 200, "pm2_5": 10})},
+		{name: "within the change", dp: newTestDataPoint(2, now, map[string]float64{"co": 209, "pm2_5": 9.5}), minChange: 0.05},
+		{name: "at the change", dp: newTestDataPoint(2, now, map[string]float64{"co": 210, "pm2_5": 10}), minChange: 0.05},
+		{name: "above the change", dp: newTestDataPoint(2, now, map[string]float64{"co": 200, "pm2_5": 10.6}), minChange: 0.05, wantChange: true},
+		{name: "decrease above the change", dp: newTestDataPoint(2, now, map[string]float64{"co": 180, "pm2_5": 10}), minChange: 0.05, wantChange: true},
+		{name: "AQI", dp: newTestDataPoint(3, now, map[string]float64{"co": 200, "pm2_5": 10}), minChange: 0.05, wantChange: true},
+		{name: "component added", dp: newTestDataPoint(2, now, map[string]float64{"co": 200, "pm2_5": 10, "o3": 1}), minChange: 0.05, wantChange: true},
+		{name: "component replaced", dp: newTestDataPoint(2, now, map[string]float64{"co": 200, "o3": 10}), minChange: 0.05, wantChange: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dataPointChanged(&prev, &tt.dp, tt.minChange); got != tt.wantChange {
+				t.Errorf("dataPointChanged() = %v, want %v", got, tt.wantChange)
+			}
+		})
+	}
+}
+
+func TestAddChangedDataPoints(t *testing.T) {
+	start := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	// dp returns a DataPoint checked h half-hours after the start
+	dp := func(h int, aqi AirQualityIndex, pm float64) DataPoint {
+		return newTestDataPoint(aqi, start.Add(time.Duration(h)*30*time.Minute), map[string]float64{"co": 200, "pm2_5": pm})
+	}
+	minsk := &Location{53.9, 27.56}
+	tests := []struct {
+		name      string
+		minChange float64
+		// stored are the DataPoints stored before
+		stored []DataPoint
+		// storedAt is the location of the stored DataPoints, minsk if nil
+		storedAt *Location
+		dps      []DataPoint
+		// want are the indexes of the stored dps
+		want []int
+	}{
+		{name: "first", minChange: 0.1, dps: []DataPoint{dp(0, 2, 10)}, want: []int{0}},
+		{name: "unchanged skipped", minChange: 0.1, stored: []DataPoint{dp(0, 2, 10)},
+			dps: []DataPoint{dp(1, 2, 10), dp(2, 2, 10.5)}},
+		{name: "stored on change", minChange: 0.1, stored: []DataPoint{dp(0, 2, 10)},
+			dps: []DataPoint{dp(1, 2, 10.5), dp(2, 3, 10.5), dp(3, 3, 12), dp(4, 3, 12.5)}, want: []int{1, 2}},
+		// the slow drift is stored once it adds up to the change, as each one is compared with the last stored one
+		{name: "drift", minChange: 0.1, stored: []DataPoint{dp(0, 2, 10)},
+			dps: []DataPoint{dp(1, 2, 10.6), dp(2, 2, 10.8), dp(3, 2, 11.2), dp(4, 2, 11.5)}, want: []int{2}},
+		// the last DataPoint of another subscription of the chat isn't compared with
+		{name: "other location", minChange: 0.1, stored: []DataPoint{dp(0, 2, 10)}, storedAt: &Location{50.45, 30.52},
+			dps: []DataPoint{dp(1, 2, 10)}, want: []int{0}},
+		{name: "same rounded location", minChange: 0.1, stored: []DataPoint{dp(0, 2, 10)}, storedAt: &Location{53.90004, 27.56004},
+			dps: []DataPoint{dp(1, 2, 10)}},
+		{name: "disabled", dps: []DataPoint{dp(0, 2, 10), dp(1, 2, 10), dp(2, 2, 10)}, stored: []DataPoint{dp(0, 2, 10)}, want: []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			store.DataPointMinChange = tt.minChange
+			if len(tt.stored) > 0 {
+				storedAt := tt.storedAt
+				if storedAt == nil {
+					storedAt = minsk
+				}
+				if err := store.AddDataPointAt(1, storedAt, &tt.stored); err != nil {
+					t.Fatal(err)
+				}
+			}
+			before := countRows(t, store, "SELECT COUNT(*) FROM data_point WHERE chat_id=1")
+			compacted := dataPointsCompacted.Value()
+
+			dps := tt.dps
+			n, err := store.AddChangedDataPoints(1, minsk, &dps)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n != len(tt.want) {
+				t.Errorf("AddChangedDataPoints() = %d, want %d", n, len(tt.want))
+			}
+			if got := countRows(t, store, "SELECT COUNT(*) FROM data_point WHERE chat_id=1") - before; got != len(tt.want) {
+				t.Errorf("%d DataPoints stored, want %d", got, len(tt.want))
+			}
+			if got := dataPointsCompacted.Value() - compacted; got != int64(len(tt.dps)-len(tt.want)) {
+				t.Errorf("data_points_compacted = %d, want %d", got, len(tt.dps)-len(tt.want))
+			}
+			if len(tt.want) == 0 {
+				return
+			}
+			last, err := store.GetLastPDAt(1, minsk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.dps[tt.want[len(tt.want)-1]]; last.Dt != want.Dt || last.GetAQI() != want.GetAQI() {
+				t.Errorf("last DataPoint = %v of AQI %v, want %v of AQI %v", last.Dt, last.GetAQI(), want.Dt, want.GetAQI())
+			}
+		})
+	}
+}
+
+func TestGeocodeCache(t *testing.T) {
+	minsk := &Location{53.9, 27.56}
+	tests := []struct {
+		name string
+		// place is cached for minsk age ago, nothing if nil
+		place  *Place
+		age    time.Duration
+		l      *Location
+		maxAge time.Duration
+		want   *Place
+	}{
+		{name: "miss", l: minsk, maxAge: time.Hour},
+		{name: "hit", place: &Place{Name: "Minsk", Country: "BY"}, l: minsk, maxAge: time.Hour,
+			want: &Place{Name: "Minsk", Country: "BY"}},
+		{name: "hit of the rounded coordinates", place: &Place{Name: "Minsk"}, l: &Location{53.9004, 27.5596}, maxAge: time.Hour,
+			want: &Place{Name: "Minsk"}},
+		{name: "miss of other coordinates", place: &Place{Name: "Minsk"}, l: &Location{53.901, 27.56}, maxAge: time.Hour},
+		{name: "within the TTL", place: &Place{Name: "Minsk"}, age: 59 * time.Minute, l: minsk, maxAge: time.Hour,
+			want: &Place{Name: "Minsk"}},
+		{name: "expired", place: &Place{Name: "Minsk"}, age: time.Hour, l: minsk, maxAge: time.Hour},
+		{name: "not found", place: &Place{}, l: minsk, maxAge: time.Hour, want: &Place{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			if tt.place != nil {
+				if err := store.SetGeocode(minsk, tt.place); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := store.DB.Exec("UPDATE geocode_cache SET created_at=?", time.Now().Add(-tt.age).UTC()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := store.GetGeocode(tt.l, tt.maxAge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetGeocode(%v) = %+v, want %+v", *tt.l, got, tt.want)
+			}
+		})
+	}
+
+	// SetGeocode replaces the cached Place
+	store := newTestStore(t)
+	for _, name := range []string{"Minsk", "Мінск"} {
+		if err := store.SetGeocode(minsk, &Place{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := store.GetGeocode(minsk, time.Hour); err != nil || got == nil || got.Name != "Мінск" {
+		t.Errorf("GetGeocode() = %+v, %v, want the last Place", got, err)
+	}
+	if n := countRows(t, store, "SELECT COUNT(*) FROM geocode_cache"); n != 1 {
+		t.Errorf("%d cached places, want 1", n)
+	}
+}
+
+func TestFavorites(t *testing.T) {
+	store := newTestStore(t)
+	store.CoordinatePrecision = 3
+	minsk := &Location{53.90012, 27.56012}
+	if err := store.AddFavorite(1, minsk, "Home"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		chatID  int64
+		l       *Location
+		wantErr error
+	}{
+		{name: "nearby", chatID: 1, l: &Location{53.9005, 27.5605}, wantErr: ErrFavoriteExists},
+		{name: "far", chatID: 1, l: &Location{52.1, 23.7}},
+		{name: "another chat", chatID: 2, l: minsk},
+	}
+	for _, tt := range tests {
+		if err := store.AddFavorite(tt.chatID, tt.l, ""); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: AddFavorite() = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	favorites, err := store.ListFavorites(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favorites) != 2 || favorites[0].Label != "Home" || favorites[0].Location != (Location{53.9, 27.56}) || favorites[1].Label != "" {
+		t.Fatalf("ListFavorites() = %+v, want the rounded Home and the far one", favorites)
+	}
+	// favorites of another chat are kept
+	if err := store.DeleteFavorite(2, favorites[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteFavorite(1, favorites[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if favorites, err := store.ListFavorites(1); err != nil || len(favorites) != 1 || favorites[0].Latitude != 52.1 {
+		t.Errorf("ListFavorites() = %+v, %v, want the far one", favorites, err)
+	}
+
+	data, err := store.ExportUserData(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ud UserData
+	if err := json.Unmarshal(data, &ud); err != nil {
+		t.Fatal(err)
+	}
+	if len(ud.Favorites) != 1 {
+		t.Errorf("exported favorites = %+v, want 1", ud.Favorites)
+	}
+	if err := store.PurgeUser(2); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, store, "SELECT COUNT(*) FROM favorite WHERE chat_id=2"); n != 0 {
+		t.Errorf("%d favorites after PurgeUser, want 0", n)
+	}
+}
+
+func TestRestoreRecentlyDisabled(t *testing.T) {
+	locations := []*Location{{53.9, 27.56}, {52.1, 23.7}, {55.75, 37.62}}
+	tests := []struct {
+		name string
+		// disabled are the ages of the disabled subscriptions of the locations
+		disabled []time.Duration
+		// enabled is subscribed after the cleanup
+		enabled *Location
+		limit   int
+		// want are the restored locations
+		want []*Location
+	}{
+		{name: "all recent", disabled: []time.Duration{time.Minute, time.Hour}, want: locations[:2]},
+		{name: "before the time", disabled: []time.Duration{time.Minute, 3 * time.Hour}, want: locations[:1]},
+		{name: "none", disabled: []time.Duration{3 * time.Hour}},
+		{name: "duplicate of an enabled one", disabled: []time.Duration{time.Minute, time.Hour},
+			enabled: &Location{53.9005, 27.56}, want: locations[1:2]},
+		{name: "limited, the most recent first", disabled: []time.Duration{time.Hour, time.Minute, 30 * time.Minute},
+			limit: 2, want: []*Location{locations[1], locations[2]}},
+		{name: "limit of the enabled", disabled: []time.Duration{time.Minute, time.Hour},
+			enabled: locations[2], limit: 2, want: locations[:1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			for i, age := range tt.disabled {
+				addTestSubscription(t, store, 1, locations[i], 2)
+				if _, err := store.DB.Exec("UPDATE subscription SET enabled=0, disabled_at=? WHERE latitude=?",
+					time.Now().Add(-age), locations[i].Latitude); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.enabled != nil {
+				if _, err := store.DB.Exec("INSERT INTO subscription (chat_id, language, longitude, latitude, aqi, enabled, created_at) VALUES (1, 'en', ?, ?, 2, 1, ?)",
+					tt.enabled.Longitude, tt.enabled.Latitude, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			store.MaxSubscriptions = tt.limit
+
+			n, err := store.RestoreRecentlyDisabled(1, time.Now().Add(-2*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n != len(tt.want) {
+				t.Errorf("RestoreRecentlyDisabled() = %d, want %d", n, len(tt.want))
+			}
+			for _, l := range tt.want {
+				if c := countRows(t, store, "SELECT COUNT(*) FROM subscription WHERE latitude=? AND enabled=1 AND disabled_at IS NULL", l.Latitude); c != 1 {
+					t.Errorf("%v isn't restored", *l)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	testNotificationHeader = "🧪 Test notification. Your alerts look like this:"
+	testUsageMsg           = "Usage: /test [N], where N is the number of the subscription in /subsriptions"
+	testNoSubsMsg          = "You have no subscriptions to test. Share your location and subscribe first"
+)
+
+// testNotificationText returns the notification Cron would send for the subscription number arg of the chat,
+// the first one if arg is empty, with the current AQI. Nothing is stored.
+func (bot *Bot) testNotificationText(chatID int64, arg string, p *message.Printer) string {
+	subs, err := bot.store.ListAQISubscriptions(chatID)
+	if err != nil {
+		log.Print("ListAQISubscriptions: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if len(*subs) == 0 {
+		return p.Sprintf(testNoSubsMsg)
+	}
+	n := 1
+	if arg = strings.TrimSpace(arg); arg != "" {
+		n, err = strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(*subs) {
+			return p.Sprintf(testUsageMsg)
+		}
+	}
+	s := (*subs)[n-1]
+
+	resp, err := bot.previewAirPollution(s.Location())
+	if err == nil && len(resp.DP) == 0 {
+		err = errNoDataPoints
+	}
+	if err != nil {
+		log.Print("GetAirPollution: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	return p.Sprintf(testNotificationHeader) + "\n\n" + bot.formatCronNotification(&s, &resp.DP[0], bot.prefsOf(chatID), p)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTestNotificationText(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		aqi     *fakeAQI
+		// sub is the number of the subscription of the test notification
+		sub  int
+		want string
+	}{
+		{name: "first subscription", command: "/test", aqi: &fakeAQI{aqi: 4}, sub: 1},
+		{name: "numbered subscription", command: "/test 2", aqi: &fakeAQI{aqi: 4}, sub: 2},
+		{name: "no such subscription", command: "/test 3", aqi: &fakeAQI{aqi: 4}, want: testUsageMsg},
+		{name: "not a number", command: "/test home", aqi: &fakeAQI{aqi: 4}, want: testUsageMsg},
+		{name: "fetch failed", command: "/test", aqi: &fakeAQI{err: errors.New("owm is down")}, want: safeToRetryErrMsg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 1)
+			addTestSubscription(t, store, 1, &Location{52.1, 23.7}, 1)
+			// the label tells the notifications of the subscriptions apart
+			subs, err := store.ListAQISubscriptions(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SetSubscriptionLabel((*subs)[1].ID, "Office"); err != nil {
+				t.Fatal(err)
+			}
+			fake := &fakeTelegram{}
+			bot := newTestCronBot(t, store, tt.aqi, fake)
+			before := countRows(t, store, "SELECT total_changes()")
+
+			bot.handleMessage(newTestCommand(1, tt.command))
+			got := fake.lastText()
+
+			if n := countRows(t, store, "SELECT total_changes()") - before; n != 0 {
+				t.Errorf("%s changed %d DB rows, want 0", tt.command, n)
+			}
+			if tt.want != "" {
+				if got != tt.want {
+					t.Errorf("%s = %q, want %q", tt.command, got, tt.want)
+				}
+				return
+			}
+
+			// the test notification is the one Cron sends for the change of the AQI
+			if !strings.HasPrefix(got, testNotificationHeader+"\n\n") {
+				t.Fatalf("%s = %q, want the prefix %q", tt.command, got, testNotificationHeader)
+			}
+			// only the tested subscription is notified
+			if _, err := store.DB.Exec("UPDATE subscription SET enabled=0 WHERE id<>?", (*subs)[tt.sub-1].ID); err != nil {
+				t.Fatal(err)
+			}
+			bot.Cron()
+			if notification := fake.lastText(); got != testNotificationHeader+"\n\n"+notification {
+				t.Errorf("%s = %q, want the header and the Cron notification %q", tt.command, got, notification)
+			}
+		})
+	}
+
+	store := newTestStore(t)
+	addTestSession(t, store, 1, &Location{53.9, 27.56})
+	fake := &fakeTelegram{}
+	bot := newTestCronBot(t, store, &fakeAQI{aqi: 4}, fake)
+	bot.handleMessage(newTestCommand(1, "/test"))
+	if got := fake.lastText(); got != testNoSubsMsg {
+		t.Errorf("/test without subscriptions = %q, want %q", got, testNoSubsMsg)
+	}
+}
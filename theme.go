@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/text/message"
+)
+
+// Theme selects the style of the AQI in the messages
+type Theme string
+
+const (
+	// ThemeEmoji shows the AQI with the colored squares, e.g. "🟨 (Fair)"
+	ThemeEmoji Theme = "emoji"
+	// ThemePlain shows the AQI as text only, e.g. "Fair", for screen readers
+	ThemePlain Theme = "plain"
+)
+
+const (
+	themeSetTmpl  = "OK. The AQI is shown like this: %s"
+	themeUsageMsg = "Usage: /theme emoji|plain"
+)
+
+// AQI returns the Air Quality Index level in the theme
+func (t Theme) AQI(aqi AirQualityIndex) string {
+	if !aqi.Valid() {
+		log.Printf("unknown AQI value %d", aqi)
+		return fmt.Sprintf(unknownAQITmpl, int(aqi))
+	}
+	if t == ThemePlain {
+		return aqi.Label()
+	}
+	return fmt.Sprintf("%s (%s)", aqi.Emoji(), aqi.Label())
+}
+
+// LocalizedAQI returns the Air Quality Index level in the theme translated by the printer
+func (t Theme) LocalizedAQI(aqi AirQualityIndex, p *message.Printer) string {
+	if !aqi.Valid() {
+		log.Printf("unknown AQI value %d", aqi)
+		return p.Sprintf(unknownAQITmpl, int(aqi))
+	}
+	if t == ThemePlain {
+		return p.Sprintf(aqi.Label())
+	}
+	return fmt.Sprintf("%s (%s)", aqi.Emoji(), p.Sprintf(aqi.Label()))
+}
+
+// Level returns the mark of the level of a pollutant appended to its concentration:
+// the emoji of the level or the label of it in parentheses in ThemePlain
+func (t Theme) Level(level AirQualityIndex, p *message.Printer) string {
+	if t == ThemePlain {
+		return "(" + p.Sprintf(level.Label()) + ")"
+	}
+	return level.Emoji()
+}
+
+// Category returns the value of an AQI scale with the emoji of its category: "🟡 72 (Moderate)",
+// or "72 (Moderate)" in ThemePlain
+func (t Theme) Category(emoji, value, category string) string {
+	if t == ThemePlain {
+		return fmt.Sprintf("%s (%s)", value, category)
+	}
+	return fmt.Sprintf("%s %s (%s)", emoji, value, category)
+}
+
+func parseTheme(arg string) (Theme, error) {
+	switch t := Theme(strings.ToLower(strings.TrimSpace(arg))); t {
+	case ThemeEmoji, ThemePlain:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown theme %q", arg)
+}
+
+// themeOf returns the Theme of UserPrefs of the chat
+func (bot *Bot) themeOf(chatID int64) Theme {
+	prefs, err := bot.store.GetUserPrefs(chatID)
+	if err != nil {
+		log.Print("GetUserPrefs: ", err)
+	}
+	return prefs.Theme
+}
+
+// themeText stores the Theme of the /theme argument in UserPrefs
+func (bot *Bot) themeText(chatID int64, arg string, p *message.Printer) string {
+	theme, err := parseTheme(arg)
+	if err != nil {
+		return p.Sprintf(themeUsageMsg)
+	}
+	prefs, err := bot.store.GetUserPrefs(chatID)
+	if err != nil {
+		log.Print("GetUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	prefs.Theme = theme
+	if err := bot.store.UpdateUserPrefs(prefs); err != nil {
+		log.Print("UpdateUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	return p.Sprintf(themeSetTmpl, theme.LocalizedAQI(2, p))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestThemeAQI(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	tests := []struct {
+		theme Theme
+		aqi   AirQualityIndex
+		want  string
+	}{
+		{theme: ThemeEmoji, aqi: 1, want: "🟩 (Good)"},
+		{theme: ThemeEmoji, aqi: 2, want: "🟨 (Fair)"},
+		{theme: ThemeEmoji, aqi: 5, want: "⬛ (Very Poor)"},
+		{theme: ThemePlain, aqi: 1, want: "Good"},
+		{theme: ThemePlain, aqi: 2, want: "Fair"},
+		{theme: ThemePlain, aqi: 5, want: "Very Poor"},
+		{theme: ThemeEmoji, aqi: 7, want: "Unknown (7)"},
+		{theme: ThemePlain, aqi: 0, want: "Unknown (0)"},
+	}
+	for _, tt := range tests {
+		if got := tt.theme.AQI(tt.aqi); got != tt.want {
+			t.Errorf("%s AQI(%d) = %q, want %q", tt.theme, tt.aqi, got, tt.want)
+		}
+		if got := tt.theme.LocalizedAQI(tt.aqi, p); got != tt.want {
+			t.Errorf("%s LocalizedAQI(%d) = %q, want %q", tt.theme, tt.aqi, got, tt.want)
+		}
+	}
+	// String and LocalizedString are in the emoji theme
+	for aqi := AirQualityIndex(0); aqi <= 6; aqi++ {
+		if got, want := aqi.String(), ThemeEmoji.AQI(aqi); got != want {
+			t.Errorf("AirQualityIndex(%d).String() = %q, want %q", aqi, got, want)
+		}
+		if got, want := aqi.LocalizedString(p), ThemeEmoji.LocalizedAQI(aqi, p); got != want {
+			t.Errorf("AirQualityIndex(%d).LocalizedString() = %q, want %q", aqi, got, want)
+		}
+	}
+}
+
+func TestThemeLevelAndCategory(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	tests := []struct {
+		theme    Theme
+		level    string
+		category string
+	}{
+		{theme: ThemeEmoji, level: AirQualityIndex(3).Emoji(), category: "🟡 72 (Moderate)"},
+		{theme: ThemePlain, level: "(Moderate)", category: "72 (Moderate)"},
+	}
+	for _, tt := range tests {
+		if got := tt.theme.Level(3, p); got != tt.level {
+			t.Errorf("%s Level(3) = %q, want %q", tt.theme, got, tt.level)
+		}
+		if got := tt.theme.Category("🟡", "72", "Moderate"); got != tt.category {
+			t.Errorf("%s Category() = %q, want %q", tt.theme, got, tt.category)
+		}
+	}
+}
+
+func TestParseTheme(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    Theme
+		wantErr bool
+	}{
+		{arg: "emoji", want: ThemeEmoji},
+		{arg: " Plain ", want: ThemePlain},
+		{arg: "PLAIN", want: ThemePlain},
+		{arg: "", wantErr: true},
+		{arg: "dark", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTheme(tt.arg)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseTheme(%q) = %q, %v, want %q, error %v", tt.arg, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestThemeMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		cmd   string
+		reply string
+		// want is the AQI line of the location and the notification, notWant mustn't be in them
+		want    string
+		notWant string
+	}{
+		{name: "default", want: "Air Quality Index: 🟧 (Moderate)"},
+		{name: "emoji", cmd: "/theme emoji", reply: "OK. The AQI is shown like this: 🟨 (Fair)", want: "Air Quality Index: 🟧 (Moderate)"},
+		{name: "plain", cmd: "/theme plain", reply: "OK. The AQI is shown like this: Fair", want: "Air Quality Index: Moderate", notWant: "🟧"},
+		{name: "unknown", cmd: "/theme dark", reply: themeUsageMsg, want: "Air Quality Index: 🟧 (Moderate)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 3}}, fake)
+			if tt.cmd != "" {
+				bot.handleMessage(newTestCommand(1, tt.cmd))
+				if got := fake.lastText(); got != tt.reply {
+					t.Errorf("%s = %q, want %q", tt.cmd, got, tt.reply)
+				}
+			}
+
+			bot.handleMessage(newTestLocationMessage(1, &Location{53.9, 27.56}))
+			aqiMsg := fake.lastText()
+			addTestSubscription(t, store, 1, &Location{52.1, 23.7}, 1)
+			bot.Cron()
+			notification := fake.lastText()
+
+			for name, got := range map[string]string{"AQI message": aqiMsg, "notification": notification} {
+				if !strings.Contains(got, tt.want) {
+					t.Errorf("%s = %q, want %q", name, got, tt.want)
+				}
+				if tt.notWant != "" && strings.Contains(got, tt.notWant) {
+					t.Errorf("%s = %q, want no %q", name, got, tt.notWant)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	// topLimit is the number of subscriptions reported by /top
+	topLimit = 3
+
+	topHeaderText = "The worst AQI among your subscriptions:"
+	topNoSubsMsg  = "You have no subscriptions yet. Share your location and tap \"Notify Me on AQI changes\""
+)
+
+// topText reports the subscriptions of the chat with the worst stored AQI.
+// They are numbered as in /subsriptions to be used with /refresh.
+func (bot *Bot) topText(chatID int64, p *message.Printer) string {
+	subs, err := bot.store.ListAQISubscriptions(chatID)
+	if err != nil {
+		log.Print("ListAQISubscriptions: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if len(*subs) == 0 {
+		return p.Sprintf(topNoSubsMsg)
+	}
+
+	numbers := make([]int, len(*subs))
+	for i := range numbers {
+		numbers[i] = i + 1
+	}
+	sort.SliceStable(numbers, func(i, j int) bool {
+		return (*subs)[numbers[i]-1].AirQualityIndex > (*subs)[numbers[j]-1].AirQualityIndex
+	})
+	if len(numbers) > topLimit {
+		numbers = numbers[:topLimit]
+	}
+
+	theme := bot.themeOf(chatID)
+	msgText := []string{p.Sprintf(topHeaderText), ""}
+	for _, n := range numbers {
+		s := (*subs)[n-1]
+		msgText = append(msgText, p.Sprintf(refreshedTmpl, n, s.Latitude, s.Longitude, theme.LocalizedAQI(s.AirQualityIndex, p)))
+	}
+	return strings.Join(msgText, "\n")
+}
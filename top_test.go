@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestTopText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	locations := []*Location{{53.9, 27.56}, {52.1, 23.7}, {55.75, 37.62}, {51.5, -0.12}, {48.85, 2.35}}
+	tests := []struct {
+		name string
+		aqis []AirQualityIndex
+		// want are the numbers of the reported subscriptions in order
+		want []int
+	}{
+		{name: "worst first", aqis: []AirQualityIndex{2, 5, 1, 4, 3}, want: []int{2, 4, 5}},
+		{name: "ties keep the order", aqis: []AirQualityIndex{3, 1, 3, 3}, want: []int{1, 3, 4}},
+		{name: "fewer than the limit", aqis: []AirQualityIndex{1, 2}, want: []int{2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			for i, aqi := range tt.aqis {
+				addTestSubscription(t, store, 1, locations[i], aqi)
+			}
+			bot := newTestBot(store, &botServices{})
+
+			got := strings.Split(bot.topText(1, p), "\n")
+
+			want := []string{topHeaderText, ""}
+			for _, n := range tt.want {
+				l := locations[n-1]
+				want = append(want, p.Sprintf(refreshedTmpl, n, l.Latitude, l.Longitude, ThemeEmoji.AQI(tt.aqis[n-1])))
+			}
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("topText() = %q, want %q", got, want)
+			}
+		})
+	}
+
+	bot := newTestBot(newTestStore(t), &botServices{})
+	if got := bot.topText(1, p); got != topNoSubsMsg {
+		t.Errorf("topText() without subscriptions = %q, want %q", got, topNoSubsMsg)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Tracer starts spans around update handling and OWM API calls.
+// It keeps to the subset of the OpenTelemetry trace API used by the bot, but only a log tracer is provided.
+// The spans are flat: no context is passed from handleUpdate down to the OWM API requests, so an OWM API span
+// isn't linked to the span of the update it was made for, and the store operations aren't traced.
+type Tracer interface {
+	Start(name string) Span
+}
+
+// Span is a timed operation started by a Tracer
+type Span interface {
+	// SetError marks the span as failed if err is not nil
+	SetError(err error)
+	End()
+}
+
+// NewTracer returns the Tracer of the exporter: "none" (or empty) disables tracing, "log" logs finished spans.
+func NewTracer(exporter string) (Tracer, error) {
+	switch exporter {
+	case "", "none":
+		return noopTracer{}, nil
+	case "log":
+		return logTracer{}, nil
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(string) Span { return noopSpan{} }
+
+type noopSpan struct{}
+
+func (noopSpan) SetError(error) {}
+func (noopSpan) End()           {}
+
+// logTracer logs the name, the duration and the error of each finished span
+type logTracer struct{}
+
+func (logTracer) Start(name string) Span {
+	return &logSpan{name: name, start: time.Now()}
+}
+
+type logSpan struct {
+	name  string
+	start time.Time
+	err   error
+}
+
+func (s *logSpan) SetError(err error) {
+	if err != nil {
+		s.err = err
+	}
+}
+
+func (s *logSpan) End() {
+	if s.err != nil {
+		log.Printf("span %s: %v, error: %v", s.name, time.Since(s.start), s.err)
+		return
+	}
+	log.Printf("span %s: %v", s.name, time.Since(s.start))
+}
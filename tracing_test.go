@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// recordingTracer keeps the finished spans in memory
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	tracer *recordingTracer
+	name   string
+	err    error
+}
+
+func (t *recordingTracer) Start(name string) Span {
+	return &recordedSpan{tracer: t, name: name}
+}
+
+func (s *recordedSpan) SetError(err error) { s.err = err }
+
+func (s *recordedSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s)
+}
+
+// names returns the names of the finished spans in the order they ended
+func (t *recordingTracer) names() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var names []string
+	for _, s := range t.spans {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+func TestTracingHandledMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "handled", status: http.StatusOK},
+		{name: "failed OWM API request", status: http.StatusUnauthorized, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := &recordingTracer{}
+			owma := newTestOWM(t, func(r *http.Request) (int, string) {
+				return tt.status, `{"coord":{"lat":53.9,"lon":27.56},"list":[{"dt":1700000000,"main":{"aqi":2},"components":{"co":200}}]}`
+			})
+			owma.Tracer = tracer
+			bot := newFakeTelegramBot(t, newTestStore(t), &botServices{wAPI: owma, tracer: tracer}, &fakeTelegram{})
+
+			bot.handleUpdate(tgbotapi.Update{UpdateID: 1, Message: newTestLocationMessage(1, &Location{53.9, 27.56})})
+
+			// the OWM API request ends before the update, the spans aren't linked
+			want := []string{"owm air_pollution", "handleUpdate"}
+			if got := tracer.names(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("spans = %v, want %v", got, want)
+			}
+			if err := tracer.spans[0].err; (err != nil) != tt.wantErr {
+				t.Errorf("OWM API span error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(tracer.spans[0].err, ErrInvalidOWMToken) {
+				t.Errorf("OWM API span error = %v, want %v", tracer.spans[0].err, ErrInvalidOWMToken)
+			}
+		})
+	}
+}
+
+func TestNewTracer(t *testing.T) {
+	tests := []struct {
+		exporter string
+		want     Tracer
+		wantErr  bool
+	}{
+		{exporter: "", want: noopTracer{}},
+		{exporter: "none", want: noopTracer{}},
+		{exporter: "log", want: logTracer{}},
+		// only the log tracer is provided
+		{exporter: "otlp", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NewTracer(tt.exporter)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewTracer(%q) error = %v, want error %v", tt.exporter, err, tt.wantErr)
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("NewTracer(%q) = %#v, want %#v", tt.exporter, got, tt.want)
+		}
+	}
+}
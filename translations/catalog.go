@@ -40,34 +40,293 @@ func init() {
 }
 
 var messageKeyToIndex = map[string]int{
-	"%s=%.2f":                     13,
-	"/about - into about the bot": 6,
+	"    e.g. %s":                  85,
+	"%d day(s)":                    184,
+	"%d h":                         185,
+	"%d. %.4f;%.4f: %s":            105,
+	"%d. %.5f;%.5f":                201,
+	"%d. %s: %.5f;%.5f":            202,
+	"%d. Location: %f;%f. AQI: %s": 53,
+	"%d. Location: %f;%f. Failed to get AQI, retry later": 54,
+	"%s (%s): %s":                  160,
+	"%s rose from %.2f to %.2f %s": 215,
+	"%s: %.2f %s":                  125,
+	"%s: %s":                       102,
+	"/%s - %s":                     84,
+	"/about - into about the bot":  6,
 	"/airQualityIndex - get the Air Quality Index for the location": 4,
+	"/export downloads all your data, /forgetme deletes it.":        183,
+	"/subscribe_all subscribes to all of them":                      204,
 	"/subsriptions - list of the active subsriptions":               5,
-	"Air Quality Index":     1,
-	"Details":               3,
-	"Error! Please, retry!": 0,
-	"Error: %v":             12,
+	"AQI along a route of waypoints":                                108,
+	"AQI along the route:":                                          104,
+	"AQI around your location":                                      91,
+	"AQI at coordinates without storing them":                       90,
+	"AQI within %d km of your location:":                            43,
+	"AQI: %s":                                                       193,
+	"Air Quality Index":                                             1,
+	"Alerts: %d":                                                    120,
+	"Already subscribed: %d":                                        210,
+	"Average AQI: %.1f, peak: %s, checks: %d":                       119,
+	"Avoid outdoor activities, keep the windows closed and follow your action plan.":                                                          65,
+	"Children should avoid long or intense outdoor activities and play indoors where possible.":                                               61,
+	"Children should stay indoors and keep the windows closed.":                                                                               62,
+	"Children should take breaks during long or intense outdoor activities. Children with asthma should keep their reliever inhaler at hand.": 60,
+	"Children with asthma should watch for symptoms during active play.":                                                                      59,
+	"Current profile: %s":             57,
+	"Details":                         3,
+	"Done. All your data is deleted.": 23,
+	"Error! Please, retry!":           0,
+	"Fair":                            32,
 	"Get the Air Quality Index (AQI) for the current location.\nContact: %s": 10,
-	"Just share your location or try /start":                                 11,
-	"Location: %f;%f. Last AQI: %s":                                          9,
-	"Notify Me on AQI changes":                                               2,
-	"OK. I won't notify you anymore":                                         14,
-	"Share location!":                                                        7,
-	"You have %d subscription(s)":                                            8,
-	"😌 AQI gets better":                                                      15,
-	"😷 AQI gets worse":                                                       16,
+	"Good":      31,
+	"Hazardous": 166,
+	"Healthy people may experience slight irritations and sensitive individuals will be slightly affected to a larger extent.":                                                  17,
+	"Healthy people will commonly show symptoms. People with respiratory or heart diseases will be significantly affected and will experience reduced endurance in activities.": 19,
+	"High risk": 169,
+	"I haven't received your location. If your phone asks, allow Telegram to access the location, or attach it with 📎 → Location. You can also send the coordinates, e.g. /air 53.9 27.56": 188,
+	"I store only the data needed to report the air quality to you:":    174,
+	"Just share your location or try /start":                            11,
+	"Last checked: %s":                                                  41,
+	"Limit outdoor activity and keep the windows closed":                133,
+	"Location: %f;%f":                                                   118,
+	"Location: %f;%f. Average AQI: %.1f, peak: %s":                      75,
+	"Location: %f;%f. Last AQI: %s":                                     9,
+	"Location: %f;%f. No checks that day":                               155,
+	"Location: %f;%f. Peak: %s at %s":                                   154,
+	"Longest good air streak: %d h":                                     121,
+	"Low risk":                                                          167,
+	"Measured %d day(s) ago":                                            27,
+	"Measured %d h ago":                                                 26,
+	"Measured %d min ago":                                               25,
+	"Measured just now":                                                 24,
+	"Moderate":                                                          33,
+	"Moderate risk":                                                     168,
+	"No checks in this period yet":                                      122,
+	"No data for the waypoints. Please, retry!":                         107,
+	"No health implications.":                                           15,
+	"No health implications. A good time for outdoor play.":             58,
+	"Not checked yet":                                                   42,
+	"Notify Me on AQI changes":                                          2,
+	"OK. AQI messages don't include the weather":                        29,
+	"OK. AQI messages include the current weather":                      28,
+	"OK. Favorite %d is removed":                                        208,
+	"OK. Favorite %d is saved":                                          205,
+	"OK. Health advice is given for the profile: %s":                    55,
+	"OK. I will attach a map pin with the AQI to your shared locations": 194,
+	"OK. I will check your subscriptions at most every %s":              38,
+	"OK. I will check your subscriptions every 30 minutes":              39,
+	"OK. I will notify you about a subscription at most every %s":       136,
+	"OK. I will notify you about every AQI change":                      137,
+	"OK. I will notify you if AQI changes at %.4f;%.4f. Current AQI: %s. /subsriptions":      152,
+	"OK. I will notify you if AQI changes in %s (%.4f;%.4f). Current AQI: %s. /subsriptions": 151,
+	"OK. I will notify you on AQI changes":                                                   77,
+	"OK. I won't notify you anymore":                                                         12,
+	"OK. I won't send the daily AQI peaks":                                                   157,
+	"OK. No more map pins":                                                                   195,
+	"OK. Notifications are paused until %s":                                                  80,
+	"OK. Notifications are resumed":                                                          81,
+	"OK. Restored subscriptions: %d":                                                         216,
+	"OK. Subscription %d is labeled: %s":                                                     111,
+	"OK. The AQI is shown in the %s scale":                                                   161,
+	"OK. The AQI is shown like this: %s":                                                     220,
+	"OK. The default cooldown is used: %s":                                                   138,
+	"OK. The label of subscription %d is removed":                                            112,
+	"OK. The temperature is shown in %s and the wind speed in %s":                            148,
+	"OK. You will get a weekly AQI digest instead of the alerts":                             76,
+	"OK. You will get the AQI peaks of the previous day every morning":                       156,
+	"OWM AQI: %d of 5, %s":                                                                   141,
+	"OWM rates the air from 1 to 5 by the concentrations of the pollutants. Apps using the US EPA scale from 0 to 500 show other numbers for the same air.": 143,
+	"Older people should avoid outdoor activities and watch for chest pain or shortness of breath.":                                                         69,
+	"Older people should reduce long or intense outdoor activities.":                                                                                        68,
+	"Older people should stay indoors and seek medical advice if they feel unwell.":                                                                         70,
+	"Older people with heart or lung disease may notice slight effects.":                                                                                    67,
+	"People with asthma or lung disease may notice symptoms. Keep your medication at hand.":                                                                 63,
+	"Place: %s":                 190,
+	"Pollutant concentrations:": 124,
+	"Poor":                      34,
+	"Reduce intense outdoor activities. Follow your action plan if symptoms appear.":                                                       64,
+	"Removed by mistake? /restore brings them back":                                                                                        218,
+	"Sensitive individuals will experience more serious conditions. The hearts and respiratory systems of healthy people may be affected.": 18,
+	"Share location!": 7,
+	"Share your location first: /airQualityIndex":                                                   50,
+	"Share your location to get the Air Quality Index and subscribe to its changes. Commands:":      83,
+	"Some pollutants may slightly affect very few hypersensitive individuals.":                      16,
+	"Stay indoors and contact your doctor if the symptoms get worse.":                               66,
+	"Subscribed to %d of %d favorite(s)":                                                            209,
+	"The bot is under maintenance. Please try again later":                                          187,
+	"The highest pollutant level: %s, level %d":                                                     142,
+	"The label is too long, at most %d characters are allowed":                                      110,
+	"The worst AQI among your subscriptions:":                                                       71,
+	"There are no recently removed subscriptions to restore":                                        217,
+	"There is no information about the air quality.":                                                37,
+	"This command is disabled":                                                                      115,
+	"This deletes your location, settings, favorites, AQI history and subscriptions. Are you sure?": 198,
+	"This location is already a favorite":                                                           206,
+	"This location is already subscribed. /subsriptions":                                            79,
+	"Unhealthy":                      164,
+	"Unhealthy for Sensitive Groups": 163,
+	"Unknown (%d)":                   36,
+	"Updated: %s":                    191,
+	"Usage: /check <latitude> <longitude>, e.g. /check 53.9 27.56":                       73,
+	"Usage: /cooldown <duration>, e.g. /cooldown 1h, /cooldown off or /cooldown default": 139,
+	"Usage: /digest on|off": 78,
+	"Usage: /favorite add [label] saves your last shared location, /favorite remove N deletes favorite N, /favorite lists them":     199,
+	"Usage: /frequency hourly|daily|default or a duration like 3h":                                                                  40,
+	"Usage: /label N <text>, where N is the number of the subscription in /subsriptions. The label is removed if the text is empty": 109,
+	"Usage: /map on|off":   196,
+	"Usage: /peaks on|off": 158,
+	"Usage: /profile general|children|respiratory|elderly":                                                             56,
+	"Usage: /refresh [N], where N is the number of the subscription in /subsriptions":                                  51,
+	"Usage: /route followed by 2 to %d waypoints, one per line or separated by |, e.g. /route 53.9 27.56 | 53.92 27.6": 103,
+	"Usage: /scale owm|epa|aqhi":                                                   162,
+	"Usage: /snooze <duration>, e.g. /snooze 24h, or /snooze off":                  82,
+	"Usage: /test [N], where N is the number of the subscription in /subsriptions": 128,
+	"Usage: /theme emoji|plain":                                                    221,
+	"Usage: /units metric|imperial":                                                149,
+	"Usage: /weather on|off":                                                       30,
+	"Very Poor":                                                                    35,
+	"Very Unhealthy":                                                               165,
+	"Very high risk":                                                               170,
+	"Worst: waypoint %d, %s":                                                       106,
+	"Yes, delete my data":                                                          22,
+	"You can have at most %d favorites":                                            207,
+	"You have %d of %d subscriptions":                                              172,
+	"You have %d subscription(s)":                                                  8,
+	"You have no favorites. Share a location and save it with /favorite add Home":                    203,
+	"You have no subscriptions to refresh":                                                           52,
+	"You have no subscriptions to test. Share your location and subscribe first":                     129,
+	"You have no subscriptions yet. Share your location and subscribe to get statistics":             117,
+	"You have no subscriptions yet. Share your location and tap \"Notify Me on AQI changes\"":        72,
+	"You have reached the limit of %d subscriptions. Remove them with /subsriptions to add new ones": 173,
+	"Your data stored by the bot":                                                                    21,
+	"Your favorites: %d":                                                                             200,
+	"Your stored location: %.5f;%.5f":                                                                189,
+	"a weekly digest instead of the alerts":                                                          97,
+	"about the bot":                                                                                  100,
+	"add the current weather to AQI messages":                                                        93,
+	"attach a map pin to the AQI of a location":                                                      197,
+	"bring back the subscriptions you removed":                                                       219,
+	"delete your data":                            99,
+	"download your data":                          98,
+	"get the Air Quality Index for your location": 86,
+	"health advice for your sensitivity profile":  95,
+	"how often your subscriptions are checked":    94,
+	"list your subscriptions":                     87,
+	"m/s":                                         146,
+	"metric or imperial units of the weather":     150,
+	"minimal interval between the notifications":  140,
+	"mph":                            147,
+	"name a subscription, like Home": 114,
+	"no data":                        49,
+	"pause the notifications":        96,
+	"pollutant concentrations at your location":       92,
+	"re-check your subscriptions now":                 88,
+	"save your location as a favorite":                211,
+	"send a test notification":                        130,
+	"show the AQI with emoji or as plain text":        222,
+	"subscribe to all your favorites":                 212,
+	"the AQI number of the provider":                  144,
+	"the AQI peaks of the previous day every morning": 159,
+	"the AQI scale: OWM, US EPA or AQHI":              171,
+	"the list of the commands":                        101,
+	"the location your AQI is shown for":              192,
+	"what data is stored and for how long":            186,
+	"your AQI statistics of the last week":            123,
+	"your subscriptions with the worst AQI":           89,
+	"μg/m³":                                           126,
+	"• the AQI history of your subscriptions for up to %s, for the digests and the statistics":      181,
+	"• the air measurements are deleted after %s":                                                   223,
+	"• the air measurements of your locations":                                                      180,
+	"• the coordinates are rounded to %d decimal places":                                            176,
+	"• the last %d air measurements of your locations":                                              179,
+	"• the raw responses of the air quality provider for your coordinates for %s":                   182,
+	"• your favorite locations, until you remove them":                                              213,
+	"• your last shared location and your language, until you delete them with /forgetme":           175,
+	"• your subscriptions with their last AQI, until you unsubscribe":                               177,
+	"• your subscriptions with their last AQI. Unsubscribed ones are deleted within %s":             178,
+	"⚠️ Health warning: the air quality is poor":                                                    131,
+	"⚠️ High carbon monoxide (CO) level: %.0f μg/m³. Avoid busy roads and ventilate indoor spaces.": 20,
+	"✅ Done":                           134,
+	"❌ Failed. Please, retry!":         135,
+	"➡️ East":                          46,
+	"⬅️ West":                          48,
+	"⬆️ North":                         45,
+	"⬇️ South":                         47,
+	"🌡 %.1f%s, 💨 %.1f %s":              145,
+	"🏷 %s":                             113,
+	"📅 Your weekly AQI digest":         74,
+	"📈 A sharp rise of a pollutant":    214,
+	"📈 AQI peaks of %s":                153,
+	"📊 Your AQI over the last %d days": 116,
+	"📍 Here":                           44,
+	"😌 AQI gets better":                13,
+	"😷 AQI gets worse":                 14,
+	"🚨 Health warning: the air quality is very poor":   132,
+	"🧪 Test notification. Your alerts look like this:": 127,
 }
 
-var beIndex = []uint32{ // 18 elements
+var beIndex = []uint32{ // 225 elements
 	0x00000000, 0x0000004d, 0x0000007c, 0x000000b2,
 	0x000000cd, 0x0000014f, 0x0000018a, 0x000001b8,
 	0x000001f3, 0x00000218, 0x0000025f, 0x000002ef,
-	0x00000352, 0x00000366, 0x00000374, 0x000003bb,
-	0x000003db, 0x000003fb,
-} // Size: 96 bytes
+	0x00000352, 0x00000399, 0x000003b9, 0x000003d9,
+	0x00000410, 0x000004c9, 0x000005a2, 0x00000689,
+	0x000007b5, 0x00000874, 0x000008a7, 0x000008d2,
+	0x0000090d, 0x00000932, 0x00000958, 0x00000982,
+	0x000009a8, 0x00000a1b, 0x00000a84, 0x00000aae,
+	0x00000abb, 0x00000ac6, 0x00000ad5, 0x00000ae2,
+	0x00000afc, 0x00000b15, 0x00000b57, 0x00000bc6,
+	0x00000c28, 0x00000c91, 0x00000cb8, 0x00000cdd,
+	0x00000d32, 0x00000d3e, 0x00000d52, 0x00000d64,
+	0x00000d7c, 0x00000d8e, 0x00000da2, 0x00000dff,
+	0x00000e5f, 0x00000e9f, 0x00000ee0, 0x00000f66,
+	0x00000fb5, 0x00000ffd, 0x00001020, 0x00001092,
+	0x0000110f, 0x00001200, 0x000012bc, 0x00001334,
+	0x000013dd, 0x0000148e, 0x00001534, 0x000015c0,
+	0x0000164e, 0x000016d5, 0x0000177f, 0x0000182d,
+	0x00001864, 0x0000191e, 0x00001985, 0x000019b5,
+	0x00001a19, 0x00001a90, 0x00001ad4, 0x00001afd,
+	0x00001b5a, 0x00001b93, 0x00001bc1, 0x00001c30,
+	0x00001cee, 0x00001cfd, 0x00001d1a, 0x00001d78,
+	0x00001d9d, 0x00001dcc, 0x00001df1, 0x00001e30,
+	0x00001e6d, 0x00001ed6, 0x00001f26, 0x00001f5a,
+	0x00001fb9, 0x00001fe3, 0x00002027, 0x00002050,
+	0x00002075, 0x00002085, 0x0000209b, 0x000020a8,
+	0x00002162, 0x00002185, 0x000021a3, 0x000021d1,
+	0x00002236, 0x0000226a, 0x0000230a, 0x00002375,
+	0x000023aa, 0x000023e1, 0x000023ec, 0x00002427,
+	0x00002454, 0x00002487, 0x00002541, 0x0000256f,
+	0x000025bd, 0x000025dd, 0x00002626, 0x0000266d,
+	0x000026b0, 0x000026eb, 0x00002700, 0x0000270c,
+	0x00002777, 0x000027d6, 0x00002878, 0x000028b3,
+	0x000028ff, 0x00002956, 0x000029c4, 0x000029d5,
+	0x00002a1d, 0x00002a93, 0x00002aea, 0x00002b47,
+	0x00002bc7, 0x00002c13, 0x00002c2e, 0x00002c93,
+	0x00002d8f, 0x00002dc1, 0x00002de7, 0x00002ded,
+	0x00002df9, 0x00002e65, 0x00002e96, 0x00002ee2,
+	0x00002f73, 0x00003009, 0x00003026, 0x00003060,
+	0x000030b9, 0x0000313a, 0x0000318a, 0x000031b2,
+	0x000031fc, 0x00003211, 0x0000324e, 0x0000327c,
+	0x000032b0, 0x000032bd, 0x000032d7, 0x000032ec,
+	0x00003306, 0x00003324, 0x00003340, 0x00003369,
+	0x00003391, 0x000033bb, 0x00003448, 0x000034d2,
+	0x00003573, 0x000035d1, 0x00003632, 0x000036a9,
+	0x00003701, 0x00003742, 0x000037af, 0x00003848,
+	0x0000389c, 0x000038a8, 0x000038b1, 0x000038ef,
+	0x00003963, 0x00003acb, 0x00003b19, 0x00003b2b,
+	0x00003b45, 0x00003b9b, 0x00003ba6, 0x00003c1e,
+	0x00003c5d, 0x00003c83, 0x00003ccb, 0x00003d6f,
+	0x00003e43, 0x00003e62, 0x00003e79, 0x00003e97,
+	0x00003f1b, 0x00003f51, 0x00003f83, 0x00003fb0,
+	0x00004006, 0x00004038, 0x00004091, 0x000040b5,
+	0x00004102, 0x00004132, 0x00004187, 0x000041bf,
+	0x000041ee, 0x00004225, 0x0000427d, 0x000042bb,
+	0x000042ee, 0x00004323, 0x00004350, 0x0000439c,
+	0x000043e6,
+} // Size: 924 bytes
 
-const beData string = "" + // Size: 1019 bytes
+const beData string = "" + // Size: 17382 bytes
 	"\x02Памылка! Калі ласка, паспрабуйце яшчэ раз!\x02Індэкс якасці паветра " +
 	"(AQI)\x02Паведамляйце мне пра змены AQI\x02Падрабязнасці\x02/airQualityI" +
 	"ndex - атрымаць Індэкс якасці паветра для гэтага месцазнаходжання\x02/su" +
@@ -75,45 +334,572 @@ const beData string = "" + // Size: 1019 bytes
 	"адзяліцеся месцазнаходжаннем!\x02У вас %[1]d падпіска(і)\x02Месцазнаход" +
 	"жанне: %[1]f;%[2]f. Апошні AQI: %[3]s\x02Атрымаць Індэкс якасці паветра" +
 	" (AQI) для бягучага месцазнаходжання.\x0aКантакт: %[1]s\x02Падзяліцеся с" +
-	"ваім месцазнаходжаннем ці пачніце з /start\x02Ошибка: %[1]v\x02%[1]s=%." +
-	"2[2]f\x02Добра. Я больш не буду паведамляць вам.\x02😌 AQI паляпшаецца" +
-	"\x02😷 AQI пагаршаецца"
+	"ваім месцазнаходжаннем ці пачніце з /start\x02Добра. Я больш не буду па" +
+	"ведамляць вам.\x02😌 AQI паляпшаецца\x02😷 AQI пагаршаецца\x02Няма наступ" +
+	"стваў для здароўя.\x02Некаторыя забруджвальнікі могуць нязначна ўплывац" +
+	"ь на вельмі нешматлікіх гіперадчувальных людзей.\x02Здаровыя людзі могу" +
+	"ць адчуваць лёгкае раздражненне, а адчувальныя людзі будуць закрануты ў" +
+	" некалькі большай ступені.\x02Адчувальныя людзі будуць адчуваць больш су" +
+	"р'ёзныя праблемы. Сэрца і дыхальная сістэма здаровых людзей могуць быць" +
+	" закрануты.\x02У здаровых людзей звычайна праяўляюцца сімптомы. Людзі з " +
+	"захворваннямі органаў дыхання або сэрца будуць значна закрануты, іх вын" +
+	"ослівасць пры нагрузках знізіцца.\x02⚠️ Высокі ўзровень чаднага газу (C" +
+	"O): %.0[1]f мкг/м³. Пазбягайце ажыўленых дарог і праветрывайце памяшканн" +
+	"і.\x02Вашы даныя, захаваныя ботам\x02Так, выдаліць мае даныя\x02Гатова." +
+	" Усе вашы даныя выдалены.\x02Вымерана толькі што\x02Вымерана %[1]d хв. т" +
+	"аму\x02Вымерана %[1]d гадз. таму\x02Вымерана %[1]d дз. таму\x02Добра. П" +
+	"аведамленні пра AQI будуць утрымліваць бягучае надвор'е\x02Добра. Павед" +
+	"амленні пра AQI не будуць утрымліваць надвор'е\x02Выкарыстанне: /weathe" +
+	"r on|off\x02Якасны\x02Добры\x02Умераны\x02Дрэнны\x02Вельмі дрэнны\x02Нев" +
+	"ядома (%[1]d)\x02Няма інфармацыі пра якасць паветра.\x02Добра. Я буду п" +
+	"равяраць вашы падпіскі не часцей, чым раз у %[1]s\x02Добра. Я буду прав" +
+	"яраць вашы падпіскі кожныя 30 хвілін\x02Выкарыстанне: /frequency hourly" +
+	"|daily|default або інтэрвал, напрыклад 3h\x02Апошняя праверка: %[1]s\x02" +
+	"Яшчэ не правяралася\x02AQI у радыусе %[1]d км ад вашага месцазнаходжанн" +
+	"я:\x02📍 Тут\x02⬆️ Поўнач\x02➡️ Усход\x02⬇️ Поўдзень\x02⬅️ Захад\x02няма" +
+	" даных\x02Спачатку падзяліцеся месцазнаходжаннем: /airQualityIndex\x02Вы" +
+	"карыстанне: /refresh [N], дзе N - нумар падпіскі ў /subsriptions\x02У в" +
+	"ас няма падпісак для абнаўлення\x02%[1]d. Месцазнаходжанне: %[2]f;%[3]f" +
+	". AQI: %[4]s\x02%[1]d. Месцазнаходжанне: %[2]f;%[3]f. Не атрымалася атры" +
+	"маць AQI, паўтарыце пазней\x02OK. Парады па здароўі даюцца для профілю:" +
+	" %[1]s\x02Выкарыстанне: /profile general|children|respiratory|elderly" +
+	"\x02Бягучы профіль: %[1]s\x02Няма наступстваў для здароўя. Добры час для" +
+	" гульняў на вуліцы.\x02Дзецям з астмай варта сачыць за сімптомамі падчас" +
+	" актыўных гульняў.\x02Дзецям варта рабіць перапынкі падчас доўгіх або ін" +
+	"тэнсіўных заняткаў на вуліцы. Дзецям з астмай варта трымаць інгалятар п" +
+	"ад рукой.\x02Дзецям варта пазбягаць доўгіх або інтэнсіўных заняткаў на " +
+	"вуліцы і па магчымасці гуляць у памяшканні.\x02Дзецям варта заставацца " +
+	"ў памяшканні і трымаць вокны зачыненымі.\x02Людзі з астмай або захворва" +
+	"ннямі лёгкіх могуць заўважыць сімптомы. Трымайце лекі пад рукой.\x02Ска" +
+	"раціце інтэнсіўныя заняткі на вуліцы. Выконвайце свой план дзеянняў пры" +
+	" з'яўленні сімптомаў.\x02Пазбягайце заняткаў на вуліцы, трымайце вокны з" +
+	"ачыненымі і выконвайце свой план дзеянняў.\x02Заставайцеся ў памяшканні" +
+	" і звярніцеся да лекара, калі сімптомы ўзмоцняцца.\x02Пажылыя людзі з за" +
+	"хворваннямі сэрца або лёгкіх могуць заўважыць лёгкі ўплыў.\x02Пажылым л" +
+	"юдзям варта скараціць доўгія або інтэнсіўныя заняткі на вуліцы.\x02Пажы" +
+	"лым людзям варта пазбягаць заняткаў на вуліцы і сачыць за болем у грудз" +
+	"ях або дыхавіцай.\x02Пажылым людзям варта заставацца ў памяшканні і звя" +
+	"рнуцца да лекара пры дрэнным самаадчуванні.\x02Горшы AQI сярод вашых па" +
+	"дпісак:\x02У вас пакуль няма падпісак. Падзяліцеся месцазнаходжаннем і " +
+	"націсніце \x22Паведамляйце мне пра змены AQI\x22\x02Выкарыстанне: /chec" +
+	"k <шырата> <даўгата>, напрыклад /check 53.9 27.56\x02📅 Ваша тыднёвая зво" +
+	"дка AQI\x02Месцазнаходжанне: %[1]f;%[2]f. Сярэдні AQI: %.1[3]f, максіму" +
+	"м: %[4]s\x02OK. Вы будзеце атрымліваць тыднёвую зводку AQI замест апавя" +
+	"шчэнняў\x02OK. Я буду паведамляць вам пра змены AQI\x02Выкарыстанне: /d" +
+	"igest on|off\x02На гэта месцазнаходжанне вы ўжо падпісаны. /subsriptions" +
+	"\x02OK. Апавяшчэнні прыпынены да %[1]s\x02OK. Апавяшчэнні адноўлены\x02В" +
+	"ыкарыстанне: /snooze <працягласць>, напрыклад /snooze 24h, або /snooze " +
+	"off\x02Падзяліцеся месцазнаходжаннем, каб даведацца Індэкс якасці паветр" +
+	"а і падпісацца на яго змены. Каманды:\x02/%[1]s - %[2]s\x02    напрыкла" +
+	"д %[1]s\x02Індэкс якасці паветра для вашага месцазнаходжання\x02спіс ва" +
+	"шых падпісак\x02праверыць падпіскі зараз\x02падпіскі з горшым AQI\x02AQ" +
+	"I па каардынатах без іх захавання\x02AQI вакол вашага месцазнаходжання" +
+	"\x02канцэнтрацыі забруджвальнікаў у вашым месцазнаходжанні\x02дадаваць б" +
+	"ягучае надвор'е ў паведамленні AQI\x02як часта правяраць падпіскі\x02па" +
+	"рады па здароўі для вашага профілю адчувальнасці\x02прыпыніць апавяшчэн" +
+	"ні\x02тыднёвая зводка замест апавяшчэнняў\x02спампаваць вашы даныя\x02в" +
+	"ыдаліць вашы даныя\x02пра бота\x02спіс каманд\x02%[1]s: %[2]s\x02Выкары" +
+	"станне: /route і ад 2 да %[1]d пунктаў маршруту, па адным у радку або п" +
+	"раз |, напрыклад /route 53.9 27.56 | 53.92 27.6\x02AQI уздоўж маршруту:" +
+	"\x02%[1]d. %.4[2]f;%.4[3]f: %[4]s\x02Горш за ўсё: пункт %[1]d, %[2]s\x02" +
+	"Няма даных для пунктаў маршруту. Калі ласка, паўтарыце!\x02AQI уздоўж м" +
+	"аршруту з пунктаў\x02Выкарыстанне: /label N <тэкст>, дзе N — нумар падп" +
+	"іскі ў /subsriptions. Без тэксту метка выдаляецца\x02Метка занадта доўг" +
+	"ая, дазваляецца не больш за %[1]d сімвалаў\x02OK. Падпіска %[1]d пазнач" +
+	"ана: %[2]s\x02OK. Метка падпіскі %[1]d выдалена\x02🏷 %[1]s\x02назваць п" +
+	"адпіску, напрыклад Дом\x02Гэтая каманда адключана\x02📊 Ваш AQI за апошн" +
+	"ія %[1]d дзён\x02У вас пакуль няма падпісак. Падзяліцеся месцазнаходжан" +
+	"нем і падпішыцеся, каб атрымліваць статыстыку\x02Месцазнаходжанне: %[1]" +
+	"f;%[2]f\x02Сярэдні AQI: %.1[1]f, максімум: %[2]s, праверак: %[3]d\x02Апа" +
+	"вяшчэнняў: %[1]d\x02Самы доўгі перыяд чыстага паветра: %[1]d г\x02За гэ" +
+	"ты перыяд праверак пакуль не было\x02ваша статыстыка AQI за апошні тыдз" +
+	"ень\x02Канцэнтрацыі забруджвальнікаў:\x02%[1]s: %.2[2]f %[3]s\x02мкг/м³" +
+	"\x02🧪 Тэставае апавяшчэнне. Вашы апавяшчэнні выглядаюць так:\x02Выкарыст" +
+	"анне: /test [N], дзе N — нумар падпіскі ў /subsriptions\x02У вас няма п" +
+	"адпісак для праверкі. Спачатку падзяліцеся месцазнаходжаннем і падпішыц" +
+	"еся\x02адправіць тэставае апавяшчэнне\x02⚠️ Папярэджанне: дрэнная якасц" +
+	"ь паветра\x02🚨 Папярэджанне: вельмі дрэнная якасць паветра\x02Абмяжуйце" +
+	" актыўнасць на вуліцы і трымайце вокны зачыненымі\x02✅ Гатова\x02❌ Не ат" +
+	"рымалася. Калі ласка, паўтарыце!\x02Добра. Я буду апавяшчаць вас пра па" +
+	"дпіску не часцей, чым раз у %[1]s\x02Добра. Я буду апавяшчаць вас пра к" +
+	"ожную змену AQI\x02Добра. Выкарыстоўваецца інтэрвал па змаўчанні: %[1]s" +
+	"\x02Выкарыстанне: /cooldown <інтэрвал>, напрыклад /cooldown 1h, /cooldow" +
+	"n off або /cooldown default\x02мінімальны інтэрвал паміж апавяшчэннямі" +
+	"\x02OWM AQI: %[1]d з 5, %[2]s\x02Самы высокі ўзровень забруджвальніка: %" +
+	"[1]s, узровень %[2]d\x02OWM ацэньвае паветра ад 1 да 5 па канцэнтрацыях " +
+	"забруджвальнікаў. Праграмы са шкалой US EPA ад 0 да 500 паказваюць іншы" +
+	"я лічбы для таго ж паветра.\x02лік AQI ад пастаўшчыка даных\x02🌡 %.1[1]" +
+	"f%[2]s, 💨 %.1[3]f %[4]s\x02м/с\x02міль/г\x02Добра. Тэмпература паказваец" +
+	"ца ў %[1]s, а хуткасць ветру ў %[2]s\x02Выкарыстанне: /units metric|imp" +
+	"erial\x02метрычныя або імперскія адзінкі надвор'я\x02Добра. Я паведамлю " +
+	"вам, калі AQI зменіцца ў %[1]s (%.4[2]f;%.4[3]f). Цяперашні AQI: %[4]s." +
+	" /subsriptions\x02Добра. Я паведамлю вам, калі AQI зменіцца ў пункце %.4" +
+	"[1]f;%.4[2]f. Цяперашні AQI: %[3]s. /subsriptions\x02📈 Пікі AQI за %[1]s" +
+	"\x02Каардынаты: %[1]f;%[2]f. Пік: %[3]s а %[4]s\x02Каардынаты: %[1]f;%[2" +
+	"]f. У гэты дзень праверак не было\x02Добра. Кожную раніцу вы будзеце атр" +
+	"ымліваць пікі AQI за папярэдні дзень\x02Добра. Я не буду дасылаць штодз" +
+	"ённыя пікі AQI\x02Выкарыстанне: /peaks on|off\x02пікі AQI за папярэдні " +
+	"дзень кожную раніцу\x02%[1]s (%[2]s): %[3]s\x02Добра. AQI паказваецца п" +
+	"а шкале %[1]s\x02Выкарыстанне: /scale owm|epa|aqhi\x02Шкодна для адчува" +
+	"льных груп\x02Шкодна\x02Вельмі шкодна\x02Небяспечна\x02Нізкая рызыка" +
+	"\x02Умераная рызыка\x02Высокая рызыка\x02Вельмі высокая рызыка\x02шкала " +
+	"AQI: OWM, US EPA або AQHI\x02У вас %[1]d з %[2]d падпісак\x02Вы дасягнул" +
+	"і ліміту ў %[1]d падпісак. Выдаліце іх праз /subsriptions, каб дадаць н" +
+	"овыя\x02Я захоўваю толькі даныя, патрэбныя, каб паведамляць вам пра яка" +
+	"сць паветра:\x02• ваша апошняе адпраўленае месцазнаходжанне і мова, пак" +
+	"уль вы не выдаліце іх праз /forgetme\x02• каардынаты акругляюцца да %[1" +
+	"]d знакаў пасля коскі\x02• вашы падпіскі з апошнім AQI, пакуль вы не адп" +
+	"ішацеся\x02• вашы падпіскі з апошнім AQI. Адмененыя выдаляюцца на праця" +
+	"гу %[1]s\x02• апошнія %[1]d вымярэнняў паветра ў вашых месцах\x02• вымя" +
+	"рэнні паветра ў вашых месцах\x02• гісторыя AQI вашых падпісак да %[1]s," +
+	" для зводак і статыстыкі\x02• зыходныя адказы пастаўшчыка даных пра паве" +
+	"тра для вашых каардынат на працягу %[1]s\x02/export выгружае ўсе вашы д" +
+	"аныя, /forgetme выдаляе іх.\x02%[1]d дз.\x02%[1]d г\x02якія даныя захоў" +
+	"ваюцца і як доўга\x02Бот на тэхнічным абслугоўванні. Калі ласка, паспра" +
+	"буйце пазней\x02Я не атрымаў ваша месцазнаходжанне. Калі тэлефон спытае" +
+	", дазвольце Telegram доступ да месцазнаходжання або прымацуйце яго праз " +
+	"📎 → Геапазіцыя. Таксама можна адправіць каардынаты, напрыклад /air 53" +
+	".9 27.56\x02Ваша захаванае месцазнаходжанне: %.5[1]f;%.5[2]f\x02Месца: %" +
+	"[1]s\x02Абноўлена: %[1]s\x02месцазнаходжанне, для якога паказваецца ваш " +
+	"AQI\x02AQI: %[1]s\x02Добра. Я буду прымацоўваць метку на карце з AQI да " +
+	"вашых геапазіцый\x02Добра. Больш ніякіх метак на карце\x02Выкарыстанне:" +
+	" /map on|off\x02прымацоўваць метку на карце да AQI месца\x02Гэта выдаліц" +
+	"ь ваша месцазнаходжанне, налады, абранае, гісторыю AQI і падпіскі. Вы ў" +
+	"пэўнены?\x02Выкарыстанне: /favorite add [метка] захоўвае ваша апошняе м" +
+	"есцазнаходжанне, /favorite remove N выдаляе абранае N, /favorite паказв" +
+	"ае іх\x02Ваша абранае: %[1]d\x02%[1]d. %.5[2]f;%.5[3]f\x02%[1]d. %[2]s:" +
+	" %.5[3]f;%.5[4]f\x02У вас няма абранага. Адпраўце геапазіцыю і захавайце" +
+	" яе праз /favorite add Дом\x02/subscribe_all падпісвае на ўсе з іх\x02До" +
+	"бра. Абранае %[1]d захавана\x02Гэта месца ўжо ў абраным\x02Можна захава" +
+	"ць не больш за %[1]d месцаў у абраным\x02Добра. Абранае %[1]d выдалена" +
+	"\x02Аформлена падпіска на %[1]d з %[2]d месцаў з абранага\x02Ужо ў падпі" +
+	"сках: %[1]d\x02захаваць ваша месцазнаходжанне ў абранае\x02падпісацца н" +
+	"а ўсё абранае\x02• вашы абраныя месцы, пакуль вы іх не выдаліце\x02📈 Рэ" +
+	"зкі рост забруджвальніка\x02%[1]s вырас з %.2[2]f да %.2[3]f %[4]s\x02Д" +
+	"обра. Адноўлена падпісак: %[1]d\x02Няма нядаўна выдаленых падпісак для " +
+	"аднаўлення\x02Выдалілі памылкова? /restore верне іх\x02вярнуць выдалены" +
+	"я падпіскі\x02Добра. AQI паказваецца так: %[1]s\x02Выкарыстанне: /theme" +
+	" emoji|plain\x02паказваць AQI з эмодзі або простым тэкстам\x02• вымярэнн" +
+	"і паветра выдаляюцца праз %[1]s"
 
-var enIndex = []uint32{ // 18 elements
+var enIndex = []uint32{ // 225 elements
 	0x00000000, 0x00000016, 0x00000028, 0x00000041,
 	0x00000049, 0x00000087, 0x000000b7, 0x000000d3,
 	0x000000e3, 0x00000102, 0x00000129, 0x00000172,
-	0x00000199, 0x000001a6, 0x000001b4, 0x000001d3,
-	0x000001e8, 0x000001fc,
-} // Size: 96 bytes
+	0x00000199, 0x000001b8, 0x000001cd, 0x000001e1,
+	0x000001f9, 0x00000242, 0x000002bb, 0x00000340,
+	0x000003ea, 0x00000451, 0x0000046d, 0x00000481,
+	0x000004a1, 0x000004b3, 0x000004ca, 0x000004df,
+	0x000004f9, 0x00000526, 0x00000551, 0x00000568,
+	0x0000056d, 0x00000572, 0x0000057b, 0x00000580,
+	0x0000058a, 0x0000059a, 0x000005c9, 0x00000601,
+	0x00000636, 0x00000673, 0x00000687, 0x00000697,
+	0x000006bd, 0x000006c7, 0x000006d4, 0x000006e0,
+	0x000006ed, 0x000006f9, 0x00000701, 0x0000072d,
+	0x0000077d, 0x000007a2, 0x000007cb, 0x00000808,
+	0x0000083a, 0x0000086f, 0x00000886, 0x000008bc,
+	0x000008ff, 0x00000987, 0x000009e1, 0x00000a1b,
+	0x00000a71, 0x00000ac0, 0x00000b0f, 0x00000b4f,
+	0x00000b92, 0x00000bd1, 0x00000c2f, 0x00000c7d,
+	0x00000ca5, 0x00000cfb, 0x00000d38, 0x00000d54,
+	0x00000d8d, 0x00000dc8, 0x00000ded, 0x00000e03,
+	0x00000e36, 0x00000e5f, 0x00000e7d, 0x00000eb9,
+	0x00000f12, 0x00000f21, 0x00000f30, 0x00000f5c,
+	0x00000f74, 0x00000f94, 0x00000fba, 0x00000fe2,
+	0x00000ffb, 0x00001025, 0x0000104d, 0x00001076,
+	0x000010a1, 0x000010b9, 0x000010df, 0x000010f2,
+	0x00001103, 0x00001111, 0x0000112a, 0x00001137,
+	0x000011ab, 0x000011c0, 0x000011de, 0x000011fb,
+	0x00001225, 0x00001244, 0x000012c2, 0x000012fe,
+	0x00001327, 0x00001356, 0x00001361, 0x00001380,
+	0x00001399, 0x000013c0, 0x00001413, 0x00001429,
+	0x0000145a, 0x00001468, 0x00001489, 0x000014a6,
+	0x000014cb, 0x000014e5, 0x000014fa, 0x00001502,
+	0x00001536, 0x00001583, 0x000015ce, 0x000015e7,
+	0x00001616, 0x00001648, 0x0000167b, 0x00001684,
+	0x0000169f, 0x000016de, 0x0000170b, 0x00001733,
+	0x00001786, 0x000017b1, 0x000017cc, 0x000017fc,
+	0x00001892, 0x000018b1, 0x000018d7, 0x000018db,
+	0x000018df, 0x00001921, 0x0000193f, 0x00001967,
+	0x000019ca, 0x00001a25, 0x00001a3d, 0x00001a69,
+	0x00001a93, 0x00001ad4, 0x00001af9, 0x00001b0e,
+	0x00001b3e, 0x00001b53, 0x00001b7b, 0x00001b96,
+	0x00001bb5, 0x00001bbf, 0x00001bce, 0x00001bd8,
+	0x00001be1, 0x00001bef, 0x00001bf9, 0x00001c08,
+	0x00001c2b, 0x00001c51, 0x00001cb3, 0x00001cf2,
+	0x00001d48, 0x00001d80, 0x00001dc2, 0x00001e19,
+	0x00001e4f, 0x00001e7a, 0x00001ed8, 0x00001f29,
+	0x00001f60, 0x00001f6d, 0x00001f75, 0x00001f9a,
+	0x00001fcf, 0x00002089, 0x000020af, 0x000020bc,
+	0x000020cb, 0x000020ee, 0x000020f9, 0x0000213b,
+	0x00002150, 0x00002163, 0x0000218d, 0x000021eb,
+	0x00002265, 0x0000227b, 0x00002292, 0x000022b0,
+	0x000022fc, 0x00002325, 0x00002341, 0x00002365,
+	0x0000238a, 0x000023a8, 0x000023d1, 0x000023eb,
+	0x0000240c, 0x0000242c, 0x0000245f, 0x00002480,
+	0x000024a9, 0x000024cb, 0x00002502, 0x00002530,
+	0x00002559, 0x0000257f, 0x00002599, 0x000025c2,
+	0x000025f3,
+} // Size: 924 bytes
 
-const enData string = "" + // Size: 508 bytes
+const enData string = "" + // Size: 9715 bytes
 	"\x02Error! Please, retry!\x02Air Quality Index\x02Notify Me on AQI chang" +
 	"es\x02Details\x02/airQualityIndex - get the Air Quality Index for the lo" +
 	"cation\x02/subsriptions - list of the active subsriptions\x02/about - in" +
 	"to about the bot\x02Share location!\x02You have %[1]d subscription(s)" +
 	"\x02Location: %[1]f;%[2]f. Last AQI: %[3]s\x02Get the Air Quality Index " +
 	"(AQI) for the current location.\x0aContact: %[1]s\x02Just share your loc" +
-	"ation or try /start\x02Error: %[1]v\x02%[1]s=%.2[2]f\x02OK. I won't noti" +
-	"fy you anymore\x02😌 AQI gets better\x02😷 AQI gets worse"
+	"ation or try /start\x02OK. I won't notify you anymore\x02😌 AQI gets bett" +
+	"er\x02😷 AQI gets worse\x02No health implications.\x02Some pollutants may" +
+	" slightly affect very few hypersensitive individuals.\x02Healthy people " +
+	"may experience slight irritations and sensitive individuals will be slig" +
+	"htly affected to a larger extent.\x02Sensitive individuals will experien" +
+	"ce more serious conditions. The hearts and respiratory systems of health" +
+	"y people may be affected.\x02Healthy people will commonly show symptoms." +
+	" People with respiratory or heart diseases will be significantly affecte" +
+	"d and will experience reduced endurance in activities.\x02⚠️ High carbon" +
+	" monoxide (CO) level: %.0[1]f μg/m³. Avoid busy roads and ventilate indo" +
+	"or spaces.\x02Your data stored by the bot\x02Yes, delete my data\x02Done" +
+	". All your data is deleted.\x02Measured just now\x02Measured %[1]d min a" +
+	"go\x02Measured %[1]d h ago\x02Measured %[1]d day(s) ago\x02OK. AQI messa" +
+	"ges include the current weather\x02OK. AQI messages don't include the we" +
+	"ather\x02Usage: /weather on|off\x02Good\x02Fair\x02Moderate\x02Poor\x02V" +
+	"ery Poor\x02Unknown (%[1]d)\x02There is no information about the air qua" +
+	"lity.\x02OK. I will check your subscriptions at most every %[1]s\x02OK. " +
+	"I will check your subscriptions every 30 minutes\x02Usage: /frequency ho" +
+	"urly|daily|default or a duration like 3h\x02Last checked: %[1]s\x02Not c" +
+	"hecked yet\x02AQI within %[1]d km of your location:\x02📍 Here\x02⬆️ Nort" +
+	"h\x02➡️ East\x02⬇️ South\x02⬅️ West\x02no data\x02Share your location fi" +
+	"rst: /airQualityIndex\x02Usage: /refresh [N], where N is the number of t" +
+	"he subscription in /subsriptions\x02You have no subscriptions to refresh" +
+	"\x02%[1]d. Location: %[2]f;%[3]f. AQI: %[4]s\x02%[1]d. Location: %[2]f;%" +
+	"[3]f. Failed to get AQI, retry later\x02OK. Health advice is given for t" +
+	"he profile: %[1]s\x02Usage: /profile general|children|respiratory|elderl" +
+	"y\x02Current profile: %[1]s\x02No health implications. A good time for o" +
+	"utdoor play.\x02Children with asthma should watch for symptoms during ac" +
+	"tive play.\x02Children should take breaks during long or intense outdoor" +
+	" activities. Children with asthma should keep their reliever inhaler at " +
+	"hand.\x02Children should avoid long or intense outdoor activities and pl" +
+	"ay indoors where possible.\x02Children should stay indoors and keep the " +
+	"windows closed.\x02People with asthma or lung disease may notice symptom" +
+	"s. Keep your medication at hand.\x02Reduce intense outdoor activities. F" +
+	"ollow your action plan if symptoms appear.\x02Avoid outdoor activities, " +
+	"keep the windows closed and follow your action plan.\x02Stay indoors and" +
+	" contact your doctor if the symptoms get worse.\x02Older people with hea" +
+	"rt or lung disease may notice slight effects.\x02Older people should red" +
+	"uce long or intense outdoor activities.\x02Older people should avoid out" +
+	"door activities and watch for chest pain or shortness of breath.\x02Olde" +
+	"r people should stay indoors and seek medical advice if they feel unwell" +
+	".\x02The worst AQI among your subscriptions:\x02You have no subscription" +
+	"s yet. Share your location and tap \x22Notify Me on AQI changes\x22\x02U" +
+	"sage: /check <latitude> <longitude>, e.g. /check 53.9 27.56\x02📅 Your we" +
+	"ekly AQI digest\x02Location: %[1]f;%[2]f. Average AQI: %.1[3]f, peak: %[" +
+	"4]s\x02OK. You will get a weekly AQI digest instead of the alerts\x02OK." +
+	" I will notify you on AQI changes\x02Usage: /digest on|off\x02This locat" +
+	"ion is already subscribed. /subsriptions\x02OK. Notifications are paused" +
+	" until %[1]s\x02OK. Notifications are resumed\x02Usage: /snooze <duratio" +
+	"n>, e.g. /snooze 24h, or /snooze off\x02Share your location to get the A" +
+	"ir Quality Index and subscribe to its changes. Commands:\x02/%[1]s - %[2" +
+	"]s\x02    e.g. %[1]s\x02get the Air Quality Index for your location\x02l" +
+	"ist your subscriptions\x02re-check your subscriptions now\x02your subscr" +
+	"iptions with the worst AQI\x02AQI at coordinates without storing them" +
+	"\x02AQI around your location\x02pollutant concentrations at your locatio" +
+	"n\x02add the current weather to AQI messages\x02how often your subscript" +
+	"ions are checked\x02health advice for your sensitivity profile\x02pause " +
+	"the notifications\x02a weekly digest instead of the alerts\x02download y" +
+	"our data\x02delete your data\x02about the bot\x02the list of the command" +
+	"s\x02%[1]s: %[2]s\x02Usage: /route followed by 2 to %[1]d waypoints, one" +
+	" per line or separated by |, e.g. /route 53.9 27.56 | 53.92 27.6\x02AQI " +
+	"along the route:\x02%[1]d. %.4[2]f;%.4[3]f: %[4]s\x02Worst: waypoint %[1" +
+	"]d, %[2]s\x02No data for the waypoints. Please, retry!\x02AQI along a ro" +
+	"ute of waypoints\x02Usage: /label N <text>, where N is the number of the" +
+	" subscription in /subsriptions. The label is removed if the text is empt" +
+	"y\x02The label is too long, at most %[1]d characters are allowed\x02OK. " +
+	"Subscription %[1]d is labeled: %[2]s\x02OK. The label of subscription %[" +
+	"1]d is removed\x02🏷 %[1]s\x02name a subscription, like Home\x02This comm" +
+	"and is disabled\x02📊 Your AQI over the last %[1]d days\x02You have no su" +
+	"bscriptions yet. Share your location and subscribe to get statistics\x02" +
+	"Location: %[1]f;%[2]f\x02Average AQI: %.1[1]f, peak: %[2]s, checks: %[3]" +
+	"d\x02Alerts: %[1]d\x02Longest good air streak: %[1]d h\x02No checks in t" +
+	"his period yet\x02your AQI statistics of the last week\x02Pollutant conc" +
+	"entrations:\x02%[1]s: %.2[2]f %[3]s\x02μg/m³\x02🧪 Test notification. You" +
+	"r alerts look like this:\x02Usage: /test [N], where N is the number of t" +
+	"he subscription in /subsriptions\x02You have no subscriptions to test. S" +
+	"hare your location and subscribe first\x02send a test notification\x02⚠️" +
+	" Health warning: the air quality is poor\x02🚨 Health warning: the air qu" +
+	"ality is very poor\x02Limit outdoor activity and keep the windows closed" +
+	"\x02✅ Done\x02❌ Failed. Please, retry!\x02OK. I will notify you about a " +
+	"subscription at most every %[1]s\x02OK. I will notify you about every AQ" +
+	"I change\x02OK. The default cooldown is used: %[1]s\x02Usage: /cooldown " +
+	"<duration>, e.g. /cooldown 1h, /cooldown off or /cooldown default\x02min" +
+	"imal interval between the notifications\x02OWM AQI: %[1]d of 5, %[2]s" +
+	"\x02The highest pollutant level: %[1]s, level %[2]d\x02OWM rates the air" +
+	" from 1 to 5 by the concentrations of the pollutants. Apps using the US " +
+	"EPA scale from 0 to 500 show other numbers for the same air.\x02the AQI " +
+	"number of the provider\x02🌡 %.1[1]f%[2]s, 💨 %.1[3]f %[4]s\x02m/s\x02mph" +
+	"\x02OK. The temperature is shown in %[1]s and the wind speed in %[2]s" +
+	"\x02Usage: /units metric|imperial\x02metric or imperial units of the wea" +
+	"ther\x02OK. I will notify you if AQI changes in %[1]s (%.4[2]f;%.4[3]f)." +
+	" Current AQI: %[4]s. /subsriptions\x02OK. I will notify you if AQI chang" +
+	"es at %.4[1]f;%.4[2]f. Current AQI: %[3]s. /subsriptions\x02📈 AQI peaks " +
+	"of %[1]s\x02Location: %[1]f;%[2]f. Peak: %[3]s at %[4]s\x02Location: %[1" +
+	"]f;%[2]f. No checks that day\x02OK. You will get the AQI peaks of the pr" +
+	"evious day every morning\x02OK. I won't send the daily AQI peaks\x02Usag" +
+	"e: /peaks on|off\x02the AQI peaks of the previous day every morning\x02%" +
+	"[1]s (%[2]s): %[3]s\x02OK. The AQI is shown in the %[1]s scale\x02Usage:" +
+	" /scale owm|epa|aqhi\x02Unhealthy for Sensitive Groups\x02Unhealthy\x02V" +
+	"ery Unhealthy\x02Hazardous\x02Low risk\x02Moderate risk\x02High risk\x02" +
+	"Very high risk\x02the AQI scale: OWM, US EPA or AQHI\x02You have %[1]d o" +
+	"f %[2]d subscriptions\x02You have reached the limit of %[1]d subscriptio" +
+	"ns. Remove them with /subsriptions to add new ones\x02I store only the d" +
+	"ata needed to report the air quality to you:\x02• your last shared locat" +
+	"ion and your language, until you delete them with /forgetme\x02• the coo" +
+	"rdinates are rounded to %[1]d decimal places\x02• your subscriptions wit" +
+	"h their last AQI, until you unsubscribe\x02• your subscriptions with the" +
+	"ir last AQI. Unsubscribed ones are deleted within %[1]s\x02• the last %[" +
+	"1]d air measurements of your locations\x02• the air measurements of your" +
+	" locations\x02• the AQI history of your subscriptions for up to %[1]s, f" +
+	"or the digests and the statistics\x02• the raw responses of the air qual" +
+	"ity provider for your coordinates for %[1]s\x02/export downloads all you" +
+	"r data, /forgetme deletes it.\x02%[1]d day(s)\x02%[1]d h\x02what data is" +
+	" stored and for how long\x02The bot is under maintenance. Please try aga" +
+	"in later\x02I haven't received your location. If your phone asks, allow " +
+	"Telegram to access the location, or attach it with 📎 → Location. You can" +
+	" also send the coordinates, e.g. /air 53.9 27.56\x02Your stored location" +
+	": %.5[1]f;%.5[2]f\x02Place: %[1]s\x02Updated: %[1]s\x02the location your" +
+	" AQI is shown for\x02AQI: %[1]s\x02OK. I will attach a map pin with the " +
+	"AQI to your shared locations\x02OK. No more map pins\x02Usage: /map on|o" +
+	"ff\x02attach a map pin to the AQI of a location\x02This deletes your loc" +
+	"ation, settings, favorites, AQI history and subscriptions. Are you sure?" +
+	"\x02Usage: /favorite add [label] saves your last shared location, /favor" +
+	"ite remove N deletes favorite N, /favorite lists them\x02Your favorites:" +
+	" %[1]d\x02%[1]d. %.5[2]f;%.5[3]f\x02%[1]d. %[2]s: %.5[3]f;%.5[4]f\x02You" +
+	" have no favorites. Share a location and save it with /favorite add Home" +
+	"\x02/subscribe_all subscribes to all of them\x02OK. Favorite %[1]d is sa" +
+	"ved\x02This location is already a favorite\x02You can have at most %[1]d" +
+	" favorites\x02OK. Favorite %[1]d is removed\x02Subscribed to %[1]d of %[" +
+	"2]d favorite(s)\x02Already subscribed: %[1]d\x02save your location as a " +
+	"favorite\x02subscribe to all your favorites\x02• your favorite locations" +
+	", until you remove them\x02📈 A sharp rise of a pollutant\x02%[1]s rose f" +
+	"rom %.2[2]f to %.2[3]f %[4]s\x02OK. Restored subscriptions: %[1]d\x02The" +
+	"re are no recently removed subscriptions to restore\x02Removed by mistak" +
+	"e? /restore brings them back\x02bring back the subscriptions you removed" +
+	"\x02OK. The AQI is shown like this: %[1]s\x02Usage: /theme emoji|plain" +
+	"\x02show the AQI with emoji or as plain text\x02• the air measurements a" +
+	"re deleted after %[1]s"
 
-var ruIndex = []uint32{ // 18 elements
+var ruIndex = []uint32{ // 225 elements
 	0x00000000, 0x00000042, 0x00000075, 0x000000b1,
 	0x000000be, 0x00000128, 0x0000015a, 0x00000184,
 	0x000001aa, 0x000001c3, 0x00000204, 0x00000283,
-	0x000002e2, 0x000002f6, 0x00000304, 0x00000325,
-	0x00000341, 0x0000035d,
-} // Size: 96 bytes
+	0x000002e2, 0x00000303, 0x0000031f, 0x0000033b,
+	0x00000372, 0x00000425, 0x00000500, 0x000005f0,
+	0x0000071e, 0x000007dd, 0x00000816, 0x0000083f,
+	0x0000087a, 0x0000089f, 0x000008c9, 0x000008ef,
+	0x00000917, 0x0000097b, 0x000009d5, 0x00000a01,
+	0x00000a10, 0x00000a35, 0x00000a48, 0x00000a55,
+	0x00000a6d, 0x00000a8a, 0x00000aca, 0x00000b37,
+	0x00000b99, 0x00000c02, 0x00000c2d, 0x00000c50,
+	0x00000ca1, 0x00000cb1, 0x00000cc3, 0x00000cd7,
+	0x00000ce3, 0x00000cf5, 0x00000d09, 0x00000d52,
+	0x00000db4, 0x00000df2, 0x00000e27, 0x00000e99,
+	0x00000eea, 0x00000f34, 0x00000f59, 0x00000fc9,
+	0x00001045, 0x00001137, 0x000011ef, 0x00001263,
+	0x0000130e, 0x000013c0, 0x0000145c, 0x000014dc,
+	0x0000156c, 0x000015f3, 0x00001693, 0x0000173b,
+	0x00001774, 0x00001818, 0x0000187f, 0x000018b1,
+	0x00001909, 0x00001978, 0x000019c2, 0x000019ed,
+	0x00001a44, 0x00001a87, 0x00001abb, 0x00001b2c,
+	0x00001be4, 0x00001bf3, 0x00001c0e, 0x00001c6c,
+	0x00001c95, 0x00001cd1, 0x00001cf6, 0x00001d37,
+	0x00001d72, 0x00001dd1, 0x00001e1a, 0x00001e50,
+	0x00001eb7, 0x00001ee9, 0x00001f2d, 0x00001f52,
+	0x00001f77, 0x00001f83, 0x00001f9d, 0x00001faa,
+	0x00002064, 0x00002085, 0x000020a3, 0x000020d0,
+	0x00002132, 0x00002162, 0x00002202, 0x0000226a,
+	0x0000229d, 0x000022d2, 0x000022dd, 0x00002316,
+	0x0000233f, 0x00002376, 0x00002416, 0x00002440,
+	0x0000248e, 0x000024ac, 0x000024f9, 0x0000253c,
+	0x00002583, 0x000025b8, 0x000025cd, 0x000025d9,
+	0x0000263e, 0x0000269f, 0x0000272b, 0x00002766,
+	0x000027b8, 0x00002813, 0x0000287b, 0x0000288c,
+	0x000028d5, 0x00002945, 0x000029a2, 0x000029f9,
+	0x00002a79, 0x00002ac7, 0x00002ae4, 0x00002b43,
+	0x00002c41, 0x00002c77, 0x00002c9d, 0x00002ca3,
+	0x00002caf, 0x00002d1f, 0x00002d52, 0x00002d9f,
+	0x00002e2a, 0x00002eb8, 0x00002ed5, 0x00002f0f,
+	0x00002f66, 0x00002fdd, 0x00003031, 0x0000305b,
+	0x000030a1, 0x000030b6, 0x000030f7, 0x00003127,
+	0x00003163, 0x00003170, 0x00003188, 0x00003195,
+	0x000031ab, 0x000031c7, 0x000031df, 0x00003202,
+	0x0000322a, 0x00003256, 0x000032e9, 0x00003367,
+	0x00003406, 0x00003468, 0x000034c9, 0x00003542,
+	0x0000359c, 0x000035dd, 0x00003648, 0x000036db,
+	0x00003733, 0x0000373f, 0x00003748, 0x00003786,
+	0x000037f9, 0x00003955, 0x000039a3, 0x000039b5,
+	0x000039cf, 0x00003a29, 0x00003a34, 0x00003aac,
+	0x00003af1, 0x00003b19, 0x00003b5d, 0x00003bfd,
+	0x00003cdd, 0x00003d00, 0x00003d17, 0x00003d35,
+	0x00003dbf, 0x00003dfd, 0x00003e37, 0x00003e66,
+	0x00003eb9, 0x00003eef, 0x00003f4c, 0x00003f70,
+	0x00003fbf, 0x00003ff5, 0x00004048, 0x0000407c,
+	0x000040ab, 0x000040ec, 0x0000414a, 0x00004187,
+	0x000041ba, 0x000041f3, 0x00004222, 0x00004270,
+	0x000042ba,
+} // Size: 924 bytes
 
-const ruData string = "" + // Size: 861 bytes
+const ruData string = "" + // Size: 17082 bytes
 	"\x02Ошибка! Пожлуйста, повторите запрос\x02Индекс Качества Воздуха (AQI)" +
 	"\x02Уведомлять меня об изменениях AQI\x02Детали\x02/airQualityIndex - Ин" +
 	"декс Качества Воздуха (AQI) для местоположения\x02/subsriptions - актив" +
 	"ные подписки\x02/about - информация о боте\x02Оправить геопозицию\x02По" +
 	"дписок: %[1]d.\x02Координаты: %[1]f;%[2]f. Последний AQI: %[3]s\x02Инде" +
 	"кс Качества Воздуха (AQI) для текущего местоположения.\x0aКонтакт: %[1]" +
-	"s\x02Поделитесь своим местоположением или начните с /start\x02Ошибка: %[" +
-	"1]v\x02%[1]s=%.2[2]f\x02Подписки удалены.\x02😌 AQI улучшился\x02😷 AQI ух" +
-	"удшился"
+	"s\x02Поделитесь своим местоположением или начните с /start\x02Подписки у" +
+	"далены.\x02😌 AQI улучшился\x02😷 AQI ухудшился\x02Нет последствий для зд" +
+	"оровья.\x02Некоторые загрязнители могут незначительно влиять на очень н" +
+	"емногих сверхчувствительных людей.\x02Здоровые люди могут испытывать лё" +
+	"гкое раздражение, а чувствительные люди будут затронуты в несколько бол" +
+	"ьшей степени.\x02Чувствительные люди будут испытывать более серьёзные п" +
+	"роблемы. Сердце и дыхательная система здоровых людей могут быть затрону" +
+	"ты.\x02У здоровых людей обычно проявляются симптомы. Люди с заболевания" +
+	"ми органов дыхания или сердца будут значительно затронуты, их выносливо" +
+	"сть при нагрузках снизится.\x02⚠️ Высокий уровень угарного газа (CO): %" +
+	".0[1]f мкг/м³. Избегайте оживлённых дорог и проветривайте помещения.\x02" +
+	"Ваши данные, сохранённые ботом\x02Да, удалить мои данные\x02Готово. Все" +
+	" ваши данные удалены.\x02Измерено только что\x02Измерено %[1]d мин. наза" +
+	"д\x02Измерено %[1]d ч. назад\x02Измерено %[1]d дн. назад\x02Хорошо. Соо" +
+	"бщения об AQI будут содержать текущую погоду\x02Хорошо. Сообщения об AQ" +
+	"I не будут содержать погоду\x02Использование: /weather on|off\x02Хороший" +
+	"\x02Удовлетворительный\x02Умеренный\x02Плохой\x02Очень плохой\x02Неизвес" +
+	"тно (%[1]d)\x02Нет информации о качестве воздуха.\x02Хорошо. Я буду про" +
+	"верять ваши подписки не чаще, чем раз в %[1]s\x02Хорошо. Я буду проверя" +
+	"ть ваши подписки каждые 30 минут\x02Использование: /frequency hourly|da" +
+	"ily|default или интервал, например 3h\x02Последняя проверка: %[1]s\x02Ещ" +
+	"ё не проверялась\x02AQI в радиусе %[1]d км от вашего местоположения:" +
+	"\x02📍 Здесь\x02⬆️ Север\x02➡️ Восток\x02⬇️ Юг\x02⬅️ Запад\x02нет данных" +
+	"\x02Сначала отправьте геопозицию: /airQualityIndex\x02Использование: /re" +
+	"fresh [N], где N - номер подписки в /subsriptions\x02У вас нет подписок " +
+	"для обновления\x02%[1]d. Координаты: %[2]f;%[3]f. AQI: %[4]s\x02%[1]d. " +
+	"Координаты: %[2]f;%[3]f. Не удалось получить AQI, повторите позже\x02OK" +
+	". Советы по здоровью даются для профиля: %[1]s\x02Использование: /profil" +
+	"e general|children|respiratory|elderly\x02Текущий профиль: %[1]s\x02Нет " +
+	"последствий для здоровья. Хорошее время для игр на улице.\x02Детям с ас" +
+	"тмой следует следить за симптомами во время активных игр.\x02Детям след" +
+	"ует делать перерывы во время долгих или интенсивных занятий на улице. Д" +
+	"етям с астмой следует держать ингалятор под рукой.\x02Детям следует изб" +
+	"егать долгих или интенсивных занятий на улице и по возможности играть в" +
+	" помещении.\x02Детям следует оставаться в помещении и держать окна закры" +
+	"тыми.\x02Люди с астмой или заболеваниями легких могут заметить симптомы" +
+	". Держите лекарства под рукой.\x02Сократите интенсивные занятия на улице" +
+	". Следуйте своему плану действий при появлении симптомов.\x02Избегайте з" +
+	"анятий на улице, держите окна закрытыми и следуйте своему плану действи" +
+	"й.\x02Оставайтесь в помещении и обратитесь к врачу, если симптомы усиля" +
+	"тся.\x02Пожилые люди с заболеваниями сердца или легких могут заметить л" +
+	"егкое влияние.\x02Пожилым людям следует сократить долгие или интенсивны" +
+	"е занятия на улице.\x02Пожилым людям следует избегать занятий на улице " +
+	"и следить за болью в груди или одышкой.\x02Пожилым людям следует остава" +
+	"ться в помещении и обратиться к врачу при плохом самочувствии.\x02Худши" +
+	"й AQI среди ваших подписок:\x02У вас пока нет подписок. Отправьте геопо" +
+	"зицию и нажмите \x22Уведомлять меня об изменениях AQI\x22\x02Использова" +
+	"ние: /check <широта> <долгота>, например /check 53.9 27.56\x02📅 Ваша не" +
+	"дельная сводка AQI\x02Координаты: %[1]f;%[2]f. Средний AQI: %.1[3]f, ма" +
+	"ксимум: %[4]s\x02OK. Вы будете получать недельную сводку AQI вместо уве" +
+	"домлений\x02OK. Я буду уведомлять вас об изменениях AQI\x02Использовани" +
+	"е: /digest on|off\x02На это местоположение вы уже подписаны. /subsripti" +
+	"ons\x02OK. Уведомления приостановлены до %[1]s\x02OK. Уведомления возобн" +
+	"овлены\x02Использование: /snooze <длительность>, например /snooze 24h, " +
+	"или /snooze off\x02Отправьте геопозицию, чтобы узнать Индекс Качества В" +
+	"оздуха и подписаться на его изменения. Команды:\x02/%[1]s - %[2]s\x02  " +
+	"  например %[1]s\x02Индекс Качества Воздуха для вашего местоположения" +
+	"\x02список ваших подписок\x02проверить подписки прямо сейчас\x02подписки" +
+	" с худшим AQI\x02AQI по координатам без их сохранения\x02AQI вокруг ваше" +
+	"го местоположения\x02концентрации загрязнителей в вашем местоположении" +
+	"\x02добавлять текущую погоду в сообщения AQI\x02как часто проверять подп" +
+	"иски\x02советы по здоровью для вашего профиля чувствительности\x02приос" +
+	"тановить уведомления\x02недельная сводка вместо уведомлений\x02скачать " +
+	"ваши данные\x02удалить ваши данные\x02о боте\x02список команд\x02%[1]s:" +
+	" %[2]s\x02Использование: /route и от 2 до %[1]d точек маршрута, по одной" +
+	" в строке или через |, например /route 53.9 27.56 | 53.92 27.6\x02AQI вд" +
+	"оль маршрута:\x02%[1]d. %.4[2]f;%.4[3]f: %[4]s\x02Хуже всего: точка %[1" +
+	"]d, %[2]s\x02Нет данных для точек маршрута. Пожалуйста, повторите!\x02AQ" +
+	"I вдоль маршрута из точек\x02Использование: /label N <текст>, где N — но" +
+	"мер подписки в /subsriptions. Без текста метка удаляется\x02Метка слишк" +
+	"ом длинная, допускается не более %[1]d символов\x02OK. Подписка %[1]d п" +
+	"омечена: %[2]s\x02OK. Метка подписки %[1]d удалена\x02🏷 %[1]s\x02назват" +
+	"ь подписку, например Дом\x02Эта команда отключена\x02📊 Ваш AQI за после" +
+	"дние %[1]d дней\x02У вас пока нет подписок. Отправьте геопозицию и подп" +
+	"ишитесь, чтобы получать статистику\x02Местоположение: %[1]f;%[2]f\x02Ср" +
+	"едний AQI: %.1[1]f, максимум: %[2]s, проверок: %[3]d\x02Уведомлений: %[" +
+	"1]d\x02Самый долгий период чистого воздуха: %[1]d ч\x02За этот период пр" +
+	"оверок пока не было\x02ваша статистика AQI за последнюю неделю\x02Конце" +
+	"нтрации загрязнителей:\x02%[1]s: %.2[2]f %[3]s\x02мкг/м³\x02🧪 Тестовое " +
+	"уведомление. Ваши оповещения выглядят так:\x02Использование: /test [N]," +
+	" где N — номер подписки в /subsriptions\x02У вас нет подписок для провер" +
+	"ки. Сначала отправьте геопозицию и подпишитесь\x02отправить тестовое ув" +
+	"едомление\x02⚠️ Предупреждение: плохое качество воздуха\x02🚨 Предупрежд" +
+	"ение: очень плохое качество воздуха\x02Ограничьте активность на улице и" +
+	" держите окна закрытыми\x02✅ Готово\x02❌ Не получилось. Пожалуйста, повт" +
+	"орите!\x02Хорошо. Я буду уведомлять вас о подписке не чаще, чем раз в %" +
+	"[1]s\x02Хорошо. Я буду уведомлять вас о каждом изменении AQI\x02Хорошо. " +
+	"Используется интервал по умолчанию: %[1]s\x02Использование: /cooldown <" +
+	"интервал>, например /cooldown 1h, /cooldown off или /cooldown default" +
+	"\x02минимальный интервал между уведомлениями\x02OWM AQI: %[1]d из 5, %[2" +
+	"]s\x02Самый высокий уровень загрязнителя: %[1]s, уровень %[2]d\x02OWM оц" +
+	"енивает воздух от 1 до 5 по концентрациям загрязнителей. Приложения со " +
+	"шкалой US EPA от 0 до 500 показывают другие числа для того же воздуха." +
+	"\x02число AQI от поставщика данных\x02🌡 %.1[1]f%[2]s, 💨 %.1[3]f %[4]s" +
+	"\x02м/с\x02миль/ч\x02Хорошо. Температура показывается в %[1]s, а скорост" +
+	"ь ветра в %[2]s\x02Использование: /units metric|imperial\x02метрические" +
+	" или имперские единицы погоды\x02Хорошо. Я сообщу вам, если AQI изменитс" +
+	"я в %[1]s (%.4[2]f;%.4[3]f). Текущий AQI: %[4]s. /subsriptions\x02Хорош" +
+	"о. Я сообщу вам, если AQI изменится в точке %.4[1]f;%.4[2]f. Текущий AQ" +
+	"I: %[3]s. /subsriptions\x02📈 Пики AQI за %[1]s\x02Координаты: %[1]f;%[2]" +
+	"f. Пик: %[3]s в %[4]s\x02Координаты: %[1]f;%[2]f. В этот день проверок н" +
+	"е было\x02Хорошо. Каждое утро вы будете получать пики AQI за предыдущий" +
+	" день\x02Хорошо. Я не буду присылать ежедневные пики AQI\x02Использовани" +
+	"е: /peaks on|off\x02пики AQI за предыдущий день каждое утро\x02%[1]s (%" +
+	"[2]s): %[3]s\x02Хорошо. AQI показывается по шкале %[1]s\x02Использование" +
+	": /scale owm|epa|aqhi\x02Вредно для чувствительных групп\x02Вредно\x02Оч" +
+	"ень вредно\x02Опасно\x02Низкий риск\x02Умеренный риск\x02Высокий риск" +
+	"\x02Очень высокий риск\x02шкала AQI: OWM, US EPA или AQHI\x02У вас %[1]d" +
+	" из %[2]d подписок\x02Вы достигли лимита в %[1]d подписок. Удалите их че" +
+	"рез /subsriptions, чтобы добавить новые\x02Я храню только данные, нужны" +
+	"е, чтобы сообщать вам о качестве воздуха:\x02• ваше последнее отправлен" +
+	"ное местоположение и язык, пока вы не удалите их через /forgetme\x02• к" +
+	"оординаты округляются до %[1]d знаков после запятой\x02• ваши подписки " +
+	"с последним AQI, пока вы не отпишетесь\x02• ваши подписки с последним A" +
+	"QI. Отменённые удаляются в течение %[1]s\x02• последние %[1]d измерений " +
+	"воздуха в ваших местах\x02• измерения воздуха в ваших местах\x02• истор" +
+	"ия AQI ваших подписок до %[1]s, для сводок и статистики\x02• исходные о" +
+	"тветы поставщика данных о воздухе для ваших координат в течение %[1]s" +
+	"\x02/export выгружает все ваши данные, /forgetme удаляет их.\x02%[1]d дн" +
+	".\x02%[1]d ч\x02какие данные хранятся и как долго\x02Бот на техническом " +
+	"обслуживании. Пожалуйста, попробуйте позже\x02Я не получил ваше местопо" +
+	"ложение. Если телефон спросит, разрешите Telegram доступ к местоположен" +
+	"ию или прикрепите его через 📎 → Геопозиция. Также можно отправить коорд" +
+	"инаты, например /air 53.9 27.56\x02Ваше сохранённое местоположение: %.5" +
+	"[1]f;%.5[2]f\x02Место: %[1]s\x02Обновлено: %[1]s\x02местоположение, для " +
+	"которого показывается ваш AQI\x02AQI: %[1]s\x02Хорошо. Я буду прикрепля" +
+	"ть метку на карте с AQI к вашим геопозициям\x02Хорошо. Больше никаких м" +
+	"еток на карте\x02Использование: /map on|off\x02прикреплять метку на кар" +
+	"те к AQI места\x02Это удалит ваше местоположение, настройки, избранное," +
+	" историю AQI и подписки. Вы уверены?\x02Использование: /favorite add [ме" +
+	"тка] сохраняет ваше последнее местоположение, /favorite remove N удаляе" +
+	"т избранное N, /favorite показывает их\x02Ваше избранное: %[1]d\x02%[1]" +
+	"d. %.5[2]f;%.5[3]f\x02%[1]d. %[2]s: %.5[3]f;%.5[4]f\x02У вас нет избранн" +
+	"ого. Отправьте геопозицию и сохраните её через /favorite add Дом\x02/su" +
+	"bscribe_all подписывает на все из них\x02Хорошо. Избранное %[1]d сохране" +
+	"но\x02Это место уже в избранном\x02Можно сохранить не более %[1]d мест " +
+	"в избранном\x02Хорошо. Избранное %[1]d удалено\x02Оформлена подписка на" +
+	" %[1]d из %[2]d мест из избранного\x02Уже в подписках: %[1]d\x02сохранит" +
+	"ь ваше местоположение в избранное\x02подписаться на всё избранное\x02• " +
+	"ваши избранные места, пока вы их не удалите\x02📈 Резкий рост загрязните" +
+	"ля\x02%[1]s вырос с %.2[2]f до %.2[3]f %[4]s\x02Хорошо. Восстановлено п" +
+	"одписок: %[1]d\x02Нет недавно удалённых подписок для восстановления\x02" +
+	"Удалили по ошибке? /restore вернёт их\x02вернуть удалённые подписки\x02" +
+	"Хорошо. AQI показывается так: %[1]s\x02Использование: /theme emoji|plai" +
+	"n\x02показывать AQI с эмодзи или простым текстом\x02• измерения воздуха " +
+	"удаляются через %[1]s"
 
-	// Total table size 2676 bytes (2KiB); checksum: 6702F8D3
+	// Total table size 46951 bytes (45KiB); checksum: 6B37941D
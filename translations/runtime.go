@@ -0,0 +1,120 @@
+package translations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// compiledDictionaries are the dictionaries of catalog.go, in sync with the -lang flag of go:generate
+var compiledDictionaries = map[string]catalog.Dictionary{
+	"be": &dictionary{index: beIndex, data: beData},
+	"en": &dictionary{index: enIndex, data: enData},
+	"ru": &dictionary{index: ruIndex, data: ruData},
+}
+
+// rawMessage is the prefix of a message in the catalog data rendered as is. gotext encodes the messages without plurals this way.
+const rawMessage = "\x02"
+
+// argIndexRe matches the explicit argument index of a verb in a placeholder, e.g. [1] of %[1]d
+var argIndexRe = regexp.MustCompile(`%([^\[a-zA-Z%]*)\[\d+\]`)
+
+// messageFile is a gotext messages file, e.g. locales/ru/messages.gotext.json
+type messageFile struct {
+	Language string `json:"language"`
+	Messages []struct {
+		Message      string `json:"message"`
+		Translation  string `json:"translation"`
+		Placeholders []struct {
+			ID     string `json:"id"`
+			String string `json:"string"`
+		} `json:"placeholders"`
+	} `json:"messages"`
+}
+
+// overlay looks up the messages loaded at runtime first and the compiled ones then
+type overlay struct {
+	messages map[string]string
+	base     catalog.Dictionary
+}
+
+func (o *overlay) Lookup(key string) (string, bool) {
+	if data, ok := o.messages[key]; ok {
+		return data, true
+	}
+	if o.base == nil {
+		return "", false
+	}
+	return o.base.Lookup(key)
+}
+
+// LoadDir merges the translations of the gotext JSON files in the dir and its subdirectories into message.DefaultCatalog.
+// They override the compiled ones of the same message and may add languages. Messages without a translation are skipped.
+// It returns the number of the loaded translations.
+func LoadDir(dir string) (int, error) {
+	overlays := map[string]*overlay{}
+	for lang, d := range compiledDictionaries {
+		overlays[lang] = &overlay{messages: map[string]string{}, base: d}
+	}
+
+	loaded := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var f messageFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+		tag, err := language.Parse(f.Language)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		o, ok := overlays[tag.String()]
+		if !ok {
+			o = &overlay{messages: map[string]string{}}
+			overlays[tag.String()] = o
+		}
+		for _, m := range f.Messages {
+			if m.Translation == "" {
+				continue
+			}
+			msg, translation := m.Message, m.Translation
+			for _, ph := range m.Placeholders {
+				msg = strings.ReplaceAll(msg, "{"+ph.ID+"}", ph.String)
+				translation = strings.ReplaceAll(translation, "{"+ph.ID+"}", ph.String)
+			}
+			// the key is the format of the source, e.g. "%d" for the placeholder "%[1]d"
+			key := argIndexRe.ReplaceAllString(msg, "%$1")
+			o.messages[key] = rawMessage + translation
+			loaded++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("LoadDir: %w", err)
+	}
+
+	dict := map[string]catalog.Dictionary{}
+	for lang, o := range overlays {
+		dict[lang] = o
+	}
+	cat, err := catalog.NewFromMap(dict, catalog.Fallback(language.English))
+	if err != nil {
+		return 0, fmt.Errorf("LoadDir: %w", err)
+	}
+	message.DefaultCatalog = cat
+	return loaded, nil
+}
@@ -0,0 +1,104 @@
+package translations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// writeMessageFile writes a gotext messages file to the path relative to the dir
+func writeMessageFile(t *testing.T, dir, path, content string) {
+	t.Helper()
+	path = filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	compiled := message.DefaultCatalog
+	t.Cleanup(func() { message.DefaultCatalog = compiled })
+
+	dir := t.TempDir()
+	writeMessageFile(t, dir, "ru/messages.gotext.json", `{
+		"language": "ru",
+		"messages": [
+			{"message": "Error! Please, retry!", "translation": "Ошибка! Повторите, пожалуйста"},
+			{"message": "You have {Lensubs} subscription(s)", "translation": "У вас подписок: {Lensubs}",
+				"placeholders": [{"id": "Lensubs", "string": "%[1]d"}]},
+			{"message": "Air Quality Index", "translation": ""}
+		]
+	}`)
+	writeMessageFile(t, dir, "uk/messages.gotext.json", `{
+		"language": "uk",
+		"messages": [{"message": "Details", "translation": "Деталі"}]
+	}`)
+	writeMessageFile(t, dir, "README.md", "not a messages file")
+
+	n, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("LoadDir() = %d, want 3 translations", n)
+	}
+
+	tests := []struct {
+		name string
+		lang language.Tag
+		key  string
+		args []interface{}
+		want string
+	}{
+		{name: "override", lang: language.Russian, key: "Error! Please, retry!", want: "Ошибка! Повторите, пожалуйста"},
+		{name: "override with placeholder", lang: language.Russian, key: "You have %d subscription(s)", args: []interface{}{2}, want: "У вас подписок: 2"},
+		{name: "empty translation keeps compiled", lang: language.Russian, key: "Air Quality Index", want: "Индекс Качества Воздуха (AQI)"},
+		{name: "compiled fallback", lang: language.Russian, key: "Details", want: "Детали"},
+		{name: "added language", lang: language.Ukrainian, key: "Details", want: "Деталі"},
+		{name: "added language falls back to English", lang: language.Ukrainian, key: "Air Quality Index", want: "Air Quality Index"},
+		{name: "other languages untouched", lang: language.English, key: "Error! Please, retry!", want: "Error! Please, retry!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := message.NewPrinter(tt.lang)
+			if got := p.Sprintf(tt.key, tt.args...); got != tt.want {
+				t.Errorf("Sprintf(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDirMalformed(t *testing.T) {
+	compiled := message.DefaultCatalog
+	t.Cleanup(func() { message.DefaultCatalog = compiled })
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "invalid JSON", content: `{"language": "ru", "messages": [`},
+		{name: "invalid language", content: `{"language": "not a language!", "messages": []}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeMessageFile(t, dir, "messages.gotext.json", tt.content)
+			if _, err := LoadDir(dir); err == nil {
+				t.Error("LoadDir() = nil error, want an error")
+			}
+			if message.DefaultCatalog != compiled {
+				t.Error("LoadDir() replaced the catalog on an error")
+			}
+		})
+	}
+
+	if _, err := LoadDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadDir() of a missing dir = nil error, want an error")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/text/message"
+)
+
+// Unit is a unit of the measurements in the messages. It is localized by the message catalog.
+// Pollutant concentrations are in μg/m³ in all the unit systems as in OWM API.
+type Unit string
+
+const (
+	UnitCelsius                 Unit = "°C"
+	UnitFahrenheit              Unit = "°F"
+	UnitMetersPerSecond         Unit = "m/s"
+	UnitMilesPerHour            Unit = "mph"
+	UnitMicrogramsPerCubicMeter Unit = "μg/m³"
+)
+
+// UnitSystem selects the units of the measurements in the messages
+type UnitSystem string
+
+const (
+	UnitsMetric   UnitSystem = "metric"
+	UnitsImperial UnitSystem = "imperial"
+)
+
+const (
+	unitsSetTmpl  = "OK. The temperature is shown in %s and the wind speed in %s"
+	unitsUsageMsg = "Usage: /units metric|imperial"
+)
+
+// Temperature returns the temperature unit of the system
+func (us UnitSystem) Temperature() Unit {
+	if us == UnitsImperial {
+		return UnitFahrenheit
+	}
+	return UnitCelsius
+}
+
+// Speed returns the wind speed unit of the system
+func (us UnitSystem) Speed() Unit {
+	if us == UnitsImperial {
+		return UnitMilesPerHour
+	}
+	return UnitMetersPerSecond
+}
+
+// FromMetric converts the value of the metric unit of OWM API to the unit: °C to °F and m/s to mph.
+// Values of the metric units are returned as is.
+func (u Unit) FromMetric(value float64) float64 {
+	switch u {
+	case UnitFahrenheit:
+		return value*9/5 + 32
+	case UnitMilesPerHour:
+		return value * 3600 / 1609.344
+	}
+	return value
+}
+
+// Localized returns the unit in the language of the printer
+func (u Unit) Localized(p *message.Printer) string {
+	return p.Sprintf(string(u))
+}
+
+func parseUnitSystem(arg string) (UnitSystem, error) {
+	switch us := UnitSystem(strings.ToLower(strings.TrimSpace(arg))); us {
+	case UnitsMetric, UnitsImperial:
+		return us, nil
+	}
+	return "", fmt.Errorf("unknown units %q", arg)
+}
+
+// unitsText stores the UnitSystem of the /units argument in UserPrefs
+func (bot *Bot) unitsText(chatID int64, arg string, p *message.Printer) string {
+	units, err := parseUnitSystem(arg)
+	if err != nil {
+		return p.Sprintf(unitsUsageMsg)
+	}
+	prefs, err := bot.store.GetUserPrefs(chatID)
+	if err != nil {
+		log.Print("GetUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	prefs.Units = units
+	if err := bot.store.UpdateUserPrefs(prefs); err != nil {
+		log.Print("UpdateUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	return p.Sprintf(unitsSetTmpl, units.Temperature().Localized(p), units.Speed().Localized(p))
+}
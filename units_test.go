@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestUnitFromMetric(t *testing.T) {
+	tests := []struct {
+		unit  Unit
+		value float64
+		want  float64
+	}{
+		{unit: UnitCelsius, value: 21.5, want: 21.5},
+		{unit: UnitFahrenheit, value: 0, want: 32},
+		{unit: UnitFahrenheit, value: -40, want: -40},
+		{unit: UnitFahrenheit, value: 100, want: 212},
+		{unit: UnitMetersPerSecond, value: 3, want: 3},
+		{unit: UnitMilesPerHour, value: 1609.344 / 3600, want: 1},
+		{unit: UnitMilesPerHour, value: 10, want: 22.369363},
+		{unit: UnitMicrogramsPerCubicMeter, value: 68.66, want: 68.66},
+	}
+	for _, tt := range tests {
+		if got := tt.unit.FromMetric(tt.value); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s.FromMetric(%v) = %v, want %v", tt.unit, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestUnitSystem(t *testing.T) {
+	tests := []struct {
+		arg         string
+		want        UnitSystem
+		wantErr     bool
+		temperature Unit
+		speed       Unit
+	}{
+		{arg: "metric", want: UnitsMetric, temperature: UnitCelsius, speed: UnitMetersPerSecond},
+		{arg: "imperial", want: UnitsImperial, temperature: UnitFahrenheit, speed: UnitMilesPerHour},
+		{arg: "Imperial", want: UnitsImperial, temperature: UnitFahrenheit, speed: UnitMilesPerHour},
+		{arg: " METRIC ", want: UnitsMetric, temperature: UnitCelsius, speed: UnitMetersPerSecond},
+		// UserPrefs of the users who never chose default to metric
+		{arg: "", wantErr: true, temperature: UnitCelsius, speed: UnitMetersPerSecond},
+		{arg: "kelvin", wantErr: true, temperature: UnitCelsius, speed: UnitMetersPerSecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parseUnitSystem(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseUnitSystem() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseUnitSystem() = %q, want %q", got, tt.want)
+			}
+			if got.Temperature() != tt.temperature || got.Speed() != tt.speed {
+				t.Errorf("%q units = %s, %s, want %s, %s", got, got.Temperature(), got.Speed(), tt.temperature, tt.speed)
+			}
+		})
+	}
+}
+
+func TestUnitsText(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	tests := []struct {
+		arg   string
+		want  string
+		units UnitSystem
+	}{
+		{arg: "Imperial", want: "OK. The temperature is shown in °F and the wind speed in mph", units: UnitsImperial},
+		{arg: "metric", want: "OK. The temperature is shown in °C and the wind speed in m/s", units: UnitsMetric},
+		{arg: "kelvin", want: unitsUsageMsg, units: UnitsMetric},
+	}
+	store := newTestStore(t)
+	bot := newTestBot(store, &botServices{})
+	for _, tt := range tests {
+		if got := bot.unitsText(1, tt.arg, p); got != tt.want {
+			t.Errorf("unitsText(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+		prefs, err := store.GetUserPrefs(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prefs.Units != tt.units {
+			t.Errorf("units after %q = %q, want %q", tt.arg, prefs.Units, tt.units)
+		}
+	}
+
+	ru := message.NewPrinter(language.Russian)
+	if got := bot.unitsText(1, "metric", ru); got == bot.unitsText(1, "metric", p) {
+		t.Errorf("unitsText() in Russian = %q, the same as in English", got)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golang.org/x/text/message"
+)
+
+const (
+	venueTitleTmpl = "AQI: %s"
+	mapOnText      = "OK. I will attach a map pin with the AQI to your shared locations"
+	mapOffText     = "OK. No more map pins"
+	mapUsageMsg    = "Usage: /map on|off"
+)
+
+// aqiVenue returns a venue of the location with the AQI in the theme in the title, opening a map on a tap.
+// The address is the place name or the coordinates.
+func aqiVenue(chatID int64, location *Location, aqi AirQualityIndex, place *Place, theme Theme, p *message.Printer) tgbotapi.VenueConfig {
+	address := fmt.Sprintf("%.5f;%.5f", location.Latitude, location.Longitude)
+	if place != nil && place.Name != "" {
+		address = place.String()
+	}
+	return tgbotapi.NewVenue(chatID, p.Sprintf(venueTitleTmpl, theme.LocalizedAQI(aqi, p)), address, location.Latitude, location.Longitude)
+}
+
+// sendAQIVenue sends the venue of the location with the AQI to the chat
+func (bot *Bot) sendAQIVenue(chatID int64, location *Location, aqi AirQualityIndex, p *message.Printer) {
+	// the coordinates are the address if the place is unknown
+	var place *Place
+	if bot.geocoder != nil {
+		var err error
+		place, err = bot.geocoder.ReverseGeocode(location)
+		if err != nil && !errors.Is(err, errPlaceNotFound) {
+			log.Print("ReverseGeocode: ", err)
+		}
+	}
+	bot.Send(aqiVenue(chatID, location, aqi, place, bot.themeOf(chatID), p))
+}
+
+// setMapText switches the venues attached to the AQI of the shared locations
+func (bot *Bot) setMapText(chatID int64, arg string, p *message.Printer) string {
+	var on bool
+	switch strings.TrimSpace(arg) {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return p.Sprintf(mapUsageMsg)
+	}
+	prefs, err := bot.store.GetUserPrefs(chatID)
+	if err != nil {
+		log.Print("GetUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	prefs.Map = on
+	if err := bot.store.UpdateUserPrefs(prefs); err != nil {
+		log.Print("UpdateUserPrefs: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+	if on {
+		return p.Sprintf(mapOnText)
+	}
+	return p.Sprintf(mapOffText)
+}
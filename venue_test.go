@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestAQIVenue(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	minsk := &Location{53.9, 27.56}
+	tests := []struct {
+		name     string
+		location *Location
+		place    *Place
+		theme    Theme
+		aqi      AirQualityIndex
+		title    string
+		address  string
+	}{
+		{name: "place", location: minsk, place: &Place{Name: "Minsk", Country: "BY"}, theme: ThemeEmoji, aqi: 2,
+			title: "AQI: " + ThemeEmoji.LocalizedAQI(2, p), address: "Minsk, BY"},
+		{name: "no place", location: &Location{-33.8688, 151.2093}, theme: ThemeEmoji, aqi: 4,
+			title: "AQI: " + ThemeEmoji.LocalizedAQI(4, p), address: "-33.86880;151.20930"},
+		{name: "place not found", location: minsk, place: &Place{}, theme: ThemeEmoji, aqi: 1,
+			title: "AQI: " + ThemeEmoji.LocalizedAQI(1, p), address: "53.90000;27.56000"},
+		{name: "plain", location: minsk, theme: ThemePlain, aqi: 3,
+			title: "AQI: " + ThemePlain.LocalizedAQI(3, p), address: "53.90000;27.56000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			venue := aqiVenue(1, tt.location, tt.aqi, tt.place, tt.theme, p)
+			if venue.ChatID != 1 || venue.Latitude != tt.location.Latitude || venue.Longitude != tt.location.Longitude {
+				t.Errorf("aqiVenue() = chat %d at %v;%v, want chat 1 at %v;%v",
+					venue.ChatID, venue.Latitude, venue.Longitude, tt.location.Latitude, tt.location.Longitude)
+			}
+			if venue.Title != tt.title || venue.Address != tt.address {
+				t.Errorf("aqiVenue() = %q at %q, want %q at %q", venue.Title, venue.Address, tt.title, tt.address)
+			}
+		})
+	}
+}
+
+func TestMapVenue(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		reply    string
+		geocoder Geocoder
+		// address of the venue sent with the AQI, none if empty
+		address string
+	}{
+		{name: "off by default", address: ""},
+		{name: "on", cmd: "/map on", reply: mapOnText, geocoder: &fakeGeocoder{place: Place{Name: "Minsk"}}, address: "Minsk"},
+		{name: "on without geocoder", cmd: "/map on", reply: mapOnText, address: "53.90000;27.56000"},
+		{name: "geocoding error", cmd: "/map on", reply: mapOnText, geocoder: &fakeGeocoder{err: errors.New("timeout")}, address: "53.90000;27.56000"},
+		{name: "off", cmd: "/map off", reply: mapOffText},
+		{name: "usage", cmd: "/map maybe", reply: mapUsageMsg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, newTestStore(t), &botServices{wAPI: &fakeAQI{aqi: 2}, geocoder: tt.geocoder}, fake)
+			if tt.cmd != "" {
+				bot.handleMessage(newTestCommand(1, tt.cmd))
+				if got := fake.lastText(); got != tt.reply {
+					t.Errorf("%s = %q, want %q", tt.cmd, got, tt.reply)
+				}
+			}
+
+			bot.handleMessage(newTestLocationMessage(1, &Location{53.9, 27.56}))
+
+			venues := fake.sent("sendVenue")
+			if tt.address == "" {
+				if len(venues) != 0 {
+					t.Errorf("venues sent %v, want none", venues)
+				}
+				return
+			}
+			if len(venues) != 1 {
+				t.Fatalf("venues sent %v, want one", venues)
+			}
+			v := venues[0]
+			if v.Get("chat_id") != "1" || v.Get("latitude") != "53.900000" || v.Get("longitude") != "27.560000" {
+				t.Errorf("venue = %v, want chat 1 at 53.9;27.56", v)
+			}
+			if v.Get("address") != tt.address {
+				t.Errorf("venue address = %q, want %q", v.Get("address"), tt.address)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// webhookSecretHeader is the header Telegram sends the secret_token of the webhook in
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// RunWebhook registers publicURL as the Telegram webhook and serves Updates on listenAddr until Stop is called.
+// Telegram sends the secret with each Update, the requests without it are rejected. A random one is generated
+// if the secret is empty. Only the path of publicURL is served, the service endpoints of HTTPHandler are not public.
+func (bot *Bot) RunWebhook(listenAddr, publicURL, secret string) error {
+	wh, err := tgbotapi.NewWebhook(publicURL)
+	if err != nil {
+		return fmt.Errorf("parsing webhook URL: %w", err)
+	}
+	if secret == "" {
+		if secret, err = newWebhookSecret(); err != nil {
+			return fmt.Errorf("generating webhook secret: %w", err)
+		}
+	}
+	// the WebhookConfig of tgbotapi has no secret_token
+	params := tgbotapi.Params{"url": wh.URL.String(), "secret_token": secret}
+	if _, err := bot.tApi.MakeRequest("setWebhook", params); err != nil {
+		return fmt.Errorf("setting webhook: %w", err)
+	}
+
+	srv := &http.Server{Addr: listenAddr, Handler: bot.webhookMux(wh.URL.Path, secret)}
+	go func() {
+		<-bot.stop
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Print("webhook server shutdown: ", err)
+		}
+	}()
+
+	log.Printf("Listening for webhook %s on %s", wh.URL.Redacted(), listenAddr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("webhook server: %w", err)
+	}
+	return nil
+}
+
+// newWebhookSecret returns a random secret_token of the webhook
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// webhookMux returns the handler serving only webhookHandler on the path
+func (bot *Bot) webhookMux(path, secret string) *http.ServeMux {
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, bot.webhookHandler(secret))
+	return mux
+}
+
+// webhookHandler returns the handler of the Updates POSTed by Telegram with the secret
+func (bot *Bot) webhookHandler(secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookSecretHeader)), []byte(secret)) != 1 {
+			log.Print("webhook: wrong secret token from ", r.RemoteAddr)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		update, err := bot.tApi.HandleUpdate(r)
+		if err != nil {
+			log.Print("webhook: ", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		go bot.handleUpdate(*update)
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookMux(t *testing.T) {
+	tests := []struct {
+		path string
+		want int
+	}{
+		// the webhook is reached, the requests without the secret are rejected
+		{path: "/webhook", want: http.StatusUnauthorized},
+		{path: "/healthz", want: http.StatusNotFound},
+		{path: "/readyz", want: http.StatusNotFound},
+		{path: "/metrics", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			bot := newFakeTelegramBot(t, newTestStore(t), &botServices{}, &fakeTelegram{})
+
+			rec := httptest.NewRecorder()
+			bot.webhookMux("/webhook", "s3cret").ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("{}")))
+			if rec.Code != tt.want {
+				t.Errorf("POST %s = %d, want %d: %s", tt.path, rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	// the update of the chat blocking the bot
+	const kicked = `{"update_id":1,"my_chat_member":{"chat":{"id":1,"type":"private"},"from":{"id":1},"date":1700000000,` +
+		`"old_chat_member":{"user":{"id":2},"status":"member"},"new_chat_member":{"user":{"id":2},"status":"kicked"}}}`
+	tests := []struct {
+		name   string
+		method string
+		// secret is the header value, the header is omitted if empty
+		secret string
+		body   string
+		want   int
+	}{
+		{name: "update", method: http.MethodPost, secret: "s3cret", body: kicked, want: http.StatusOK},
+		{name: "wrong secret", method: http.MethodPost, secret: "guess", body: kicked, want: http.StatusUnauthorized},
+		{name: "missing secret", method: http.MethodPost, body: kicked, want: http.StatusUnauthorized},
+		{name: "GET", method: http.MethodGet, secret: "s3cret", want: http.StatusMethodNotAllowed},
+		{name: "malformed update", method: http.MethodPost, secret: "s3cret", body: "{", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			addTestSubscription(t, store, 1, &Location{53.9, 27.56}, 2)
+			bot := newFakeTelegramBot(t, store, &botServices{}, &fakeTelegram{})
+
+			r := httptest.NewRequest(tt.method, "/webhook", strings.NewReader(tt.body))
+			if tt.secret != "" {
+				r.Header.Set(webhookSecretHeader, tt.secret)
+			}
+			rec := httptest.NewRecorder()
+			bot.webhookHandler("s3cret").ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Fatalf("%s = %d, want %d: %s", tt.method, rec.Code, tt.want, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			// the update is handled in background
+			for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+				subs, err := store.ListAQISubscriptions(1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(*subs) == 0 {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("the update is not handled")
+				}
+			}
+		})
+	}
+}
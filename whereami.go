@@ -0,0 +1,41 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+
+	"golang.org/x/text/message"
+)
+
+const (
+	whereAmITmpl        = "Your stored location: %.5f;%.5f"
+	whereAmIPlaceTmpl   = "Place: %s"
+	whereAmIUpdatedTmpl = "Updated: %s"
+)
+
+// whereAmIText reports the location of the UserSession of the chat, the AQI of which is shown, with the place name
+func (bot *Bot) whereAmIText(chatID int64, p *message.Printer) string {
+	us, err := bot.store.GetSessionByChatID(chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p.Sprintf(nearbyNoSessionMsg)
+	}
+	if err != nil {
+		log.Print("GetSessionByChatID: ", err)
+		return p.Sprintf(safeToRetryErrMsg)
+	}
+
+	msgText := []string{p.Sprintf(whereAmITmpl, us.Latitude, us.Longitude)}
+	// the coordinates are still shown if the place is unknown
+	if bot.geocoder != nil {
+		place, err := bot.geocoder.ReverseGeocode(us.Location())
+		if err == nil && place.Name != "" {
+			msgText = append(msgText, p.Sprintf(whereAmIPlaceTmpl, place.String()))
+		} else if err != nil && !errors.Is(err, errPlaceNotFound) {
+			log.Print("ReverseGeocode: ", err)
+		}
+	}
+	msgText = append(msgText, p.Sprintf(whereAmIUpdatedTmpl, us.CreatedAt.Format(lastCheckedLayout)))
+	return strings.Join(msgText, "\n")
+}
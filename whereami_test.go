@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestWhereAmI(t *testing.T) {
+	p := message.NewPrinter(language.English)
+	updated := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		session  *Location
+		geocoder Geocoder
+		want     []string
+	}{
+		{name: "no session", geocoder: &fakeGeocoder{place: Place{Name: "Minsk"}}, want: []string{p.Sprintf(nearbyNoSessionMsg)}},
+		{name: "place", session: &Location{53.9, 27.56}, geocoder: &fakeGeocoder{place: Place{Name: "Minsk", Country: "BY"}},
+			want: []string{"Your stored location: 53.90000;27.56000", "Place: Minsk, BY", "Updated: 2024-03-01 08:30 UTC"}},
+		{name: "place not found", session: &Location{-33.8688, 151.2093}, geocoder: &fakeGeocoder{err: errPlaceNotFound},
+			want: []string{"Your stored location: -33.86880;151.20930", "Updated: 2024-03-01 08:30 UTC"}},
+		{name: "geocoding error", session: &Location{53.9, 27.56}, geocoder: &fakeGeocoder{err: errors.New("geocoding is down")},
+			want: []string{"Your stored location: 53.90000;27.56000", "Updated: 2024-03-01 08:30 UTC"}},
+		{name: "no geocoder", session: &Location{53.9, 27.56},
+			want: []string{"Your stored location: 53.90000;27.56000", "Updated: 2024-03-01 08:30 UTC"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			if tt.session != nil {
+				addTestSession(t, store, 1, tt.session)
+				if _, err := store.DB.Exec("UPDATE user_session SET created_at=? WHERE chatid=1", updated); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fake := &fakeTelegram{}
+			bot := newFakeTelegramBot(t, store, &botServices{geocoder: tt.geocoder}, fake)
+
+			bot.handleMessage(newTestCommand(1, "/whereami"))
+
+			if got, want := fake.lastText(), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("/whereami = %q, want %q", got, want)
+			}
+		})
+	}
+
+	// the stored location is the last shared one
+	store := newTestStore(t)
+	bot := newFakeTelegramBot(t, store, &botServices{wAPI: &fakeAQI{aqi: 2}}, &fakeTelegram{})
+	bot.handleMessage(newTestLocationMessage(1, &Location{53.9, 27.56}))
+	bot.handleMessage(newTestLocationMessage(1, &Location{52.1, 23.7}))
+	if got := bot.whereAmIText(1, p); !strings.HasPrefix(got, "Your stored location: 52.10000;23.70000\n") {
+		t.Errorf("whereAmIText() = %q, want the last shared location", got)
+	}
+
+	ru := message.NewPrinter(language.Russian)
+	if got := bot.whereAmIText(1, ru); got == bot.whereAmIText(1, p) {
+		t.Errorf("whereAmIText() in Russian = %q, the same as in English", got)
+	}
+}